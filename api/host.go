@@ -3,7 +3,10 @@ package api
 import (
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
@@ -21,6 +24,15 @@ var (
 	// storage folder which does not appear to exist within the storage
 	// manager.
 	errStorageFolderNotFound = errors.New("storage folder with the provided path could not be found")
+
+	// obligationStatuses maps the values of the status parameter of
+	// /host/contracts to the obligation statuses they select.
+	obligationStatuses = map[string]uint64{
+		"unresolved": modules.ObligationStatusUnresolved,
+		"rejected":   modules.ObligationStatusRejected,
+		"succeeded":  modules.ObligationStatusSucceeded,
+		"failed":     modules.ObligationStatusFailed,
+	}
 )
 
 type (
@@ -35,6 +47,33 @@ type (
 		WorkingStatus        modules.HostWorkingStatus        `json:"workingstatus"`
 	}
 
+	// HostBandwidthGET contains the traffic of the host, per RPC, per
+	// contract, and over time.
+	HostBandwidthGET struct {
+		modules.HostBandwidth
+	}
+
+	// HostLedgerGET contains the entries of the host's financial ledger.
+	HostLedgerGET struct {
+		Entries []modules.HostLedgerEntry `json:"entries"`
+	}
+
+	// HostMaintenanceGET contains the scheduled maintenance of the host and the
+	// storage obligations that it affects.
+	HostMaintenanceGET struct {
+		modules.HostMaintenance
+	}
+
+	// HostContractsGET contains the storage obligations of the host.
+	HostContractsGET struct {
+		Contracts []modules.StorageObligation `json:"contracts"`
+	}
+
+	// HostContractGET contains a single storage obligation of the host.
+	HostContractGET struct {
+		Contract modules.StorageObligation `json:"contract"`
+	}
+
 	// HostEstimateScoreGET contains the information that is returned from a
 	// /host/estimatescore call.
 	HostEstimateScoreGET struct {
@@ -81,6 +120,146 @@ func (api *API) hostHandlerGET(w http.ResponseWriter, req *http.Request, _ httpr
 	WriteJSON(w, hg)
 }
 
+// hostBandwidthHandlerGET handles GET requests to the /host/bandwidth API
+// endpoint, returning the traffic of the host.
+func (api *API) hostBandwidthHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, HostBandwidthGET{
+		HostBandwidth: api.host.Bandwidth(),
+	})
+}
+
+// hostLedgerHandlerGET handles GET requests to the /host/ledger API endpoint,
+// returning the entries of the host's financial ledger. The entries can be
+// limited to a range of block heights and to a range of unix timestamps.
+func (api *API) hostLedgerHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	// Parse the ranges. Bounds that are not provided are unlimited.
+	var startHeight, startTime uint64
+	endHeight, endTime := uint64(math.MaxUint64), uint64(math.MaxUint64)
+	bounds := map[string]*uint64{
+		"startheight": &startHeight,
+		"endheight":   &endHeight,
+		"starttime":   &startTime,
+		"endtime":     &endTime,
+	}
+	for param, bound := range bounds {
+		if req.FormValue(param) == "" {
+			continue
+		}
+		value, err := strconv.ParseUint(req.FormValue(param), 10, 64)
+		if err != nil {
+			WriteError(w, Error{"parsing integer value for parameter `" + param + "` failed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		*bound = value
+	}
+
+	entries, err := api.host.LedgerEntries(types.BlockHeight(startHeight), types.BlockHeight(endHeight))
+	if err != nil {
+		WriteError(w, Error{"error when calling /host/ledger: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	filtered := entries[:0]
+	for _, entry := range entries {
+		timestamp := uint64(entry.Timestamp.Unix())
+		if timestamp >= startTime && timestamp <= endTime {
+			filtered = append(filtered, entry)
+		}
+	}
+	WriteJSON(w, HostLedgerGET{
+		Entries: filtered,
+	})
+}
+
+// hostMaintenanceHandlerGET handles GET requests to the /host/maintenance API
+// endpoint, returning the scheduled maintenance of the host.
+func (api *API) hostMaintenanceHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, HostMaintenanceGET{
+		HostMaintenance: api.host.Maintenance(),
+	})
+}
+
+// hostMaintenanceHandlerPOST handles POST requests to the /host/maintenance
+// API endpoint, scheduling a maintenance of the host between the provided unix
+// timestamps.
+func (api *API) hostMaintenanceHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var shutdown, restart int64
+	_, err := fmt.Sscan(req.FormValue("shutdown"), &shutdown)
+	if err != nil {
+		WriteError(w, Error{"parsing integer value for parameter `shutdown` failed: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	_, err = fmt.Sscan(req.FormValue("restart"), &restart)
+	if err != nil {
+		WriteError(w, Error{"parsing integer value for parameter `restart` failed: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	err = api.host.StartMaintenance(time.Unix(shutdown, 0), time.Unix(restart, 0))
+	if err != nil {
+		WriteError(w, Error{"error when calling /host/maintenance: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// hostMaintenanceStopHandler handles POST requests to the
+// /host/maintenance/stop API endpoint, ending the maintenance of the host.
+func (api *API) hostMaintenanceStopHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	err := api.host.StopMaintenance()
+	if err != nil {
+		WriteError(w, Error{"error when calling /host/maintenance/stop: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// hostContractsHandlerGET handles GET requests to the /host/contracts API
+// endpoint, returning the storage obligations of the host. The obligations can
+// be filtered by status.
+func (api *API) hostContractsHandlerGET(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var status uint64
+	filter := req.FormValue("status") != ""
+	if filter {
+		var exists bool
+		status, exists = obligationStatuses[req.FormValue("status")]
+		if !exists {
+			WriteError(w, Error{"unknown obligation status: " + req.FormValue("status")}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	contracts := []modules.StorageObligation{}
+	for _, so := range api.host.StorageObligations() {
+		if filter && so.ObligationStatus != status {
+			continue
+		}
+		contracts = append(contracts, so)
+	}
+	WriteJSON(w, HostContractsGET{
+		Contracts: contracts,
+	})
+}
+
+// hostContractHandlerGET handles GET requests to the /host/contracts/:id API
+// endpoint, returning a single storage obligation of the host.
+func (api *API) hostContractHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	id, err := scanHash(ps.ByName("id"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse contract id: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	contract, err := api.host.StorageObligation(types.FileContractID(id))
+	if err == modules.ErrNoStorageObligation {
+		WriteError(w, Error{err.Error()}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, HostContractGET{
+		Contract: contract,
+	})
+}
+
 // parseHostSettings a request's query strings and returns a
 // modules.HostInternalSettings configured with the request's query string
 // parameters.
@@ -194,6 +373,87 @@ func (api *API) parseHostSettings(req *http.Request) (modules.HostInternalSettin
 		settings.MinUploadBandwidthPrice = x
 	}
 
+	if req.FormValue("autopricing") != "" {
+		var x bool
+		_, err := fmt.Sscan(req.FormValue("autopricing"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.AutoPricing = x
+	}
+	if req.FormValue("autopricingcurve") != "" {
+		var x float64
+		_, err := fmt.Sscan(req.FormValue("autopricingcurve"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.AutoPricingCurve = x
+	}
+	if req.FormValue("autopricingdemandtarget") != "" {
+		var x uint64
+		_, err := fmt.Sscan(req.FormValue("autopricingdemandtarget"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.AutoPricingDemandTarget = x
+	}
+	if req.FormValue("autopricingdemandweight") != "" {
+		var x float64
+		_, err := fmt.Sscan(req.FormValue("autopricingdemandweight"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.AutoPricingDemandWeight = x
+	}
+	if req.FormValue("maxdownloadbandwidthprice") != "" {
+		var x types.Currency
+		_, err := fmt.Sscan(req.FormValue("maxdownloadbandwidthprice"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxDownloadBandwidthPrice = x
+	}
+	if req.FormValue("maxstorageprice") != "" {
+		var x types.Currency
+		_, err := fmt.Sscan(req.FormValue("maxstorageprice"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxStoragePrice = x
+	}
+	if req.FormValue("maxuploadbandwidthprice") != "" {
+		var x types.Currency
+		_, err := fmt.Sscan(req.FormValue("maxuploadbandwidthprice"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxUploadBandwidthPrice = x
+	}
+	if req.FormValue("maxbandwidthspeed") != "" {
+		var x uint64
+		_, err := fmt.Sscan(req.FormValue("maxbandwidthspeed"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxBandwidthSpeed = x
+	}
+	if req.FormValue("maxrenterbandwidthspeed") != "" {
+		var x uint64
+		_, err := fmt.Sscan(req.FormValue("maxrenterbandwidthspeed"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxRenterBandwidthSpeed = x
+	}
+	if req.FormValue("maxrenterbandwidthquota") != "" {
+		var x uint64
+		_, err := fmt.Sscan(req.FormValue("maxrenterbandwidthquota"), &x)
+		if err != nil {
+			return modules.HostInternalSettings{}, err
+		}
+		settings.MaxRenterBandwidthQuota = x
+	}
+
 	return settings, nil
 }
 
@@ -181,6 +181,58 @@ func TestHostSettingsHandlerParsing(t *testing.T) {
 	}
 }
 
+// TestHostMaintenance checks that scheduling a maintenance stops the host from
+// accepting contracts, and that stopping it restores the setting.
+func TestHostMaintenance(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+	if err := st.announceHost(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restart before the shutdown is rejected.
+	now := time.Now()
+	maintenanceValues := url.Values{}
+	maintenanceValues.Set("shutdown", strconv.FormatInt(now.Add(time.Hour).Unix(), 10))
+	maintenanceValues.Set("restart", strconv.FormatInt(now.Unix(), 10))
+	if err := st.stdPostAPI("/host/maintenance", maintenanceValues); err == nil {
+		t.Fatal("expected an error when the restart is before the shutdown")
+	}
+
+	maintenanceValues.Set("restart", strconv.FormatInt(now.Add(2*time.Hour).Unix(), 10))
+	if err := st.stdPostAPI("/host/maintenance", maintenanceValues); err != nil {
+		t.Fatal(err)
+	}
+	if st.host.InternalSettings().AcceptingContracts {
+		t.Error("host is accepting contracts during maintenance")
+	}
+	var hmg HostMaintenanceGET
+	if err := st.getAPI("/host/maintenance", &hmg); err != nil {
+		t.Fatal(err)
+	}
+	if !hmg.Active || !hmg.AcceptingContracts || hmg.RestartHeight <= hmg.ShutdownHeight {
+		t.Error("wrong maintenance status:", hmg.HostMaintenance)
+	}
+
+	if err := st.stdPostAPI("/host/maintenance/stop", url.Values{}); err != nil {
+		t.Fatal(err)
+	}
+	if !st.host.InternalSettings().AcceptingContracts {
+		t.Error("accepting contracts was not restored after maintenance")
+	}
+	if err := st.stdPostAPI("/host/maintenance/stop", url.Values{}); err == nil {
+		t.Error("expected an error when stopping without an active maintenance")
+	}
+}
+
 // TestWorkingStatus tests that the host's WorkingStatus field is set
 // correctly.
 func TestWorkingStatus(t *testing.T) {
@@ -3,6 +3,7 @@ package api
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
@@ -35,6 +36,20 @@ type (
 		Entry          ExtendedHostDBEntry        `json:"entry"`
 		ScoreBreakdown modules.HostScoreBreakdown `json:"scorebreakdown"`
 	}
+
+	// HostdbFilterModeGET contains the filter mode of the hostdb and the
+	// hosts on its filter list.
+	HostdbFilterModeGET struct {
+		FilterMode string   `json:"filtermode"`
+		Hosts      []string `json:"hosts"`
+	}
+
+	// HostdbWeightProfileGET contains the weight profile of the hostdb and
+	// the built-in profiles that can be selected.
+	HostdbWeightProfileGET struct {
+		WeightProfile   modules.HostWeightProfile   `json:"weightprofile"`
+		BuiltinProfiles []modules.HostWeightProfile `json:"builtinprofiles"`
+	}
 )
 
 // hostdbActiveHandler handles the API call asking for the list of active
@@ -114,3 +129,114 @@ func (api *API) hostdbHostsHandler(w http.ResponseWriter, req *http.Request, ps
 		ScoreBreakdown: breakdown,
 	})
 }
+
+// hostdbFilterModeHandlerGET handles the API call asking for the filter mode
+// of the hostdb.
+func (api *API) hostdbFilterModeHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	fm, hosts := api.renter.Filter()
+	var hostStrings []string
+	for _, spk := range hosts {
+		hostStrings = append(hostStrings, spk.String())
+	}
+	WriteJSON(w, HostdbFilterModeGET{
+		FilterMode: fm.String(),
+		Hosts:      hostStrings,
+	})
+}
+
+// hostdbFilterModeHandlerPOST handles the API call setting the filter mode of
+// the hostdb.
+func (api *API) hostdbFilterModeHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var fm modules.FilterMode
+	if err := fm.FromString(req.FormValue("filtermode")); err != nil {
+		WriteError(w, Error{"unable to parse filtermode: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	var hosts []types.SiaPublicKey
+	if req.FormValue("hosts") != "" {
+		for _, s := range strings.Split(req.FormValue("hosts"), ",") {
+			var spk types.SiaPublicKey
+			spk.LoadString(strings.TrimSpace(s))
+			if len(spk.Key) == 0 {
+				WriteError(w, Error{"unable to parse host public key: " + s}, http.StatusBadRequest)
+				return
+			}
+			hosts = append(hosts, spk)
+		}
+	}
+	if err := api.renter.SetFilterMode(fm, hosts); err != nil {
+		WriteError(w, Error{"failed to set the filter mode: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// hostdbWeightProfileHandlerGET handles the API call asking for the weight
+// profile of the hostdb.
+func (api *API) hostdbWeightProfileHandlerGET(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	WriteJSON(w, HostdbWeightProfileGET{
+		WeightProfile:   api.renter.WeightProfile(),
+		BuiltinProfiles: modules.HostWeightProfiles,
+	})
+}
+
+// hostdbWeightProfileHandlerPOST handles the API call setting the weight
+// profile of the hostdb. A built-in profile is selected by its name. Any other
+// name creates a custom profile, which starts from the exponents and
+// multipliers of the default profile and overrides the ones that are provided.
+func (api *API) hostdbWeightProfileHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	name := req.FormValue("profile")
+	if name == "" {
+		WriteError(w, Error{"profile must be provided"}, http.StatusBadRequest)
+		return
+	}
+	wp := modules.DefaultHostWeightProfile
+	wp.Name = name
+	params := map[string]*float64{
+		"ageexponent":              &wp.AgeExponent,
+		"collateralexponent":       &wp.CollateralExponent,
+		"interactionexponent":      &wp.InteractionExponent,
+		"performanceexponent":      &wp.PerformanceExponent,
+		"priceexponent":            &wp.PriceExponent,
+		"storageremainingexponent": &wp.StorageRemainingExponent,
+		"uptimeexponent":           &wp.UptimeExponent,
+		"versionexponent":          &wp.VersionExponent,
+
+		"agemultiplier":              &wp.AgeMultiplier,
+		"collateralmultiplier":       &wp.CollateralMultiplier,
+		"interactionmultiplier":      &wp.InteractionMultiplier,
+		"performancemultiplier":      &wp.PerformanceMultiplier,
+		"pricemultiplier":            &wp.PriceMultiplier,
+		"storageremainingmultiplier": &wp.StorageRemainingMultiplier,
+		"uptimemultiplier":           &wp.UptimeMultiplier,
+		"versionmultiplier":          &wp.VersionMultiplier,
+	}
+
+	var custom bool
+	for param, val := range params {
+		if req.FormValue(param) == "" {
+			continue
+		}
+		if _, err := fmt.Sscan(req.FormValue(param), val); err != nil {
+			WriteError(w, Error{"unable to parse " + param + ": " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		custom = true
+	}
+	for _, builtin := range modules.HostWeightProfiles {
+		if builtin.Name != name {
+			continue
+		}
+		if custom {
+			WriteError(w, Error{"cannot change the exponents or multipliers of a built-in profile"}, http.StatusBadRequest)
+			return
+		}
+		wp = builtin
+	}
+
+	if err := api.renter.SetWeightProfile(wp); err != nil {
+		WriteError(w, Error{"failed to set the weight profile: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
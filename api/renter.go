@@ -4,7 +4,9 @@ package api
 // zeroing them out.
 
 import (
+	"errors"
 	"fmt"
+	"mime"
 	"net/http"
 	"path/filepath"
 	"sort"
@@ -72,6 +74,7 @@ type (
 		Settings         modules.RenterSettings     `json:"settings"`
 		FinancialMetrics modules.ContractorSpending `json:"financialmetrics"`
 		CurrentPeriod    types.BlockHeight          `json:"currentperiod"`
+		ChunkCache       modules.ChunkCacheMetrics  `json:"chunkcache"`
 	}
 
 	// RenterContract represents a contract formed by the renter.
@@ -82,6 +85,9 @@ type (
 		EndHeight types.BlockHeight `json:"endheight"`
 		// Fees paid in order to form the file contract.
 		Fees types.Currency `json:"fees"`
+		// Whether the contract is used for uploads and renewed.
+		GoodForUpload bool `json:"goodforupload"`
+		GoodForRenew  bool `json:"goodforrenew"`
 		// Public key of the host the contract was formed with.
 		HostPublicKey types.SiaPublicKey `json:"hostpublickey"`
 		// ID of the file contract.
@@ -90,6 +96,14 @@ type (
 		LastTransaction types.Transaction `json:"lasttransaction"`
 		// Address of the host the file contract was formed with.
 		NetAddress modules.NetAddress `json:"netaddress"`
+		// How the proof window of the contract ended. Only reported for
+		// expired and renewed contracts.
+		Outcome *modules.ContractOutcome `json:"outcome,omitempty"`
+		// IDs of the contract that this contract was renewed from and the
+		// contract that it was renewed to. Zero if the contract was not
+		// renewed.
+		RenewedFrom types.FileContractID `json:"renewedfrom"`
+		RenewedTo   types.FileContractID `json:"renewedto"`
 		// Remaining funds left for the renter to spend on uploads & downloads.
 		RenterFunds types.Currency `json:"renterfunds"`
 		// Size of the file contract, which is typically equal to the number of
@@ -108,6 +122,8 @@ type (
 	// RenterContracts contains the renter's contracts.
 	RenterContracts struct {
 		Contracts []RenterContract `json:"contracts"`
+		// Expired and renewed contracts, only reported if requested.
+		ExpiredContracts []RenterContract `json:"expiredcontracts,omitempty"`
 	}
 
 	// DownloadQueue contains the renter's download queue.
@@ -115,11 +131,25 @@ type (
 		Downloads []DownloadInfo `json:"downloads"`
 	}
 
+	// RenterDirectory lists the contents of a directory of the renter. The
+	// subdirectories and files are sorted by siapath and paginated together,
+	// subdirectories first.
+	RenterDirectory struct {
+		Directory   modules.DirectoryInfo   `json:"directory"`
+		Directories []modules.DirectoryInfo `json:"directories"`
+		Files       []modules.FileInfo      `json:"files"`
+	}
+
 	// RenterFiles lists the files known to the renter.
 	RenterFiles struct {
 		Files []modules.FileInfo `json:"files"`
 	}
 
+	// RenterHealthGET summarizes the health of the renter's files.
+	RenterHealthGET struct {
+		modules.RenterHealth
+	}
+
 	// RenterLoad lists files that were loaded into the renter.
 	RenterLoad struct {
 		FilesAdded []string `json:"filesadded"`
@@ -131,6 +161,18 @@ type (
 		modules.RenterPriceEstimation
 	}
 
+	// RenterSpendingGET contains a spending report for every billing period,
+	// the last of which is the current period.
+	RenterSpendingGET struct {
+		Periods []modules.SpendingReport `json:"periods"`
+	}
+
+	// RenterAlertsGET contains the renter's alerts about its spending and
+	// funds.
+	RenterAlertsGET struct {
+		Alerts []modules.RenterAlert `json:"alerts"`
+	}
+
 	// RenterShareASCII contains an ASCII-encoded .sia file.
 	RenterShareASCII struct {
 		ASCIIsia string `json:"asciisia"`
@@ -138,12 +180,14 @@ type (
 
 	// DownloadInfo contains all client-facing information of a file.
 	DownloadInfo struct {
-		SiaPath     string    `json:"siapath"`
-		Destination string    `json:"destination"`
-		Filesize    uint64    `json:"filesize"`
-		Received    uint64    `json:"received"`
-		StartTime   time.Time `json:"starttime"`
-		Error       string    `json:"error"`
+		ID          modules.DownloadID `json:"id"`
+		Paused      bool               `json:"paused"`
+		SiaPath     string             `json:"siapath"`
+		Destination string             `json:"destination"`
+		Filesize    uint64             `json:"filesize"`
+		Received    uint64             `json:"received"`
+		StartTime   time.Time          `json:"starttime"`
+		Error       string             `json:"error"`
 	}
 )
 
@@ -155,16 +199,114 @@ func (api *API) renterHandlerGET(w http.ResponseWriter, req *http.Request, _ htt
 		Settings:         settings,
 		FinancialMetrics: api.renter.PeriodSpending(),
 		CurrentPeriod:    periodStart,
+		ChunkCache:       api.renter.ChunkCacheMetrics(),
 	})
 }
 
 // renterHandlerPOST handles the API call to set the Renter's settings.
 func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	settings := api.renter.Settings()
+
+	// Scan the size of the chunk cache. (optional parameters)
+	if req.FormValue("chunkcachesize") != "" {
+		_, err := fmt.Sscan(req.FormValue("chunkcachesize"), &settings.ChunkCacheSize)
+		if err != nil {
+			WriteError(w, Error{"unable to parse chunkcachesize: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.FormValue("chunkcachedisksize") != "" {
+		_, err := fmt.Sscan(req.FormValue("chunkcachedisksize"), &settings.ChunkCacheDiskSize)
+		if err != nil {
+			WriteError(w, Error{"unable to parse chunkcachedisksize: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Scan the bandwidth limits. (optional parameters)
+	if req.FormValue("maxuploadspeed") != "" {
+		_, err := fmt.Sscan(req.FormValue("maxuploadspeed"), &settings.MaxUploadSpeed)
+		if err != nil {
+			WriteError(w, Error{"unable to parse maxuploadspeed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+	if req.FormValue("maxdownloadspeed") != "" {
+		_, err := fmt.Sscan(req.FormValue("maxdownloadspeed"), &settings.MaxDownloadSpeed)
+		if err != nil {
+			WriteError(w, Error{"unable to parse maxdownloadspeed: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Scan the top-up policy and the spending alerts. (optional parameters)
+	if req.FormValue("topupmaxfunds") != "" {
+		maxFunds, ok := scanAmount(req.FormValue("topupmaxfunds"))
+		if !ok {
+			WriteError(w, Error{"unable to parse topupmaxfunds"}, http.StatusBadRequest)
+			return
+		}
+		settings.TopUp.MaxFunds = maxFunds
+	}
+	_, setAlerts := req.Form["spendingalerts"]
+	if setAlerts {
+		alerts, err := scanSpendingAlerts(req.FormValue("spendingalerts"))
+		if err != nil {
+			WriteError(w, Error{"unable to parse spendingalerts: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+		settings.SpendingAlerts = alerts
+	}
+
+	// The allowance is only changed if either the funds or the period are
+	// supplied, so that the other settings can be changed on their own.
+	if req.FormValue("funds") != "" || req.FormValue("period") != "" {
+		allowance, err := scanAllowance(req)
+		if err != nil {
+			WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+			return
+		}
+		settings.Allowance = allowance
+	} else if req.FormValue("chunkcachesize") == "" && req.FormValue("chunkcachedisksize") == "" &&
+		req.FormValue("maxuploadspeed") == "" && req.FormValue("maxdownloadspeed") == "" &&
+		req.FormValue("topupmaxfunds") == "" && !setAlerts {
+		WriteError(w, Error{"unable to parse funds"}, http.StatusBadRequest)
+		return
+	}
+
+	// Set the settings in the renter.
+	err := api.renter.SetSettings(settings)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// scanSpendingAlerts parses a comma-separated list of spending alert
+// thresholds. An empty list removes all spending alerts.
+func scanSpendingAlerts(s string) ([]uint64, error) {
+	var alerts []uint64
+	if s == "" {
+		return alerts, nil
+	}
+	for _, field := range strings.Split(s, ",") {
+		var alert uint64
+		_, err := fmt.Sscan(field, &alert)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// scanAllowance parses the allowance parameters of a request to /renter.
+func scanAllowance(req *http.Request) (modules.Allowance, error) {
 	// Scan the allowance amount.
 	funds, ok := scanAmount(req.FormValue("funds"))
 	if !ok {
-		WriteError(w, Error{"unable to parse funds"}, http.StatusBadRequest)
-		return
+		return modules.Allowance{}, errors.New("unable to parse funds")
 	}
 
 	// Scan the number of hosts to use. (optional parameter)
@@ -172,12 +314,10 @@ func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ ht
 	if req.FormValue("hosts") != "" {
 		_, err := fmt.Sscan(req.FormValue("hosts"), &hosts)
 		if err != nil {
-			WriteError(w, Error{"unable to parse hosts: " + err.Error()}, http.StatusBadRequest)
-			return
+			return modules.Allowance{}, errors.New("unable to parse hosts: " + err.Error())
 		}
 		if hosts != 0 && hosts < requiredHosts {
-			WriteError(w, Error{fmt.Sprintf("insufficient number of hosts, need at least %v but have %v", recommendedHosts, hosts)}, http.StatusBadRequest)
-			return
+			return modules.Allowance{}, fmt.Errorf("insufficient number of hosts, need at least %v but have %v", recommendedHosts, hosts)
 		}
 	} else {
 		hosts = recommendedHosts
@@ -187,8 +327,7 @@ func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ ht
 	var period types.BlockHeight
 	_, err := fmt.Sscan(req.FormValue("period"), &period)
 	if err != nil {
-		WriteError(w, Error{"unable to parse period: " + err.Error()}, http.StatusBadRequest)
-		return
+		return modules.Allowance{}, errors.New("unable to parse period: " + err.Error())
 	}
 
 	// Scan the renew window. (optional parameter)
@@ -196,75 +335,208 @@ func (api *API) renterHandlerPOST(w http.ResponseWriter, req *http.Request, _ ht
 	if req.FormValue("renewwindow") != "" {
 		_, err = fmt.Sscan(req.FormValue("renewwindow"), &renewWindow)
 		if err != nil {
-			WriteError(w, Error{"unable to parse renewwindow: " + err.Error()}, http.StatusBadRequest)
-			return
+			return modules.Allowance{}, errors.New("unable to parse renewwindow: " + err.Error())
 		}
 		if renewWindow != 0 && renewWindow < requiredRenewWindow {
-			WriteError(w, Error{fmt.Sprintf("renew window is too small, must be at least %v blocks but have %v blocks", requiredRenewWindow, renewWindow)}, http.StatusBadRequest)
-			return
+			return modules.Allowance{}, fmt.Errorf("renew window is too small, must be at least %v blocks but have %v blocks", requiredRenewWindow, renewWindow)
 		}
 	} else {
 		renewWindow = period / 2
 	}
 
-	// Set the settings in the renter.
-	err = api.renter.SetSettings(modules.RenterSettings{
-		Allowance: modules.Allowance{
-			Funds:       funds,
-			Hosts:       hosts,
-			Period:      period,
-			RenewWindow: renewWindow,
-		},
-	})
+	return modules.Allowance{
+		Funds:       funds,
+		Hosts:       hosts,
+		Period:      period,
+		RenewWindow: renewWindow,
+	}, nil
+}
+
+// contractLineage maps contracts to the contract that they were renewed from
+// and the contract that they were renewed to.
+type contractLineage struct {
+	from map[types.FileContractID]types.FileContractID
+	to   map[types.FileContractID]types.FileContractID
+}
+
+// renterContractLineage returns the lineage of the renter's contracts.
+func (api *API) renterContractLineage() contractLineage {
+	l := contractLineage{
+		from: make(map[types.FileContractID]types.FileContractID),
+		to:   api.renter.RenewedIDs(),
+	}
+	for oldID, newID := range l.to {
+		l.from[newID] = oldID
+	}
+	return l
+}
+
+// renterContract converts a contract of the renter to its API representation.
+func (api *API) renterContract(c modules.RenterContract, l contractLineage) RenterContract {
+	var size uint64
+	if len(c.Transaction.FileContractRevisions) != 0 {
+		size = c.Transaction.FileContractRevisions[0].NewFileSize
+	}
+
+	// Fetch host address
+	var netAddress modules.NetAddress
+	hdbe, exists := api.renter.Host(c.HostPublicKey)
+	if exists {
+		netAddress = hdbe.NetAddress
+	}
+
+	utility, _ := api.renter.ContractUtility(c.ID)
+	return RenterContract{
+		DownloadSpending: c.DownloadSpending,
+		EndHeight:        c.EndHeight,
+		Fees:             c.TxnFee.Add(c.SiafundFee).Add(c.ContractFee),
+		GoodForUpload:    utility.GoodForUpload,
+		GoodForRenew:     utility.GoodForRenew,
+		HostPublicKey:    c.HostPublicKey,
+		ID:               c.ID,
+		LastTransaction:  c.Transaction,
+		NetAddress:       netAddress,
+		RenewedFrom:      l.from[c.ID],
+		RenewedTo:        l.to[c.ID],
+		RenterFunds:      c.RenterFunds,
+		Size:             size,
+		StartHeight:      c.StartHeight,
+		StorageSpending:  c.StorageSpending,
+		TotalCost:        c.TotalCost,
+		UploadSpending:   c.UploadSpending,
+	}
+}
+
+// renterContractsHandler handles the API call to request the Renter's
+// contracts. If the expired parameter is set, expired and renewed contracts
+// are included along with the outcome of their proof windows.
+func (api *API) renterContractsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	expired, err := scanBool(req.FormValue("expired"))
 	if err != nil {
-		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		WriteError(w, Error{"unable to parse expired: " + err.Error()}, http.StatusBadRequest)
 		return
 	}
-	WriteSuccess(w)
-}
 
-// renterContractsHandler handles the API call to request the Renter's contracts.
-func (api *API) renterContractsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
+	lineage := api.renterContractLineage()
 	contracts := []RenterContract{}
 	for _, c := range api.renter.Contracts() {
-		var size uint64
-		if len(c.Transaction.FileContractRevisions) != 0 {
-			size = c.Transaction.FileContractRevisions[0].NewFileSize
-		}
-
-		// Fetch host address
-		var netAddress modules.NetAddress
-		hdbe, exists := api.renter.Host(c.HostPublicKey)
-		if exists {
-			netAddress = hdbe.NetAddress
+		contracts = append(contracts, api.renterContract(c, lineage))
+	}
+	var expiredContracts []RenterContract
+	if expired {
+		expiredContracts = []RenterContract{}
+		for _, c := range api.renter.OldContracts() {
+			contract := api.renterContract(c, lineage)
+			// Old contracts are never used or renewed again.
+			contract.GoodForUpload = false
+			contract.GoodForRenew = false
+			if outcome, ok := api.renter.ContractOutcome(c.ID); ok {
+				contract.Outcome = &outcome
+			}
+			expiredContracts = append(expiredContracts, contract)
 		}
-
-		contracts = append(contracts, RenterContract{
-			DownloadSpending: c.DownloadSpending,
-			EndHeight:        c.EndHeight,
-			Fees:             c.TxnFee.Add(c.SiafundFee).Add(c.ContractFee),
-			HostPublicKey:    c.HostPublicKey,
-			ID:               c.ID,
-			LastTransaction:  c.Transaction,
-			NetAddress:       netAddress,
-			RenterFunds:      c.RenterFunds,
-			Size:             size,
-			StartHeight:      c.StartHeight,
-			StorageSpending:  c.StorageSpending,
-			TotalCost:        c.TotalCost,
-			UploadSpending:   c.UploadSpending,
+		sort.Slice(expiredContracts, func(i, j int) bool {
+			return expiredContracts[i].EndHeight < expiredContracts[j].EndHeight
 		})
 	}
 	WriteJSON(w, RenterContracts{
-		Contracts: contracts,
+		Contracts:        contracts,
+		ExpiredContracts: expiredContracts,
 	})
 }
 
+// renterContractsFormHandler handles the API call to form a contract with a
+// specific host.
+func (api *API) renterContractsFormHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	var hostKey types.SiaPublicKey
+	hostKey.LoadString(req.FormValue("host"))
+	if len(hostKey.Key) == 0 {
+		WriteError(w, Error{"unable to parse host: " + req.FormValue("host")}, http.StatusBadRequest)
+		return
+	}
+	funds, ok := scanAmount(req.FormValue("funds"))
+	if !ok {
+		WriteError(w, Error{"unable to parse funds"}, http.StatusBadRequest)
+		return
+	}
+
+	contract, err := api.renter.FormContract(hostKey, funds)
+	if err != nil {
+		WriteError(w, Error{"unable to form contract: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, api.renterContract(contract, api.renterContractLineage()))
+}
+
+// renterContractsRenewHandler handles the API call to renew or extend a
+// specific contract.
+func (api *API) renterContractsRenewHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	id, err := scanHash(ps.ByName("id"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse contract id: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	funds, ok := scanAmount(req.FormValue("funds"))
+	if !ok {
+		WriteError(w, Error{"unable to parse funds"}, http.StatusBadRequest)
+		return
+	}
+	var endHeight types.BlockHeight
+	if req.FormValue("endheight") != "" {
+		_, err := fmt.Sscan(req.FormValue("endheight"), &endHeight)
+		if err != nil {
+			WriteError(w, Error{"unable to parse endheight: " + err.Error()}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	contract, err := api.renter.RenewContract(types.FileContractID(id), funds, endHeight)
+	if err != nil {
+		WriteError(w, Error{"unable to renew contract: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, api.renterContract(contract, api.renterContractLineage()))
+}
+
+// renterContractsUtilityHandler handles the API call to mark a specific
+// contract as not good for upload or not good for renew.
+func (api *API) renterContractsUtilityHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	id, err := scanHash(ps.ByName("id"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse contract id: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	if req.FormValue("goodforupload") == "" || req.FormValue("goodforrenew") == "" {
+		WriteError(w, Error{"goodforupload and goodforrenew must be specified"}, http.StatusBadRequest)
+		return
+	}
+	var utility modules.ContractUtility
+	utility.GoodForUpload, err = scanBool(req.FormValue("goodforupload"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse goodforupload: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	utility.GoodForRenew, err = scanBool(req.FormValue("goodforrenew"))
+	if err != nil {
+		WriteError(w, Error{"unable to parse goodforrenew: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	err = api.renter.SetContractUtility(types.FileContractID(id), utility)
+	if err != nil {
+		WriteError(w, Error{"unable to set contract utility: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // renterDownloadsHandler handles the API call to request the download queue.
 func (api *API) renterDownloadsHandler(w http.ResponseWriter, _ *http.Request, _ httprouter.Params) {
 	var downloads []DownloadInfo
 	for _, d := range api.renter.DownloadQueue() {
 		downloads = append(downloads, DownloadInfo{
+			ID:          d.ID,
+			Paused:      d.Paused,
 			SiaPath:     d.SiaPath,
 			Destination: d.Destination.Destination(),
 			Filesize:    d.Filesize,
@@ -321,6 +593,65 @@ func (api *API) renterRenameHandler(w http.ResponseWriter, req *http.Request, ps
 	WriteSuccess(w)
 }
 
+// renterDirHandlerGET handles the API call to list the contents of a
+// directory.
+func (api *API) renterDirHandlerGET(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	siaPath := strings.TrimPrefix(ps.ByName("siapath"), "/")
+
+	// Parse the pagination parameters. A limit of 0 means that there is no
+	// limit.
+	var offset, limit int
+	if o := req.FormValue("offset"); o != "" {
+		if _, err := fmt.Sscan(o, &offset); err != nil || offset < 0 {
+			WriteError(w, Error{"unable to parse offset"}, http.StatusBadRequest)
+			return
+		}
+	}
+	if l := req.FormValue("limit"); l != "" {
+		if _, err := fmt.Sscan(l, &limit); err != nil || limit < 0 {
+			WriteError(w, Error{"unable to parse limit"}, http.StatusBadRequest)
+			return
+		}
+	}
+
+	dir, dirs, files, err := api.renter.DirList(siaPath, offset, limit)
+	if err == renter.ErrUnknownDir {
+		WriteError(w, Error{err.Error()}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteJSON(w, RenterDirectory{
+		Directory:   dir,
+		Directories: dirs,
+		Files:       files,
+	})
+}
+
+// renterDirHandlerPOST handles the API call to create, delete or rename a
+// directory.
+func (api *API) renterDirHandlerPOST(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	siaPath := strings.TrimPrefix(ps.ByName("siapath"), "/")
+	var err error
+	switch action := req.FormValue("action"); action {
+	case "create":
+		err = api.renter.CreateDir(siaPath)
+	case "delete":
+		err = api.renter.DeleteDir(siaPath)
+	case "rename":
+		err = api.renter.RenameDir(siaPath, req.FormValue("newsiapath"))
+	default:
+		WriteError(w, Error{"unknown action: " + action}, http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // renterFilesHandler handles the API call to list all of the files.
 func (api *API) renterFilesHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
 	WriteJSON(w, RenterFiles{
@@ -336,6 +667,27 @@ func (api *API) renterPricesHandler(w http.ResponseWriter, req *http.Request, _
 	})
 }
 
+// renterAlertsHandler handles the API call to /renter/alerts.
+func (api *API) renterAlertsHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, RenterAlertsGET{
+		Alerts: api.renter.Alerts(),
+	})
+}
+
+// renterHealthHandler handles the API call to /renter/health.
+func (api *API) renterHealthHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, RenterHealthGET{
+		RenterHealth: api.renter.Health(),
+	})
+}
+
+// renterSpendingHandler handles the API call to /renter/spending.
+func (api *API) renterSpendingHandler(w http.ResponseWriter, req *http.Request, _ httprouter.Params) {
+	WriteJSON(w, RenterSpendingGET{
+		Periods: api.renter.SpendingHistory(),
+	})
+}
+
 // renterDeleteHandler handles the API call to delete a file entry from the
 // renter.
 func (api *API) renterDeleteHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
@@ -395,6 +747,64 @@ func (api *API) renterDownloadAsyncHandler(w http.ResponseWriter, req *http.Requ
 	api.renterDownloadHandler(w, req, ps)
 }
 
+// renterDownloadCancelHandler handles the API call to cancel a download.
+func (api *API) renterDownloadCancelHandler(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	err := api.renter.CancelDownload(modules.DownloadID(ps.ByName("id")))
+	if err != nil {
+		WriteError(w, Error{"unable to cancel download: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterDownloadPauseHandler handles the API call to pause a download.
+func (api *API) renterDownloadPauseHandler(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	err := api.renter.PauseDownload(modules.DownloadID(ps.ByName("id")))
+	if err != nil {
+		WriteError(w, Error{"unable to pause download: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterDownloadResumeHandler handles the API call to resume a paused
+// download.
+func (api *API) renterDownloadResumeHandler(w http.ResponseWriter, _ *http.Request, ps httprouter.Params) {
+	err := api.renter.ResumeDownload(modules.DownloadID(ps.ByName("id")))
+	if err != nil {
+		WriteError(w, Error{"unable to resume download: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterStreamHandler handles the API call to stream the contents of a file.
+// Range and If-Range headers are honored, so the contents can be seeked by
+// media players.
+func (api *API) renterStreamHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	siapath := strings.TrimPrefix(ps.ByName("siapath"), "/")
+	streamer, err := api.renter.Streamer(siapath)
+	if err == renter.ErrUnknownPath {
+		WriteError(w, Error{"unable to stream file: " + err.Error()}, http.StatusNotFound)
+		return
+	} else if err != nil {
+		WriteError(w, Error{"unable to stream file: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	defer streamer.Close()
+
+	// Set the content type from the extension of the file. Setting it
+	// explicitly prevents the content from being sniffed, which would require
+	// the first chunk of the file to be downloaded for every request.
+	contentType := mime.TypeByExtension(filepath.Ext(siapath))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", streamer.ETag())
+	http.ServeContent(w, req, siapath, time.Time{}, streamer)
+}
+
 // parseDownloadParameters parses the download parameters passed to the
 // /renter/download endpoint. Validation of these parameters is done by the
 // renter.
@@ -487,6 +897,152 @@ func (api *API) renterShareAsciiHandler(w http.ResponseWriter, req *http.Request
 	})
 }
 
+// erasureCoderTypes maps the values of the 'erasurecode' parameter to the
+// types of erasure coders.
+var erasureCoderTypes = map[string]modules.ErasureCoderType{
+	"reedsolomon": modules.ECReedSolomon,
+	"systematic":  modules.ECSystematicRS,
+	"replication": modules.ECReplication,
+}
+
+// parseErasureCodingParameters parses the supplied string values and creates
+// an erasure coder. If values haven't been supplied it will fill in sane
+// defaults.
+func parseErasureCodingParameters(strType, strDataPieces, strParityPieces string) (modules.ErasureCoder, error) {
+	// Check whether the erasure coding parameters have been supplied.
+	if strType == "" && strDataPieces == "" && strParityPieces == "" {
+		return nil, nil
+	}
+
+	// Check that both values have been supplied.
+	if strDataPieces == "" || strParityPieces == "" {
+		return nil, errors.New("must provide both the datapieces paramaeter and the paritypieces parameter if specifying erasure coding parameters")
+	}
+
+	// Parse the erasure coding parameters.
+	ecType := modules.ECReedSolomon
+	if strType != "" {
+		var exists bool
+		ecType, exists = erasureCoderTypes[strType]
+		if !exists {
+			return nil, fmt.Errorf("unknown erasure code %q, must be one of 'reedsolomon', 'systematic' or 'replication'", strType)
+		}
+	}
+	var dataPieces, parityPieces int
+	_, err := fmt.Sscan(strDataPieces, &dataPieces)
+	if err != nil {
+		return nil, errors.New("unable to read parameter 'datapieces': " + err.Error())
+	}
+	_, err = fmt.Sscan(strParityPieces, &parityPieces)
+	if err != nil {
+		return nil, errors.New("unable to read parameter 'paritypieces': " + err.Error())
+	}
+
+	// Verify that sane values for parityPieces and redundancy are being
+	// supplied.
+	if parityPieces < requiredParityPieces {
+		return nil, fmt.Errorf("a minimum of %v parity pieces is required, but %v parity pieces requested", requiredParityPieces, parityPieces)
+	}
+	redundancy := float64(dataPieces+parityPieces) / float64(dataPieces)
+	if float64(dataPieces+parityPieces)/float64(dataPieces) < requiredRedundancy {
+		return nil, fmt.Errorf("a redundancy of %.2f is required, but redundancy of %.2f supplied", requiredRedundancy, redundancy)
+	}
+
+	// Create the erasure coder. A replication code stores every piece as a
+	// full copy of the data, so it has exactly one data piece, and every
+	// parity piece is another copy.
+	var ec modules.ErasureCoder
+	if ecType == modules.ECReplication {
+		if dataPieces != 1 {
+			return nil, errors.New("replication requires exactly 1 data piece")
+		}
+		ec, err = renter.NewReplicationCode(dataPieces + parityPieces)
+	} else {
+		ec, err = renter.NewErasureCoder(ecType, []uint64{uint64(dataPieces), uint64(parityPieces)})
+	}
+	if err != nil {
+		return nil, errors.New("unable to encode file using the provided parameters: " + err.Error())
+	}
+	return ec, nil
+}
+
+// parsePriority parses the upload priority of a file. The priority defaults
+// to 0 if it has not been supplied.
+func parsePriority(strPriority string) (int, error) {
+	if strPriority == "" {
+		return 0, nil
+	}
+	var priority int
+	_, err := fmt.Sscan(strPriority, &priority)
+	if err != nil {
+		return 0, errors.New("unable to read parameter 'priority': " + err.Error())
+	}
+	return priority, nil
+}
+
+// renterPriorityHandler handles the API call to change the upload priority of
+// a file or of the files in a directory.
+func (api *API) renterPriorityHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	if req.FormValue("priority") == "" {
+		WriteError(w, Error{"priority must be specified"}, http.StatusBadRequest)
+		return
+	}
+	priority, err := parsePriority(req.FormValue("priority"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	err = api.renter.SetFilePriority(strings.TrimPrefix(ps.ByName("siapath"), "/"), priority)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterUploadsPauseHandler handles the API call to pause uploading and
+// repairing a file or the files in a directory.
+func (api *API) renterUploadsPauseHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	err := api.renter.PauseUploads(strings.TrimPrefix(ps.ByName("siapath"), "/"))
+	if err != nil {
+		WriteError(w, Error{"unable to pause uploads: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterUploadsResumeHandler handles the API call to resume uploading and
+// repairing a file or the files in a directory.
+func (api *API) renterUploadsResumeHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	err := api.renter.ResumeUploads(strings.TrimPrefix(ps.ByName("siapath"), "/"))
+	if err != nil {
+		WriteError(w, Error{"unable to resume uploads: " + err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
+// renterRedundancyHandler handles the API call to change the erasure coding
+// parameters of a file.
+func (api *API) renterRedundancyHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	ec, err := parseErasureCodingParameters(req.FormValue("erasurecode"), req.FormValue("datapieces"), req.FormValue("paritypieces"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	} else if ec == nil {
+		WriteError(w, Error{"datapieces and paritypieces must be specified"}, http.StatusBadRequest)
+		return
+	}
+
+	err = api.renter.SetFileErasureCode(strings.TrimPrefix(ps.ByName("siapath"), "/"), ec)
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	WriteSuccess(w)
+}
+
 // renterUploadHandler handles the API call to upload a file.
 func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
 	source := req.FormValue("source")
@@ -496,52 +1052,23 @@ func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps
 	}
 
 	// Check whether the erasure coding parameters have been supplied.
-	var ec modules.ErasureCoder
-	if req.FormValue("datapieces") != "" || req.FormValue("paritypieces") != "" {
-		// Check that both values have been supplied.
-		if req.FormValue("datapieces") == "" || req.FormValue("paritypieces") == "" {
-			WriteError(w, Error{"must provide both the datapieces paramaeter and the paritypieces parameter if specifying erasure coding parameters"}, http.StatusBadRequest)
-			return
-		}
-
-		// Parse the erasure coding parameters.
-		var dataPieces, parityPieces int
-		_, err := fmt.Sscan(req.FormValue("datapieces"), &dataPieces)
-		if err != nil {
-			WriteError(w, Error{"unable to read parameter 'datapieces': " + err.Error()}, http.StatusBadRequest)
-			return
-		}
-		_, err = fmt.Sscan(req.FormValue("paritypieces"), &parityPieces)
-		if err != nil {
-			WriteError(w, Error{"unable to read parameter 'paritypieces': " + err.Error()}, http.StatusBadRequest)
-			return
-		}
-
-		// Verify that sane values for parityPieces and redundancy are being
-		// supplied.
-		if parityPieces < requiredParityPieces {
-			WriteError(w, Error{fmt.Sprintf("a minimum of %v parity pieces is required, but %v parity pieces requested", parityPieces, requiredParityPieces)}, http.StatusBadRequest)
-			return
-		}
-		redundancy := float64(dataPieces+parityPieces) / float64(dataPieces)
-		if float64(dataPieces+parityPieces)/float64(dataPieces) < requiredRedundancy {
-			WriteError(w, Error{fmt.Sprintf("a redundancy of %.2f is required, but redundancy of %.2f supplied", redundancy, requiredRedundancy)}, http.StatusBadRequest)
-			return
-		}
-
-		// Create the erasure coder.
-		ec, err = renter.NewRSCode(dataPieces, parityPieces)
-		if err != nil {
-			WriteError(w, Error{"unable to encode file using the provided parameters: " + err.Error()}, http.StatusBadRequest)
-			return
-		}
+	ec, err := parseErasureCodingParameters(req.FormValue("erasurecode"), req.FormValue("datapieces"), req.FormValue("paritypieces"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	priority, err := parsePriority(req.FormValue("priority"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
 	}
 
 	// Call the renter to upload the file.
-	err := api.renter.Upload(modules.FileUploadParams{
+	err = api.renter.Upload(modules.FileUploadParams{
 		Source:      source,
 		SiaPath:     strings.TrimPrefix(ps.ByName("siapath"), "/"),
 		ErasureCode: ec,
+		Priority:    priority,
 	})
 	if err != nil {
 		WriteError(w, Error{"upload failed: " + err.Error()}, http.StatusInternalServerError)
@@ -549,3 +1076,36 @@ func (api *API) renterUploadHandler(w http.ResponseWriter, req *http.Request, ps
 	}
 	WriteSuccess(w)
 }
+
+// renterUploadStreamHandler handles the API call to upload a file using a
+// stream. The data of the file is read from the request body.
+func (api *API) renterUploadStreamHandler(w http.ResponseWriter, req *http.Request, ps httprouter.Params) {
+	// The parameters are read from the query string, since calling
+	// req.FormValue would consume the body of the request.
+	queryForm := req.URL.Query()
+
+	// Check whether the erasure coding parameters have been supplied.
+	ec, err := parseErasureCodingParameters(queryForm.Get("erasurecode"), queryForm.Get("datapieces"), queryForm.Get("paritypieces"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+	priority, err := parsePriority(queryForm.Get("priority"))
+	if err != nil {
+		WriteError(w, Error{err.Error()}, http.StatusBadRequest)
+		return
+	}
+
+	// Call the renter to upload the stream.
+	up := modules.FileUploadParams{
+		SiaPath:     strings.TrimPrefix(ps.ByName("siapath"), "/"),
+		ErasureCode: ec,
+		Priority:    priority,
+	}
+	err = api.renter.UploadStreamFromReader(up, req.Body)
+	if err != nil {
+		WriteError(w, Error{"upload failed: " + err.Error()}, http.StatusInternalServerError)
+		return
+	}
+	WriteSuccess(w)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -492,6 +493,145 @@ func TestRenterAsyncDownload(t *testing.T) {
 	}
 }
 
+// TestRenterUploadStream tests that a file uploaded from the body of a
+// /renter/uploadstream request can be downloaded again.
+func TestRenterUploadStream(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, _ := setupTestDownload(t, 1e4, "test.dat", true)
+	defer st.server.panicClose()
+
+	// Upload a file that spans several chunks, the last one partial.
+	data := fastrand.Bytes(int(modules.SectorSize)*3 + 123)
+	resp, err := HttpPOST("http://"+st.server.listener.Addr().String()+"/renter/uploadstream/stream.dat?datapieces=1&paritypieces=1", string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatal("stream upload failed:", resp.Status)
+	}
+	var rf RenterFiles
+	err = st.getAPI("/renter/files", &rf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, file := range rf.Files {
+		if file.SiaPath == "stream.dat" {
+			found = true
+			if file.Filesize != uint64(len(data)) || !file.Available {
+				t.Fatal("streamed file has wrong size or is not available:", file.Filesize, file.Available)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("streamed file is not in the file list")
+	}
+
+	// Download the file and compare it to the uploaded data.
+	resp, err = HttpGET("http://" + st.server.listener.Addr().String() + "/renter/download/stream.dat?httpresp=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	downbytes, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(downbytes, data) {
+		t.Fatal("downloaded stream does not match the uploaded data:", len(downbytes), len(data))
+	}
+}
+
+// TestRenterStream tests that the /renter/stream route honors Range and
+// If-Range headers.
+func TestRenterStream(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	st, path := setupTestDownload(t, 1e4, "test.mp4", true)
+	defer st.server.panicClose()
+	original, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// stream requests the file with the supplied headers and returns the
+	// response and its body.
+	stream := func(headers map[string]string) (*http.Response, []byte) {
+		req, err := http.NewRequest("GET", "http://"+st.server.listener.Addr().String()+"/renter/stream/test.mp4", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("User-Agent", "Sia-Agent")
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp, body
+	}
+
+	// Stream the whole file.
+	resp, body := stream(nil)
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, original) {
+		t.Fatal("streamed file does not match original:", resp.Status)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "video/mp4" {
+		t.Fatal("wrong content type:", ct)
+	}
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("no entity tag set")
+	}
+
+	// Stream a range that spans multiple chunks.
+	resp, body = stream(map[string]string{"Range": "bytes=3000-8999"})
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, original[3000:9000]) {
+		t.Fatal("streamed range does not match original:", resp.Status)
+	}
+	if cr := resp.Header.Get("Content-Range"); cr != "bytes 3000-8999/10000" {
+		t.Fatal("wrong content range:", cr)
+	}
+
+	// A range should only be returned if the entity tag matches.
+	resp, body = stream(map[string]string{"Range": "bytes=9000-", "If-Range": etag})
+	if resp.StatusCode != http.StatusPartialContent || !bytes.Equal(body, original[9000:]) {
+		t.Fatal("streamed range does not match original:", resp.Status)
+	}
+	resp, body = stream(map[string]string{"Range": "bytes=9000-", "If-Range": `"foo"`})
+	if resp.StatusCode != http.StatusOK || !bytes.Equal(body, original) {
+		t.Fatal("expected whole file for mismatched entity tag:", resp.Status)
+	}
+
+	// Unsatisfiable ranges and unknown files should be rejected.
+	resp, _ = stream(map[string]string{"Range": "bytes=20000-"})
+	if resp.StatusCode != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatal("expected 416, got", resp.Status)
+	}
+	resp, err = HttpGET("http://" + st.server.listener.Addr().String() + "/renter/stream/foo.mp4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatal("expected 404 when streaming unknown file, got", resp.Status)
+	}
+}
+
 // TestRenterPaths tests that the /renter routes handle path parameters
 // properly.
 func TestRenterPaths(t *testing.T) {
@@ -765,6 +905,27 @@ func TestRenterHandlerGetAndPost(t *testing.T) {
 	if err == nil || err.Error() != contractor.ErrAllowanceZeroWindow.Error() {
 		t.Errorf("expected error to be %v, got %v", contractor.ErrAllowanceZeroWindow, err)
 	}
+
+	// Resize the chunk cache without changing the allowance.
+	cacheValues := url.Values{}
+	cacheValues.Set("chunkcachesize", "1000")
+	cacheValues.Set("chunkcachedisksize", "2000")
+	if err = st.stdPostAPI("/renter", cacheValues); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.getAPI("/renter", &get); err != nil {
+		t.Fatal(err)
+	}
+	if get.Settings.ChunkCacheSize != 1000 || get.Settings.ChunkCacheDiskSize != 2000 {
+		t.Fatal("chunk cache was not resized:", get.Settings)
+	}
+	if get.Settings.Allowance.Period != expectedPeriod {
+		t.Fatal("resizing the chunk cache changed the allowance")
+	}
+	cacheValues.Set("chunkcachesize", "-1")
+	if err = st.stdPostAPI("/renter", cacheValues); err == nil || !strings.HasPrefix(err.Error(), "unable to parse chunkcachesize") {
+		t.Errorf("expected error to begin with 'unable to parse chunkcachesize'; got %v", err)
+	}
 }
 
 // TestRenterLoadNonexistent checks that attempting to upload or download a
@@ -1006,6 +1167,84 @@ func TestRenterHandlerDelete(t *testing.T) {
 	}
 }
 
+// TestRenterShareLoadASCII checks that a file can be shared through
+// /renter/shareascii and loaded back into the renter through
+// /renter/loadascii.
+func TestRenterShareLoadASCII(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	st, err := createServerTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.server.panicClose()
+
+	// Anounce the host and start accepting contracts.
+	if err := st.announceHost(); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.acceptContracts(); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.setHostStorage(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Set an allowance for the renter, allowing a contract to be formed.
+	allowanceValues := url.Values{}
+	allowanceValues.Set("funds", testFunds)
+	allowanceValues.Set("period", testPeriod)
+	if err = st.stdPostAPI("/renter", allowanceValues); err != nil {
+		t.Fatal(err)
+	}
+
+	// Create a file and upload it to the host.
+	path := filepath.Join(st.dir, "test.dat")
+	if err = createRandFile(path, 1024); err != nil {
+		t.Fatal(err)
+	}
+	uploadValues := url.Values{}
+	uploadValues.Set("source", path)
+	if err = st.stdPostAPI("/renter/upload/test", uploadValues); err != nil {
+		t.Fatal(err)
+	}
+
+	// Share the file, then delete it from the renter.
+	var share RenterShareASCII
+	if err = st.getAPI("/renter/shareascii?siapaths=test", &share); err != nil {
+		t.Fatal(err)
+	}
+	if err = st.stdPostAPI("/renter/delete/test", url.Values{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Load the file back into the renter.
+	loadValues := url.Values{}
+	loadValues.Set("asciisia", share.ASCIIsia)
+	var load RenterLoad
+	if err = st.postAPI("/renter/loadascii", loadValues, &load); err != nil {
+		t.Fatal(err)
+	}
+	if len(load.FilesAdded) != 1 || load.FilesAdded[0] != "test" {
+		t.Fatal("file was not loaded properly:", load.FilesAdded)
+	}
+	var files RenterFiles
+	if err = st.getAPI("/renter/files", &files); err != nil {
+		t.Fatal(err)
+	}
+	if len(files.Files) != 1 || files.Files[0].SiaPath != "test" || files.Files[0].Filesize != 1024 {
+		t.Fatalf("renter's list of files should contain the loaded file; got %v instead", files)
+	}
+
+	// Loading garbage should fail.
+	loadValues.Set("asciisia", "garbage")
+	if err = st.stdPostAPI("/renter/loadascii", loadValues); err == nil {
+		t.Fatal("expected an error when loading an invalid .sia file")
+	}
+}
+
 // Tests that the /renter/upload call checks for relative paths.
 func TestRenterRelativePathErrorUpload(t *testing.T) {
 	if testing.Short() {
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
@@ -797,6 +798,73 @@ func TestHostAndRentVanilla(t *testing.T) {
 		t.Error("host has not seen the file contract on the blockchain")
 	}
 
+	// The obligation should be listed by the API, filtered by status.
+	var hc HostContractsGET
+	err = st.getAPI("/host/contracts?status=unresolved", &hc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hc.Contracts) != 1 || hc.Contracts[0].ObligationID != obligations[0].ObligationID {
+		t.Fatal("unresolved obligation was not listed:", hc.Contracts)
+	}
+	err = st.getAPI("/host/contracts?status=failed", &hc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hc.Contracts) != 0 {
+		t.Fatal("failed obligations should not be listed:", hc.Contracts)
+	}
+	var hcg HostContractGET
+	err = st.getAPI("/host/contracts/"+obligations[0].ObligationID.String(), &hcg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hcg.Contract.ObligationID != obligations[0].ObligationID || hcg.Contract.DataSize == 0 {
+		t.Fatal("wrong obligation details:", hcg.Contract)
+	}
+	resp, err := HttpGET("http://" + st.server.listener.Addr().String() + "/host/contracts/" + types.FileContractID{}.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatal("expected 404 for an unknown obligation, got", resp.StatusCode)
+	}
+
+	// The upload should show up in the bandwidth report of the host.
+	var hbg HostBandwidthGET
+	err = st.getAPI("/host/bandwidth", &hbg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hbg.RPCs["revisecontract"].Upload < modules.SectorSize {
+		t.Fatal("revision traffic was not recorded:", hbg.RPCs)
+	}
+	if len(hbg.Contracts) != 1 || hbg.Contracts[0].ID != obligations[0].ObligationID || len(hbg.History) == 0 {
+		t.Fatal("wrong bandwidth report:", hbg.Contracts, hbg.History)
+	}
+
+	// The formation and the upload should be in the ledger of the host.
+	var hlg HostLedgerGET
+	err = st.getAPI("/host/ledger", &hlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entryTypes := make(map[string]bool)
+	for _, entry := range hlg.Entries {
+		entryTypes[entry.Type] = true
+	}
+	if !entryTypes[modules.HostLedgerContractFormed] || !entryTypes[modules.HostLedgerRevisionRevenue] {
+		t.Fatal("ledger is missing entries:", hlg.Entries)
+	}
+	err = st.getAPI("/host/ledger?startheight=0&endheight=0", &hlg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hlg.Entries) != 0 {
+		t.Fatal("ledger entries were not filtered by height:", hlg.Entries)
+	}
+
 	// Mine blocks until the host should have submitted a storage proof.
 	for i := 0; i <= testPeriodInt+5; i++ {
 		_, err := st.miner.AddBlock()
@@ -44,7 +44,14 @@ func (api *API) buildHttpRoutes(requiredUserAgent string, requiredPassword strin
 		router.GET("/host", api.hostHandlerGET)                                                   // Get the host status.
 		router.POST("/host", RequirePassword(api.hostHandlerPOST, requiredPassword))              // Change the settings of the host.
 		router.POST("/host/announce", RequirePassword(api.hostAnnounceHandler, requiredPassword)) // Announce the host to the network.
+		router.GET("/host/bandwidth", api.hostBandwidthHandlerGET)
+		router.GET("/host/contracts", api.hostContractsHandlerGET)
+		router.GET("/host/contracts/:id", api.hostContractHandlerGET)
 		router.GET("/host/estimatescore", api.hostEstimateScoreGET)
+		router.GET("/host/ledger", api.hostLedgerHandlerGET)
+		router.GET("/host/maintenance", api.hostMaintenanceHandlerGET)
+		router.POST("/host/maintenance", RequirePassword(api.hostMaintenanceHandlerPOST, requiredPassword))
+		router.POST("/host/maintenance/stop", RequirePassword(api.hostMaintenanceStopHandler, requiredPassword))
 
 		// Calls pertaining to the storage manager that the host uses.
 		router.GET("/host/storage", api.storageHandler)
@@ -67,28 +74,47 @@ func (api *API) buildHttpRoutes(requiredUserAgent string, requiredPassword strin
 	if api.renter != nil {
 		router.GET("/renter", api.renterHandlerGET)
 		router.POST("/renter", RequirePassword(api.renterHandlerPOST, requiredPassword))
+		router.GET("/renter/alerts", api.renterAlertsHandler)
 		router.GET("/renter/contracts", api.renterContractsHandler)
+		router.POST("/renter/contracts/form", RequirePassword(api.renterContractsFormHandler, requiredPassword))
+		router.POST("/renter/contracts/renew/:id", RequirePassword(api.renterContractsRenewHandler, requiredPassword))
+		router.POST("/renter/contracts/utility/:id", RequirePassword(api.renterContractsUtilityHandler, requiredPassword))
 		router.GET("/renter/downloads", api.renterDownloadsHandler)
 		router.GET("/renter/files", api.renterFilesHandler)
+		router.GET("/renter/health", api.renterHealthHandler)
 		router.GET("/renter/prices", api.renterPricesHandler)
+		router.GET("/renter/spending", api.renterSpendingHandler)
 
-		// TODO: re-enable these routes once the new .sia format has been
-		// standardized and implemented.
-		// router.POST("/renter/load", RequirePassword(api.renterLoadHandler, requiredPassword))
-		// router.POST("/renter/loadascii", RequirePassword(api.renterLoadAsciiHandler, requiredPassword))
-		// router.GET("/renter/share", RequirePassword(api.renterShareHandler, requiredPassword))
-		// router.GET("/renter/shareascii", RequirePassword(api.renterShareAsciiHandler, requiredPassword))
+		router.POST("/renter/load", RequirePassword(api.renterLoadHandler, requiredPassword))
+		router.POST("/renter/loadascii", RequirePassword(api.renterLoadAsciiHandler, requiredPassword))
+		router.GET("/renter/share", RequirePassword(api.renterShareHandler, requiredPassword))
+		router.GET("/renter/shareascii", RequirePassword(api.renterShareAsciiHandler, requiredPassword))
 
+		router.GET("/renter/dir/*siapath", api.renterDirHandlerGET)
+		router.POST("/renter/dir/*siapath", RequirePassword(api.renterDirHandlerPOST, requiredPassword))
 		router.POST("/renter/delete/*siapath", RequirePassword(api.renterDeleteHandler, requiredPassword))
 		router.GET("/renter/download/*siapath", RequirePassword(api.renterDownloadHandler, requiredPassword))
 		router.GET("/renter/downloadasync/*siapath", RequirePassword(api.renterDownloadAsyncHandler, requiredPassword))
+		router.POST("/renter/download/cancel/:id", RequirePassword(api.renterDownloadCancelHandler, requiredPassword))
+		router.POST("/renter/download/pause/:id", RequirePassword(api.renterDownloadPauseHandler, requiredPassword))
+		router.POST("/renter/download/resume/:id", RequirePassword(api.renterDownloadResumeHandler, requiredPassword))
+		router.GET("/renter/stream/*siapath", RequirePassword(api.renterStreamHandler, requiredPassword))
+		router.POST("/renter/redundancy/*siapath", RequirePassword(api.renterRedundancyHandler, requiredPassword))
+		router.POST("/renter/priority/*siapath", RequirePassword(api.renterPriorityHandler, requiredPassword))
 		router.POST("/renter/rename/*siapath", RequirePassword(api.renterRenameHandler, requiredPassword))
 		router.POST("/renter/upload/*siapath", RequirePassword(api.renterUploadHandler, requiredPassword))
+		router.POST("/renter/uploadstream/*siapath", RequirePassword(api.renterUploadStreamHandler, requiredPassword))
+		router.POST("/renter/uploads/pause/*siapath", RequirePassword(api.renterUploadsPauseHandler, requiredPassword))
+		router.POST("/renter/uploads/resume/*siapath", RequirePassword(api.renterUploadsResumeHandler, requiredPassword))
 
 		// HostDB endpoints.
 		router.GET("/hostdb/active", api.hostdbActiveHandler)
 		router.GET("/hostdb/all", api.hostdbAllHandler)
+		router.GET("/hostdb/filtermode", api.hostdbFilterModeHandlerGET)
+		router.POST("/hostdb/filtermode", RequirePassword(api.hostdbFilterModeHandlerPOST, requiredPassword))
 		router.GET("/hostdb/hosts/:pubkey", api.hostdbHostsHandler)
+		router.GET("/hostdb/weightprofile", api.hostdbWeightProfileHandlerGET)
+		router.POST("/hostdb/weightprofile", RequirePassword(api.hostdbWeightProfileHandlerPOST, requiredPassword))
 	}
 
 	// Transaction pool API Calls
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/NebulousLabs/Sia/api"
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 
 	"github.com/spf13/cobra"
@@ -26,6 +29,26 @@ var (
 			"file. Intended for upload to `https://rankings.sia.tech/`.",
 		Run: wrap(renterexportcontracttxnscmd),
 	}
+
+	hostLedgerExportCmd = &cobra.Command{
+		Use:   "export [destination]",
+		Short: "export the host's financial ledger to CSV",
+		Long: `Export the entries of the host's financial ledger to the specified file as
+CSV, one row per entry. All amounts are in hastings, and times are unix
+timestamps.`,
+		Run: wrap(hostledgerexportcmd),
+	}
+
+	renterExportSpendingCmd = &cobra.Command{
+		Use:   "spending [destination]",
+		Short: "export the renter's spending for every billing period",
+		Long: `Export a report of the renter's spending for every billing period, including
+the current period, to the specified file. The spending is broken down by host
+and by contract. Reports are exported as JSON, or as CSV with --format csv. In
+the CSV file, every period has a row for the total spending, followed by a row
+for every host and a row for every contract. All amounts are in hastings.`,
+		Run: wrap(renterexportspendingcmd),
+	}
 )
 
 // renterexportcontracttxnscmd is the handler for the command `siac renter export contract-txns`.
@@ -51,3 +74,107 @@ func renterexportcontracttxnscmd(destination string) {
 	}
 	fmt.Println("Exported contract data to", destination)
 }
+
+// renterexportspendingcmd is the handler for the command `siac renter export spending`.
+// Exports the spending reports to JSON or CSV.
+func renterexportspendingcmd(destination string) {
+	if renterExportFormat != "json" && renterExportFormat != "csv" {
+		die("Could not export spending: format must be json or csv")
+	}
+	var rs api.RenterSpendingGET
+	err := getAPI("/renter/spending", &rs)
+	if err != nil {
+		die("Could not retrieve spending:", err)
+	}
+	destination = abs(destination)
+	file, err := os.Create(destination)
+	if err != nil {
+		die("Could not export to file:", err)
+	}
+	defer file.Close()
+	if renterExportFormat == "json" {
+		err = json.NewEncoder(file).Encode(rs.Periods)
+	} else {
+		err = writeSpendingCSV(file, rs.Periods)
+	}
+	if err != nil {
+		die("Could not export to file:", err)
+	}
+	fmt.Println("Exported spending data to", destination)
+}
+
+// hostledgerexportcmd is the handler for the command `siac host ledger export`.
+// Exports the ledger entries to CSV.
+func hostledgerexportcmd(destination string) {
+	var hl api.HostLedgerGET
+	err := getAPI(hostledgerquery(), &hl)
+	if err != nil {
+		die("Could not retrieve ledger:", err)
+	}
+	destination = abs(destination)
+	file, err := os.Create(destination)
+	if err != nil {
+		die("Could not export to file:", err)
+	}
+	defer file.Close()
+	err = writeLedgerCSV(file, hl.Entries)
+	if err != nil {
+		die("Could not export to file:", err)
+	}
+	fmt.Println("Exported ledger to", destination)
+}
+
+// writeLedgerCSV writes the ledger entries to out as CSV.
+func writeLedgerCSV(out io.Writer, entries []modules.HostLedgerEntry) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"Block Height", "Timestamp", "Type", "Contract", "Amount"})
+	for _, entry := range entries {
+		w.Write([]string{
+			fmt.Sprint(entry.BlockHeight),
+			fmt.Sprint(entry.Timestamp.Unix()),
+			entry.Type,
+			entry.ContractID.String(),
+			entry.Amount.String(),
+		})
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// writeSpendingCSV writes the spending reports to out as CSV.
+func writeSpendingCSV(out io.Writer, periods []modules.SpendingReport) error {
+	w := csv.NewWriter(out)
+	w.Write([]string{"Period Start", "Period End", "Host", "Contract", "Storage", "Upload", "Download", "Contract Fees", "Txn Fees", "Siafund Fees"})
+	row := func(p modules.SpendingReport, host, contract string, b modules.SpendingBreakdown) {
+		w.Write([]string{
+			fmt.Sprint(p.StartHeight),
+			fmt.Sprint(p.EndHeight),
+			host,
+			contract,
+			b.StorageSpending.String(),
+			b.UploadSpending.String(),
+			b.DownloadSpending.String(),
+			b.ContractFees.String(),
+			b.TxnFees.String(),
+			b.SiafundFees.String(),
+		})
+	}
+	for _, p := range periods {
+		row(p, "", "", modules.SpendingBreakdown{
+			DownloadSpending: p.Spending.DownloadSpending,
+			StorageSpending:  p.Spending.StorageSpending,
+			UploadSpending:   p.Spending.UploadSpending,
+			ContractFees:     p.Spending.ContractFees,
+			TxnFees:          p.Spending.TxnFees,
+			SiafundFees:      p.Spending.SiafundFees,
+		})
+		for _, hs := range p.Hosts {
+			row(p, hs.HostPublicKey.String(), "", hs.SpendingBreakdown)
+		}
+		for _, cs := range p.Contracts {
+			row(p, cs.HostPublicKey.String(), cs.ID.String(), cs.SpendingBreakdown)
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
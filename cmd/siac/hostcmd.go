@@ -7,6 +7,7 @@ import (
 	"sort"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/NebulousLabs/Sia/api"
 	"github.com/NebulousLabs/Sia/modules"
@@ -29,6 +30,66 @@ Doing so will override the standard connectivity checks.`,
 		Run: hostannouncecmd,
 	}
 
+	hostContractsCmd = &cobra.Command{
+		Use:   "contracts",
+		Short: "Show the host's storage obligations",
+		Long: `Show the storage obligations of the host, and which of them are at risk of
+missing their storage proof window. Obligations can be filtered by status with
+--status: unresolved, rejected, succeeded or failed.`,
+		Run: wrap(hostcontractscmd),
+	}
+
+	hostBandwidthCmd = &cobra.Command{
+		Use:   "bandwidth",
+		Short: "Show the host's bandwidth usage",
+		Long: `Show the traffic of the host in total, per RPC, per contract, and over
+the recent history. Download is traffic sent by the host, upload is traffic
+received by the host.`,
+		Run: wrap(hostbandwidthcmd),
+	}
+
+	hostLedgerCmd = &cobra.Command{
+		Use:   "ledger",
+		Short: "Show the host's financial ledger",
+		Long: `Show the financial events of the host: contracts formed, revision revenue,
+accepted storage proofs, lost collateral and transaction fees. The entries can
+be limited to a range of block heights with --startheight and --endheight.`,
+		Run: wrap(hostledgercmd),
+	}
+
+	hostMaintenanceCmd = &cobra.Command{
+		Use:   "maintenance",
+		Short: "Show the host's scheduled maintenance",
+		Long: `Show the scheduled maintenance of the host, and the storage obligations
+whose proof window falls within the planned downtime.`,
+		Run: wrap(hostmaintenancecmd),
+	}
+
+	hostMaintenanceStartCmd = &cobra.Command{
+		Use:   "start [time until shutdown] [downtime]",
+		Short: "Schedule a maintenance of the host",
+		Long: `Schedule a maintenance of the host. Both durations are given with units,
+e.g. 2h30m. The host stops accepting contracts until the end of the downtime,
+submits storage proofs early where possible, and reports the storage
+obligations that are at risk. The settings of the host are restored once the
+downtime has passed.`,
+		Run: wrap(hostmaintenancestartcmd),
+	}
+
+	hostMaintenanceStopCmd = &cobra.Command{
+		Use:   "stop",
+		Short: "End the maintenance of the host",
+		Long:  "End the maintenance of the host and restore its settings.",
+		Run:   wrap(hostmaintenancestopcmd),
+	}
+
+	hostContractsViewCmd = &cobra.Command{
+		Use:   "view [id]",
+		Short: "View details of a storage obligation",
+		Long:  "View all details available of a storage obligation of the host.",
+		Run:   wrap(hostcontractsviewcmd),
+	}
+
 	hostCmd = &cobra.Command{
 		Use:   "host",
 		Short: "Perform host actions",
@@ -58,6 +119,26 @@ Available settings:
      minstorageprice:           currency / TB / Month
      minuploadbandwidthprice:   currency / TB
 
+     autopricing:               boolean
+     autopricingcurve:          number
+     autopricingdemandtarget:   contracts / hour
+     autopricingdemandweight:   number between 0 and 1
+     maxdownloadbandwidthprice: currency / TB
+     maxstorageprice:           currency / TB / Month
+     maxuploadbandwidthprice:   currency / TB
+
+     maxbandwidthspeed:       bytes / second
+     maxrenterbandwidthspeed: bytes / second
+     maxrenterbandwidthquota: bytes / day
+
+With autopricing enabled, the storage and bandwidth prices move between the
+min and max prices as the host fills up and as renters form contracts with it.
+
+Bandwidth speeds limit the uploads and downloads of all renters combined, and
+of the contracts of a single renter. They are given with units, e.g. 10MB; a
+speed of 0B removes the limit. The bandwidth quota limits the total uploads and
+downloads of a single renter per day, e.g. 50GB; a quota of 0B removes it.
+
 Currency units can be specified, e.g. 10SC; run 'siac help wallet' for details.
 
 Durations (maxduration and windowsize) must be specified in either blocks (b),
@@ -146,7 +227,7 @@ func hostcmd() {
 	}
 
 	// convert price from bytes/block to TB/Month
-	price := currencyUnits(is.MinStoragePrice.Mul(modules.BlockBytesPerMonthTerabyte))
+	price := currencyUnits(es.StoragePrice.Mul(modules.BlockBytesPerMonthTerabyte))
 	// calculate total revenue
 	totalRevenue := fm.ContractCompensation.
 		Add(fm.StorageRevenue).
@@ -197,6 +278,23 @@ Host Internal Settings:
 	minstorageprice:           %v / TB / Month
 	minuploadbandwidthprice:   %v / TB
 
+	autopricing:               %v
+	autopricingcurve:          %v
+	autopricingdemandtarget:   %v Contracts / Hour
+	autopricingdemandweight:   %v
+	maxdownloadbandwidthprice: %v / TB
+	maxstorageprice:           %v / TB / Month
+	maxuploadbandwidthprice:   %v / TB
+
+	maxbandwidthspeed:       %v
+	maxrenterbandwidthspeed: %v
+	maxrenterbandwidthquota: %v
+
+Host Prices:
+	Storage Price:            %v / TB / Month
+	Download Bandwidth Price: %v / TB
+	Upload Bandwidth Price:   %v / TB
+
 Host Financials:
 	Contract Count:               %v
 	Transaction Fee Compensation: %v
@@ -223,6 +321,8 @@ RPC Stats:
 	Revise Calls:       %v
 	Settings Calls:     %v
 	FormContract Calls: %v
+	Downloaded:         %v
+	Uploaded:           %v
 `,
 			connectabilityString,
 
@@ -240,6 +340,20 @@ RPC Stats:
 			currencyUnits(is.MinStoragePrice.Mul(modules.BlockBytesPerMonthTerabyte)),
 			currencyUnits(is.MinUploadBandwidthPrice.Mul(modules.BytesPerTerabyte)),
 
+			yesNo(is.AutoPricing), is.AutoPricingCurve,
+			is.AutoPricingDemandTarget, is.AutoPricingDemandWeight,
+			currencyUnits(is.MaxDownloadBandwidthPrice.Mul(modules.BytesPerTerabyte)),
+			currencyUnits(is.MaxStoragePrice.Mul(modules.BlockBytesPerMonthTerabyte)),
+			currencyUnits(is.MaxUploadBandwidthPrice.Mul(modules.BytesPerTerabyte)),
+
+			speedLimitUnits(is.MaxBandwidthSpeed),
+			speedLimitUnits(is.MaxRenterBandwidthSpeed),
+			quotaUnits(is.MaxRenterBandwidthQuota),
+
+			currencyUnits(es.StoragePrice.Mul(modules.BlockBytesPerMonthTerabyte)),
+			currencyUnits(es.DownloadBandwidthPrice.Mul(modules.BytesPerTerabyte)),
+			currencyUnits(es.UploadBandwidthPrice.Mul(modules.BytesPerTerabyte)),
+
 			fm.ContractCount, currencyUnits(fm.ContractCompensation),
 			currencyUnits(fm.PotentialContractCompensation),
 			currencyUnits(fm.TransactionFeeExpenses),
@@ -258,7 +372,9 @@ RPC Stats:
 
 			nm.ErrorCalls, nm.UnrecognizedCalls, nm.DownloadCalls,
 			nm.RenewCalls, nm.ReviseCalls, nm.SettingsCalls,
-			nm.FormContractCalls)
+			nm.FormContractCalls,
+			filesizeUnits(int64(nm.DownloadBytes)),
+			filesizeUnits(int64(nm.UploadBytes)))
 	} else {
 		fmt.Printf(`Host info:
 	Connectability Status: %v
@@ -326,7 +442,7 @@ func hostconfigcmd(param, value string) {
 		}
 
 	// currency/TB (convert to hastings/byte)
-	case "mindownloadbandwidthprice", "minuploadbandwidthprice":
+	case "mindownloadbandwidthprice", "minuploadbandwidthprice", "maxdownloadbandwidthprice", "maxuploadbandwidthprice":
 		hastings, err := parseCurrency(value)
 		if err != nil {
 			die("Could not parse "+param+":", err)
@@ -336,7 +452,7 @@ func hostconfigcmd(param, value string) {
 		value = c.String()
 
 	// currency/TB/month (convert to hastings/byte/block)
-	case "collateral", "minstorageprice":
+	case "collateral", "minstorageprice", "maxstorageprice":
 		hastings, err := parseCurrency(value)
 		if err != nil {
 			die("Could not parse "+param+":", err)
@@ -346,7 +462,7 @@ func hostconfigcmd(param, value string) {
 		value = c.String()
 
 	// bool (allow "yes" and "no")
-	case "acceptingcontracts":
+	case "acceptingcontracts", "autopricing":
 		switch strings.ToLower(value) {
 		case "yes":
 			value = "true"
@@ -354,6 +470,13 @@ func hostconfigcmd(param, value string) {
 			value = "false"
 		}
 
+	// bytes/second and bytes/day (allow units)
+	case "maxbandwidthspeed", "maxrenterbandwidthspeed", "maxrenterbandwidthquota":
+		value, err = parseFilesize(value)
+		if err != nil {
+			die("Could not parse "+param+":", err)
+		}
+
 	// duration (convert to blocks)
 	case "maxduration", "windowsize":
 		value, err = parsePeriod(value)
@@ -362,7 +485,8 @@ func hostconfigcmd(param, value string) {
 		}
 
 	// other valid settings
-	case "maxdownloadbatchsize", "maxrevisebatchsize", "netaddress":
+	case "maxdownloadbatchsize", "maxrevisebatchsize", "netaddress",
+		"autopricingcurve", "autopricingdemandtarget", "autopricingdemandweight":
 
 	// invalid settings
 	default:
@@ -467,6 +591,317 @@ func hostfolderresizecmd(path, newsize string) {
 	fmt.Printf("Resized folder %v to %v\n", path, newsize)
 }
 
+// quotaUnits returns a daily bandwidth quota in a human-readable format.
+func quotaUnits(bytesPerDay uint64) string {
+	if bytesPerDay == 0 {
+		return "unlimited"
+	}
+	return filesizeUnits(int64(bytesPerDay)) + "/day"
+}
+
+// obligationStatusName returns the name of an obligation status, as accepted
+// by the status parameter of /host/contracts.
+func obligationStatusName(status uint64) string {
+	switch status {
+	case modules.ObligationStatusUnresolved:
+		return "unresolved"
+	case modules.ObligationStatusRejected:
+		return "rejected"
+	case modules.ObligationStatusSucceeded:
+		return "succeeded"
+	case modules.ObligationStatusFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// hostcontractscmd is the handler for the command `siac host contracts`.
+// It lists the host's storage obligations, followed by the obligations that
+// are at risk of missing their storage proof window.
+func hostcontractscmd() {
+	var hc api.HostContractsGET
+	err := getAPI("/host/contracts?status="+hostContractsStatus, &hc)
+	if err != nil {
+		die("Could not get storage obligations:", err)
+	}
+	if len(hc.Contracts) == 0 {
+		fmt.Println("No storage obligations.")
+		return
+	}
+	sort.Slice(hc.Contracts, func(i, j int) bool {
+		return hc.Contracts[i].ExpirationHeight < hc.Contracts[j].ExpirationHeight
+	})
+
+	fmt.Println("Storage Obligations:")
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Status\tData\tLocked Collateral\tPotential Revenue\tExpiration\tProof Deadline\tID")
+	var atRisk []modules.StorageObligation
+	for _, so := range hc.Contracts {
+		revenue := so.ContractCost.Add(so.PotentialStorageRevenue).Add(so.PotentialDownloadRevenue).Add(so.PotentialUploadRevenue)
+		fmt.Fprintf(w, "%v\t%v\t%8s\t%8s\t%v\t%v\t%v\n",
+			obligationStatusName(so.ObligationStatus),
+			filesizeUnits(int64(so.DataSize)),
+			currencyUnits(so.LockedCollateral),
+			currencyUnits(revenue),
+			so.ExpirationHeight,
+			so.ProofDeadline,
+			so.ObligationID)
+		if so.AtRisk {
+			atRisk = append(atRisk, so)
+		}
+	}
+	w.Flush()
+
+	if len(atRisk) == 0 {
+		return
+	}
+	fmt.Println("\nAt Risk:")
+	w = tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Reason\tRisked Collateral\tProof Deadline\tID")
+	for _, so := range atRisk {
+		fmt.Fprintf(w, "%v\t%8s\t%v\t%v\n",
+			so.RiskReason,
+			currencyUnits(so.RiskedCollateral),
+			so.ProofDeadline,
+			so.ObligationID)
+	}
+	w.Flush()
+}
+
+// hostbandwidthcmd is the handler for the command `siac host bandwidth`. It
+// prints the traffic of the host per RPC, per contract, and over time.
+func hostbandwidthcmd() {
+	var hb api.HostBandwidthGET
+	err := getAPI("/host/bandwidth", &hb)
+	if err != nil {
+		die("Could not get host bandwidth:", err)
+	}
+	fmt.Printf("Total: %v downloaded, %v uploaded\n",
+		filesizeUnits(int64(hb.Total.Download)), filesizeUnits(int64(hb.Total.Upload)))
+	if len(hb.RPCs) == 0 {
+		return
+	}
+
+	rpcs := make([]string, 0, len(hb.RPCs))
+	for rpc := range hb.RPCs {
+		rpcs = append(rpcs, rpc)
+	}
+	sort.Strings(rpcs)
+	fmt.Println("\nRPCs:")
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "RPC\tDownloaded\tUploaded")
+	for _, rpc := range rpcs {
+		fmt.Fprintf(w, "%v\t%9s\t%9s\n", rpc,
+			filesizeUnits(int64(hb.RPCs[rpc].Download)),
+			filesizeUnits(int64(hb.RPCs[rpc].Upload)))
+	}
+	w.Flush()
+
+	if len(hb.Contracts) > 0 {
+		fmt.Println("\nContracts:")
+		w = tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Downloaded\tUploaded\tID")
+		for _, c := range hb.Contracts {
+			fmt.Fprintf(w, "%9s\t%9s\t%v\n",
+				filesizeUnits(int64(c.Download)),
+				filesizeUnits(int64(c.Upload)),
+				c.ID)
+		}
+		w.Flush()
+	}
+
+	fmt.Println("\nHistory:")
+	w = tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Start\tDownloaded\tUploaded")
+	for _, p := range hb.History {
+		fmt.Fprintf(w, "%v\t%9s\t%9s\n", p.Start.Format("2006-01-02 15:04"),
+			filesizeUnits(int64(p.Download)),
+			filesizeUnits(int64(p.Upload)))
+	}
+	w.Flush()
+}
+
+// hostledgerquery returns the query string that selects the ledger entries
+// within the block heights given by the ledger flags.
+func hostledgerquery() string {
+	return "/host/ledger?startheight=" + hostLedgerStart + "&endheight=" + hostLedgerEnd
+}
+
+// hostledgercmd is the handler for the command `siac host ledger`. It prints
+// the totals of each type of ledger entry, followed by the entries.
+func hostledgercmd() {
+	var hl api.HostLedgerGET
+	err := getAPI(hostledgerquery(), &hl)
+	if err != nil {
+		die("Could not get host ledger:", err)
+	}
+	if len(hl.Entries) == 0 {
+		fmt.Println("No ledger entries.")
+		return
+	}
+
+	totals := make(map[string]types.Currency)
+	for _, entry := range hl.Entries {
+		totals[entry.Type] = totals[entry.Type].Add(entry.Amount)
+	}
+	fmt.Println("Totals:")
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	for _, entryType := range []string{modules.HostLedgerContractFormed, modules.HostLedgerRevisionRevenue,
+		modules.HostLedgerStorageProof, modules.HostLedgerRevenueLost, modules.HostLedgerCollateralLost,
+		modules.HostLedgerTransactionFee} {
+		fmt.Fprintf(w, "\t%v:\t%v\n", entryType, currencyUnits(totals[entryType]))
+	}
+	w.Flush()
+
+	fmt.Println("\nEntries:")
+	w = tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "Height\tTime\tType\tAmount\tContract")
+	for _, entry := range hl.Entries {
+		fmt.Fprintf(w, "%v\t%v\t%v\t%8s\t%v\n",
+			entry.BlockHeight,
+			entry.Timestamp.Format("2006-01-02 15:04"),
+			entry.Type,
+			currencyUnits(entry.Amount),
+			entry.ContractID)
+	}
+	w.Flush()
+}
+
+// hostmaintenancecmd is the handler for the command `siac host maintenance`.
+// It prints the scheduled maintenance of the host and the storage obligations
+// that it affects.
+func hostmaintenancecmd() {
+	var hm api.HostMaintenanceGET
+	err := getAPI("/host/maintenance", &hm)
+	if err != nil {
+		die("Could not get host maintenance:", err)
+	}
+	if !hm.Active {
+		fmt.Println("No maintenance scheduled.")
+		return
+	}
+	fmt.Printf(`Maintenance:
+  Shutdown: %v (block %v)
+  Restart:  %v (block %v)
+
+  Accepting contracts after restart: %v
+`, hm.Shutdown.Format("2006-01-02 15:04"), hm.ShutdownHeight,
+		hm.Restart.Format("2006-01-02 15:04"), hm.RestartHeight,
+		yesNo(hm.AcceptingContracts))
+
+	if len(hm.Obligations) == 0 {
+		fmt.Println("\nNo storage obligations are affected.")
+		return
+	}
+	fmt.Println("\nAffected storage obligations:")
+	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tProof Window\tCollateral\tAt Risk\tReason")
+	for _, mo := range hm.Obligations {
+		fmt.Fprintf(w, "%v\t%v-%v\t%8s\t%v\t%v\n",
+			mo.ObligationID,
+			mo.ProofWindowStart,
+			mo.ProofDeadline,
+			currencyUnits(mo.RiskedCollateral),
+			yesNo(mo.AtRisk),
+			mo.Reason)
+	}
+	w.Flush()
+}
+
+// hostmaintenancestartcmd is the handler for the command `siac host
+// maintenance start [time until shutdown] [downtime]`. It schedules a
+// maintenance of the host.
+func hostmaintenancestartcmd(untilShutdown, downtime string) {
+	untilDuration, err := time.ParseDuration(untilShutdown)
+	if err != nil {
+		die("Could not parse time until shutdown:", err)
+	}
+	downDuration, err := time.ParseDuration(downtime)
+	if err != nil {
+		die("Could not parse downtime:", err)
+	}
+	shutdown := time.Now().Add(untilDuration)
+	restart := shutdown.Add(downDuration)
+	err = post("/host/maintenance", fmt.Sprintf("shutdown=%v&restart=%v", shutdown.Unix(), restart.Unix()))
+	if err != nil {
+		die("Could not schedule maintenance:", err)
+	}
+	fmt.Printf("Maintenance scheduled from %v to %v. Run 'siac host maintenance' to see the affected storage obligations.\n",
+		shutdown.Format("2006-01-02 15:04"), restart.Format("2006-01-02 15:04"))
+}
+
+// hostmaintenancestopcmd is the handler for the command `siac host
+// maintenance stop`. It ends the maintenance of the host.
+func hostmaintenancestopcmd() {
+	err := post("/host/maintenance/stop", "")
+	if err != nil {
+		die("Could not stop maintenance:", err)
+	}
+	fmt.Println("Maintenance ended.")
+}
+
+// hostcontractsviewcmd is the handler for the command `siac host contracts
+// view [id]`. It prints all details of a storage obligation.
+func hostcontractsviewcmd(id string) {
+	var hc api.HostContractGET
+	err := getAPI("/host/contracts/"+id, &hc)
+	if err != nil {
+		die("Could not get storage obligation:", err)
+	}
+	so := hc.Contract
+	risk := "no"
+	if so.AtRisk {
+		risk = so.RiskReason
+	}
+	fmt.Printf(`Storage Obligation %v
+  Status:  %v
+  At Risk: %v
+
+  Negotiation Height: %v
+  Expiration Height:  %v
+  Proof Deadline:     %v
+  Revision Number:    %v
+
+  Data Size: %v (%v sectors)
+
+  Contract Cost:              %v
+  Potential Storage Revenue:  %v
+  Potential Download Revenue: %v
+  Potential Upload Revenue:   %v
+  Locked Collateral:          %v
+  Risked Collateral:          %v
+  Transaction Fees:           %v
+
+  Origin Confirmed:     %v
+  Revision Constructed: %v
+  Revision Confirmed:   %v
+  Proof Constructed:    %v
+  Proof Confirmed:      %v
+`, so.ObligationID, obligationStatusName(so.ObligationStatus), risk,
+		so.NegotiationHeight, so.ExpirationHeight, so.ProofDeadline, so.RevisionNumber,
+		filesizeUnits(int64(so.DataSize)), so.SectorRootsCount,
+		currencyUnits(so.ContractCost),
+		currencyUnits(so.PotentialStorageRevenue),
+		currencyUnits(so.PotentialDownloadRevenue),
+		currencyUnits(so.PotentialUploadRevenue),
+		currencyUnits(so.LockedCollateral),
+		currencyUnits(so.RiskedCollateral),
+		currencyUnits(so.TransactionFeesAdded),
+		yesNo(so.OriginConfirmed),
+		yesNo(so.RevisionConstructed),
+		yesNo(so.RevisionConfirmed),
+		yesNo(so.ProofConstructed),
+		yesNo(so.ProofConfirmed))
+	if len(so.ValidProofOutputs) == 2 && len(so.MissedProofOutputs) == 2 {
+		fmt.Printf(`
+  Payouts if the proof succeeds: renter %v, host %v
+  Payouts if the proof fails:    renter %v, host %v
+`, currencyUnits(so.ValidProofOutputs[0].Value), currencyUnits(so.ValidProofOutputs[1].Value),
+			currencyUnits(so.MissedProofOutputs[0].Value), currencyUnits(so.MissedProofOutputs[1].Value))
+	}
+}
+
 // hostsectordeletecmd deletes a sector from the host.
 func hostsectordeletecmd(root string) {
 	err := post("/host/storage/sectors/delete/"+root, "")
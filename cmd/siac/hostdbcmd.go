@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"math/big"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
@@ -17,6 +18,26 @@ const scanHistoryLen = 30
 var (
 	hostdbNumHosts int
 	hostdbVerbose  bool
+
+	// Exponents of a custom weight profile.
+	hostdbAgeExponent              string
+	hostdbCollateralExponent       string
+	hostdbInteractionExponent      string
+	hostdbPerformanceExponent      string
+	hostdbPriceExponent            string
+	hostdbStorageRemainingExponent string
+	hostdbUptimeExponent           string
+	hostdbVersionExponent          string
+
+	// Multipliers of a custom weight profile.
+	hostdbAgeMultiplier              string
+	hostdbCollateralMultiplier       string
+	hostdbInteractionMultiplier      string
+	hostdbPerformanceMultiplier      string
+	hostdbPriceMultiplier            string
+	hostdbStorageRemainingMultiplier string
+	hostdbUptimeMultiplier           string
+	hostdbVersionMultiplier          string
 )
 
 var (
@@ -27,6 +48,48 @@ var (
 		Run:   wrap(hostdbcmd),
 	}
 
+	hostdbFilterModeCmd = &cobra.Command{
+		Use:   "filtermode",
+		Short: "View the filter mode of the hostdb.",
+		Long:  "View the filter mode of the hostdb and the hosts on its filter list.",
+		Run:   wrap(hostdbfiltermodecmd),
+	}
+
+	hostdbSetFilterModeCmd = &cobra.Command{
+		Use:   "setfiltermode [filtermode] [pubkey]...",
+		Short: "Set the filter mode of the hostdb.",
+		Long: `Set the filter mode of the hostdb and replace its filter list.
+
+Available filter modes:
+	disable:    all hosts may be used; the filter list is cleared.
+	blacklist:  the listed hosts are never used.
+	whitelist:  only the listed hosts are used.
+
+Contracts with hosts that are filtered out are not renewed, and replacement
+contracts are formed with other hosts, e.g.:
+	siac hostdb setfiltermode blacklist ed25519:7ae1... ed25519:39bc...`,
+		Run: hostdbsetfiltermodecmd,
+	}
+
+	hostdbWeightProfileCmd = &cobra.Command{
+		Use:   "weightprofile",
+		Short: "View the weight profile of the hostdb.",
+		Long:  "View the profile that the hostdb uses to weight hosts, and the built-in profiles.",
+		Run:   wrap(hostdbweightprofilecmd),
+	}
+
+	hostdbSetWeightProfileCmd = &cobra.Command{
+		Use:   "setweightprofile [profile]",
+		Short: "Set the weight profile of the hostdb.",
+		Long: `Set the profile that the hostdb uses to weight hosts.
+
+The built-in profiles are 'default', 'performance', 'price' and 'reliability'. Any other name
+creates a custom profile, which starts from the exponents and multipliers of the
+default profile and uses the ones provided by the flags, e.g.:
+	siac hostdb setweightprofile cheap --price 3 --uptime 0.5 --age-multiplier 2`,
+		Run: wrap(hostdbsetweightprofilecmd),
+	}
+
 	hostdbViewCmd = &cobra.Command{
 		Use:   "view [pubkey]",
 		Short: "View the full information for a host.",
@@ -39,10 +102,12 @@ var (
 func printScoreBreakdown(info *api.HostdbHostsGET) {
 	fmt.Println("\n  Score Breakdown:")
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "\t\tWeight Profile:\t %v\n", info.ScoreBreakdown.WeightProfile)
 	fmt.Fprintf(w, "\t\tAge:\t %.3f\n", info.ScoreBreakdown.AgeAdjustment)
 	fmt.Fprintf(w, "\t\tBurn:\t %.3f\n", info.ScoreBreakdown.BurnAdjustment)
 	fmt.Fprintf(w, "\t\tCollateral:\t %.3f\n", info.ScoreBreakdown.CollateralAdjustment)
 	fmt.Fprintf(w, "\t\tInteraction:\t %.3f\n", info.ScoreBreakdown.InteractionAdjustment)
+	fmt.Fprintf(w, "\t\tPerformance:\t %.3f\n", info.ScoreBreakdown.PerformanceAdjustment)
 	fmt.Fprintf(w, "\t\tPrice:\t %.3f\n", info.ScoreBreakdown.PriceAdjustment*1e6)
 	fmt.Fprintf(w, "\t\tStorage:\t %.3f\n", info.ScoreBreakdown.StorageRemainingAdjustment)
 	fmt.Fprintf(w, "\t\tUptime:\t %.3f\n", info.ScoreBreakdown.UptimeAdjustment)
@@ -327,5 +392,124 @@ func hostdbviewcmd(pubkey string) {
 	fmt.Println("\n  Scan History Length:", len(info.Entry.ScanHistory))
 	fmt.Printf("  Overall Uptime:      %.3f\n", uptimeRatio)
 
+	// Print the performance of the host, as measured by scans and transfers.
+	fmt.Println("\n  Latency:            ", info.Entry.Latency)
+	fmt.Println("  Upload Throughput:  ", throughputUnits(info.Entry.UploadThroughput))
+	fmt.Println("  Download Throughput:", throughputUnits(info.Entry.DownloadThroughput))
+
+	// Print the addresses that the host has resolved to, most recent first.
+	if len(info.Entry.IPHistory) > 0 {
+		fmt.Println("\n  IP History:")
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for i := len(info.Entry.IPHistory) - 1; i >= 0; i-- {
+			resolved := info.Entry.IPHistory[i]
+			fmt.Fprintf(w, "\t\t%v:\t %v\n", resolved.Timestamp.Format("2006-01-02 15:04"), strings.Join(resolved.IPs, ", "))
+		}
+		w.Flush()
+	}
+
 	fmt.Println()
 }
+
+// throughputUnits converts a measured throughput in bytes per second to a
+// human-readable string.
+func throughputUnits(bytesPerSecond float64) string {
+	if bytesPerSecond == 0 {
+		return "unmeasured"
+	}
+	return filesizeUnits(int64(bytesPerSecond)) + "/s"
+}
+
+// hostdbfiltermodecmd prints the filter mode of the hostdb.
+func hostdbfiltermodecmd() {
+	var fm api.HostdbFilterModeGET
+	err := getAPI("/hostdb/filtermode", &fm)
+	if err != nil {
+		die("Could not get the filter mode:", err)
+	}
+	fmt.Println("Filter mode:", fm.FilterMode)
+	if len(fm.Hosts) == 0 {
+		return
+	}
+	fmt.Println("Filtered hosts:")
+	for _, host := range fm.Hosts {
+		fmt.Println("  " + host)
+	}
+}
+
+// hostdbsetfiltermodecmd sets the filter mode of the hostdb.
+func hostdbsetfiltermodecmd(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	err := post("/hostdb/filtermode", "filtermode="+args[0]+"&hosts="+strings.Join(args[1:], ","))
+	if err != nil {
+		die("Could not set the filter mode:", err)
+	}
+	fmt.Println("Filter mode set to", args[0])
+}
+
+// hostdbweightprofilecmd prints the weight profile of the hostdb.
+func hostdbweightprofilecmd() {
+	var wp api.HostdbWeightProfileGET
+	err := getAPI("/hostdb/weightprofile", &wp)
+	if err != nil {
+		die("Could not get the weight profile:", err)
+	}
+	fmt.Println("Weight profile:", wp.WeightProfile.Name)
+	profiles := append([]modules.HostWeightProfile{wp.WeightProfile}, wp.BuiltinProfiles...)
+
+	fmt.Println("\nExponents:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tProfile\tAge\tCollateral\tInteraction\tPerformance\tPrice\tStorage\tUptime\tVersion")
+	for _, p := range profiles {
+		fmt.Fprintf(w, "\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", p.Name, p.AgeExponent, p.CollateralExponent,
+			p.InteractionExponent, p.PerformanceExponent, p.PriceExponent, p.StorageRemainingExponent, p.UptimeExponent,
+			p.VersionExponent)
+	}
+	w.Flush()
+
+	fmt.Println("\nMultipliers:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "\tProfile\tAge\tCollateral\tInteraction\tPerformance\tPrice\tStorage\tUptime\tVersion")
+	for _, p := range profiles {
+		fmt.Fprintf(w, "\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\t%v\n", p.Name, p.AgeMultiplier, p.CollateralMultiplier,
+			p.InteractionMultiplier, p.PerformanceMultiplier, p.PriceMultiplier, p.StorageRemainingMultiplier,
+			p.UptimeMultiplier, p.VersionMultiplier)
+	}
+	w.Flush()
+}
+
+// hostdbsetweightprofilecmd sets the weight profile of the hostdb.
+func hostdbsetweightprofilecmd(profile string) {
+	vals := "profile=" + profile
+	for param, val := range map[string]string{
+		"ageexponent":              hostdbAgeExponent,
+		"collateralexponent":       hostdbCollateralExponent,
+		"interactionexponent":      hostdbInteractionExponent,
+		"performanceexponent":      hostdbPerformanceExponent,
+		"priceexponent":            hostdbPriceExponent,
+		"storageremainingexponent": hostdbStorageRemainingExponent,
+		"uptimeexponent":           hostdbUptimeExponent,
+		"versionexponent":          hostdbVersionExponent,
+
+		"agemultiplier":              hostdbAgeMultiplier,
+		"collateralmultiplier":       hostdbCollateralMultiplier,
+		"interactionmultiplier":      hostdbInteractionMultiplier,
+		"performancemultiplier":      hostdbPerformanceMultiplier,
+		"pricemultiplier":            hostdbPriceMultiplier,
+		"storageremainingmultiplier": hostdbStorageRemainingMultiplier,
+		"uptimemultiplier":           hostdbUptimeMultiplier,
+		"versionmultiplier":          hostdbVersionMultiplier,
+	} {
+		if val != "" {
+			vals += "&" + param + "=" + val
+		}
+	}
+	err := post("/hostdb/weightprofile", vals)
+	if err != nil {
+		die("Could not set the weight profile:", err)
+	}
+	fmt.Println("Weight profile set to", profile)
+}
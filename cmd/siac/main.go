@@ -17,12 +17,21 @@ import (
 
 var (
 	// Flags.
-	addr              string // override default API address
-	hostVerbose       bool   // display additional host info
-	initForce         bool   // destroy and reencrypt the wallet on init if it already exists
-	initPassword      bool   // supply a custom password when creating a wallet
-	renterListVerbose bool   // Show additional info about uploaded files.
-	renterShowHistory bool   // Show download history in addition to download queue.
+	addr                string // override default API address
+	hostVerbose         bool   // display additional host info
+	hostContractsStatus string // Status of the storage obligations to show.
+	hostLedgerStart     string // First block height of the ledger entries to show.
+	hostLedgerEnd       string // Last block height of the ledger entries to show.
+	initForce           bool   // destroy and reencrypt the wallet on init if it already exists
+	initPassword        bool   // supply a custom password when creating a wallet
+	renterListVerbose   bool   // Show additional info about uploaded files.
+	renterShowHistory   bool   // Show download history in addition to download queue.
+	renterShowExpired   bool   // Show expired contracts in addition to active contracts.
+	renterDataPieces    string // Number of data pieces to erasure code uploads with.
+	renterParityPieces  string // Number of parity pieces to erasure code uploads with.
+	renterErasureCode   string // Type of erasure code to encode uploads with.
+	renterExportFormat  string // Format to export renter data in: json or csv.
+	renterPriority      string // Upload priority of uploaded files.
 )
 
 var (
@@ -262,13 +271,35 @@ func main() {
 	updateCmd.AddCommand(updateCheckCmd)
 
 	root.AddCommand(hostCmd)
-	hostCmd.AddCommand(hostConfigCmd, hostAnnounceCmd, hostFolderCmd, hostSectorCmd)
+	hostCmd.AddCommand(hostConfigCmd, hostAnnounceCmd, hostBandwidthCmd, hostContractsCmd, hostFolderCmd, hostLedgerCmd, hostMaintenanceCmd, hostSectorCmd)
+	hostContractsCmd.AddCommand(hostContractsViewCmd)
+	hostContractsCmd.Flags().StringVarP(&hostContractsStatus, "status", "s", "", "Only show obligations with this status")
+	hostLedgerCmd.AddCommand(hostLedgerExportCmd)
+	hostLedgerCmd.PersistentFlags().StringVarP(&hostLedgerStart, "startheight", "", "", "Only include entries from this block height onwards")
+	hostLedgerCmd.PersistentFlags().StringVarP(&hostLedgerEnd, "endheight", "", "", "Only include entries up to this block height")
+	hostMaintenanceCmd.AddCommand(hostMaintenanceStartCmd, hostMaintenanceStopCmd)
 	hostFolderCmd.AddCommand(hostFolderAddCmd, hostFolderRemoveCmd, hostFolderResizeCmd)
 	hostSectorCmd.AddCommand(hostSectorDeleteCmd)
 	hostCmd.Flags().BoolVarP(&hostVerbose, "verbose", "v", false, "Display detailed host info")
 
 	root.AddCommand(hostdbCmd)
-	hostdbCmd.AddCommand(hostdbViewCmd)
+	hostdbCmd.AddCommand(hostdbViewCmd, hostdbFilterModeCmd, hostdbSetFilterModeCmd, hostdbWeightProfileCmd, hostdbSetWeightProfileCmd)
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbAgeExponent, "age", "", "Exponent of the age adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbCollateralExponent, "collateral", "", "Exponent of the collateral adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbInteractionExponent, "interaction", "", "Exponent of the interaction adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbPerformanceExponent, "performance", "", "Exponent of the performance adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbPriceExponent, "price", "", "Exponent of the price adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbStorageRemainingExponent, "storage", "", "Exponent of the storage remaining adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbUptimeExponent, "uptime", "", "Exponent of the uptime adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbVersionExponent, "version", "", "Exponent of the version adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbAgeMultiplier, "age-multiplier", "", "Multiplier of the age adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbCollateralMultiplier, "collateral-multiplier", "", "Multiplier of the collateral adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbInteractionMultiplier, "interaction-multiplier", "", "Multiplier of the interaction adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbPerformanceMultiplier, "performance-multiplier", "", "Multiplier of the performance adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbPriceMultiplier, "price-multiplier", "", "Multiplier of the price adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbStorageRemainingMultiplier, "storage-multiplier", "", "Multiplier of the storage remaining adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbUptimeMultiplier, "uptime-multiplier", "", "Multiplier of the uptime adjustment")
+	hostdbSetWeightProfileCmd.Flags().StringVar(&hostdbVersionMultiplier, "version-multiplier", "", "Multiplier of the version adjustment")
 	hostdbCmd.Flags().IntVarP(&hostdbNumHosts, "numhosts", "n", 0, "Number of hosts to display from the hostdb")
 	hostdbCmd.Flags().BoolVarP(&hostdbVerbose, "verbose", "v", false, "Display full hostdb information")
 
@@ -291,15 +322,27 @@ func main() {
 		renterDownloadsCmd, renterAllowanceCmd, renterSetAllowanceCmd,
 		renterContractsCmd, renterFilesListCmd, renterFilesRenameCmd,
 		renterFilesUploadCmd, renterUploadsCmd, renterExportCmd,
-		renterPricesCmd)
+		renterPricesCmd, renterSetRedundancyCmd, renterSetCacheCmd,
+		renterSetPriorityCmd, renterSetRateLimitCmd, renterSetTopUpCmd,
+		renterSetAlertsCmd, renterHealthCmd)
 
-	renterContractsCmd.AddCommand(renterContractsViewCmd)
+	renterContractsCmd.AddCommand(renterContractsFormCmd, renterContractsRenewCmd, renterContractsSetUtilityCmd,
+		renterContractsViewCmd)
 	renterAllowanceCmd.AddCommand(renterAllowanceCancelCmd)
+	renterDownloadsCmd.AddCommand(renterDownloadsCancelCmd, renterDownloadsPauseCmd, renterDownloadsResumeCmd)
+	renterUploadsCmd.AddCommand(renterUploadsPauseCmd, renterUploadsResumeCmd)
 
 	renterCmd.Flags().BoolVarP(&renterListVerbose, "verbose", "v", false, "Show additional file info such as redundancy")
+	renterContractsCmd.Flags().BoolVarP(&renterShowExpired, "expired", "e", false, "Show expired and renewed contracts in addition to the active contracts")
 	renterDownloadsCmd.Flags().BoolVarP(&renterShowHistory, "history", "H", false, "Show download history in addition to the download queue")
 	renterFilesListCmd.Flags().BoolVarP(&renterListVerbose, "verbose", "v", false, "Show additional file info such as redundancy")
-	renterExportCmd.AddCommand(renterExportContractTxnsCmd)
+	renterFilesUploadCmd.Flags().StringVarP(&renterDataPieces, "datapieces", "", "", "Number of data pieces to erasure code the file with")
+	renterFilesUploadCmd.Flags().StringVarP(&renterParityPieces, "paritypieces", "", "", "Number of parity pieces to erasure code the file with")
+	renterFilesUploadCmd.Flags().StringVarP(&renterErasureCode, "erasurecode", "", "", "Erasure code to encode the file with: reedsolomon, systematic or replication")
+	renterFilesUploadCmd.Flags().StringVarP(&renterPriority, "priority", "", "", "Upload priority of the file; files with a higher priority are uploaded first")
+	renterSetRedundancyCmd.Flags().StringVarP(&renterErasureCode, "erasurecode", "", "", "Erasure code to encode the file with: reedsolomon, systematic or replication")
+	renterExportCmd.AddCommand(renterExportContractTxnsCmd, renterExportSpendingCmd)
+	renterExportSpendingCmd.Flags().StringVarP(&renterExportFormat, "format", "f", "json", "Format to export the spending reports in: json or csv")
 
 	root.AddCommand(gatewayCmd)
 	gatewayCmd.AddCommand(gatewayConnectCmd, gatewayDisconnectCmd, gatewayAddressCmd, gatewayListCmd)
@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 
 	"github.com/NebulousLabs/Sia/api"
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
 )
 
 var (
@@ -40,17 +42,69 @@ var (
 	renterContractsCmd = &cobra.Command{
 		Use:   "contracts",
 		Short: "View the Renter's contracts",
-		Long:  "View the contracts that the Renter has formed with hosts.",
-		Run:   wrap(rentercontractscmd),
+		Long: `View the contracts that the Renter has formed with hosts. With --expired, the
+contracts that have expired or have been renewed are listed as well, along with
+whether their hosts submitted a storage proof.`,
+		Run: wrap(rentercontractscmd),
+	}
+
+	renterContractsFormCmd = &cobra.Command{
+		Use:   "form [pubkey] [amount]",
+		Short: "Form a contract with a host",
+		Long: `Form a contract with the host that has the provided public key, funded with
+the provided amount. The contract ends with the current allowance period.
+Amount is given in currency units (SC, KS, etc.)`,
+		Run: wrap(rentercontractsformcmd),
+	}
+
+	renterContractsRenewCmd = &cobra.Command{
+		Use:   "renew [contract-id] [amount] [end height]",
+		Short: "Renew or extend a contract",
+		Long: `Renew the specified contract, adding the provided amount. The renewed contract
+ends at the provided block height, or with the current allowance period if no
+end height is given. Amount is given in currency units (SC, KS, etc.)`,
+		Run: rentercontractsrenewcmd,
+	}
+
+	renterContractsSetUtilityCmd = &cobra.Command{
+		Use:   "setutility [contract-id] [goodforupload] [goodforrenew]",
+		Short: "Mark a contract as not good for upload or renew",
+		Long: `Mark the specified contract as not good for upload or not good for renew, e.g.
+	siac renter contracts setutility [contract-id] false true
+keeps the contract from being used for uploads, but still renews it. A contract
+that is not good for upload is replaced by a contract with another host. Marking
+a contract as good for both removes the restriction.`,
+		Run: wrap(rentercontractssetutilitycmd),
 	}
 
 	renterContractsViewCmd = &cobra.Command{
 		Use:   "view [contract-id]",
 		Short: "View details of the specified contract",
-		Long:  "View all details available of the specified contract.",
+		Long:  "View all details available of the specified contract, including its renewals.",
 		Run:   wrap(rentercontractsviewcmd),
 	}
 
+	renterDownloadsCancelCmd = &cobra.Command{
+		Use:   "cancel [id]",
+		Short: "Cancel a download",
+		Long:  "Cancel a download in the download queue. Data that has already been downloaded is left in place.",
+		Run:   wrap(renterdownloadscancelcmd),
+	}
+
+	renterDownloadsPauseCmd = &cobra.Command{
+		Use:   "pause [id]",
+		Short: "Pause a download",
+		Long:  "Pause a download in the download queue. Paused downloads stay paused after siad restarts.",
+		Run:   wrap(renterdownloadspausecmd),
+	}
+
+	renterDownloadsResumeCmd = &cobra.Command{
+		Use:   "resume [id]",
+		Short: "Resume a paused download",
+		Long:  "Resume a paused download in the download queue.",
+		Run:   wrap(renterdownloadsresumecmd),
+	}
+
 	renterDownloadsCmd = &cobra.Command{
 		Use:   "downloads",
 		Short: "View the download queue",
@@ -74,11 +128,13 @@ var (
 	}
 
 	renterFilesListCmd = &cobra.Command{
-		Use:     "list",
+		Use:     "list [path]",
 		Aliases: []string{"ls"},
-		Short:   "List the status of all files",
-		Long:    "List the status of all files known to the renter on the Sia network.",
-		Run:     wrap(renterfileslistcmd),
+		Short:   "List the contents of a directory",
+		Long: `List the status of the files and directories in a directory known to the
+renter on the Sia network. If no path is given, the root directory is listed.
+The size and redundancy of a directory are aggregated over its contents.`,
+		Run: renterfileslistcmd,
 	}
 
 	renterFilesRenameCmd = &cobra.Command{
@@ -92,8 +148,23 @@ var (
 	renterFilesUploadCmd = &cobra.Command{
 		Use:   "upload [source] [path]",
 		Short: "Upload a file",
-		Long:  "Upload a file to [path] on the Sia network.",
-		Run:   wrap(renterfilesuploadcmd),
+		Long: `Upload a file to [path] on the Sia network.
+
+The erasure coding parameters of the file can be set with the --datapieces and
+--paritypieces flags. The file can be recovered from any datapieces of its
+pieces, and its redundancy is (datapieces+paritypieces)/datapieces.
+
+The --erasurecode flag selects how the pieces are created:
+  reedsolomon  Reed-Solomon coding (default)
+  systematic   Reed-Solomon coding that can be read without decoding when all
+               data pieces are available, which speeds up downloads
+  replication  every piece is a full copy of the file, which is cheapest for
+               small files; requires --datapieces=1
+
+The --priority flag sets the upload priority of the file. Files with a higher
+priority are uploaded and repaired before files with a lower priority. The
+default priority is 0.`,
+		Run: wrap(renterfilesuploadcmd),
 	}
 
 	renterPricesCmd = &cobra.Command{
@@ -103,6 +174,75 @@ var (
 		Run:   wrap(renterpricescmd),
 	}
 
+	renterHealthCmd = &cobra.Command{
+		Use:   "health",
+		Short: "Summarize the health of the renter's files",
+		Long: `Summarize the health of the renter's files: the lowest redundancy of any
+file, the number of chunks that need to be repaired, and the time of the last
+repair. Files whose repairs keep failing are marked stuck and listed.`,
+		Run: wrap(renterhealthcmd),
+	}
+
+	renterSetRedundancyCmd = &cobra.Command{
+		Use:   "setredundancy [path] [datapieces] [paritypieces]",
+		Short: "Change the redundancy of a file",
+		Long: `Change the erasure coding parameters of a file. The file is re-encoded in
+the background using the new parameters; until then, it can still be
+downloaded using the old parameters. The --erasurecode flag selects the type
+of erasure code, as for the upload command.`,
+		Run: wrap(rentersetredundancycmd),
+	}
+
+	renterSetPriorityCmd = &cobra.Command{
+		Use:   "setpriority [path] [priority]",
+		Short: "Change the upload priority of a file or directory",
+		Long: `Change the upload priority of a file, or of every file in a directory. Files
+with a higher priority are uploaded and repaired before files with a lower
+priority. The default priority is 0, and negative priorities are allowed.`,
+		Run: wrap(rentersetprioritycmd),
+	}
+
+	renterSetCacheCmd = &cobra.Command{
+		Use:   "setcache [memory size] [disk size]",
+		Short: "Set the size of the chunk cache",
+		Long: `Set the amount of memory and disk space used to cache recently downloaded
+chunks. Downloads of cached data are served without contacting any hosts.
+Sizes are given with units (B, KB, MB, GB, KiB, MiB, GiB). A size of 0B
+disables the respective tier of the cache.`,
+		Run: wrap(rentersetcachecmd),
+	}
+
+	renterSetRateLimitCmd = &cobra.Command{
+		Use:   "setratelimit [max upload speed] [max download speed]",
+		Short: "Limit the bandwidth used by the renter",
+		Long: `Limit the average speed of uploads and downloads to and from all hosts
+combined. Speeds are given in bytes per second with units (B, KB, MB, GB, KiB,
+MiB, GiB), so 1MB limits the speed to one megabyte per second. A speed of 0B
+removes the limit.`,
+		Run: wrap(rentersetratelimitcmd),
+	}
+
+	renterSetTopUpCmd = &cobra.Command{
+		Use:   "settopup [max funds]",
+		Short: "Set how much may be spent beyond the allowance",
+		Long: `Set the amount that may be spent on top of the allowance during a billing
+period. Once the allowance is used up, contracts that run low on funds are
+refilled from the wallet until this amount is reached. An amount of 0SC
+disables top-ups.`,
+		Run: wrap(rentersettopupcmd),
+	}
+
+	renterSetAlertsCmd = &cobra.Command{
+		Use:   "setalerts [percentages]",
+		Short: "Set the spending alert thresholds",
+		Long: `Set the percentages of the allowance at which a spending alert is raised. For
+example, 'siac renter setalerts 50 90 100' raises an alert once the spending
+during the current billing period reaches half of the allowance funds. Without
+any percentages, all spending alerts are removed. Alerts are shown by
+'siac renter'.`,
+		Run: rentersetalertscmd,
+	}
+
 	renterSetAllowanceCmd = &cobra.Command{
 		Use:   "setallowance [amount] [period] [hosts] [renew window]",
 		Short: "Set the allowance",
@@ -128,6 +268,21 @@ have a reasonable number (>30) of hosts in your hostdb.`,
 		Run: rentersetallowancecmd,
 	}
 
+	renterUploadsPauseCmd = &cobra.Command{
+		Use:   "pause [path]",
+		Short: "Pause uploading a file or directory",
+		Long: `Pause uploading and repairing a file, or every file in a directory. Chunks
+that are already being uploaded are finished.`,
+		Run: wrap(renteruploadspausecmd),
+	}
+
+	renterUploadsResumeCmd = &cobra.Command{
+		Use:   "resume [path]",
+		Short: "Resume uploading a file or directory",
+		Long:  "Resume uploading and repairing a file, or every file in a directory.",
+		Run:   wrap(renteruploadsresumecmd),
+	}
+
 	renterUploadsCmd = &cobra.Command{
 		Use:   "uploads",
 		Short: "View the upload queue",
@@ -160,15 +315,46 @@ func rentercmd() {
 	Storage Spending:  %v
 	Upload Spending:   %v
 	Download Spending: %v
+	Fees:              %v
 	Unspent Funds:     %v
 	Total Allocated:   %v
 
 `, currencyUnits(fm.StorageSpending), currencyUnits(fm.UploadSpending),
-		currencyUnits(fm.DownloadSpending), currencyUnits(fm.Unspent),
-		currencyUnits(fm.ContractSpending))
+		currencyUnits(fm.DownloadSpending), currencyUnits(fm.ContractFees.Add(fm.TxnFees).Add(fm.SiafundFees)),
+		currencyUnits(fm.Unspent), currencyUnits(fm.ContractSpending))
+
+	var ra api.RenterAlertsGET
+	err = getAPI("/renter/alerts", &ra)
+	if err != nil {
+		die("Could not get renter alerts:", err)
+	}
+	if len(ra.Alerts) != 0 {
+		fmt.Println("Alerts:")
+		for _, alert := range ra.Alerts {
+			fmt.Printf("\t%v: %v\n", alert.Type, alert.Message)
+		}
+		fmt.Println()
+	}
+
+	cc := rg.ChunkCache
+	fmt.Printf(`Chunk cache:
+	Memory:   %v of %v
+	Disk:     %v of %v
+	Hits:     %v
+	Misses:   %v
+
+`, filesizeUnits(int64(cc.MemoryUsed)), filesizeUnits(int64(rg.Settings.ChunkCacheSize)),
+		filesizeUnits(int64(cc.DiskUsed)), filesizeUnits(int64(rg.Settings.ChunkCacheDiskSize)),
+		cc.Hits, cc.Misses)
+
+	fmt.Printf(`Bandwidth limits:
+	Upload:   %v
+	Download: %v
+
+`, speedLimitUnits(rg.Settings.MaxUploadSpeed), speedLimitUnits(rg.Settings.MaxDownloadSpeed))
 
 	// also list files
-	renterfileslistcmd()
+	renterdirlist("")
 }
 
 // renteruploadscmd is the handler for the command `siac renter uploads`.
@@ -198,10 +384,34 @@ func renteruploadscmd() {
 	}
 	fmt.Println("Uploading", len(filteredFiles), "files:")
 	for _, file := range filteredFiles {
-		fmt.Printf("%13s  %s (uploading, %0.2f%%)\n", filesizeUnits(int64(file.Filesize)), file.SiaPath, file.UploadProgress)
+		status := "uploading"
+		if file.UploadPaused {
+			status = "paused"
+		}
+		fmt.Printf("%13s  %s (%s, %0.2f%%, priority %d)\n", filesizeUnits(int64(file.Filesize)), file.SiaPath, status, file.UploadProgress, file.Priority)
 	}
 }
 
+// renteruploadspausecmd is the handler for the command `siac renter uploads
+// pause [path]`. Pauses uploading a file or the files in a directory.
+func renteruploadspausecmd(path string) {
+	err := post("/renter/uploads/pause/"+path, "")
+	if err != nil {
+		die("Could not pause uploads:", err)
+	}
+	fmt.Printf("Paused uploading %s.\n", path)
+}
+
+// renteruploadsresumecmd is the handler for the command `siac renter uploads
+// resume [path]`. Resumes uploading a file or the files in a directory.
+func renteruploadsresumecmd(path string) {
+	err := post("/renter/uploads/resume/"+path, "")
+	if err != nil {
+		die("Could not resume uploads:", err)
+	}
+	fmt.Printf("Resumed uploading %s.\n", path)
+}
+
 // renterdownloadscmd is the handler for the command `siac renter downloads`.
 // Lists files currently downloading, and optionally previously downloaded
 // files if the -H or --history flag is specified.
@@ -223,7 +433,11 @@ func renterdownloadscmd() {
 	} else {
 		fmt.Println("Downloading", len(downloading), "files:")
 		for _, file := range downloading {
-			fmt.Printf("%s: %5.1f%% %s -> %s\n", file.StartTime.Format("Jan 02 03:04 PM"), 100*float64(file.Received)/float64(file.Filesize), file.SiaPath, file.Destination)
+			status := ""
+			if file.Paused {
+				status = " (paused)"
+			}
+			fmt.Printf("%s: %s %5.1f%% %s -> %s%s\n", file.StartTime.Format("Jan 02 03:04 PM"), file.ID, 100*float64(file.Received)/float64(file.Filesize), file.SiaPath, file.Destination, status)
 		}
 	}
 	if !renterShowHistory {
@@ -247,6 +461,36 @@ func renterdownloadscmd() {
 	}
 }
 
+// renterdownloadscancelcmd is the handler for the command `siac renter
+// downloads cancel [id]`. Cancels a download.
+func renterdownloadscancelcmd(id string) {
+	err := post("/renter/download/cancel/"+id, "")
+	if err != nil {
+		die("Could not cancel download:", err)
+	}
+	fmt.Println("Download canceled.")
+}
+
+// renterdownloadspausecmd is the handler for the command `siac renter
+// downloads pause [id]`. Pauses a download.
+func renterdownloadspausecmd(id string) {
+	err := post("/renter/download/pause/"+id, "")
+	if err != nil {
+		die("Could not pause download:", err)
+	}
+	fmt.Println("Download paused.")
+}
+
+// renterdownloadsresumecmd is the handler for the command `siac renter
+// downloads resume [id]`. Resumes a paused download.
+func renterdownloadsresumecmd(id string) {
+	err := post("/renter/download/resume/"+id, "")
+	if err != nil {
+		die("Could not resume download:", err)
+	}
+	fmt.Println("Download resumed.")
+}
+
 // renterallowancecmd displays the current allowance.
 func renterallowancecmd() {
 	var rg api.RenterGET
@@ -260,7 +504,15 @@ func renterallowancecmd() {
 	fmt.Printf(`Allowance:
 	Amount: %v
 	Period: %v blocks
-`, currencyUnits(allowance.Funds), allowance.Period)
+	Top-up: %v
+`, currencyUnits(allowance.Funds), allowance.Period, currencyUnits(rg.Settings.TopUp.MaxFunds))
+	if len(rg.Settings.SpendingAlerts) != 0 {
+		var alerts []string
+		for _, alert := range rg.Settings.SpendingAlerts {
+			alerts = append(alerts, fmt.Sprintf("%v%%", alert))
+		}
+		fmt.Printf("\tSpending Alerts: %v\n", strings.Join(alerts, ", "))
+	}
 }
 
 // renterallowancecancelcmd cancels the current allowance.
@@ -312,6 +564,82 @@ func rentersetallowancecmd(cmd *cobra.Command, args []string) {
 	fmt.Println("Allowance updated.")
 }
 
+// rentersetcachecmd is the handler for the command `siac renter setcache
+// [memory size] [disk size]`. Sets the size of the chunk cache.
+func rentersetcachecmd(memorySize, diskSize string) {
+	memory, err := parseFilesize(memorySize)
+	if err != nil {
+		die("Could not parse memory size:", err)
+	}
+	disk, err := parseFilesize(diskSize)
+	if err != nil {
+		die("Could not parse disk size:", err)
+	}
+	err = post("/renter", "chunkcachesize="+memory+"&chunkcachedisksize="+disk)
+	if err != nil {
+		die("Could not set chunk cache size:", err)
+	}
+	fmt.Println("Chunk cache size updated.")
+}
+
+// speedLimitUnits converts a bandwidth limit in bytes per second to a
+// human-readable string.
+func speedLimitUnits(bytesPerSecond uint64) string {
+	if bytesPerSecond == 0 {
+		return "unlimited"
+	}
+	return filesizeUnits(int64(bytesPerSecond)) + "/s"
+}
+
+// rentersetratelimitcmd is the handler for the command `siac renter
+// setratelimit [max upload speed] [max download speed]`. Changes the bandwidth
+// limits of the renter.
+func rentersetratelimitcmd(uploadSpeed, downloadSpeed string) {
+	upload, err := parseFilesize(uploadSpeed)
+	if err != nil {
+		die("Could not parse upload speed:", err)
+	}
+	download, err := parseFilesize(downloadSpeed)
+	if err != nil {
+		die("Could not parse download speed:", err)
+	}
+	err = post("/renter", "maxuploadspeed="+upload+"&maxdownloadspeed="+download)
+	if err != nil {
+		die("Could not set bandwidth limits:", err)
+	}
+	fmt.Println("Bandwidth limits updated.")
+}
+
+// rentersettopupcmd is the handler for the command `siac renter settopup [max
+// funds]`. Sets the amount that may be spent beyond the allowance.
+func rentersettopupcmd(maxFunds string) {
+	hastings, err := parseCurrency(maxFunds)
+	if err != nil {
+		die("Could not parse amount:", err)
+	}
+	err = post("/renter", "topupmaxfunds="+hastings)
+	if err != nil {
+		die("Could not set top-up policy:", err)
+	}
+	fmt.Println("Top-up policy updated.")
+}
+
+// rentersetalertscmd is the handler for the command `siac renter setalerts
+// [percentages]`. Sets the spending alert thresholds.
+func rentersetalertscmd(cmd *cobra.Command, args []string) {
+	for _, arg := range args {
+		var percentage uint64
+		if _, err := fmt.Sscan(arg, &percentage); err != nil || percentage == 0 {
+			die("Could not parse percentage:", arg)
+		}
+	}
+	err := post("/renter", "spendingalerts="+strings.Join(args, ","))
+	if err != nil {
+		die("Could not set spending alerts:", err)
+	}
+	fmt.Println("Spending alerts updated.")
+}
+
 // byValue sorts contracts by their value in siacoins, high to low. If two
 // contracts have the same value, they are sorted by their host's address.
 type byValue []api.RenterContract
@@ -330,41 +658,114 @@ func (s byValue) Less(i, j int) bool {
 // It lists the Renter's contracts.
 func rentercontractscmd() {
 	var rc api.RenterContracts
-	err := getAPI("/renter/contracts", &rc)
+	err := getAPI(fmt.Sprintf("/renter/contracts?expired=%v", renterShowExpired), &rc)
 	if err != nil {
 		die("Could not get contracts:", err)
 	}
-	if len(rc.Contracts) == 0 {
+	if len(rc.Contracts) == 0 && len(rc.ExpiredContracts) == 0 {
 		fmt.Println("No contracts have been formed.")
 		return
 	}
-	sort.Sort(byValue(rc.Contracts))
-	fmt.Println("Contracts:")
-	w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "Host\tRemaining Funds\tSpent Funds\tSpent Fees\tData\tEnd Height\tID")
-	for _, c := range rc.Contracts {
-		fmt.Fprintf(w, "%v\t%8s\t%8s\t%8s\t%v\t%v\t%v\n",
-			c.NetAddress,
-			currencyUnits(c.RenterFunds),
-			currencyUnits(c.TotalCost.Sub(c.RenterFunds).Sub(c.Fees)),
-			currencyUnits(c.Fees),
-			filesizeUnits(int64(c.Size)),
-			c.EndHeight,
-			c.ID)
+	if len(rc.Contracts) != 0 {
+		sort.Sort(byValue(rc.Contracts))
+		fmt.Println("Contracts:")
+		w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Host\tRemaining Funds\tSpent Funds\tSpent Fees\tData\tEnd Height\tID")
+		for _, c := range rc.Contracts {
+			fmt.Fprintf(w, "%v\t%8s\t%8s\t%8s\t%v\t%v\t%v\n",
+				c.NetAddress,
+				currencyUnits(c.RenterFunds),
+				currencyUnits(c.TotalCost.Sub(c.RenterFunds).Sub(c.Fees)),
+				currencyUnits(c.Fees),
+				filesizeUnits(int64(c.Size)),
+				c.EndHeight,
+				c.ID)
+		}
+		w.Flush()
 	}
-	w.Flush()
+	if len(rc.ExpiredContracts) != 0 {
+		fmt.Println("\nExpired Contracts:")
+		w := tabwriter.NewWriter(os.Stdout, 2, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "Host\tSpent Funds\tSpent Fees\tData\tEnd Height\tStorage Proof\tCollateral Lost\tID")
+		for _, c := range rc.ExpiredContracts {
+			fmt.Fprintf(w, "%v\t%8s\t%8s\t%v\t%v\t%v\t%8s\t%v\n",
+				c.NetAddress,
+				currencyUnits(c.TotalCost.Sub(c.RenterFunds).Sub(c.Fees)),
+				currencyUnits(c.Fees),
+				filesizeUnits(int64(c.Size)),
+				c.EndHeight,
+				proofStatus(c.Outcome),
+				currencyUnits(collateralLost(c.Outcome)),
+				c.ID)
+		}
+		w.Flush()
+	}
+}
+
+// proofStatus returns a human-readable description of whether the host of a
+// contract submitted a storage proof.
+func proofStatus(outcome *modules.ContractOutcome) string {
+	switch {
+	case outcome == nil:
+		return "unknown"
+	case outcome.StorageProofSubmitted:
+		return fmt.Sprintf("submitted (height %v)", outcome.StorageProofHeight)
+	case outcome.Resolved:
+		return "missed"
+	case outcome.WindowClosed:
+		return "unknown"
+	default:
+		return "pending"
+	}
+}
+
+// collateralLost returns the collateral that the host of a contract lost.
+func collateralLost(outcome *modules.ContractOutcome) types.Currency {
+	if outcome == nil {
+		return types.ZeroCurrency
+	}
+	return outcome.CollateralLost
+}
+
+// contractLineage returns the IDs of the contracts in the renew chain of the
+// contract with the provided ID, from the original contract to the most recent
+// renewal.
+func contractLineage(contracts []api.RenterContract, id types.FileContractID) []types.FileContractID {
+	byID := make(map[types.FileContractID]api.RenterContract)
+	for _, c := range contracts {
+		byID[c.ID] = c
+	}
+	var zeroID types.FileContractID
+	for {
+		c, exists := byID[id]
+		if !exists || c.RenewedFrom == zeroID {
+			break
+		}
+		id = c.RenewedFrom
+	}
+	lineage := []types.FileContractID{id}
+	for {
+		c, exists := byID[id]
+		if !exists || c.RenewedTo == zeroID {
+			break
+		}
+		id = c.RenewedTo
+		lineage = append(lineage, id)
+	}
+	return lineage
 }
 
 // rentercontractsviewcmd is the handler for the command `siac renter contracts <id>`.
 // It lists details of a specific contract.
 func rentercontractsviewcmd(cid string) {
 	var rc api.RenterContracts
-	err := getAPI("/renter/contracts", &rc)
+	err := getAPI("/renter/contracts?expired=true", &rc)
 	if err != nil {
 		die("Could not get contract details: ", err)
 	}
 
-	for _, rc := range rc.Contracts {
+	allContracts := append(rc.Contracts, rc.ExpiredContracts...)
+	for _, rc := range allContracts {
 		if rc.ID.String() == cid {
 			var hostInfo api.HostdbHostsGET
 			err = getAPI("/hostdb/hosts/"+rc.HostPublicKey.String(), &hostInfo)
@@ -386,6 +787,9 @@ Contract %v
   Remaining Funds:   %v
 
   File Size: %v
+
+  Good for Upload: %v
+  Good for Renew:  %v
 `, rc.ID, rc.NetAddress, rc.HostPublicKey.String(), rc.StartHeight, rc.EndHeight,
 				currencyUnits(rc.TotalCost),
 				currencyUnits(rc.Fees),
@@ -394,7 +798,28 @@ Contract %v
 				currencyUnits(rc.StorageSpending),
 				currencyUnits(rc.DownloadSpending),
 				currencyUnits(rc.RenterFunds),
-				filesizeUnits(int64(rc.Size)))
+				filesizeUnits(int64(rc.Size)),
+				rc.GoodForUpload,
+				rc.GoodForRenew)
+
+			if rc.Outcome != nil {
+				fmt.Println("\n  Storage Proof:  ", proofStatus(rc.Outcome))
+				if rc.Outcome.Resolved {
+					fmt.Println("  Renter Payout:  ", currencyUnits(rc.Outcome.RenterPayout))
+					fmt.Println("  Host Payout:    ", currencyUnits(rc.Outcome.HostPayout))
+					fmt.Println("  Collateral Lost:", currencyUnits(rc.Outcome.CollateralLost))
+				}
+			}
+			if lineage := contractLineage(allContracts, rc.ID); len(lineage) > 1 {
+				fmt.Println("\n  Lineage:")
+				for _, id := range lineage {
+					marker := " "
+					if id == rc.ID {
+						marker = "*"
+					}
+					fmt.Printf("  %v %v\n", marker, id)
+				}
+			}
 
 			printScoreBreakdown(&hostInfo)
 			return
@@ -470,25 +895,58 @@ func (s bySiaPath) Len() int           { return len(s) }
 func (s bySiaPath) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
 func (s bySiaPath) Less(i, j int) bool { return s[i].SiaPath < s[j].SiaPath }
 
-// renterfileslistcmd is the handler for the command `siac renter list`.
-// Lists files known to the renter on the network.
-func renterfileslistcmd() {
-	var rf api.RenterFiles
-	err := getAPI("/renter/files", &rf)
+// renterfileslistcmd is the handler for the command `siac renter list
+// [path]`. Lists the files and directories in a directory known to the renter
+// on the network.
+func renterfileslistcmd(cmd *cobra.Command, args []string) {
+	if len(args) > 1 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	var path string
+	if len(args) == 1 {
+		path = args[0]
+	}
+	renterdirlist(path)
+}
+
+// renterdirlist prints the contents of the directory at path.
+func renterdirlist(path string) {
+	var rd api.RenterDirectory
+	err := getAPI("/renter/dir/"+strings.Trim(path, "/"), &rd)
 	if err != nil {
 		die("Could not get file list:", err)
 	}
-	if len(rf.Files) == 0 {
-		fmt.Println("No files have been uploaded.")
+	if len(rd.Directories) == 0 && len(rd.Files) == 0 {
+		if rd.Directory.SiaPath == "" {
+			fmt.Println("No files have been uploaded.")
+		} else {
+			fmt.Println("Directory is empty.")
+		}
 		return
 	}
-	fmt.Println("Tracking", len(rf.Files), "files:")
+	fmt.Println("Tracking", rd.Directory.AggregateNumFiles, "files:")
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
 	if renterListVerbose {
 		fmt.Fprintln(w, "File size\tAvailable\tUploaded\tProgress\tRedundancy\tRenewing\tSia path")
 	}
-	sort.Sort(bySiaPath(rf.Files))
-	for _, file := range rf.Files {
+	for _, dir := range rd.Directories {
+		fmt.Fprintf(w, "%9s", filesizeUnits(int64(dir.AggregateSize)))
+		if renterListVerbose {
+			redundancyStr := fmt.Sprintf("%.2f", dir.MinRedundancy)
+			if dir.MinRedundancy == -1 {
+				redundancyStr = "-"
+			}
+			fmt.Fprintf(w, "\t%s\t%9s\t%8s\t%10s\t%s", yesNo(dir.Available), "-", "-", redundancyStr, "-")
+		}
+		fmt.Fprintf(w, "\t%s/", dir.SiaPath)
+		if !renterListVerbose && !dir.Available {
+			fmt.Fprint(w, " (uploading)")
+		}
+		fmt.Fprintln(w, "")
+	}
+	sort.Sort(bySiaPath(rd.Files))
+	for _, file := range rd.Files {
 		fmt.Fprintf(w, "%9s", filesizeUnits(int64(file.Filesize)))
 		if renterListVerbose {
 			availableStr := yesNo(file.Available)
@@ -522,6 +980,47 @@ func renterfilesrenamecmd(path, newpath string) {
 	fmt.Printf("Renamed %s to %s\n", path, newpath)
 }
 
+// erasureCodingValues returns the query string values for the erasure coding
+// parameters supplied through the --datapieces, --paritypieces and
+// --erasurecode flags.
+func erasureCodingValues() string {
+	if renterDataPieces == "" && renterParityPieces == "" && renterErasureCode == "" {
+		return ""
+	}
+	return "&datapieces=" + renterDataPieces + "&paritypieces=" + renterParityPieces + "&erasurecode=" + renterErasureCode
+}
+
+// priorityValue returns the query string value for the upload priority
+// supplied through the --priority flag.
+func priorityValue() string {
+	if renterPriority == "" {
+		return ""
+	}
+	return "&priority=" + renterPriority
+}
+
+// rentersetprioritycmd is the handler for the command `siac renter
+// setpriority [path] [priority]`. Changes the upload priority of a file or the
+// files in a directory.
+func rentersetprioritycmd(path, priority string) {
+	err := post("/renter/priority/"+path, "priority="+priority)
+	if err != nil {
+		die("Could not change priority:", err)
+	}
+	fmt.Printf("Set the priority of %s to %s.\n", path, priority)
+}
+
+// rentersetredundancycmd is the handler for the command `siac renter
+// setredundancy [path] [datapieces] [paritypieces]`. Changes the erasure coding
+// parameters of a file.
+func rentersetredundancycmd(path, dataPieces, parityPieces string) {
+	err := post("/renter/redundancy/"+path, "datapieces="+dataPieces+"&paritypieces="+parityPieces+"&erasurecode="+renterErasureCode)
+	if err != nil {
+		die("Could not change redundancy:", err)
+	}
+	fmt.Printf("Re-encoding %s with %s data pieces and %s parity pieces.\n", path, dataPieces, parityPieces)
+}
+
 // renterfilesuploadcmd is the handler for the command `siac renter upload
 // [source] [path]`. Uploads the [source] file to [path] on the Sia network.
 // If [source] is a directory, all files inside it will be uploaded and named
@@ -555,7 +1054,7 @@ func renterfilesuploadcmd(source, path string) {
 			fpath, _ := filepath.Rel(source, file)
 			fpath = filepath.Join(path, fpath)
 			fpath = filepath.ToSlash(fpath)
-			err = post("/renter/upload/"+fpath, "source="+abs(file))
+			err = post("/renter/upload/"+fpath, "source="+abs(file)+erasureCodingValues()+priorityValue())
 			if err != nil {
 				die("Could not upload file:", err)
 			}
@@ -563,7 +1062,7 @@ func renterfilesuploadcmd(source, path string) {
 		fmt.Printf("Uploaded %d files into '%s'.\n", len(files), path)
 	} else {
 		// single file
-		err = post("/renter/upload/"+path, "source="+abs(source))
+		err = post("/renter/upload/"+path, "source="+abs(source)+erasureCodingValues()+priorityValue())
 		if err != nil {
 			die("Could not upload file:", err)
 		}
@@ -588,3 +1087,102 @@ func renterpricescmd() {
 	fmt.Fprintln(w, "\tUpload 1 TB:\t", currencyUnits(rpg.UploadTerabyte))
 	w.Flush()
 }
+
+// repairTime returns a human-readable description of the time of a repair.
+func repairTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format(time.RFC822)
+}
+
+// renterhealthcmd is the handler for the command `siac renter health`. It
+// summarizes the health of the renter's files and lists the stuck files.
+func renterhealthcmd() {
+	var rh api.RenterHealthGET
+	err := getAPI("/renter/health", &rh)
+	if err != nil {
+		die("Could not get file health:", err)
+	}
+	minRedundancy := "-"
+	if rh.MinRedundancy >= 0 {
+		minRedundancy = fmt.Sprintf("%.2f", rh.MinRedundancy)
+	}
+	fmt.Printf(`File Health:
+	Files:                  %v
+	Minimum Redundancy:     %v
+	Chunks Below Threshold: %v (in %v files)
+	Stuck Files:            %v
+	Last Repair:            %v
+`, rh.NumFiles, minRedundancy, rh.ChunksBelowThreshold, rh.UnhealthyFiles,
+		len(rh.StuckFiles), repairTime(rh.LastRepair))
+	if len(rh.StuckFiles) == 0 {
+		return
+	}
+
+	fmt.Println("\nStuck Files:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "  Redundancy\tChunks Below Threshold\tLast Repair\tSia Path")
+	for _, file := range rh.StuckFiles {
+		fmt.Fprintf(w, "  %.2f\t%v\t%v\t%v\n", file.Redundancy, file.ChunksBelowThreshold,
+			repairTime(file.LastRepair), file.SiaPath)
+	}
+	w.Flush()
+}
+
+// rentercontractsformcmd is the handler for the command `siac renter contracts
+// form [pubkey] [amount]`. It forms a contract with a specific host.
+func rentercontractsformcmd(pubkey, amount string) {
+	hastings, err := parseCurrency(amount)
+	if err != nil {
+		die("Could not parse amount:", err)
+	}
+	var rc api.RenterContract
+	err = postResp("/renter/contracts/form", fmt.Sprintf("host=%s&funds=%s", pubkey, hastings), &rc)
+	if err != nil {
+		die("Could not form contract:", err)
+	}
+	fmt.Println("Formed contract", rc.ID)
+}
+
+// rentercontractsrenewcmd is the handler for the command `siac renter
+// contracts renew [contract-id] [amount] [end height]`. It renews or extends
+// a specific contract.
+func rentercontractsrenewcmd(cmd *cobra.Command, args []string) {
+	if len(args) < 2 || len(args) > 3 {
+		cmd.UsageFunc()(cmd)
+		os.Exit(exitCodeUsage)
+	}
+	hastings, err := parseCurrency(args[1])
+	if err != nil {
+		die("Could not parse amount:", err)
+	}
+	queryString := "funds=" + hastings
+	if len(args) > 2 {
+		if _, err := strconv.ParseUint(args[2], 10, 64); err != nil {
+			die("Could not parse end height")
+		}
+		queryString += "&endheight=" + args[2]
+	}
+	var rc api.RenterContract
+	err = postResp("/renter/contracts/renew/"+args[0], queryString, &rc)
+	if err != nil {
+		die("Could not renew contract:", err)
+	}
+	fmt.Printf("Renewed contract %v as %v, ending at height %v\n", args[0], rc.ID, rc.EndHeight)
+}
+
+// rentercontractssetutilitycmd is the handler for the command `siac renter
+// contracts setutility [contract-id] [goodforupload] [goodforrenew]`.
+func rentercontractssetutilitycmd(id, goodForUpload, goodForRenew string) {
+	for _, b := range []string{goodForUpload, goodForRenew} {
+		if b != "true" && b != "false" {
+			die("Could not parse utility: expected true or false, got", b)
+		}
+	}
+	err := post("/renter/contracts/utility/"+id, fmt.Sprintf("goodforupload=%s&goodforrenew=%s", goodForUpload, goodForRenew))
+	if err != nil {
+		die("Could not set contract utility:", err)
+	}
+	fmt.Println("Contract utility updated.")
+}
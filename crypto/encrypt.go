@@ -5,6 +5,7 @@ package crypto
 
 import (
 	"crypto/cipher"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"io"
@@ -15,7 +16,8 @@ import (
 )
 
 const (
-	TwofishOverhead = 28 // number of bytes added by EncryptBytes
+	TwofishOverhead  = 28 // number of bytes added by EncryptBytes
+	TwofishNonceSize = 12 // size of the nonce prepended by EncryptBytes
 )
 
 var (
@@ -72,6 +74,32 @@ func (key TwofishKey) DecryptBytes(ct Ciphertext) ([]byte, error) {
 	return aead.Open(nil, ct[:aead.NonceSize()], ct[aead.NonceSize():], nil)
 }
 
+// DecryptRange decrypts part of the plaintext of a ciphertext created by
+// EncryptBytes. ct contains the encrypted bytes of the plaintext starting at
+// offset, and nonce is the nonce at the start of the ciphertext. Unlike
+// DecryptBytes, DecryptRange does not authenticate the data, so the caller
+// must verify it by other means, such as a Merkle proof.
+func (key TwofishKey) DecryptRange(nonce []byte, ct Ciphertext, offset uint64) ([]byte, error) {
+	if len(nonce) != TwofishNonceSize {
+		return nil, ErrInsufficientLen
+	}
+
+	// GCM encrypts the plaintext in counter mode. The counter block of the
+	// first block of plaintext is the nonce followed by the counter 2.
+	iv := make([]byte, twofish.BlockSize)
+	copy(iv, nonce)
+	binary.BigEndian.PutUint32(iv[TwofishNonceSize:], uint32(2+offset/twofish.BlockSize))
+	stream := cipher.NewCTR(key.NewCipher(), iv)
+
+	// Discard the key stream of the plaintext that precedes offset within
+	// its block.
+	skip := make([]byte, offset%twofish.BlockSize)
+	stream.XORKeyStream(skip, skip)
+	plaintext := make([]byte, len(ct))
+	stream.XORKeyStream(plaintext, ct)
+	return plaintext, nil
+}
+
 // NewWriter returns a writer that encrypts or decrypts its input stream.
 func (key TwofishKey) NewWriter(w io.Writer) io.Writer {
 	// OK to use a zero IV if the key is unique for each ciphertext.
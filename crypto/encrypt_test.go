@@ -62,6 +62,32 @@ func TestTwofishEncryption(t *testing.T) {
 	}
 }
 
+// TestDecryptRange checks that parts of a ciphertext created by EncryptBytes
+// can be decrypted on their own.
+func TestDecryptRange(t *testing.T) {
+	key := GenerateTwofishKey()
+	plaintext := fastrand.Bytes(5000)
+	ciphertext := key.EncryptBytes(plaintext)
+	nonce := ciphertext[:TwofishNonceSize]
+
+	for _, r := range []struct{ offset, length uint64 }{{0, 10}, {7, 100}, {16, 16}, {4999, 1}, {1234, 3000}} {
+		ct := ciphertext[TwofishNonceSize+r.offset:][:r.length]
+		decrypted, err := key.DecryptRange(nonce, ct, r.offset)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(decrypted, plaintext[r.offset:][:r.length]) {
+			t.Fatalf("range at offset %v with length %v was not decrypted correctly", r.offset, r.length)
+		}
+	}
+
+	// Decrypting with a bad nonce should fail.
+	_, err := key.DecryptRange(nonce[:4], ciphertext[TwofishNonceSize:], 0)
+	if err != ErrInsufficientLen {
+		t.Error("Expecting ErrInsufficientLen:", err)
+	}
+}
+
 // TestReaderWriter probes the NewReader and NewWriter methods of the key type.
 func TestReaderWriter(t *testing.T) {
 	// Get a key for encryption.
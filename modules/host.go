@@ -1,6 +1,9 @@
 package modules
 
 import (
+	"errors"
+	"time"
+
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -9,7 +12,67 @@ const (
 	HostDir = "host"
 )
 
+// The ObligationStatus of a StorageObligation is one of the following.
+const (
+	// ObligationStatusUnresolved indicates that the obligation is still
+	// active; its storage proof window has not yet closed.
+	ObligationStatusUnresolved uint64 = iota
+
+	// ObligationStatusRejected indicates that the obligation never got
+	// started, no revenue was gained or lost.
+	ObligationStatusRejected
+
+	// ObligationStatusSucceeded indicates that the obligation was completed,
+	// revenues were gained.
+	ObligationStatusSucceeded
+
+	// ObligationStatusFailed indicates that the obligation failed, revenues
+	// and collateral were lost.
+	ObligationStatusFailed
+)
+
+// The Type of a HostLedgerEntry is one of the following. The revenue of a
+// contract is pending until the contract ends: the contractformed and
+// revisionrevenue entries of a contract are followed by either a storageproof
+// entry that realises them or a revenuelost entry that reverses them. The
+// earnings of the host are the storageproof entries, minus the collaterallost
+// and transactionfee entries.
+const (
+	// HostLedgerContractFormed records the contract price paid by a renter
+	// when forming or renewing a contract. For renewals, this includes the
+	// storage revenue for the data that is carried over. The revenue is
+	// pending until the contract ends.
+	HostLedgerContractFormed = "contractformed"
+
+	// HostLedgerRevisionRevenue records the storage and bandwidth revenue
+	// that a renter paid in a revision of a contract. The revenue is pending
+	// until the contract ends.
+	HostLedgerRevisionRevenue = "revisionrevenue"
+
+	// HostLedgerStorageProof records the pending revenue of a contract that
+	// the host earned because its storage proof was accepted by the
+	// blockchain.
+	HostLedgerStorageProof = "storageproof"
+
+	// HostLedgerRevenueLost records the pending revenue of a contract that
+	// the host did not earn, because it missed the storage proof or the
+	// contract never confirmed.
+	HostLedgerRevenueLost = "revenuelost"
+
+	// HostLedgerCollateralLost records the collateral that the host lost
+	// when it missed a storage proof.
+	HostLedgerCollateralLost = "collaterallost"
+
+	// HostLedgerTransactionFee records the transaction fees that the host
+	// paid for a contract, its revisions, and its storage proof.
+	HostLedgerTransactionFee = "transactionfee"
+)
+
 var (
+	// ErrNoStorageObligation is returned by StorageObligation if the host has
+	// no storage obligation with the requested ID.
+	ErrNoStorageObligation = errors.New("storage obligation not found in database")
+
 	// BlockBytesPerMonthTerabyte is the conversion rate between block-bytes and month-TB.
 	BlockBytesPerMonthTerabyte = BytesPerTerabyte.Mul64(4320)
 
@@ -94,10 +157,39 @@ type (
 		MinDownloadBandwidthPrice types.Currency `json:"mindownloadbandwidthprice"`
 		MinStoragePrice           types.Currency `json:"minstorageprice"`
 		MinUploadBandwidthPrice   types.Currency `json:"minuploadbandwidthprice"`
+
+		// When AutoPricing is enabled, the storage and bandwidth prices of the
+		// host move between the minimum prices above and the maximum prices
+		// below. The position between the bounds is a weighted mix of the
+		// utilization of the host's storage, raised to the power of
+		// AutoPricingCurve, and of the recent demand: the number of contracts
+		// formed per hour relative to AutoPricingDemandTarget.
+		AutoPricing               bool           `json:"autopricing"`
+		AutoPricingCurve          float64        `json:"autopricingcurve"`
+		AutoPricingDemandTarget   uint64         `json:"autopricingdemandtarget"`
+		AutoPricingDemandWeight   float64        `json:"autopricingdemandweight"`
+		MaxDownloadBandwidthPrice types.Currency `json:"maxdownloadbandwidthprice"`
+		MaxStoragePrice           types.Currency `json:"maxstorageprice"`
+		MaxUploadBandwidthPrice   types.Currency `json:"maxuploadbandwidthprice"`
+
+		// MaxBandwidthSpeed limits the traffic of all renters combined, and
+		// MaxRenterBandwidthSpeed limits the traffic of the contracts of a
+		// single renter. Both count uploads and downloads, in bytes per
+		// second. A limit of zero means no limit.
+		//
+		// MaxRenterBandwidthQuota is the number of bytes that the contracts
+		// of a single renter may upload and download per day. The day starts
+		// with the first traffic of the renter, and RPCs of a renter that
+		// has used up its quota fail until the day is over. A quota of zero
+		// means no quota.
+		MaxBandwidthSpeed       uint64 `json:"maxbandwidthspeed"`
+		MaxRenterBandwidthSpeed uint64 `json:"maxrenterbandwidthspeed"`
+		MaxRenterBandwidthQuota uint64 `json:"maxrenterbandwidthquota"`
 	}
 
 	// HostNetworkMetrics reports the quantity of each type of RPC call that
-	// has been made to the host.
+	// has been made to the host, and the number of bytes that renters have
+	// downloaded from and uploaded to the host.
 	HostNetworkMetrics struct {
 		DownloadCalls     uint64 `json:"downloadcalls"`
 		ErrorCalls        uint64 `json:"errorcalls"`
@@ -106,12 +198,106 @@ type (
 		ReviseCalls       uint64 `json:"revisecalls"`
 		SettingsCalls     uint64 `json:"settingscalls"`
 		UnrecognizedCalls uint64 `json:"unrecognizedcalls"`
+
+		DownloadBytes uint64 `json:"downloadbytes"`
+		UploadBytes   uint64 `json:"uploadbytes"`
+	}
+
+	// HostBandwidthUsage is an amount of traffic between renters and the
+	// host. Download is the number of bytes sent by the host, Upload the
+	// number of bytes received by the host.
+	HostBandwidthUsage struct {
+		Download uint64 `json:"download"`
+		Upload   uint64 `json:"upload"`
+	}
+
+	// HostBandwidthPeriod is the traffic of the host during one period of
+	// its bandwidth history.
+	HostBandwidthPeriod struct {
+		Start time.Time `json:"start"`
+		HostBandwidthUsage
+	}
+
+	// HostContractBandwidth is the traffic of the download and revision RPCs
+	// for a single contract.
+	HostContractBandwidth struct {
+		ID types.FileContractID `json:"id"`
+		HostBandwidthUsage
+	}
+
+	// HostBandwidth reports the traffic of the host, in total, per RPC, per
+	// contract, and over time. The history is ordered from oldest to newest,
+	// and the last period is the current one.
+	HostBandwidth struct {
+		Total     HostBandwidthUsage            `json:"total"`
+		RPCs      map[string]HostBandwidthUsage `json:"rpcs"`
+		Contracts []HostContractBandwidth       `json:"contracts"`
+		History   []HostBandwidthPeriod         `json:"history"`
+	}
+
+	// HostLedgerEntry is a financial event in the ledger of the host. Amount
+	// is always positive; the Type determines whether it was earned or spent.
+	HostLedgerEntry struct {
+		Type        string               `json:"type"`
+		BlockHeight types.BlockHeight    `json:"blockheight"`
+		Timestamp   time.Time            `json:"timestamp"`
+		ContractID  types.FileContractID `json:"contractid"`
+		Amount      types.Currency       `json:"amount"`
+	}
+
+	// HostMaintenance describes a scheduled maintenance of the host. While
+	// maintenance is active, the host does not accept new contracts and
+	// submits storage proofs as soon as their proof window opens. The heights
+	// of the shutdown and restart are estimates based on the block frequency.
+	// AcceptingContracts is the setting that the host has when maintenance
+	// ends.
+	HostMaintenance struct {
+		Active             bool                        `json:"active"`
+		Shutdown           time.Time                   `json:"shutdown"`
+		Restart            time.Time                   `json:"restart"`
+		ShutdownHeight     types.BlockHeight           `json:"shutdownheight"`
+		RestartHeight      types.BlockHeight           `json:"restartheight"`
+		AcceptingContracts bool                        `json:"acceptingcontracts"`
+		Obligations        []HostMaintenanceObligation `json:"obligations"`
+	}
+
+	// HostMaintenanceObligation is a storage obligation whose proof window
+	// overlaps the downtime of a scheduled maintenance. EarlyProof is set if
+	// the storage proof will be submitted before the shutdown.
+	HostMaintenanceObligation struct {
+		ObligationID     types.FileContractID `json:"obligationid"`
+		ProofWindowStart types.BlockHeight    `json:"proofwindowstart"`
+		ProofDeadline    types.BlockHeight    `json:"proofdeadline"`
+		RiskedCollateral types.Currency       `json:"riskedcollateral"`
+		EarlyProof       bool                 `json:"earlyproof"`
+		AtRisk           bool                 `json:"atrisk"`
+		Reason           string               `json:"reason"`
 	}
 
 	// StorageObligation contains information about a storage obligation that
 	// the host has accepted.
 	StorageObligation struct {
-		NegotiationHeight types.BlockHeight `json:"negotiationheight"`
+		ObligationID      types.FileContractID `json:"obligationid"`
+		NegotiationHeight types.BlockHeight    `json:"negotiationheight"`
+		ExpirationHeight  types.BlockHeight    `json:"expirationheight"`
+		ProofDeadline     types.BlockHeight    `json:"proofdeadline"`
+		RevisionNumber    uint64               `json:"revisionnumber"`
+
+		// The data stored in the obligation.
+		DataSize         uint64 `json:"datasize"`
+		SectorRootsCount uint64 `json:"sectorrootscount"`
+
+		// The revenue and collateral of the obligation, and the payouts of the
+		// latest revision.
+		ContractCost             types.Currency        `json:"contractcost"`
+		LockedCollateral         types.Currency        `json:"lockedcollateral"`
+		PotentialDownloadRevenue types.Currency        `json:"potentialdownloadrevenue"`
+		PotentialStorageRevenue  types.Currency        `json:"potentialstoragerevenue"`
+		PotentialUploadRevenue   types.Currency        `json:"potentialuploadrevenue"`
+		RiskedCollateral         types.Currency        `json:"riskedcollateral"`
+		TransactionFeesAdded     types.Currency        `json:"transactionfeesadded"`
+		ValidProofOutputs        []types.SiacoinOutput `json:"validproofoutputs"`
+		MissedProofOutputs       []types.SiacoinOutput `json:"missedproofoutputs"`
 
 		OriginConfirmed     bool   `json:"originconfirmed"`
 		RevisionConstructed bool   `json:"revisionconstructed"`
@@ -119,6 +305,11 @@ type (
 		ProofConstructed    bool   `json:"proofconstructed"`
 		ProofConfirmed      bool   `json:"proofconfirmed"`
 		ObligationStatus    uint64 `json:"obligationstatus"`
+
+		// AtRisk is set if an unresolved obligation is in danger of missing
+		// its storage proof window, with RiskReason explaining why.
+		AtRisk     bool   `json:"atrisk"`
+		RiskReason string `json:"riskreason"`
 	}
 
 	// HostWorkingStatus reports the working state of a host. Can be one of
@@ -154,6 +345,27 @@ type (
 		// have been made to the host.
 		NetworkMetrics() HostNetworkMetrics
 
+		// Bandwidth returns the traffic of the host, broken down by RPC and
+		// by contract, and its recent history.
+		Bandwidth() HostBandwidth
+
+		// LedgerEntries returns the entries of the host's financial ledger
+		// between the provided block heights, inclusive, oldest first.
+		LedgerEntries(startHeight, endHeight types.BlockHeight) ([]HostLedgerEntry, error)
+
+		// Maintenance returns the scheduled maintenance of the host, and the
+		// storage obligations that it affects.
+		Maintenance() HostMaintenance
+
+		// StartMaintenance schedules a maintenance of the host, which stops
+		// accepting contracts until the restart time. Scheduling a new
+		// maintenance while one is active replaces its times.
+		StartMaintenance(shutdown, restart time.Time) error
+
+		// StopMaintenance ends the maintenance of the host and restores its
+		// settings.
+		StopMaintenance() error
+
 		// PublicKey returns the public key of the host.
 		PublicKey() types.SiaPublicKey
 
@@ -164,6 +376,10 @@ type (
 		// the host.
 		StorageObligations() []StorageObligation
 
+		// StorageObligation returns the storage obligation with the provided
+		// id.
+		StorageObligation(id types.FileContractID) (StorageObligation, error)
+
 		// ConnectabilityStatus returns the connectability status of the host, that
 		// is, if it can connect to itself on the configured NetAddress.
 		ConnectabilityStatus() HostConnectabilityStatus
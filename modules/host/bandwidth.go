@@ -0,0 +1,347 @@
+package host
+
+// bandwidth.go meters the traffic of the host and enforces its bandwidth
+// limits. Every incoming connection is wrapped in a meteredConn, which counts
+// the bytes read and written and waits on the host's global rate limit. Once
+// the renter of a download or revision RPC has proven which contract it is
+// using, the connection is also throttled by the rate limit of that renter,
+// and its traffic is charged to the quota of the renter. The quota is kept in
+// memory, and starts over when the host restarts. When the RPC completes, its traffic is recorded per RPC, per contract, and in
+// the bandwidth history of the host.
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	siasync "github.com/NebulousLabs/Sia/sync"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// errRenterQuotaExceeded is returned when a renter has transferred more
+	// than MaxRenterBandwidthQuota bytes in the current quota period.
+	errRenterQuotaExceeded = errors.New("renter has exceeded its bandwidth quota")
+
+	// rpcNames are the names of the RPCs in the bandwidth report of the host.
+	// Traffic of unknown or malformed RPCs is reported as "unrecognized".
+	rpcNames = map[types.Specifier]string{
+		modules.RPCDownload:       "download",
+		modules.RPCDownloadRange:  "downloadrange",
+		modules.RPCFormContract:   "formcontract",
+		modules.RPCRenewContract:  "renewcontract",
+		modules.RPCReviseContract: "revisecontract",
+		modules.RPCSettings:       "settings",
+		rpcSettingsDeprecated:     "settings",
+	}
+)
+
+// renterLimit is the bandwidth limit of a renter, shared by the connections
+// of all of its contracts. contracts holds the storage obligations of the
+// renter that connections have used since the host started, and is protected
+// by the host's lock. The limit is dropped once all of them have been removed.
+type renterLimit struct {
+	*siasync.RateLimit
+	contracts map[types.FileContractID]struct{}
+
+	// quota is the number of bytes that the renter may transfer per
+	// renterQuotaPeriod, and used is the number of bytes that it has
+	// transferred since quotaStart. A quota of zero means no quota.
+	quota      uint64
+	quotaStart time.Time
+	used       uint64
+	mu         sync.Mutex
+}
+
+// chargeQuota charges n bytes to the quota of the renter. If they do not fit
+// within the quota, they are not charged and errRenterQuotaExceeded is
+// returned.
+func (rl *renterLimit) chargeQuota(n uint64, now time.Time) error {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	if now.Sub(rl.quotaStart) >= renterQuotaPeriod {
+		rl.quotaStart = now
+		rl.used = 0
+	}
+	if rl.quota != 0 && rl.used+n > rl.quota {
+		return errRenterQuotaExceeded
+	}
+	rl.used += n
+	return nil
+}
+
+// setQuota changes the quota of the renter. Traffic in the current quota
+// period counts against the new quota.
+func (rl *renterLimit) setQuota(quota uint64) {
+	rl.mu.Lock()
+	rl.quota = quota
+	rl.mu.Unlock()
+}
+
+// meteredConn is a connection to a renter that counts its traffic and is
+// throttled by the bandwidth limits of the host.
+type meteredConn struct {
+	// atomicDownload and atomicUpload count the bytes written to and read
+	// from the connection. They are placed first to preserve compatibility
+	// with 32bit systems.
+	atomicDownload uint64
+	atomicUpload   uint64
+
+	net.Conn
+	h *Host
+
+	// contract and renterLimit are set once the renter has proven which
+	// contract the connection is used for.
+	contract    types.FileContractID
+	renterLimit *renterLimit
+	mu          sync.Mutex
+}
+
+// newMeteredConn wraps a connection to a renter.
+func (h *Host) newMeteredConn(conn net.Conn) *meteredConn {
+	return &meteredConn{
+		Conn: conn,
+		h:    h,
+	}
+}
+
+// managedWait charges n bytes to the quota of the renter of the connection,
+// and blocks until they fit within the bandwidth limits that apply to the
+// connection.
+func (mc *meteredConn) managedWait(n uint64) error {
+	mc.mu.Lock()
+	renterLimit := mc.renterLimit
+	mc.mu.Unlock()
+
+	if renterLimit != nil {
+		if err := renterLimit.chargeQuota(n, time.Now()); err != nil {
+			return err
+		}
+	}
+	err := mc.h.bandwidthLimit.Wait(n, mc.h.tg.StopChan())
+	if err != nil || renterLimit == nil {
+		return err
+	}
+	return renterLimit.Wait(n, mc.h.tg.StopChan())
+}
+
+// Read reads at most meteredConnChunkSize bytes from the connection, counting
+// them as uploaded by the renter.
+func (mc *meteredConn) Read(b []byte) (int, error) {
+	if len(b) > meteredConnChunkSize {
+		b = b[:meteredConnChunkSize]
+	}
+	n, err := mc.Conn.Read(b)
+	atomic.AddUint64(&mc.atomicUpload, uint64(n))
+	atomic.AddUint64(&mc.h.atomicUploadBytes, uint64(n))
+	if waitErr := mc.managedWait(uint64(n)); waitErr != nil && err == nil {
+		err = waitErr
+	}
+	return n, err
+}
+
+// Write writes to the connection in chunks of at most meteredConnChunkSize
+// bytes, waiting on the bandwidth limits before each chunk and counting the
+// bytes as downloaded by the renter.
+func (mc *meteredConn) Write(b []byte) (int, error) {
+	var n int
+	for len(b) > 0 {
+		chunk := b
+		if len(chunk) > meteredConnChunkSize {
+			chunk = chunk[:meteredConnChunkSize]
+		}
+		if err := mc.managedWait(uint64(len(chunk))); err != nil {
+			return n, err
+		}
+		written, err := mc.Conn.Write(chunk)
+		n += written
+		atomic.AddUint64(&mc.atomicDownload, uint64(written))
+		atomic.AddUint64(&mc.h.atomicDownloadBytes, uint64(written))
+		if err != nil {
+			return n, err
+		}
+		b = b[written:]
+	}
+	return n, nil
+}
+
+// usage returns the traffic of the connection so far.
+func (mc *meteredConn) usage() modules.HostBandwidthUsage {
+	return modules.HostBandwidthUsage{
+		Download: atomic.LoadUint64(&mc.atomicDownload),
+		Upload:   atomic.LoadUint64(&mc.atomicUpload),
+	}
+}
+
+// addBandwidth returns the sum of two amounts of traffic.
+func addBandwidth(a, b modules.HostBandwidthUsage) modules.HostBandwidthUsage {
+	return modules.HostBandwidthUsage{
+		Download: a.Download + b.Download,
+		Upload:   a.Upload + b.Upload,
+	}
+}
+
+// renterKey identifies the renter of a storage obligation by the first public
+// key of the unlock conditions of its contract.
+func renterKey(so storageObligation) string {
+	if n := len(so.RevisionTransactionSet); n > 0 && len(so.RevisionTransactionSet[n-1].FileContractRevisions) > 0 {
+		uc := so.RevisionTransactionSet[n-1].FileContractRevisions[0].UnlockConditions
+		if len(uc.PublicKeys) > 0 {
+			return uc.PublicKeys[0].String()
+		}
+	}
+	return ""
+}
+
+// managedMeterContract attributes the traffic of the connection to the
+// contract of the storage obligation, and applies the bandwidth limit of the
+// contract's renter to the rest of the connection.
+func (h *Host) managedMeterContract(conn net.Conn, so storageObligation) {
+	mc, ok := conn.(*meteredConn)
+	if !ok {
+		return
+	}
+
+	key := renterKey(so)
+	h.mu.Lock()
+	rl, exists := h.renterLimits[key]
+	if !exists {
+		rl = &renterLimit{
+			RateLimit: siasync.NewRateLimit(h.settings.MaxRenterBandwidthSpeed),
+			contracts: make(map[types.FileContractID]struct{}),
+			quota:     h.settings.MaxRenterBandwidthQuota,
+		}
+		h.renterLimits[key] = rl
+	}
+	rl.contracts[so.id()] = struct{}{}
+	h.mu.Unlock()
+
+	mc.mu.Lock()
+	mc.contract = so.id()
+	mc.renterLimit = rl
+	mc.mu.Unlock()
+}
+
+// forgetBandwidth removes the traffic of a storage obligation that has been
+// removed from the bandwidth report of the host, and drops the bandwidth limit
+// of its renter once none of the renter's contracts are in use. The host's
+// lock must be held by the caller.
+func (h *Host) forgetBandwidth(so storageObligation) {
+	delete(h.bandwidthContracts, so.id())
+	key := renterKey(so)
+	if rl, exists := h.renterLimits[key]; exists {
+		delete(rl.contracts, so.id())
+		if len(rl.contracts) == 0 {
+			delete(h.renterLimits, key)
+		}
+	}
+}
+
+// setBandwidthLimits applies the bandwidth limits of the host's settings to
+// the global and per-renter rate limits, and to the quotas of the renters. The
+// host's lock must be held by the caller.
+func (h *Host) setBandwidthLimits() {
+	h.bandwidthLimit.SetLimit(h.settings.MaxBandwidthSpeed)
+	for _, rl := range h.renterLimits {
+		rl.SetLimit(h.settings.MaxRenterBandwidthSpeed)
+		rl.setQuota(h.settings.MaxRenterBandwidthQuota)
+	}
+}
+
+// recordBandwidth adds the traffic of a completed RPC to the bandwidth report
+// of the host. The host's lock must be held by the caller.
+func (h *Host) recordBandwidth(rpc string, contract types.FileContractID, usage modules.HostBandwidthUsage, now time.Time) {
+	h.bandwidthRPCs[rpc] = addBandwidth(h.bandwidthRPCs[rpc], usage)
+	if contract != (types.FileContractID{}) {
+		h.bandwidthContracts[contract] = addBandwidth(h.bandwidthContracts[contract], usage)
+	}
+
+	// Start a new period if the current one has ended, dropping the oldest
+	// period once the history is full.
+	start := now.Truncate(bandwidthHistoryInterval)
+	if len(h.bandwidthHistory) == 0 || h.bandwidthHistory[len(h.bandwidthHistory)-1].Start.Before(start) {
+		h.bandwidthHistory = append(h.bandwidthHistory, modules.HostBandwidthPeriod{Start: start})
+		if len(h.bandwidthHistory) > bandwidthHistoryLength {
+			h.bandwidthHistory = h.bandwidthHistory[len(h.bandwidthHistory)-bandwidthHistoryLength:]
+		}
+	}
+	current := &h.bandwidthHistory[len(h.bandwidthHistory)-1]
+	current.HostBandwidthUsage = addBandwidth(current.HostBandwidthUsage, usage)
+}
+
+// managedRecordBandwidth records the traffic of a connection once its RPC has
+// completed.
+func (h *Host) managedRecordBandwidth(id types.Specifier, mc *meteredConn) {
+	rpc, exists := rpcNames[id]
+	if !exists {
+		rpc = "unrecognized"
+	}
+	mc.mu.Lock()
+	contract := mc.contract
+	mc.mu.Unlock()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.recordBandwidth(rpc, contract, mc.usage(), time.Now())
+}
+
+// bandwidth returns the bandwidth report of the host. Contracts are sorted by
+// their total traffic, largest first. The host's lock must be held by the
+// caller.
+func (h *Host) bandwidth() modules.HostBandwidth {
+	var bw modules.HostBandwidth
+	bw.RPCs = make(map[string]modules.HostBandwidthUsage, len(h.bandwidthRPCs))
+	for rpc, usage := range h.bandwidthRPCs {
+		bw.RPCs[rpc] = usage
+		bw.Total = addBandwidth(bw.Total, usage)
+	}
+	for id, usage := range h.bandwidthContracts {
+		bw.Contracts = append(bw.Contracts, modules.HostContractBandwidth{
+			ID:                 id,
+			HostBandwidthUsage: usage,
+		})
+	}
+	sort.Slice(bw.Contracts, func(i, j int) bool {
+		ci, cj := bw.Contracts[i], bw.Contracts[j]
+		return ci.Download+ci.Upload > cj.Download+cj.Upload
+	})
+	bw.History = append([]modules.HostBandwidthPeriod(nil), h.bandwidthHistory...)
+	return bw
+}
+
+// persistBandwidth returns the bandwidth report of the host in the form that
+// is persisted. Only the bandwidthPersistedContracts contracts with the most
+// traffic are kept. The host's lock must be held by the caller.
+func (h *Host) persistBandwidth() modules.HostBandwidth {
+	bw := h.bandwidth()
+	if len(bw.Contracts) > bandwidthPersistedContracts {
+		bw.Contracts = bw.Contracts[:bandwidthPersistedContracts]
+	}
+	return bw
+}
+
+// loadBandwidth restores the bandwidth report of the host from its persisted
+// form. The host's lock must be held by the caller.
+func (h *Host) loadBandwidth(bw modules.HostBandwidth) {
+	h.bandwidthRPCs = make(map[string]modules.HostBandwidthUsage, len(bw.RPCs))
+	for rpc, usage := range bw.RPCs {
+		h.bandwidthRPCs[rpc] = usage
+	}
+	h.bandwidthContracts = make(map[types.FileContractID]modules.HostBandwidthUsage, len(bw.Contracts))
+	for _, c := range bw.Contracts {
+		h.bandwidthContracts[c.ID] = c.HostBandwidthUsage
+	}
+	h.bandwidthHistory = bw.History
+}
+
+// Bandwidth returns the traffic of the host, broken down by RPC and by
+// contract, and its recent history.
+func (h *Host) Bandwidth() modules.HostBandwidth {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.bandwidth()
+}
@@ -0,0 +1,235 @@
+package host
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	siasync "github.com/NebulousLabs/Sia/sync"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// newBandwidthTestHost returns a host with only the fields needed to meter
+// bandwidth.
+func newBandwidthTestHost() *Host {
+	return &Host{
+		bandwidthContracts: make(map[types.FileContractID]modules.HostBandwidthUsage),
+		bandwidthLimit:     siasync.NewRateLimit(0),
+		bandwidthRPCs:      make(map[string]modules.HostBandwidthUsage),
+		renterLimits:       make(map[string]*renterLimit),
+	}
+}
+
+// TestMeteredConn checks that a metered connection counts the bytes that are
+// read and written.
+func TestMeteredConn(t *testing.T) {
+	t.Parallel()
+	h := newBandwidthTestHost()
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	mc := h.newMeteredConn(c1)
+
+	go func() {
+		c2.Write(make([]byte, 10))
+		c2.Read(make([]byte, 25))
+	}()
+	if _, err := mc.Read(make([]byte, 10)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mc.Write(make([]byte, 25)); err != nil {
+		t.Fatal(err)
+	}
+
+	usage := mc.usage()
+	if usage.Upload != 10 || usage.Download != 25 {
+		t.Error("wrong connection usage:", usage)
+	}
+	metrics := h.NetworkMetrics()
+	if metrics.UploadBytes != 10 || metrics.DownloadBytes != 25 {
+		t.Error("wrong host byte counters:", metrics.UploadBytes, metrics.DownloadBytes)
+	}
+}
+
+// writeRecorder is a connection that records the size of the largest write.
+type writeRecorder struct {
+	net.Conn
+	largest int
+}
+
+func (wr *writeRecorder) Write(b []byte) (int, error) {
+	if len(b) > wr.largest {
+		wr.largest = len(b)
+	}
+	return len(b), nil
+}
+
+// TestMeteredConnChunks checks that a metered connection writes large buffers
+// in chunks, and stops writing once the quota of the renter is used up.
+func TestMeteredConnChunks(t *testing.T) {
+	t.Parallel()
+	h := newBandwidthTestHost()
+	wr := new(writeRecorder)
+	mc := h.newMeteredConn(wr)
+	mc.renterLimit = &renterLimit{
+		RateLimit: siasync.NewRateLimit(0),
+		quota:     3 * meteredConnChunkSize,
+	}
+
+	n, err := mc.Write(make([]byte, 2*meteredConnChunkSize+10))
+	if err != nil || n != 2*meteredConnChunkSize+10 {
+		t.Fatal("write failed:", n, err)
+	}
+	if wr.largest != meteredConnChunkSize {
+		t.Error("write was not split into chunks:", wr.largest)
+	}
+
+	// Chunks that do not fit within the quota are not written.
+	n, err = mc.Write(make([]byte, meteredConnChunkSize))
+	if err != errRenterQuotaExceeded || n != 0 {
+		t.Error("write exceeded the quota:", n, err)
+	}
+	n, err = mc.Write(make([]byte, meteredConnChunkSize-10))
+	if err != nil || n != meteredConnChunkSize-10 {
+		t.Fatal("write within the quota failed:", n, err)
+	}
+	if _, err := mc.Write([]byte{1}); err != errRenterQuotaExceeded {
+		t.Error("expected errRenterQuotaExceeded, got", err)
+	}
+	if usage := mc.usage(); usage.Download != 3*meteredConnChunkSize {
+		t.Error("wrong connection usage:", usage)
+	}
+}
+
+// TestRenterQuota checks that the quota of a renter is enforced and starts
+// over in the next quota period.
+func TestRenterQuota(t *testing.T) {
+	t.Parallel()
+	rl := &renterLimit{RateLimit: siasync.NewRateLimit(0)}
+	now := time.Now()
+	if err := rl.chargeQuota(1000, now); err != nil {
+		t.Fatal("traffic without a quota was rejected:", err)
+	}
+
+	// Traffic of the current period counts against a new quota.
+	rl.setQuota(1500)
+	if err := rl.chargeQuota(400, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := rl.chargeQuota(200, now); err != errRenterQuotaExceeded {
+		t.Fatal("expected errRenterQuotaExceeded, got", err)
+	}
+	if err := rl.chargeQuota(100, now); err != nil {
+		t.Fatal("rejected traffic was charged to the quota:", err)
+	}
+
+	// The quota starts over in the next period.
+	if err := rl.chargeQuota(1500, now.Add(renterQuotaPeriod)); err != nil {
+		t.Fatal("quota did not start over:", err)
+	}
+}
+
+// TestRecordBandwidth checks the per-RPC, per-contract and historical
+// bandwidth records of the host.
+func TestRecordBandwidth(t *testing.T) {
+	t.Parallel()
+	h := newBandwidthTestHost()
+	id := types.FileContractID{1}
+	start := time.Now().Truncate(bandwidthHistoryInterval)
+
+	h.recordBandwidth("settings", types.FileContractID{}, modules.HostBandwidthUsage{Download: 100, Upload: 16}, start)
+	h.recordBandwidth("download", id, modules.HostBandwidthUsage{Download: 1000, Upload: 50}, start)
+	h.recordBandwidth("download", id, modules.HostBandwidthUsage{Download: 1000, Upload: 50}, start.Add(bandwidthHistoryInterval))
+
+	bw := h.bandwidth()
+	if bw.Total.Download != 2100 || bw.Total.Upload != 116 {
+		t.Error("wrong total:", bw.Total)
+	}
+	if bw.RPCs["download"].Download != 2000 || bw.RPCs["settings"].Upload != 16 {
+		t.Error("wrong rpc breakdown:", bw.RPCs)
+	}
+	if len(bw.Contracts) != 1 || bw.Contracts[0].ID != id || bw.Contracts[0].Download != 2000 {
+		t.Error("wrong contract breakdown:", bw.Contracts)
+	}
+	if len(bw.History) != 2 || bw.History[0].Download != 1100 || bw.History[1].Download != 1000 {
+		t.Fatal("wrong history:", bw.History)
+	}
+
+	// The history is limited to bandwidthHistoryLength periods.
+	for i := 0; i < bandwidthHistoryLength+10; i++ {
+		h.recordBandwidth("settings", types.FileContractID{}, modules.HostBandwidthUsage{}, start.Add(time.Duration(i+2)*bandwidthHistoryInterval))
+	}
+	if len(h.bandwidthHistory) != bandwidthHistoryLength {
+		t.Error("history was not trimmed:", len(h.bandwidthHistory))
+	}
+
+	// The report survives a round trip through persistence.
+	h2 := newBandwidthTestHost()
+	h2.loadBandwidth(h.bandwidth())
+	if bw2 := h2.bandwidth(); bw2.Total != h.bandwidth().Total || len(bw2.Contracts) != 1 || len(bw2.History) != bandwidthHistoryLength {
+		t.Error("bandwidth report changed after reload")
+	}
+}
+
+// TestForgetBandwidth checks that the traffic and the renter limit of a
+// removed storage obligation are dropped.
+func TestForgetBandwidth(t *testing.T) {
+	t.Parallel()
+	h := newBandwidthTestHost()
+	renterObligation := func(fileSize uint64) storageObligation {
+		return storageObligation{
+			OriginTransactionSet: []types.Transaction{{
+				FileContracts: []types.FileContract{{FileSize: fileSize}},
+			}},
+			RevisionTransactionSet: []types.Transaction{{
+				FileContractRevisions: []types.FileContractRevision{{
+					UnlockConditions: types.UnlockConditions{
+						PublicKeys: []types.SiaPublicKey{{Key: []byte{1}}},
+					},
+				}},
+			}},
+		}
+	}
+	so1, so2 := renterObligation(1), renterObligation(2)
+	for _, so := range []storageObligation{so1, so2} {
+		c1, c2 := net.Pipe()
+		h.managedMeterContract(h.newMeteredConn(c1), so)
+		h.recordBandwidth("download", so.id(), modules.HostBandwidthUsage{Download: 100}, time.Now())
+		c1.Close()
+		c2.Close()
+	}
+	if len(h.renterLimits) != 1 || len(h.bandwidthContracts) != 2 {
+		t.Fatal("wrong bandwidth state:", len(h.renterLimits), len(h.bandwidthContracts))
+	}
+
+	// The renter limit is kept while one of its contracts remains.
+	h.forgetBandwidth(so1)
+	if len(h.renterLimits) != 1 || len(h.bandwidthContracts) != 1 {
+		t.Error("wrong bandwidth state after removing one contract:", len(h.renterLimits), len(h.bandwidthContracts))
+	}
+	h.forgetBandwidth(so2)
+	if len(h.renterLimits) != 0 || len(h.bandwidthContracts) != 0 {
+		t.Error("wrong bandwidth state after removing all contracts:", len(h.renterLimits), len(h.bandwidthContracts))
+	}
+	if bw := h.bandwidth(); bw.Total.Download != 200 {
+		t.Error("total traffic changed after removing contracts:", bw.Total)
+	}
+}
+
+// TestPersistBandwidth checks that only the contracts with the most traffic
+// are persisted.
+func TestPersistBandwidth(t *testing.T) {
+	t.Parallel()
+	h := newBandwidthTestHost()
+	for i := 0; i < bandwidthPersistedContracts+10; i++ {
+		h.bandwidthContracts[types.FileContractID{byte(i), byte(i >> 8)}] = modules.HostBandwidthUsage{Download: uint64(i)}
+	}
+	bw := h.persistBandwidth()
+	if len(bw.Contracts) != bandwidthPersistedContracts {
+		t.Fatal("wrong number of persisted contracts:", len(bw.Contracts))
+	}
+	if bw.Contracts[len(bw.Contracts)-1].Download != 10 {
+		t.Error("persisted contracts are not the ones with the most traffic")
+	}
+}
@@ -31,9 +31,75 @@ const (
 	// Typically, this transaction will contain either a file contract, a file
 	// contract revision, or a storage proof.
 	resubmissionTimeout = 3
+
+	// defaultAutoPricingCurve is the default exponent of the utilization of
+	// the host in the auto pricing curve. Prices rise slowly while the host
+	// has plenty of free storage, and quickly as it fills up.
+	defaultAutoPricingCurve = 2
+
+	// defaultAutoPricingDemandTarget is the default number of contracts formed
+	// per hour at which demand pushes prices to their maximum.
+	defaultAutoPricingDemandTarget = 10
+
+	// defaultAutoPricingDemandWeight is the default weight of demand, relative
+	// to utilization, in the auto pricing curve.
+	defaultAutoPricingDemandWeight = 0.25
+
+	// autoPricingMaxStep is the largest change of a price in a single
+	// adjustment, as a fraction of the distance between its minimum and
+	// maximum. This limits how fast prices move.
+	autoPricingMaxStep = 0.1
+
+	// bandwidthHistoryLength is the number of periods of bandwidth history
+	// that the host keeps.
+	bandwidthHistoryLength = 168
+
+	// bandwidthPersistedContracts is the number of contracts whose traffic is
+	// persisted. The contracts with the most traffic are kept, and the traffic
+	// of the others starts from zero when the host restarts.
+	bandwidthPersistedContracts = 1000
+
+	// meteredConnChunkSize is the largest number of bytes that a metered
+	// connection reads or writes at once. Large writes are split into chunks,
+	// so that they are spread out by the bandwidth limits instead of waiting
+	// for the whole buffer at once.
+	meteredConnChunkSize = 1 << 16
 )
 
 var (
+	// autoPricingFrequency defines how often the host adjusts its prices
+	// when auto pricing is enabled. Demand is measured over the same
+	// interval.
+	autoPricingFrequency = build.Select(build.Var{
+		Standard: time.Hour,
+		Dev:      time.Minute * 5,
+		Testing:  time.Second * 3,
+	}).(time.Duration)
+
+	// bandwidthHistoryInterval is the length of a period in the bandwidth
+	// history of the host.
+	bandwidthHistoryInterval = build.Select(build.Var{
+		Standard: time.Hour,
+		Dev:      time.Minute,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// renterQuotaPeriod is the period over which the traffic of a renter is
+	// counted against MaxRenterBandwidthQuota.
+	renterQuotaPeriod = build.Select(build.Var{
+		Standard: 24 * time.Hour,
+		Dev:      time.Hour,
+		Testing:  2 * time.Second,
+	}).(time.Duration)
+
+	// maintenanceCheckFrequency defines how often the host checks whether
+	// a scheduled maintenance has ended.
+	maintenanceCheckFrequency = build.Select(build.Var{
+		Standard: time.Minute,
+		Dev:      time.Second * 10,
+		Testing:  time.Second,
+	}).(time.Duration)
+
 	// connectablityCheckFirstWait defines how often the host's connectability
 	// check is run.
 	connectabilityCheckFirstWait = build.Select(build.Var{
@@ -235,6 +301,12 @@ var (
 	// using the id.
 	bucketActionItems = []byte("BucketActionItems")
 
+	// bucketLedger contains the entries of the host's financial ledger,
+	// serialized as JSON. The entries are keyed by a big endian sequence
+	// number, which means that they are sorted in the order they were
+	// appended.
+	bucketLedger = []byte("BucketLedger")
+
 	// bucketStorageObligations contains a set of serialized
 	// 'storageObligations' sorted by their file contract id.
 	bucketStorageObligations = []byte("BucketStorageObligations")
@@ -124,6 +124,11 @@ type Host struct {
 	atomicSettingsCalls     uint64
 	atomicUnrecognizedCalls uint64
 
+	// Bandwidth metrics, counted by the metered connections of the host.
+	// These values are not persistent.
+	atomicDownloadBytes uint64
+	atomicUploadBytes   uint64
+
 	// Error management. There are a few different types of errors returned by
 	// the host. These errors intentionally not persistent, so that the logging
 	// limits of each error type will be reset each time the host is reset.
@@ -154,12 +159,23 @@ type Host struct {
 	// Host transient fields - these fields are either determined at startup or
 	// otherwise are not critical to always be correct.
 	autoAddress          modules.NetAddress // Determined using automatic tooling in network.go
+	autoPrices           hostPrices         // Adjusted in pricing.go when auto pricing is enabled
 	financialMetrics     modules.HostFinancialMetrics
+	maintenanceState     hostMaintenance // See maintenance.go
 	settings             modules.HostInternalSettings
 	revisionNumber       uint64
 	workingStatus        modules.HostWorkingStatus
 	connectabilityStatus modules.HostConnectabilityStatus
 
+	// The traffic of the host per RPC, per contract and over time, and the
+	// rate limits that enforce the bandwidth limits of the host. See
+	// bandwidth.go.
+	bandwidthContracts map[types.FileContractID]modules.HostBandwidthUsage
+	bandwidthHistory   []modules.HostBandwidthPeriod
+	bandwidthLimit     *siasync.RateLimit
+	bandwidthRPCs      map[string]modules.HostBandwidthUsage
+	renterLimits       map[string]*renterLimit
+
 	// A map of storage obligations that are currently being modified. Locks on
 	// storage obligations can be long-running, and each storage obligation can
 	// be locked separately.
@@ -230,6 +246,11 @@ func newHost(dependencies dependencies, cs modules.ConsensusSet, tpool modules.T
 		wallet:       wallet,
 		dependencies: dependencies,
 
+		bandwidthContracts: make(map[types.FileContractID]modules.HostBandwidthUsage),
+		bandwidthLimit:     siasync.NewRateLimit(0),
+		bandwidthRPCs:      make(map[string]modules.HostBandwidthUsage),
+		renterLimits:       make(map[string]*renterLimit),
+
 		lockedStorageObligations: make(map[types.FileContractID]*siasync.TryMutex),
 
 		persistDir: persistDir,
@@ -297,6 +318,20 @@ func newHost(dependencies dependencies, cs modules.ConsensusSet, tpool modules.T
 		h.log.Println("Could not initialize host networking:", err)
 		return nil, err
 	}
+
+	// Start adjusting the prices of the host.
+	threadedAutoPricingClosedChan := make(chan struct{})
+	go h.threadedAutoPricing(threadedAutoPricingClosedChan)
+	h.tg.OnStop(func() {
+		<-threadedAutoPricingClosedChan
+	})
+
+	// Restore the settings of the host once a scheduled maintenance ends.
+	threadedMaintenanceClosedChan := make(chan struct{})
+	go h.threadedMaintenance(threadedMaintenanceClosedChan)
+	h.tg.OnStop(func() {
+		<-threadedMaintenanceClosedChan
+	})
 	return h, nil
 }
 
@@ -388,6 +423,10 @@ func (h *Host) SetInternalSettings(settings modules.HostInternalSettings) error
 		}
 	}
 
+	if err := checkAutoPricing(settings); err != nil {
+		return errors.New("internal settings not updated, invalid auto pricing: " + err.Error())
+	}
+
 	// Check if the net address for the host has changed. If it has, and it's
 	// not equal to the auto address, then the host is going to need to make
 	// another blockchain announcement.
@@ -395,8 +434,15 @@ func (h *Host) SetInternalSettings(settings modules.HostInternalSettings) error
 		h.announced = false
 	}
 
+	// If the user changes whether the host accepts contracts during
+	// maintenance, the change is kept when the maintenance ends.
+	if h.maintenanceState.Active && settings.AcceptingContracts != h.settings.AcceptingContracts {
+		h.maintenanceState.AcceptingContractsChanged = true
+	}
+
 	h.settings = settings
 	h.revisionNumber++
+	h.setBandwidthLimits()
 
 	err = h.saveSync()
 	if err != nil {
@@ -0,0 +1,82 @@
+package host
+
+// ledger.go maintains the financial ledger of the host. Unlike the financial
+// metrics, which are running totals, the ledger is an append-only record of
+// every financial event of the host. Entries are written in the same database
+// transaction as the storage obligation change that caused them, so the ledger
+// stays consistent with the obligations after a crash.
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+var (
+	// errLedgerRange is returned if the start of a ledger query is after its
+	// end.
+	errLedgerRange = errors.New("start height of the ledger range is after the end height")
+)
+
+// appendLedgerEntry appends an entry to the ledger of the host. Entries
+// without an amount are not recorded.
+func appendLedgerEntry(tx *bolt.Tx, entryType string, height types.BlockHeight, soid types.FileContractID, amount types.Currency) error {
+	if amount.IsZero() {
+		return nil
+	}
+	entryBytes, err := json.Marshal(modules.HostLedgerEntry{
+		Type:        entryType,
+		BlockHeight: height,
+		Timestamp:   time.Now(),
+		ContractID:  soid,
+		Amount:      amount,
+	})
+	if err != nil {
+		return err
+	}
+	b := tx.Bucket(bucketLedger)
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return b.Put(key, entryBytes)
+}
+
+// LedgerEntries returns the entries of the host's financial ledger between the
+// provided block heights, inclusive, oldest first.
+func (h *Host) LedgerEntries(startHeight, endHeight types.BlockHeight) ([]modules.HostLedgerEntry, error) {
+	if startHeight > endHeight {
+		return nil, errLedgerRange
+	}
+	err := h.tg.Add()
+	if err != nil {
+		return nil, err
+	}
+	defer h.tg.Done()
+
+	entries := []modules.HostLedgerEntry{}
+	err = h.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketLedger).ForEach(func(_, v []byte) error {
+			var entry modules.HostLedgerEntry
+			if err := json.Unmarshal(v, &entry); err != nil {
+				return err
+			}
+			if entry.BlockHeight >= startHeight && entry.BlockHeight <= endHeight {
+				entries = append(entries, entry)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
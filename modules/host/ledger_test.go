@@ -0,0 +1,173 @@
+package host
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// ledgerTotals returns the totals of each type of ledger entry of a contract.
+func ledgerTotals(t *testing.T, h *Host, id types.FileContractID) map[string]types.Currency {
+	entries, err := h.LedgerEntries(0, h.blockHeight)
+	if err != nil {
+		t.Fatal(err)
+	}
+	totals := make(map[string]types.Currency)
+	for _, entry := range entries {
+		if entry.ContractID == id {
+			totals[entry.Type] = totals[entry.Type].Add(entry.Amount)
+		}
+	}
+	return totals
+}
+
+// TestHostLedger checks that the pending revenue of a contract is recorded in
+// the ledger when the contract is formed and revised, and that it is realised
+// by the storage proof or reversed when the proof is missed.
+func TestHostLedger(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester("TestHostLedger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	// Add a storage obligation with a contract price.
+	so, err := ht.newTesterStorageObligation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	so.ContractCost = types.SiacoinPrecision.Mul64(50)
+	ht.host.managedLockStorageObligation(so.id())
+	err = ht.host.managedAddStorageObligation(so)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.host.managedUnlockStorageObligation(so.id())
+
+	// Revise the obligation to pay for a sector.
+	sectorRoot, sectorData := randSector()
+	so.SectorRoots = []crypto.Hash{sectorRoot}
+	sectorCost := types.SiacoinPrecision.Mul64(550)
+	so.PotentialStorageRevenue = so.PotentialStorageRevenue.Add(sectorCost)
+	ht.host.financialMetrics.PotentialStorageRevenue = ht.host.financialMetrics.PotentialStorageRevenue.Add(sectorCost)
+	validPayouts, missedPayouts := so.payouts()
+	validPayouts[0].Value = validPayouts[0].Value.Sub(sectorCost)
+	validPayouts[1].Value = validPayouts[1].Value.Add(sectorCost)
+	missedPayouts[0].Value = missedPayouts[0].Value.Sub(sectorCost)
+	missedPayouts[1].Value = missedPayouts[1].Value.Add(sectorCost)
+	revisionSet := []types.Transaction{{
+		FileContractRevisions: []types.FileContractRevision{{
+			ParentID:          so.id(),
+			UnlockConditions:  types.UnlockConditions{},
+			NewRevisionNumber: 1,
+
+			NewFileSize:           uint64(len(sectorData)),
+			NewFileMerkleRoot:     sectorRoot,
+			NewWindowStart:        so.expiration(),
+			NewWindowEnd:          so.proofDeadline(),
+			NewValidProofOutputs:  validPayouts,
+			NewMissedProofOutputs: missedPayouts,
+			NewUnlockHash:         types.UnlockConditions{}.UnlockHash(),
+		}},
+	}}
+	ht.host.managedLockStorageObligation(so.id())
+	err = ht.host.modifyStorageObligation(so, nil, []crypto.Hash{sectorRoot}, [][]byte{sectorData})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.host.managedUnlockStorageObligation(so.id())
+	err = ht.tpool.AcceptTransactionSet(revisionSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The revenue of the contract is pending.
+	totals := ledgerTotals(t, ht.host, so.id())
+	if !totals[modules.HostLedgerContractFormed].Equals(so.ContractCost) {
+		t.Error("wrong contract formation revenue in the ledger:", totals[modules.HostLedgerContractFormed])
+	}
+	if !totals[modules.HostLedgerRevisionRevenue].Equals(sectorCost) {
+		t.Error("wrong revision revenue in the ledger:", totals[modules.HostLedgerRevisionRevenue])
+	}
+	if !totals[modules.HostLedgerStorageProof].IsZero() {
+		t.Error("storage proof revenue recorded before the storage proof")
+	}
+
+	// Mine until the host has submitted the storage proof, and until the
+	// proof has enough confirmations for the host to finalize the obligation.
+	for i := ht.host.blockHeight; i <= so.proofDeadline()+defaultWindowSize; i++ {
+		_, err := ht.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ht.host.tg.Flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = ht.host.db.View(func(tx *bolt.Tx) error {
+		so, err = getStorageObligation(tx, so.id())
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if so.ObligationStatus != obligationSucceeded {
+		t.Fatal("obligation is not being reported as successful:", so.ObligationStatus)
+	}
+
+	// The storage proof realises the pending revenue, without adding to it.
+	totals = ledgerTotals(t, ht.host, so.id())
+	pending := totals[modules.HostLedgerContractFormed].Add(totals[modules.HostLedgerRevisionRevenue])
+	if !totals[modules.HostLedgerStorageProof].Equals(pending) {
+		t.Error("storage proof does not realise the pending revenue:", totals[modules.HostLedgerStorageProof], pending)
+	}
+	if !totals[modules.HostLedgerRevenueLost].IsZero() || !totals[modules.HostLedgerCollateralLost].IsZero() {
+		t.Error("successful obligation recorded losses in the ledger")
+	}
+
+	// A missed storage proof reverses the pending revenue and records the
+	// lost collateral.
+	so, err = ht.newTesterStorageObligation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	so.ContractCost = types.SiacoinPrecision.Mul64(50)
+	so.PotentialStorageRevenue = sectorCost
+	so.RiskedCollateral = types.SiacoinPrecision.Mul64(100)
+	ht.host.managedLockStorageObligation(so.id())
+	err = ht.host.managedAddStorageObligation(so)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.host.mu.Lock()
+	err = ht.host.removeStorageObligation(so, obligationFailed)
+	ht.host.mu.Unlock()
+	ht.host.managedUnlockStorageObligation(so.id())
+	if err != nil {
+		t.Fatal(err)
+	}
+	totals = ledgerTotals(t, ht.host, so.id())
+	pending = totals[modules.HostLedgerContractFormed].Add(totals[modules.HostLedgerRevisionRevenue])
+	if !pending.Equals(so.revenue()) || !totals[modules.HostLedgerRevenueLost].Equals(pending) {
+		t.Error("missed storage proof does not reverse the pending revenue:", totals[modules.HostLedgerRevenueLost], pending)
+	}
+	if !totals[modules.HostLedgerStorageProof].IsZero() {
+		t.Error("failed obligation recorded storage proof revenue")
+	}
+	if !totals[modules.HostLedgerCollateralLost].Equals(so.RiskedCollateral) {
+		t.Error("wrong lost collateral in the ledger:", totals[modules.HostLedgerCollateralLost])
+	}
+
+	if _, err := ht.host.LedgerEntries(1, 0); err != errLedgerRange {
+		t.Error("expected errLedgerRange, got", err)
+	}
+}
@@ -0,0 +1,276 @@
+package host
+
+// maintenance.go implements scheduled maintenance of the host. When a
+// maintenance is scheduled, the host stops accepting contracts and checks
+// every storage obligation whose proof window overlaps the planned downtime.
+// Obligations whose proof window opens before the shutdown get an action item
+// at the start of their window, and the host submits their storage proofs as
+// soon as consensus allows instead of waiting out the resubmission timeout.
+// Other obligations are proven as usual. Obligations that cannot be proven
+// safely are reported as at risk. Once the restart time has passed, the
+// settings of the host are restored, unless the user changed them during the
+// maintenance.
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+var (
+	// errMaintenanceTimes is returned if a maintenance is scheduled with a
+	// restart time that is not after the shutdown time, or that has already
+	// passed.
+	errMaintenanceTimes = errors.New("maintenance restart must be after the shutdown and in the future")
+
+	// errNoMaintenance is returned when stopping a maintenance if no
+	// maintenance is active.
+	errNoMaintenance = errors.New("host has no active maintenance")
+)
+
+// hostMaintenance is the persisted state of a scheduled maintenance.
+// AcceptingContracts is the setting of the host that is restored when the
+// maintenance ends. AcceptingContractsChanged is set if the user changed the
+// setting during the maintenance, in which case it is not restored.
+type hostMaintenance struct {
+	Active                    bool      `json:"active"`
+	Shutdown                  time.Time `json:"shutdown"`
+	Restart                   time.Time `json:"restart"`
+	AcceptingContracts        bool      `json:"acceptingcontracts"`
+	AcceptingContractsChanged bool      `json:"acceptingcontractschanged"`
+}
+
+// estimateHeight estimates the block height at time t, given the current block
+// height.
+func estimateHeight(blockHeight types.BlockHeight, now, t time.Time) types.BlockHeight {
+	if !t.After(now) {
+		return blockHeight
+	}
+	blockTime := time.Duration(types.BlockFrequency) * time.Second
+	return blockHeight + types.BlockHeight(t.Sub(now)/blockTime)
+}
+
+// maintenanceObligation determines how a storage obligation is affected by a
+// downtime between the provided heights. False is returned if the proof window
+// of the obligation does not overlap the downtime, or if the obligation does
+// not need a storage proof anymore.
+func maintenanceObligation(so storageObligation, shutdownHeight, restartHeight types.BlockHeight) (modules.HostMaintenanceObligation, bool) {
+	if so.ObligationStatus != obligationUnresolved || so.ProofConfirmed {
+		return modules.HostMaintenanceObligation{}, false
+	}
+	if so.proofDeadline() < shutdownHeight || so.expiration() > restartHeight {
+		return modules.HostMaintenanceObligation{}, false
+	}
+
+	mo := modules.HostMaintenanceObligation{
+		ObligationID:     so.id(),
+		ProofWindowStart: so.expiration(),
+		ProofDeadline:    so.proofDeadline(),
+		RiskedCollateral: so.RiskedCollateral,
+	}
+	switch {
+	case so.expiration() < shutdownHeight:
+		// The proof can be submitted before the shutdown, as long as it has
+		// time to be resubmitted if it does not confirm.
+		mo.EarlyProof = true
+		if so.expiration()+resubmissionTimeout > shutdownHeight {
+			mo.AtRisk = true
+			mo.Reason = "proof window opens too close to the shutdown to resubmit the proof"
+		} else {
+			mo.Reason = "proof will be submitted before the shutdown"
+		}
+	case so.proofDeadline() <= restartHeight:
+		mo.AtRisk = true
+		mo.Reason = "proof window opens and closes during the downtime"
+	case so.proofDeadline() <= restartHeight+resubmissionTimeout:
+		mo.AtRisk = true
+		mo.Reason = "proof window closes too soon after the restart to resubmit the proof"
+	default:
+		mo.Reason = "proof will be submitted after the restart"
+	}
+	return mo, true
+}
+
+// earlyProof returns whether the storage proof of the obligation should be
+// submitted before the shutdown of the active maintenance. The host's lock must
+// be held by the caller.
+func (h *Host) earlyProof(so storageObligation) bool {
+	if !h.maintenanceState.Active {
+		return false
+	}
+	now := time.Now()
+	shutdownHeight := estimateHeight(h.blockHeight, now, h.maintenanceState.Shutdown)
+	restartHeight := estimateHeight(h.blockHeight, now, h.maintenanceState.Restart)
+	mo, affected := maintenanceObligation(so, shutdownHeight, restartHeight)
+	return affected && mo.EarlyProof
+}
+
+// maintenanceObligations returns the storage obligations that are affected by
+// the active maintenance. The host's lock must be held by the caller.
+func (h *Host) maintenanceObligations(shutdownHeight, restartHeight types.BlockHeight) ([]modules.HostMaintenanceObligation, error) {
+	mos := []modules.HostMaintenanceObligation{}
+	err := h.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucketStorageObligations).ForEach(func(_, soBytes []byte) error {
+			var so storageObligation
+			err := json.Unmarshal(soBytes, &so)
+			if err != nil {
+				return build.ExtendErr("unable to unmarshal storage obligation:", err)
+			}
+			if mo, affected := maintenanceObligation(so, shutdownHeight, restartHeight); affected {
+				mos = append(mos, mo)
+			}
+			return nil
+		})
+	})
+	return mos, err
+}
+
+// maintenance returns the status of the active maintenance. The host's lock
+// must be held by the caller.
+func (h *Host) maintenance() (modules.HostMaintenance, error) {
+	if !h.maintenanceState.Active {
+		return modules.HostMaintenance{}, nil
+	}
+	now := time.Now()
+	hm := modules.HostMaintenance{
+		Active:             true,
+		Shutdown:           h.maintenanceState.Shutdown,
+		Restart:            h.maintenanceState.Restart,
+		ShutdownHeight:     estimateHeight(h.blockHeight, now, h.maintenanceState.Shutdown),
+		RestartHeight:      estimateHeight(h.blockHeight, now, h.maintenanceState.Restart),
+		AcceptingContracts: h.maintenanceState.AcceptingContracts,
+	}
+	if h.maintenanceState.AcceptingContractsChanged {
+		hm.AcceptingContracts = h.settings.AcceptingContracts
+	}
+	var err error
+	hm.Obligations, err = h.maintenanceObligations(hm.ShutdownHeight, hm.RestartHeight)
+	return hm, err
+}
+
+// endMaintenance ends the active maintenance and restores the settings of the
+// host, unless the user changed them during the maintenance. The host's lock
+// must be held by the caller.
+func (h *Host) endMaintenance() error {
+	if !h.maintenanceState.AcceptingContractsChanged {
+		h.settings.AcceptingContracts = h.maintenanceState.AcceptingContracts
+	}
+	h.maintenanceState = hostMaintenance{}
+	h.revisionNumber++
+	h.log.Println("Maintenance ended, accepting contracts:", h.settings.AcceptingContracts)
+	return h.saveSync()
+}
+
+// managedCheckMaintenance ends the active maintenance once its restart time
+// has passed.
+func (h *Host) managedCheckMaintenance() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.maintenanceState.Active || time.Now().Before(h.maintenanceState.Restart) {
+		return
+	}
+	err := h.endMaintenance()
+	if err != nil {
+		h.log.Println("Could not save host after ending maintenance:", err)
+	}
+}
+
+// threadedMaintenance periodically checks whether the scheduled maintenance of
+// the host has ended.
+func (h *Host) threadedMaintenance(closeChan chan struct{}) {
+	defer close(closeChan)
+
+	for {
+		h.managedCheckMaintenance()
+		select {
+		case <-h.tg.StopChan():
+			return
+		case <-time.After(maintenanceCheckFrequency):
+		}
+	}
+}
+
+// Maintenance returns the scheduled maintenance of the host, and the storage
+// obligations that it affects.
+func (h *Host) Maintenance() modules.HostMaintenance {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	hm, err := h.maintenance()
+	if err != nil {
+		h.log.Println("Could not check the storage obligations affected by maintenance:", err)
+	}
+	return hm
+}
+
+// StartMaintenance schedules a maintenance of the host. The host stops
+// accepting contracts until the restart time, and storage proofs that can be
+// submitted before the shutdown are queued for the start of their proof
+// window.
+func (h *Host) StartMaintenance(shutdown, restart time.Time) error {
+	if !restart.After(shutdown) || !restart.After(time.Now()) {
+		return errMaintenanceTimes
+	}
+	err := h.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer h.tg.Done()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Rescheduling an active maintenance keeps the setting that was saved
+	// when it started, unless the user has changed it since.
+	if !h.maintenanceState.Active || h.maintenanceState.AcceptingContractsChanged {
+		h.maintenanceState.AcceptingContracts = h.settings.AcceptingContracts
+		h.maintenanceState.AcceptingContractsChanged = false
+	}
+	h.maintenanceState.Active = true
+	h.maintenanceState.Shutdown = shutdown
+	h.maintenanceState.Restart = restart
+	h.settings.AcceptingContracts = false
+	h.revisionNumber++
+
+	hm, err := h.maintenance()
+	if err != nil {
+		return build.ExtendErr("could not check storage obligations:", err)
+	}
+	h.log.Printf("Maintenance scheduled from %v to %v, estimated heights %v to %v\n", shutdown, restart, hm.ShutdownHeight, hm.RestartHeight)
+	for _, mo := range hm.Obligations {
+		if mo.EarlyProof {
+			height := mo.ProofWindowStart
+			if height <= h.blockHeight {
+				height = h.blockHeight + 1
+			}
+			err = h.queueActionItem(height, mo.ObligationID)
+			if err != nil {
+				return build.ExtendErr("could not queue early storage proof:", err)
+			}
+		}
+		if mo.AtRisk {
+			h.log.Printf("WARN: storage obligation %v is at risk during maintenance, %v collateral: %v\n", mo.ObligationID, mo.RiskedCollateral, mo.Reason)
+		}
+	}
+	return h.saveSync()
+}
+
+// StopMaintenance ends the maintenance of the host and restores its settings.
+func (h *Host) StopMaintenance() error {
+	err := h.tg.Add()
+	if err != nil {
+		return err
+	}
+	defer h.tg.Done()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.maintenanceState.Active {
+		return errNoMaintenance
+	}
+	return h.endMaintenance()
+}
@@ -0,0 +1,290 @@
+package host
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/types"
+
+	"github.com/NebulousLabs/bolt"
+)
+
+// maintenanceTestObligation returns a storage obligation with the provided
+// proof window.
+func maintenanceTestObligation(windowStart, windowEnd types.BlockHeight) storageObligation {
+	return storageObligation{
+		OriginTransactionSet: []types.Transaction{{
+			FileContracts: []types.FileContract{{
+				WindowStart: windowStart,
+				WindowEnd:   windowEnd,
+			}},
+		}},
+	}
+}
+
+// TestMaintenanceObligation checks which storage obligations are affected by a
+// downtime, and which of them are at risk.
+func TestMaintenanceObligation(t *testing.T) {
+	t.Parallel()
+	shutdown := types.BlockHeight(10 * resubmissionTimeout)
+	restart := types.BlockHeight(20 * resubmissionTimeout)
+
+	tests := []struct {
+		name                  string
+		windowStart           types.BlockHeight
+		windowEnd             types.BlockHeight
+		affected, early, risk bool
+	}{
+		{"window before shutdown", 2 * resubmissionTimeout, 5 * resubmissionTimeout, false, false, false},
+		{"window after restart", 21 * resubmissionTimeout, 30 * resubmissionTimeout, false, false, false},
+		{"early proof", 5 * resubmissionTimeout, 15 * resubmissionTimeout, true, true, false},
+		{"early proof too close to shutdown", shutdown - 1, 15 * resubmissionTimeout, true, true, true},
+		{"window during downtime", 12 * resubmissionTimeout, 18 * resubmissionTimeout, true, false, true},
+		{"window closes soon after restart", 12 * resubmissionTimeout, restart + 1, true, false, true},
+		{"proof after restart", 12 * resubmissionTimeout, 30 * resubmissionTimeout, true, false, false},
+	}
+	for _, test := range tests {
+		mo, affected := maintenanceObligation(maintenanceTestObligation(test.windowStart, test.windowEnd), shutdown, restart)
+		if affected != test.affected || mo.EarlyProof != test.early || mo.AtRisk != test.risk {
+			t.Errorf("%v: got affected %v, early proof %v, at risk %v", test.name, affected, mo.EarlyProof, mo.AtRisk)
+		}
+	}
+
+	// Obligations that have a confirmed proof are not affected.
+	so := maintenanceTestObligation(12*resubmissionTimeout, 18*resubmissionTimeout)
+	so.ProofConfirmed = true
+	if _, affected := maintenanceObligation(so, shutdown, restart); affected {
+		t.Error("obligation with a confirmed proof is affected by maintenance")
+	}
+}
+
+// TestEstimateHeight checks the estimated block height of a future time.
+func TestEstimateHeight(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	blockTime := time.Duration(types.BlockFrequency) * time.Second
+	if h := estimateHeight(100, now, now.Add(10*blockTime)); h != 110 {
+		t.Error("wrong estimate for a future time:", h)
+	}
+	if h := estimateHeight(100, now, now.Add(-blockTime)); h != 100 {
+		t.Error("wrong estimate for a past time:", h)
+	}
+}
+
+// TestEarlyProof checks that only the obligations whose proof window opens
+// before the shutdown are proven early during maintenance.
+func TestEarlyProof(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	blockTime := time.Duration(types.BlockFrequency) * time.Second
+	h := &Host{
+		blockHeight: 100,
+		maintenanceState: hostMaintenance{
+			Active:   true,
+			Shutdown: now.Add(50 * blockTime),
+			Restart:  now.Add(100 * blockTime),
+		},
+	}
+	if !h.earlyProof(maintenanceTestObligation(120, 170)) {
+		t.Error("obligation with a window that opens before the shutdown is not proven early")
+	}
+	if h.earlyProof(maintenanceTestObligation(160, 250)) {
+		t.Error("obligation with a window that opens after the shutdown is proven early")
+	}
+	if h.earlyProof(maintenanceTestObligation(200, 250)) {
+		t.Error("obligation that is not affected by the maintenance is proven early")
+	}
+	h.maintenanceState = hostMaintenance{}
+	if h.earlyProof(maintenanceTestObligation(120, 170)) {
+		t.Error("obligation is proven early without a maintenance")
+	}
+}
+
+// TestMaintenanceSettings checks that the host stops accepting contracts
+// during maintenance, and that the setting is restored afterwards only if the
+// user did not change it.
+func TestMaintenanceSettings(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester("TestMaintenanceSettings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	settings := ht.host.InternalSettings()
+	settings.AcceptingContracts = true
+	err = ht.host.SetInternalSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ht.host.StartMaintenance(time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ht.host.InternalSettings().AcceptingContracts {
+		t.Fatal("host accepts contracts during maintenance")
+	}
+
+	// Changing other settings during maintenance does not prevent the
+	// setting from being restored.
+	settings = ht.host.InternalSettings()
+	settings.MaxDuration++
+	err = ht.host.SetInternalSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ht.host.Maintenance().AcceptingContracts {
+		t.Error("maintenance does not report the setting that will be restored")
+	}
+	err = ht.host.StopMaintenance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ht.host.InternalSettings().AcceptingContracts {
+		t.Fatal("setting was not restored after maintenance")
+	}
+
+	// A setting that the user changes during maintenance is kept.
+	err = ht.host.StartMaintenance(time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings = ht.host.InternalSettings()
+	settings.AcceptingContracts = true
+	err = ht.host.SetInternalSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings.AcceptingContracts = false
+	err = ht.host.SetInternalSettings(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ht.host.Maintenance().AcceptingContracts {
+		t.Error("maintenance does not report the setting of the user")
+	}
+	err = ht.host.StopMaintenance()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ht.host.InternalSettings().AcceptingContracts {
+		t.Error("setting changed during maintenance was overwritten")
+	}
+}
+
+// TestMaintenanceProofSubmission checks that the host submits the storage
+// proof of an obligation at the start of its proof window when a maintenance
+// is scheduled during the window, and that the proof confirms before the
+// shutdown.
+func TestMaintenanceProofSubmission(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	ht, err := newHostTester("TestMaintenanceProofSubmission")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ht.Close()
+
+	// Add a storage obligation with one sector, and a proof window that is
+	// wide enough to contain the shutdown. The obligation needs a value that
+	// covers the fee of the storage proof.
+	so, err := ht.newTesterStorageObligation()
+	if err != nil {
+		t.Fatal(err)
+	}
+	so.ContractCost = types.SiacoinPrecision.Mul64(50)
+	ht.host.managedLockStorageObligation(so.id())
+	err = ht.host.managedAddStorageObligation(so)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.host.managedUnlockStorageObligation(so.id())
+	sectorRoot, sectorData := randSector()
+	so.SectorRoots = []crypto.Hash{sectorRoot}
+	validPayouts, missedPayouts := so.payouts()
+	revisionSet := []types.Transaction{{
+		FileContractRevisions: []types.FileContractRevision{{
+			ParentID:          so.id(),
+			UnlockConditions:  types.UnlockConditions{},
+			NewRevisionNumber: 1,
+
+			NewFileSize:           uint64(len(sectorData)),
+			NewFileMerkleRoot:     sectorRoot,
+			NewWindowStart:        so.expiration(),
+			NewWindowEnd:          so.expiration() + 40,
+			NewValidProofOutputs:  validPayouts,
+			NewMissedProofOutputs: missedPayouts,
+			NewUnlockHash:         types.UnlockConditions{}.UnlockHash(),
+		}},
+	}}
+	so.RevisionTransactionSet = revisionSet
+	ht.host.managedLockStorageObligation(so.id())
+	err = ht.host.modifyStorageObligation(so, nil, []crypto.Hash{sectorRoot}, [][]byte{sectorData})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ht.host.managedUnlockStorageObligation(so.id())
+	err = ht.tpool.AcceptTransactionSet(revisionSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Schedule a maintenance that starts halfway through the proof window.
+	blockTime := time.Duration(types.BlockFrequency) * time.Second
+	shutdown := time.Now().Add(time.Duration(so.expiration()-ht.host.blockHeight+20) * blockTime)
+	err = ht.host.StartMaintenance(shutdown, shutdown.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hm := ht.host.Maintenance()
+	if len(hm.Obligations) != 1 || !hm.Obligations[0].EarlyProof {
+		t.Fatal("obligation is not scheduled for an early proof:", hm.Obligations)
+	}
+
+	// Mine to the start of the proof window. The host submits the proof in
+	// the background, so keep mining blocks until the proof is confirmed.
+	for ht.host.blockHeight < so.expiration() {
+		_, err := ht.miner.AddBlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ht.host.tg.Flush()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = build.Retry(50, 250*time.Millisecond, func() error {
+		_, err := ht.miner.AddBlock()
+		if err != nil {
+			return err
+		}
+		err = ht.host.db.View(func(tx *bolt.Tx) error {
+			so, err = getStorageObligation(tx, so.id())
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if !so.ProofConfirmed {
+			return errors.New("storage proof was not confirmed at the start of the proof window")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without the maintenance, the host would not have submitted the proof
+	// before the resubmission timeout.
+	if ht.host.blockHeight >= so.expiration()+resubmissionTimeout || ht.host.blockHeight >= hm.ShutdownHeight {
+		t.Error("storage proof confirmed too late:", ht.host.blockHeight, so.expiration(), hm.ShutdownHeight)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"net"
 	"time"
 
+	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
@@ -19,11 +20,95 @@ var (
 	// errRequestOutOfBounds is returned when a download request is made which
 	// asks for elements of a sector which do not exist.
 	errRequestOutOfBounds = ErrorCommunication("download request has invalid sector bounds")
+
+	// errRequestNotAligned is returned when a range download request asks for
+	// part of a sector that does not start and end on a segment boundary.
+	errRequestNotAligned = ErrorCommunication("range download request is not aligned to segments")
+)
+
+const (
+	// proofBlockSegments is the number of segments in the blocks of a sector
+	// that segmentProofs builds separate trees for. Only the trees of the
+	// blocks that contain the requested range are built.
+	proofBlockSegments = 256
 )
 
+// joinHashes returns the Merkle tree node whose children are left and right.
+func joinHashes(left, right crypto.Hash) crypto.Hash {
+	ct := crypto.NewCachedTree(0)
+	ct.Push(left)
+	ct.Push(right)
+	return ct.Root()
+}
+
+// merkleLevels returns every level of the Merkle tree whose lowest level is
+// hashes, from the lowest level up to the root. The number of hashes must be a
+// power of two.
+func merkleLevels(hashes []crypto.Hash) [][]crypto.Hash {
+	levels := [][]crypto.Hash{hashes}
+	for len(hashes) > 1 {
+		next := make([]crypto.Hash, len(hashes)/2)
+		for i := range next {
+			next[i] = joinHashes(hashes[2*i], hashes[2*i+1])
+		}
+		levels = append(levels, next)
+		hashes = next
+	}
+	return levels
+}
+
+// levelProof returns the hashes that prove the element at index of the lowest
+// level of a Merkle tree, given every level of the tree.
+func levelProof(levels [][]crypto.Hash, index uint64) []crypto.Hash {
+	proof := make([]crypto.Hash, 0, len(levels)-1)
+	for _, level := range levels[:len(levels)-1] {
+		proof = append(proof, level[index^1])
+		index /= 2
+	}
+	return proof
+}
+
+// segmentProofs builds a Merkle proof for every segment in [start, end) of a
+// sector. The sector is split into blocks, and the tree over the roots of the
+// blocks is built once. The trees within the blocks are built once for every
+// block that contains part of the range, and the proof of a segment is its
+// proof within its block followed by the proof of the block.
+func segmentProofs(sector []byte, start, end uint64) [][]crypto.Hash {
+	numSegments := uint64(len(sector)) / crypto.SegmentSize
+	blockSegments := uint64(proofBlockSegments)
+	if blockSegments > numSegments {
+		blockSegments = numSegments
+	}
+	blockSize := blockSegments * crypto.SegmentSize
+
+	blockRoots := make([]crypto.Hash, 0, numSegments/blockSegments)
+	for i := uint64(0); i < uint64(len(sector)); i += blockSize {
+		blockRoots = append(blockRoots, crypto.MerkleRoot(sector[i:i+blockSize]))
+	}
+	rootLevels := merkleLevels(blockRoots)
+
+	proofs := make([][]crypto.Hash, 0, end-start)
+	var blockLevels [][]crypto.Hash
+	for i := start; i < end; i++ {
+		block := i / blockSegments
+		if i == start || i%blockSegments == 0 {
+			leaves := make([]crypto.Hash, blockSegments)
+			for j := range leaves {
+				leaves[j] = crypto.MerkleRoot(sector[block*blockSize+uint64(j)*crypto.SegmentSize:][:crypto.SegmentSize])
+			}
+			blockLevels = merkleLevels(leaves)
+		}
+		proof := levelProof(blockLevels, i%blockSegments)
+		proofs = append(proofs, append(proof, levelProof(rootLevels, block)...))
+	}
+	return proofs
+}
+
 // managedDownloadIteration is responsible for managing a single iteration of
-// the download loop for RPCDownload.
-func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) error {
+// the download loop for RPCDownload and RPCDownloadRange. If withProofs is
+// set, the host sends a Merkle proof for every segment of the requests that
+// do not cover a whole sector.
+func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation, withProofs bool) error {
 	// Exchange settings with the renter.
 	err := h.managedRPCSettings(conn)
 	if err != nil {
@@ -46,6 +131,7 @@ func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) er
 	blockHeight := h.blockHeight
 	secretKey := h.secretKey
 	settings := h.settings
+	prices := h.prices()
 	h.mu.RUnlock()
 
 	// Read the download requests, followed by the file contract revision that
@@ -65,6 +151,7 @@ func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) er
 	// for the renter.
 	existingRevision := so.RevisionTransactionSet[len(so.RevisionTransactionSet)-1].FileContractRevisions[0]
 	var payload [][]byte
+	var proofs [][][]crypto.Hash
 	err = func() error {
 		// Check that the length of each file is in-bounds, and that the total
 		// size being requested is acceptable.
@@ -73,7 +160,14 @@ func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) er
 			if request.Length > modules.SectorSize || request.Offset+request.Length > modules.SectorSize {
 				return extendErr("download iteration request failed: ", errRequestOutOfBounds)
 			}
-			totalSize += request.Length
+			if !withProofs {
+				totalSize += request.Length
+				continue
+			}
+			if request.Length == 0 || request.Offset%crypto.SegmentSize != 0 || request.Length%crypto.SegmentSize != 0 {
+				return extendErr("download iteration request failed: ", errRequestNotAligned)
+			}
+			totalSize += modules.RangeDownloadBandwidth(request.Length)
 		}
 		if totalSize > settings.MaxDownloadBatchSize {
 			return extendErr("download iteration batch failed: ", errLargeDownloadBatch)
@@ -81,7 +175,7 @@ func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) er
 
 		// Verify that the correct amount of money has been moved from the
 		// renter's contract funds to the host's contract funds.
-		expectedTransfer := settings.MinDownloadBandwidthPrice.Mul64(totalSize)
+		expectedTransfer := prices.DownloadBandwidth.Mul64(totalSize)
 		err = verifyPaymentRevision(existingRevision, paymentRevision, blockHeight, expectedTransfer)
 		if err != nil {
 			return extendErr("payment verification failed: ", err)
@@ -94,6 +188,13 @@ func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) er
 				return extendErr("failed to load sector: ", ErrorInternal(err.Error()))
 			}
 			payload = append(payload, sectorData[request.Offset:request.Offset+request.Length])
+			if withProofs && request.Length != modules.SectorSize {
+				start := request.Offset / crypto.SegmentSize
+				end := (request.Offset + request.Length) / crypto.SegmentSize
+				proofs = append(proofs, segmentProofs(sectorData, start, end))
+			} else if withProofs {
+				proofs = append(proofs, nil)
+			}
 		}
 		return nil
 	}()
@@ -144,6 +245,12 @@ func (h *Host) managedDownloadIteration(conn net.Conn, so *storageObligation) er
 	if err != nil {
 		return extendErr("failed to write payload: ", ErrorConnection(err.Error()))
 	}
+	if withProofs {
+		err = encoding.WriteObject(conn, proofs)
+		if err != nil {
+			return extendErr("failed to write proofs: ", ErrorConnection(err.Error()))
+		}
+	}
 	return nil
 }
 
@@ -232,8 +339,8 @@ func verifyPaymentRevision(existingRevision, paymentRevision types.FileContractR
 }
 
 // managedRPCDownload is responsible for handling an RPC request from the
-// renter to download data.
-func (h *Host) managedRPCDownload(conn net.Conn) error {
+// renter to download data. withProofs is set for RPCDownloadRange.
+func (h *Host) managedRPCDownload(conn net.Conn, withProofs bool) error {
 	// Get the start time to limit the length of the whole connection.
 	startTime := time.Now()
 	// Perform the file contract revision exchange, giving the renter the most
@@ -248,11 +355,13 @@ func (h *Host) managedRPCDownload(conn net.Conn) error {
 	defer func() {
 		h.managedUnlockStorageObligation(so.id())
 	}()
+	// The downloads are throttled by the bandwidth limit of the renter.
+	h.managedMeterContract(conn, so)
 
 	// Perform a loop that will allow downloads to happen until the maximum
 	// time for a single connection has been reached.
 	for time.Now().Before(startTime.Add(iteratedConnectionTime)) {
-		err := h.managedDownloadIteration(conn, &so)
+		err := h.managedDownloadIteration(conn, &so, withProofs)
 		if err == modules.ErrStopResponse {
 			// The renter has indicated that it has finished downloading the
 			// data, therefore there is no error. Return nil.
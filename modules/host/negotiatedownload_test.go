@@ -0,0 +1,36 @@
+package host
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestSegmentProofs checks that the proofs built by segmentProofs verify
+// against the Merkle root of the sector, both for sectors that fit in a single
+// proof block and for sectors that span several blocks.
+func TestSegmentProofs(t *testing.T) {
+	t.Parallel()
+	for _, sectorSize := range []uint64{64 * crypto.SegmentSize, 4 * proofBlockSegments * crypto.SegmentSize} {
+		sector := fastrand.Bytes(int(sectorSize))
+		root := crypto.MerkleRoot(sector)
+		numSegments := sectorSize / crypto.SegmentSize
+
+		start, end := numSegments/2-3, numSegments/2+3
+		proofs := segmentProofs(sector, start, end)
+		if uint64(len(proofs)) != end-start {
+			t.Fatalf("expected %v proofs, got %v", end-start, len(proofs))
+		}
+		for i, proof := range proofs {
+			index := start + uint64(i)
+			segment := sector[index*crypto.SegmentSize:][:crypto.SegmentSize]
+			if !crypto.VerifySegment(segment, proof, numSegments, index, root) {
+				t.Fatalf("proof for segment %v of a %v byte sector is invalid", index, sectorSize)
+			}
+			if crypto.VerifySegment(segment, proof, numSegments, index+1, root) {
+				t.Fatal("proof verified for the wrong segment")
+			}
+		}
+	}
+}
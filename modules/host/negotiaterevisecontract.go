@@ -39,6 +39,7 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 	// Read some variables from the host for use later in the function.
 	h.mu.RLock()
 	settings := h.settings
+	prices := h.prices()
 	secretKey := h.secretKey
 	blockHeight := h.blockHeight
 	h.mu.RUnlock()
@@ -96,8 +97,8 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 				// Update finances.
 				blocksRemaining := so.proofDeadline() - blockHeight
 				blockBytesCurrency := types.NewCurrency64(uint64(blocksRemaining)).Mul64(modules.SectorSize)
-				bandwidthRevenue = bandwidthRevenue.Add(settings.MinUploadBandwidthPrice.Mul64(modules.SectorSize))
-				storageRevenue = storageRevenue.Add(settings.MinStoragePrice.Mul(blockBytesCurrency))
+				bandwidthRevenue = bandwidthRevenue.Add(prices.UploadBandwidth.Mul64(modules.SectorSize))
+				storageRevenue = storageRevenue.Add(prices.Storage.Mul(blockBytesCurrency))
 				newCollateral = newCollateral.Add(settings.Collateral.Mul(blockBytesCurrency))
 
 				// Insert the sector into the root list.
@@ -122,7 +123,7 @@ func (h *Host) managedRevisionIteration(conn net.Conn, so *storageObligation, fi
 				copy(sector[modification.Offset:], modification.Data)
 
 				// Update finances.
-				bandwidthRevenue = bandwidthRevenue.Add(settings.MinUploadBandwidthPrice.Mul64(uint64(len(modification.Data))))
+				bandwidthRevenue = bandwidthRevenue.Add(prices.UploadBandwidth.Mul64(uint64(len(modification.Data))))
 
 				// Update the sectors removed and gained to indicate that the old
 				// sector has been replaced with a new sector.
@@ -209,6 +210,8 @@ func (h *Host) managedRPCReviseContract(conn net.Conn) error {
 	defer func() {
 		h.managedUnlockStorageObligation(so.id())
 	}()
+	// The revisions are throttled by the bandwidth limit of the renter.
+	h.managedMeterContract(conn, so)
 
 	// Begin the revision loop. The host will process revisions until a
 	// timeout is reached, or until the renter sends a StopResponse.
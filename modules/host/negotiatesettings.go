@@ -26,6 +26,7 @@ func (h *Host) capacity() (total, remaining uint64) {
 // externalSettings compiles and returns the external settings for the host.
 func (h *Host) externalSettings() modules.HostExternalSettings {
 	totalStorage, remainingStorage := h.capacity()
+	prices := h.prices()
 	var netAddr modules.NetAddress
 	if h.settings.NetAddress != "" {
 		netAddr = h.settings.NetAddress
@@ -48,12 +49,14 @@ func (h *Host) externalSettings() modules.HostExternalSettings {
 		MaxCollateral: h.settings.MaxCollateral,
 
 		ContractPrice:          h.settings.MinContractPrice,
-		DownloadBandwidthPrice: h.settings.MinDownloadBandwidthPrice,
-		StoragePrice:           h.settings.MinStoragePrice,
-		UploadBandwidthPrice:   h.settings.MinUploadBandwidthPrice,
+		DownloadBandwidthPrice: prices.DownloadBandwidth,
+		StoragePrice:           prices.Storage,
+		UploadBandwidthPrice:   prices.UploadBandwidth,
 
 		RevisionNumber: h.revisionNumber,
 		Version:        build.Version,
+
+		RangeDownloads: true,
 	}
 }
 
@@ -249,6 +249,11 @@ func (h *Host) threadedHandleConn(conn net.Conn) {
 	}
 	defer h.tg.Done()
 
+	// Meter the traffic of the connection and apply the bandwidth limits of
+	// the host.
+	mc := h.newMeteredConn(conn)
+	conn = mc
+
 	// Close the conn on host.Close or when the method terminates, whichever comes
 	// first.
 	connCloseChan := make(chan struct{})
@@ -271,6 +276,9 @@ func (h *Host) threadedHandleConn(conn net.Conn) {
 
 	// Read a specifier indicating which action is being called.
 	var id types.Specifier
+	defer func() {
+		h.managedRecordBandwidth(id, mc)
+	}()
 	if err := encoding.ReadObject(conn, &id, 16); err != nil {
 		atomic.AddUint64(&h.atomicUnrecognizedCalls, 1)
 		h.log.Debugf("WARN: incoming conn %v was malformed: %v", conn.RemoteAddr(), err)
@@ -280,7 +288,10 @@ func (h *Host) threadedHandleConn(conn net.Conn) {
 	switch id {
 	case modules.RPCDownload:
 		atomic.AddUint64(&h.atomicDownloadCalls, 1)
-		err = extendErr("incoming RPCDownload failed: ", h.managedRPCDownload(conn))
+		err = extendErr("incoming RPCDownload failed: ", h.managedRPCDownload(conn, false))
+	case modules.RPCDownloadRange:
+		atomic.AddUint64(&h.atomicDownloadCalls, 1)
+		err = extendErr("incoming RPCDownloadRange failed: ", h.managedRPCDownload(conn, true))
 	case modules.RPCRenewContract:
 		atomic.AddUint64(&h.atomicRenewCalls, 1)
 		err = extendErr("incoming RPCRenewContract failed: ", h.managedRPCRenewContract(conn))
@@ -353,5 +364,8 @@ func (h *Host) NetworkMetrics() modules.HostNetworkMetrics {
 		ReviseCalls:       atomic.LoadUint64(&h.atomicReviseCalls),
 		SettingsCalls:     atomic.LoadUint64(&h.atomicSettingsCalls),
 		UnrecognizedCalls: atomic.LoadUint64(&h.atomicUnrecognizedCalls),
+
+		DownloadBytes: atomic.LoadUint64(&h.atomicDownloadBytes),
+		UploadBytes:   atomic.LoadUint64(&h.atomicUploadBytes),
 	}
 }
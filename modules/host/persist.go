@@ -23,7 +23,10 @@ type persistence struct {
 	// Host Identity.
 	Announced        bool                         `json:"announced"`
 	AutoAddress      modules.NetAddress           `json:"autoaddress"`
+	AutoPrices       hostPrices                   `json:"autoprices"`
+	Bandwidth        modules.HostBandwidth        `json:"bandwidth"`
 	FinancialMetrics modules.HostFinancialMetrics `json:"financialmetrics"`
+	Maintenance      hostMaintenance              `json:"maintenance"`
 	PublicKey        types.SiaPublicKey           `json:"publickey"`
 	RevisionNumber   uint64                       `json:"revisionnumber"`
 	SecretKey        crypto.SecretKey             `json:"secretkey"`
@@ -41,7 +44,10 @@ func (h *Host) persistData() persistence {
 		// Host Identity.
 		Announced:        h.announced,
 		AutoAddress:      h.autoAddress,
+		AutoPrices:       h.autoPrices,
+		Bandwidth:        h.persistBandwidth(),
 		FinancialMetrics: h.financialMetrics,
+		Maintenance:      h.maintenanceState,
 		PublicKey:        h.publicKey,
 		RevisionNumber:   h.revisionNumber,
 		SecretKey:        h.secretKey,
@@ -69,6 +75,7 @@ func (h *Host) establishDefaults() error {
 		MinDownloadBandwidthPrice: defaultDownloadBandwidthPrice,
 		MinUploadBandwidthPrice:   defaultUploadBandwidthPrice,
 	}
+	setAutoPricingDefaults(&h.settings)
 
 	// Generate signing key, for revising contracts.
 	sk, pk := crypto.GenerateKeyPair()
@@ -83,6 +90,18 @@ func (h *Host) establishDefaults() error {
 	return nil
 }
 
+// setAutoPricingDefaults configures the default auto pricing settings. Auto
+// pricing is disabled by default, and the maximum prices are twice the minimum
+// prices.
+func setAutoPricingDefaults(settings *modules.HostInternalSettings) {
+	settings.AutoPricingCurve = defaultAutoPricingCurve
+	settings.AutoPricingDemandTarget = defaultAutoPricingDemandTarget
+	settings.AutoPricingDemandWeight = defaultAutoPricingDemandWeight
+	settings.MaxDownloadBandwidthPrice = settings.MinDownloadBandwidthPrice.Mul64(2)
+	settings.MaxStoragePrice = settings.MinStoragePrice.Mul64(2)
+	settings.MaxUploadBandwidthPrice = settings.MinUploadBandwidthPrice.Mul64(2)
+}
+
 // loadPersistObject will take a persist object and copy the data into the
 // host.
 func (h *Host) loadPersistObject(p *persistence) {
@@ -97,7 +116,10 @@ func (h *Host) loadPersistObject(p *persistence) {
 		h.log.Printf("WARN: AutoAddress '%v' loaded from persist is invalid: %v", p.AutoAddress, err)
 		h.autoAddress = ""
 	}
+	h.autoPrices = p.AutoPrices
+	h.loadBandwidth(p.Bandwidth)
 	h.financialMetrics = p.FinancialMetrics
+	h.maintenanceState = p.Maintenance
 	h.publicKey = p.PublicKey
 	h.revisionNumber = p.RevisionNumber
 	h.secretKey = p.SecretKey
@@ -107,6 +129,13 @@ func (h *Host) loadPersistObject(p *persistence) {
 		h.settings.NetAddress = ""
 	}
 	h.unlockHash = p.UnlockHash
+
+	// Hosts that were created before auto pricing was added have no auto
+	// pricing settings.
+	if h.settings.AutoPricingCurve == 0 {
+		setAutoPricingDefaults(&h.settings)
+	}
+	h.setBandwidthLimits()
 }
 
 // initDB will check that the database has been initialized and if not, will
@@ -129,6 +158,7 @@ func (h *Host) initDB() (err error) {
 		// database needs to be initialized. Create the database buckets.
 		buckets := [][]byte{
 			bucketActionItems,
+			bucketLedger,
 			bucketStorageObligations,
 		}
 		for _, bucket := range buckets {
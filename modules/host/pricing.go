@@ -0,0 +1,175 @@
+package host
+
+// pricing.go adjusts the storage and bandwidth prices of the host when auto
+// pricing is enabled. Prices follow a curve over the utilization of the host's
+// storage and the recent demand for contracts, bounded by the minimum and
+// maximum prices in the host's internal settings. Every adjustment moves a
+// price by at most autoPricingMaxStep of its range, so that renters see prices
+// change gradually.
+
+import (
+	"errors"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	// errAutoPricingBounds is returned if auto pricing is enabled with a
+	// maximum price below the corresponding minimum price.
+	errAutoPricingBounds = errors.New("maximum prices must not be lower than minimum prices")
+
+	// errAutoPricingCurve is returned if auto pricing is enabled with a curve
+	// exponent that is not positive.
+	errAutoPricingCurve = errors.New("auto pricing curve must be positive")
+
+	// errAutoPricingDemandWeight is returned if auto pricing is enabled with
+	// a demand weight outside of [0, 1].
+	errAutoPricingDemandWeight = errors.New("auto pricing demand weight must be between 0 and 1")
+)
+
+// hostPrices are the storage and bandwidth prices that the host charges.
+type hostPrices struct {
+	DownloadBandwidth types.Currency `json:"downloadbandwidth"`
+	Storage           types.Currency `json:"storage"`
+	UploadBandwidth   types.Currency `json:"uploadbandwidth"`
+}
+
+// equals returns true if all prices of p and q are equal.
+func (p hostPrices) equals(q hostPrices) bool {
+	return p.DownloadBandwidth.Equals(q.DownloadBandwidth) && p.Storage.Equals(q.Storage) && p.UploadBandwidth.Equals(q.UploadBandwidth)
+}
+
+// checkAutoPricing checks that the auto pricing settings are sane.
+func checkAutoPricing(settings modules.HostInternalSettings) error {
+	if !settings.AutoPricing {
+		return nil
+	}
+	if settings.MaxDownloadBandwidthPrice.Cmp(settings.MinDownloadBandwidthPrice) < 0 ||
+		settings.MaxStoragePrice.Cmp(settings.MinStoragePrice) < 0 ||
+		settings.MaxUploadBandwidthPrice.Cmp(settings.MinUploadBandwidthPrice) < 0 {
+		return errAutoPricingBounds
+	}
+	if settings.AutoPricingCurve <= 0 {
+		return errAutoPricingCurve
+	}
+	if settings.AutoPricingDemandWeight < 0 || settings.AutoPricingDemandWeight > 1 {
+		return errAutoPricingDemandWeight
+	}
+	return nil
+}
+
+// pricePosition returns where the prices should be between their minimum
+// (0) and their maximum (1), given the fraction of storage that is used and
+// the number of contracts formed per hour.
+func pricePosition(settings modules.HostInternalSettings, utilization, contractsPerHour float64) float64 {
+	demand := 0.0
+	if settings.AutoPricingDemandTarget > 0 {
+		demand = math.Min(1, contractsPerHour/float64(settings.AutoPricingDemandTarget))
+	}
+	utilization = math.Max(0, math.Min(1, utilization))
+	w := settings.AutoPricingDemandWeight
+	return (1-w)*math.Pow(utilization, settings.AutoPricingCurve) + w*demand
+}
+
+// clampPrice returns price, restricted to [min, max].
+func clampPrice(price, min, max types.Currency) types.Currency {
+	if price.Cmp(min) < 0 {
+		return min
+	} else if price.Cmp(max) > 0 {
+		return max
+	}
+	return price
+}
+
+// stepPrice moves the current price towards the price at the provided
+// position between min and max, by at most autoPricingMaxStep of the range.
+func stepPrice(current, min, max types.Currency, position float64) types.Currency {
+	current = clampPrice(current, min, max)
+	target := min.Add(max.Sub(min).MulFloat(position))
+	step := max.Sub(min).MulFloat(autoPricingMaxStep)
+	if target.Cmp(current) > 0 && target.Sub(current).Cmp(step) > 0 {
+		target = current.Add(step)
+	} else if target.Cmp(current) < 0 && current.Sub(target).Cmp(step) > 0 {
+		target = current.Sub(step)
+	}
+	return clampPrice(target, min, max)
+}
+
+// prices returns the storage and bandwidth prices that the host currently
+// charges. The host's lock must be held by the caller.
+func (h *Host) prices() hostPrices {
+	if !h.settings.AutoPricing {
+		return hostPrices{
+			DownloadBandwidth: h.settings.MinDownloadBandwidthPrice,
+			Storage:           h.settings.MinStoragePrice,
+			UploadBandwidth:   h.settings.MinUploadBandwidthPrice,
+		}
+	}
+	return hostPrices{
+		DownloadBandwidth: clampPrice(h.autoPrices.DownloadBandwidth, h.settings.MinDownloadBandwidthPrice, h.settings.MaxDownloadBandwidthPrice),
+		Storage:           clampPrice(h.autoPrices.Storage, h.settings.MinStoragePrice, h.settings.MaxStoragePrice),
+		UploadBandwidth:   clampPrice(h.autoPrices.UploadBandwidth, h.settings.MinUploadBandwidthPrice, h.settings.MaxUploadBandwidthPrice),
+	}
+}
+
+// managedAdjustPrices moves the auto prices of the host one step along the
+// pricing curve, given the number of contracts formed since the previous
+// adjustment.
+func (h *Host) managedAdjustPrices(contracts uint64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.settings.AutoPricing {
+		return
+	}
+
+	totalStorage, remainingStorage := h.capacity()
+	var utilization float64
+	if totalStorage > 0 {
+		utilization = 1 - float64(remainingStorage)/float64(totalStorage)
+	}
+	contractsPerHour := float64(contracts) * float64(time.Hour) / float64(autoPricingFrequency)
+	position := pricePosition(h.settings, utilization, contractsPerHour)
+
+	old := h.prices()
+	h.autoPrices = hostPrices{
+		DownloadBandwidth: stepPrice(old.DownloadBandwidth, h.settings.MinDownloadBandwidthPrice, h.settings.MaxDownloadBandwidthPrice, position),
+		Storage:           stepPrice(old.Storage, h.settings.MinStoragePrice, h.settings.MaxStoragePrice, position),
+		UploadBandwidth:   stepPrice(old.UploadBandwidth, h.settings.MinUploadBandwidthPrice, h.settings.MaxUploadBandwidthPrice, position),
+	}
+	if h.autoPrices.equals(old) {
+		return
+	}
+	h.log.Printf("Auto pricing at %.2f utilization and %.2f contracts per hour: storage price %v -> %v, upload bandwidth price %v -> %v, download bandwidth price %v -> %v",
+		utilization, contractsPerHour,
+		old.Storage, h.autoPrices.Storage,
+		old.UploadBandwidth, h.autoPrices.UploadBandwidth,
+		old.DownloadBandwidth, h.autoPrices.DownloadBandwidth)
+
+	// The external settings have changed.
+	h.revisionNumber++
+	err := h.saveSync()
+	if err != nil {
+		h.log.Println("Could not save host after adjusting prices:", err)
+	}
+}
+
+// threadedAutoPricing periodically adjusts the prices of the host when auto
+// pricing is enabled.
+func (h *Host) threadedAutoPricing(closeChan chan struct{}) {
+	defer close(closeChan)
+
+	for {
+		prevContracts := atomic.LoadUint64(&h.atomicFormContractCalls)
+		select {
+		case <-h.tg.StopChan():
+			return
+		case <-time.After(autoPricingFrequency):
+		}
+		contracts := atomic.LoadUint64(&h.atomicFormContractCalls)
+		h.managedAdjustPrices(contracts - prevContracts)
+	}
+}
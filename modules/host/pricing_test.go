@@ -0,0 +1,106 @@
+package host
+
+import (
+	"math"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestPricePosition checks the auto pricing curve over utilization and
+// demand.
+func TestPricePosition(t *testing.T) {
+	t.Parallel()
+	settings := modules.HostInternalSettings{
+		AutoPricingCurve:        2,
+		AutoPricingDemandTarget: 10,
+		AutoPricingDemandWeight: 0.25,
+	}
+	tests := []struct {
+		utilization, contractsPerHour, position float64
+	}{
+		{0, 0, 0},
+		{1, 0, 0.75},
+		{0.5, 0, 0.1875},
+		{0, 5, 0.125},
+		{1, 10, 1},
+		{1, 100, 1}, // demand is capped at the target
+	}
+	for _, test := range tests {
+		position := pricePosition(settings, test.utilization, test.contractsPerHour)
+		if math.Abs(position-test.position) > 1e-9 {
+			t.Errorf("pricePosition(%v, %v): expected %v, got %v", test.utilization, test.contractsPerHour, test.position, position)
+		}
+	}
+
+	// Without a demand target, only utilization is considered.
+	settings.AutoPricingDemandTarget = 0
+	if position := pricePosition(settings, 0, 100); position != 0 {
+		t.Error("demand should be ignored without a target, got", position)
+	}
+}
+
+// TestStepPrice checks that prices move towards their target by at most
+// autoPricingMaxStep of their range, and stay within their bounds.
+func TestStepPrice(t *testing.T) {
+	t.Parallel()
+	min, max := types.NewCurrency64(100), types.NewCurrency64(200)
+
+	// A large move is limited to a step of 10.
+	if price := stepPrice(min, min, max, 1); price.Cmp(types.NewCurrency64(110)) != 0 {
+		t.Error("expected price to rise by one step, got", price)
+	}
+	if price := stepPrice(max, min, max, 0); price.Cmp(types.NewCurrency64(190)) != 0 {
+		t.Error("expected price to fall by one step, got", price)
+	}
+	// A small move reaches the target.
+	if price := stepPrice(types.NewCurrency64(145), min, max, 0.5); price.Cmp(types.NewCurrency64(150)) != 0 {
+		t.Error("expected price to reach the target, got", price)
+	}
+	// Prices outside of the bounds are clamped before stepping.
+	if price := stepPrice(types.NewCurrency64(500), min, max, 1); price.Cmp(max) != 0 {
+		t.Error("expected price to be clamped to the maximum, got", price)
+	}
+	if price := stepPrice(types.ZeroCurrency, min, max, 0); price.Cmp(min) != 0 {
+		t.Error("expected price to be clamped to the minimum, got", price)
+	}
+}
+
+// TestCheckAutoPricing checks that invalid auto pricing settings are
+// rejected.
+func TestCheckAutoPricing(t *testing.T) {
+	t.Parallel()
+	settings := modules.HostInternalSettings{
+		AutoPricing:             true,
+		AutoPricingCurve:        1,
+		AutoPricingDemandWeight: 0.5,
+		MinStoragePrice:         types.NewCurrency64(10),
+		MaxStoragePrice:         types.NewCurrency64(20),
+	}
+	if err := checkAutoPricing(settings); err != nil {
+		t.Fatal(err)
+	}
+
+	bad := settings
+	bad.MaxStoragePrice = types.NewCurrency64(5)
+	if err := checkAutoPricing(bad); err != errAutoPricingBounds {
+		t.Error("expected errAutoPricingBounds, got", err)
+	}
+	bad = settings
+	bad.AutoPricingCurve = 0
+	if err := checkAutoPricing(bad); err != errAutoPricingCurve {
+		t.Error("expected errAutoPricingCurve, got", err)
+	}
+	bad = settings
+	bad.AutoPricingDemandWeight = 1.5
+	if err := checkAutoPricing(bad); err != errAutoPricingDemandWeight {
+		t.Error("expected errAutoPricingDemandWeight, got", err)
+	}
+
+	// Settings are not checked while auto pricing is disabled.
+	bad.AutoPricing = false
+	if err := checkAutoPricing(bad); err != nil {
+		t.Error("disabled auto pricing should not be checked:", err)
+	}
+}
@@ -42,10 +42,10 @@ import (
 )
 
 const (
-	obligationUnresolved storageObligationStatus = iota // Indicatees that an unitialized value was used.
-	obligationRejected                                  // Indicates that the obligation never got started, no revenue gained or lost.
-	obligationSucceeded                                 // Indicates that the obligation was completed, revenues were gained.
-	obligationFailed                                    // Indicates that the obligation failed, revenues and collateral were lost.
+	obligationUnresolved = storageObligationStatus(modules.ObligationStatusUnresolved) // Indicatees that an unitialized value was used.
+	obligationRejected   = storageObligationStatus(modules.ObligationStatusRejected)   // Indicates that the obligation never got started, no revenue gained or lost.
+	obligationSucceeded  = storageObligationStatus(modules.ObligationStatusSucceeded)  // Indicates that the obligation was completed, revenues were gained.
+	obligationFailed     = storageObligationStatus(modules.ObligationStatusFailed)     // Indicates that the obligation failed, revenues and collateral were lost.
 )
 
 var (
@@ -94,10 +94,6 @@ var (
 	// revisionSubmissionBuffer blocks.
 	errNoBuffer = errors.New("file contract rejected because storage proof window is too close")
 
-	// errNoStorageObligation is returned if the requested storage obligation
-	// is not found in the database.
-	errNoStorageObligation = errors.New("storage obligation not found in database")
-
 	// errObligationUnlocked is returned when a storage obligation is being
 	// removed from lock, but is already unlocked.
 	errObligationUnlocked = errors.New("storage obligation is unlocked, and should not be getting unlocked")
@@ -151,7 +147,7 @@ type storageObligation struct {
 func getStorageObligation(tx *bolt.Tx, soid types.FileContractID) (so storageObligation, err error) {
 	soBytes := tx.Bucket(bucketStorageObligations).Get(soid[:])
 	if soBytes == nil {
-		return storageObligation{}, errNoStorageObligation
+		return storageObligation{}, modules.ErrNoStorageObligation
 	}
 	err = json.Unmarshal(soBytes, &so)
 	if err != nil {
@@ -275,6 +271,77 @@ func (so storageObligation) proofDeadline() types.BlockHeight {
 	return so.OriginTransactionSet[len(so.OriginTransactionSet)-1].FileContracts[0].WindowEnd
 }
 
+// revisionNumber returns the revision number of the latest revision of the
+// storage obligation.
+func (so storageObligation) revisionNumber() uint64 {
+	if len(so.RevisionTransactionSet) > 0 {
+		return so.RevisionTransactionSet[len(so.RevisionTransactionSet)-1].FileContractRevisions[0].NewRevisionNumber
+	}
+	return so.OriginTransactionSet[len(so.OriginTransactionSet)-1].FileContracts[0].RevisionNumber
+}
+
+// risk returns the reason why an unresolved storage obligation is in danger
+// of missing its storage proof window at the provided height, or an empty
+// string if the obligation is on track. The checks follow the action items in
+// threadedHandleActionItem.
+func (so storageObligation) risk(blockHeight types.BlockHeight) string {
+	if so.ObligationStatus != obligationUnresolved {
+		return ""
+	}
+	switch {
+	case !so.OriginConfirmed && blockHeight >= so.NegotiationHeight+resubmissionTimeout:
+		return "file contract has not been confirmed"
+	case len(so.RevisionTransactionSet) > 0 && !so.RevisionConfirmed && blockHeight+revisionSubmissionBuffer >= so.expiration():
+		return "latest revision has not been confirmed"
+	case !so.ProofConfirmed && blockHeight >= so.expiration()+resubmissionTimeout:
+		return "storage proof has not been confirmed"
+	}
+	return ""
+}
+
+// obligation returns the modules.StorageObligation that describes the storage
+// obligation at the provided height.
+func (so storageObligation) obligation(blockHeight types.BlockHeight) modules.StorageObligation {
+	valid, missed := so.payouts()
+	risk := so.risk(blockHeight)
+	return modules.StorageObligation{
+		ObligationID:      so.id(),
+		NegotiationHeight: so.NegotiationHeight,
+		ExpirationHeight:  so.expiration(),
+		ProofDeadline:     so.proofDeadline(),
+		RevisionNumber:    so.revisionNumber(),
+
+		DataSize:         so.fileSize(),
+		SectorRootsCount: uint64(len(so.SectorRoots)),
+
+		ContractCost:             so.ContractCost,
+		LockedCollateral:         so.LockedCollateral,
+		PotentialDownloadRevenue: so.PotentialDownloadRevenue,
+		PotentialStorageRevenue:  so.PotentialStorageRevenue,
+		PotentialUploadRevenue:   so.PotentialUploadRevenue,
+		RiskedCollateral:         so.RiskedCollateral,
+		TransactionFeesAdded:     so.TransactionFeesAdded,
+		ValidProofOutputs:        valid,
+		MissedProofOutputs:       missed,
+
+		OriginConfirmed:     so.OriginConfirmed,
+		RevisionConstructed: so.RevisionConstructed,
+		RevisionConfirmed:   so.RevisionConfirmed,
+		ProofConstructed:    so.ProofConstructed,
+		ProofConfirmed:      so.ProofConfirmed,
+		ObligationStatus:    uint64(so.ObligationStatus),
+
+		AtRisk:     risk != "",
+		RiskReason: risk,
+	}
+}
+
+// revenue returns the payments of the renter that the host earns once the
+// storage proof of the obligation is accepted.
+func (so storageObligation) revenue() types.Currency {
+	return so.ContractCost.Add(so.PotentialStorageRevenue).Add(so.PotentialDownloadRevenue).Add(so.PotentialUploadRevenue)
+}
+
 // value returns the value of fulfilling the storage obligation to the host.
 func (so storageObligation) value() types.Currency {
 	return so.ContractCost.Add(so.PotentialDownloadRevenue).Add(so.PotentialStorageRevenue).Add(so.PotentialUploadRevenue).Add(so.RiskedCollateral)
@@ -358,6 +425,17 @@ func (h *Host) managedAddStorageObligation(so storageObligation) error {
 				}
 			}
 
+			// Record the payment of the renter, which is pending until the
+			// storage proof, and the fees of the host in the ledger.
+			err := appendLedgerEntry(tx, modules.HostLedgerContractFormed, h.blockHeight, soid, so.revenue())
+			if err != nil {
+				return err
+			}
+			err = appendLedgerEntry(tx, modules.HostLedgerTransactionFee, h.blockHeight, soid, so.TransactionFeesAdded)
+			if err != nil {
+				return err
+			}
+
 			// Add the storage obligation to the database.
 			soBytes, err := json.Marshal(so)
 			if err != nil {
@@ -485,6 +563,14 @@ func (h *Host) modifyStorageObligation(so storageObligation, sectorsRemoved []cr
 			return err
 		}
 
+		// Record the pending revenue of the revision in the ledger.
+		if so.revenue().Cmp(oldSO.revenue()) > 0 {
+			err = appendLedgerEntry(tx, modules.HostLedgerRevisionRevenue, h.blockHeight, soid, so.revenue().Sub(oldSO.revenue()))
+			if err != nil {
+				return err
+			}
+		}
+
 		// Store the new storage obligation to replace the old one.
 		return putStorageObligation(tx, so)
 	})
@@ -588,10 +674,26 @@ func (h *Host) removeStorageObligation(so storageObligation, sos storageObligati
 	// obligation status is updated so that the user can see how the obligation
 	// ended up, and the sector roots are removed because they are large
 	// objects with little purpose once storage proofs are no longer needed.
+	h.forgetBandwidth(so)
 	h.financialMetrics.ContractCount--
 	so.ObligationStatus = sos
 	so.SectorRoots = nil
 	return h.db.Update(func(tx *bolt.Tx) error {
+		// Record the outcome of the obligation in the ledger. The pending
+		// revenue of the contract is either realised by the storage proof or
+		// lost.
+		var err error
+		if sos == obligationSucceeded {
+			err = appendLedgerEntry(tx, modules.HostLedgerStorageProof, h.blockHeight, so.id(), so.revenue())
+		} else {
+			err = appendLedgerEntry(tx, modules.HostLedgerRevenueLost, h.blockHeight, so.id(), so.revenue())
+		}
+		if err == nil && sos == obligationFailed {
+			err = appendLedgerEntry(tx, modules.HostLedgerCollateralLost, h.blockHeight, so.id(), so.RiskedCollateral)
+		}
+		if err != nil {
+			return err
+		}
 		return putStorageObligation(tx, so)
 	})
 }
@@ -619,11 +721,13 @@ func (h *Host) threadedHandleActionItem(soid types.FileContractID) {
 		so, err = getStorageObligation(tx, soid)
 		return err
 	})
+	earlyProof := err == nil && h.earlyProof(so)
 	h.mu.RUnlock()
 	if err != nil {
 		h.log.Println("Could not get storage obligation:", err)
 		return
 	}
+	feesBefore := so.TransactionFeesAdded
 
 	// Check whether the storage obligation has already been completed.
 	if so.ObligationStatus != obligationUnresolved {
@@ -739,8 +843,15 @@ func (h *Host) threadedHandleActionItem(soid types.FileContractID) {
 	}
 
 	// Check whether a storage proof is ready to be provided, and whether it
-	// has been accepted. Check for death.
-	if !so.ProofConfirmed && blockHeight >= so.expiration()+resubmissionTimeout {
+	// has been accepted. Check for death. If the proof window opens before a
+	// scheduled maintenance, the proof is submitted as soon as the window
+	// opens, which consensus allows, so that it confirms before the host
+	// shuts down.
+	proofHeight := so.expiration() + resubmissionTimeout
+	if earlyProof {
+		proofHeight = so.expiration()
+	}
+	if !so.ProofConfirmed && blockHeight >= proofHeight {
 		h.log.Debugln("Host is attempting a storage proof for", so.id())
 
 		// If the window has closed, the host has failed and the obligation can
@@ -835,6 +946,10 @@ func (h *Host) threadedHandleActionItem(soid types.FileContractID) {
 
 	// Save the storage obligation to account for any fee changes.
 	err = h.db.Update(func(tx *bolt.Tx) error {
+		err := appendLedgerEntry(tx, modules.HostLedgerTransactionFee, blockHeight, soid, so.TransactionFeesAdded.Sub(feesBefore))
+		if err != nil {
+			return err
+		}
 		soBytes, err := json.Marshal(so)
 		if err != nil {
 			return err
@@ -869,17 +984,7 @@ func (h *Host) StorageObligations() (sos []modules.StorageObligation) {
 			if err != nil {
 				return build.ExtendErr("unable to unmarshal storage obligation:", err)
 			}
-			mso := modules.StorageObligation{
-				NegotiationHeight: so.NegotiationHeight,
-
-				OriginConfirmed:     so.OriginConfirmed,
-				RevisionConstructed: so.RevisionConstructed,
-				RevisionConfirmed:   so.RevisionConfirmed,
-				ProofConstructed:    so.ProofConstructed,
-				ProofConfirmed:      so.ProofConfirmed,
-				ObligationStatus:    uint64(so.ObligationStatus),
-			}
-			sos = append(sos, mso)
+			sos = append(sos, so.obligation(h.blockHeight))
 			return nil
 		})
 		if err != nil {
@@ -893,3 +998,20 @@ func (h *Host) StorageObligations() (sos []modules.StorageObligation) {
 
 	return sos
 }
+
+// StorageObligation returns the storage obligation with the provided id.
+func (h *Host) StorageObligation(id types.FileContractID) (modules.StorageObligation, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var so storageObligation
+	err := h.db.View(func(tx *bolt.Tx) error {
+		var err error
+		so, err = getStorageObligation(tx, id)
+		return err
+	})
+	if err != nil {
+		return modules.StorageObligation{}, err
+	}
+	return so.obligation(h.blockHeight), nil
+}
@@ -80,3 +80,49 @@ func TestStorageObligationID(t *testing.T) {
 		t.Error("id function of storage obligation incorrect for file contracts with dependencies")
 	}
 }
+
+// TestStorageObligationRisk checks that unresolved storage obligations are
+// reported as at risk when their transactions are not confirmed in time.
+func TestStorageObligationRisk(t *testing.T) {
+	t.Parallel()
+	so := storageObligation{
+		NegotiationHeight: 10,
+		OriginTransactionSet: []types.Transaction{{
+			FileContracts: []types.FileContract{{
+				WindowStart:        100,
+				WindowEnd:          110,
+				ValidProofOutputs:  make([]types.SiacoinOutput, 2),
+				MissedProofOutputs: make([]types.SiacoinOutput, 2),
+			}},
+		}},
+	}
+
+	// An unconfirmed file contract is at risk once the host had time to
+	// resubmit it.
+	if risk := so.risk(10); risk != "" {
+		t.Error("new obligation should not be at risk:", risk)
+	}
+	if risk := so.risk(10 + resubmissionTimeout); risk == "" {
+		t.Error("obligation with an unconfirmed file contract should be at risk")
+	}
+
+	// A confirmed obligation is at risk once its storage proof is late.
+	so.OriginConfirmed = true
+	if risk := so.risk(99); risk != "" {
+		t.Error("confirmed obligation should not be at risk:", risk)
+	}
+	if risk := so.risk(100 + resubmissionTimeout); risk == "" {
+		t.Error("obligation without a storage proof should be at risk")
+	}
+	so.ProofConfirmed = true
+	if risk := so.risk(100 + resubmissionTimeout); risk != "" {
+		t.Error("obligation with a storage proof should not be at risk:", risk)
+	}
+
+	// Resolved obligations are never at risk.
+	so.ProofConfirmed = false
+	so.ObligationStatus = obligationFailed
+	if obligation := so.obligation(100 + resubmissionTimeout); obligation.AtRisk {
+		t.Error("resolved obligation should not be at risk")
+	}
+}
@@ -148,6 +148,12 @@ var (
 	// RPCDownload is the specifier for downloading a file from a host.
 	RPCDownload = types.Specifier{'D', 'o', 'w', 'n', 'l', 'o', 'a', 'd', 2}
 
+	// RPCDownloadRange is the specifier for downloading parts of sectors from
+	// a host. Unlike RPCDownload, the host proves every segment of a partial
+	// sector download with a Merkle proof, so that the renter does not need
+	// the whole sector to verify the data.
+	RPCDownloadRange = types.Specifier{'D', 'o', 'w', 'n', 'l', 'o', 'a', 'd', 'R', 'a', 'n', 'g', 'e', 2}
+
 	// RPCFormContract is the specifier for forming a contract with a host.
 	RPCFormContract = types.Specifier{'F', 'o', 'r', 'm', 'C', 'o', 'n', 't', 'r', 'a', 'c', 't', 2}
 
@@ -254,6 +260,11 @@ type (
 		// which is the most recent.
 		RevisionNumber uint64 `json:"revisionnumber"`
 		Version        string `json:"version"`
+
+		// RangeDownloads indicates that the host supports RPCDownloadRange.
+		// It is sent after every other setting, and hosts that do not send
+		// it are assumed not to support range downloads.
+		RangeDownloads bool `json:"rangedownloads"`
 	}
 
 	// A RevisionAction is a description of an edit to be performed on a file
@@ -276,6 +287,66 @@ type (
 	}
 )
 
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface. Settings sent
+// by hosts that do not know about RangeDownloads end after Version.
+func (hes *HostExternalSettings) UnmarshalSia(r io.Reader) error {
+	err := encoding.NewDecoder(r).DecodeAll(
+		&hes.AcceptingContracts,
+		&hes.MaxDownloadBatchSize,
+		&hes.MaxDuration,
+		&hes.MaxReviseBatchSize,
+		&hes.NetAddress,
+		&hes.RemainingStorage,
+		&hes.SectorSize,
+		&hes.TotalStorage,
+		&hes.UnlockHash,
+		&hes.WindowSize,
+		&hes.Collateral,
+		&hes.MaxCollateral,
+		&hes.ContractPrice,
+		&hes.DownloadBandwidthPrice,
+		&hes.StoragePrice,
+		&hes.UploadBandwidthPrice,
+		&hes.RevisionNumber,
+		&hes.Version,
+	)
+	if err != nil {
+		return err
+	}
+
+	// COMPATv1.3.1
+	var rangeDownloads [1]byte
+	_, err = io.ReadFull(r, rangeDownloads[:])
+	if err == io.EOF {
+		hes.RangeDownloads = false
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if rangeDownloads[0] > 1 {
+		return errors.New("boolean value was not 0 or 1")
+	}
+	hes.RangeDownloads = rangeDownloads[0] == 1
+	return nil
+}
+
+// RangeDownloadBandwidth returns the number of bytes that a host sends to
+// serve a range of a sector through RPCDownloadRange. A range covering the
+// whole sector is verified against the sector's Merkle root and needs no
+// proofs. Any other range must be aligned to segments, and every segment is
+// sent with a Merkle proof. The renter pays for all of the bytes.
+func RangeDownloadBandwidth(length uint64) uint64 {
+	if length == SectorSize {
+		return SectorSize
+	}
+	proofLen := uint64(0)
+	for 1<<proofLen < SectorSize/crypto.SegmentSize {
+		proofLen++
+	}
+	numSegments := length / crypto.SegmentSize
+	return numSegments * (crypto.SegmentSize + proofLen*crypto.HashSize)
+}
+
 // ReadNegotiationAcceptance reads an accept/reject response from r (usually a
 // net.Conn). If the response is not AcceptResponse, ReadNegotiationAcceptance
 // returns the response as an error. If the response is StopResponse,
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -125,3 +126,39 @@ func TestNegotiationResponses(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// TestHostExternalSettingsEncoding checks that host settings survive encoding,
+// and that settings sent by hosts that do not know about RangeDownloads can
+// still be decoded.
+func TestHostExternalSettingsEncoding(t *testing.T) {
+	hes := HostExternalSettings{
+		AcceptingContracts:     true,
+		NetAddress:             "foo.com:1234",
+		DownloadBandwidthPrice: types.NewCurrency64(100),
+		RevisionNumber:         5,
+		Version:                "1.3.1",
+		RangeDownloads:         true,
+	}
+	b := encoding.Marshal(hes)
+	var decoded HostExternalSettings
+	if err := encoding.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoding.Marshal(decoded), b) {
+		t.Fatal("settings changed during encoding:", decoded)
+	}
+
+	// Settings of older hosts end after Version.
+	decoded = HostExternalSettings{}
+	if err := encoding.Unmarshal(b[:len(b)-1], &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.RangeDownloads || decoded.Version != hes.Version || decoded.RevisionNumber != hes.RevisionNumber {
+		t.Fatal("settings of an older host were not decoded correctly:", decoded)
+	}
+
+	// Truncated settings are rejected.
+	if err := encoding.Unmarshal(b[:len(b)-2], &decoded); err == nil {
+		t.Fatal("truncated settings were decoded")
+	}
+}
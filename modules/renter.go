@@ -2,6 +2,7 @@ package modules
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"time"
 
@@ -15,8 +16,186 @@ const (
 	RenterDir = "renter"
 )
 
+var (
+	// ECReedSolomon identifies the Reed-Solomon erasure coder. Its parameters
+	// are the number of data pieces and the number of parity pieces.
+	ECReedSolomon = ErasureCoderType{'R', 'e', 'e', 'd', '-', 'S', 'o', 'l', 'o', 'm', 'o', 'n'}
+
+	// ECSystematicRS identifies the systematic Reed-Solomon erasure coder,
+	// which implements SystematicErasureCoder. Its parameters are the number
+	// of data pieces and the number of parity pieces.
+	ECSystematicRS = ErasureCoderType{'S', 'y', 's', 't', 'e', 'm', 'a', 't', 'i', 'c', '-', 'R', 'S'}
+
+	// ECReplication identifies the replication erasure coder, which stores a
+	// full copy of the data in every piece. Its only parameter is the number
+	// of copies.
+	ECReplication = ErasureCoderType{'R', 'e', 'p', 'l', 'i', 'c', 'a', 't', 'i', 'o', 'n'}
+
+	// ErrUnknownFilterMode is returned when a filter mode string does not
+	// name a known filter mode.
+	ErrUnknownFilterMode = errors.New("unknown filter mode")
+
+	// DefaultHostWeightProfile weights all of the adjustments of a host
+	// equally, except for the performance adjustment, which is ignored.
+	DefaultHostWeightProfile = HostWeightProfile{
+		Name:                     "default",
+		AgeExponent:              1,
+		CollateralExponent:       1,
+		InteractionExponent:      1,
+		PriceExponent:            1,
+		StorageRemainingExponent: 1,
+		UptimeExponent:           1,
+		VersionExponent:          1,
+
+		AgeMultiplier:              1,
+		CollateralMultiplier:       1,
+		InteractionMultiplier:      1,
+		PerformanceMultiplier:      1,
+		PriceMultiplier:            1,
+		StorageRemainingMultiplier: 1,
+		UptimeMultiplier:           1,
+		VersionMultiplier:          1,
+	}
+
+	// PriceHostWeightProfile strongly prefers cheap hosts, at the cost of
+	// caring less about the age, uptime and past interactions of a host.
+	PriceHostWeightProfile = HostWeightProfile{
+		Name:                     "price",
+		AgeExponent:              0.5,
+		CollateralExponent:       1,
+		InteractionExponent:      0.5,
+		PriceExponent:            2,
+		StorageRemainingExponent: 1,
+		UptimeExponent:           0.5,
+		VersionExponent:          1,
+
+		AgeMultiplier:              1,
+		CollateralMultiplier:       1,
+		InteractionMultiplier:      1,
+		PerformanceMultiplier:      1,
+		PriceMultiplier:            1,
+		StorageRemainingMultiplier: 1,
+		UptimeMultiplier:           1,
+		VersionMultiplier:          1,
+	}
+
+	// ReliabilityHostWeightProfile strongly prefers old hosts with a good
+	// uptime and history of interactions, at the cost of caring less about
+	// price.
+	ReliabilityHostWeightProfile = HostWeightProfile{
+		Name:                     "reliability",
+		AgeExponent:              2,
+		CollateralExponent:       1,
+		InteractionExponent:      2,
+		PriceExponent:            0.5,
+		StorageRemainingExponent: 1,
+		UptimeExponent:           2,
+		VersionExponent:          1,
+
+		AgeMultiplier:              1,
+		CollateralMultiplier:       1,
+		InteractionMultiplier:      1,
+		PerformanceMultiplier:      1,
+		PriceMultiplier:            1,
+		StorageRemainingMultiplier: 1,
+		UptimeMultiplier:           1,
+		VersionMultiplier:          1,
+	}
+
+	// PerformanceHostWeightProfile weights all of the adjustments of a host
+	// equally, and also penalizes hosts with a high latency or a low measured
+	// throughput.
+	PerformanceHostWeightProfile = HostWeightProfile{
+		Name:                     "performance",
+		AgeExponent:              1,
+		CollateralExponent:       1,
+		InteractionExponent:      1,
+		PerformanceExponent:      1,
+		PriceExponent:            1,
+		StorageRemainingExponent: 1,
+		UptimeExponent:           1,
+		VersionExponent:          1,
+
+		AgeMultiplier:              1,
+		CollateralMultiplier:       1,
+		InteractionMultiplier:      1,
+		PerformanceMultiplier:      1,
+		PriceMultiplier:            1,
+		StorageRemainingMultiplier: 1,
+		UptimeMultiplier:           1,
+		VersionMultiplier:          1,
+	}
+
+	// HostWeightProfiles lists the built-in host weight profiles.
+	HostWeightProfiles = []HostWeightProfile{
+		DefaultHostWeightProfile,
+		PerformanceHostWeightProfile,
+		PriceHostWeightProfile,
+		ReliabilityHostWeightProfile,
+	}
+)
+
+// Filter modes determine which hosts the hostdb is allowed to select. In
+// blacklist mode, the hosts on the filter list are never selected. In
+// whitelist mode, only the hosts on the filter list are selected.
+const (
+	HostDBFilterDisabled FilterMode = iota
+	HostDBFilterBlacklist
+	HostDBFilterWhitelist
+)
+
+// A FilterMode is the mode of the hostdb's host filter.
+type FilterMode int
+
+// String returns the name of the filter mode.
+func (fm FilterMode) String() string {
+	switch fm {
+	case HostDBFilterDisabled:
+		return "disable"
+	case HostDBFilterBlacklist:
+		return "blacklist"
+	case HostDBFilterWhitelist:
+		return "whitelist"
+	default:
+		return "unknown"
+	}
+}
+
+// FromString sets the filter mode to the mode named by s.
+func (fm *FilterMode) FromString(s string) error {
+	switch s {
+	case "disable":
+		*fm = HostDBFilterDisabled
+	case "blacklist":
+		*fm = HostDBFilterBlacklist
+	case "whitelist":
+		*fm = HostDBFilterWhitelist
+	default:
+		return ErrUnknownFilterMode
+	}
+	return nil
+}
+
+// An ErasureCoderType identifies an erasure coding scheme. The type of an
+// ErasureCoder is stored in the metadata of a file, so that the file can be
+// decoded using the same scheme that it was encoded with.
+type ErasureCoderType types.Specifier
+
+// String returns the name of the erasure coding scheme.
+func (ect ErasureCoderType) String() string {
+	return types.Specifier(ect).String()
+}
+
 // An ErasureCoder is an error-correcting encoder and decoder.
 type ErasureCoder interface {
+	// Type returns the type of the ErasureCoder.
+	Type() ErasureCoderType
+
+	// Params returns the parameters of the ErasureCoder. Together with the
+	// type of the ErasureCoder, the parameters are sufficient to create an
+	// identical ErasureCoder.
+	Params() []uint64
+
 	// NumPieces is the number of pieces returned by Encode.
 	NumPieces() int
 
@@ -36,6 +215,19 @@ type ErasureCoder interface {
 	Recover(pieces [][]byte, n uint64, w io.Writer) error
 }
 
+// A SystematicErasureCoder is an ErasureCoder whose first MinPieces pieces
+// contain the original data, unmodified and in order. Data can be read from
+// these pieces without decoding, so downloaders should prefer them over
+// parity pieces.
+type SystematicErasureCoder interface {
+	ErasureCoder
+
+	// ReadData writes the first n bytes of the original data to w, reading
+	// them directly from the data pieces. Unlike Recover, ReadData requires
+	// all of the data pieces to be present.
+	ReadData(pieces [][]byte, n uint64, w io.Writer) error
+}
+
 // An Allowance dictates how much the Renter is allowed to spend in a given
 // period. Note that funds are spent on both storage and bandwidth.
 type Allowance struct {
@@ -48,13 +240,18 @@ type Allowance struct {
 // ContractUtility contains metrics internal to the contractor that reflect the
 // utility of a given contract.
 type ContractUtility struct {
-	GoodForUpload bool
-	GoodForRenew  bool
+	GoodForUpload bool `json:"goodforupload"`
+	GoodForRenew  bool `json:"goodforrenew"`
 }
 
+// DownloadID uniquely identifies a download in the renter's download queue.
+type DownloadID string
+
 // DownloadInfo provides information about a file that has been requested for
 // download.
 type DownloadInfo struct {
+	ID          DownloadID     `json:"id"`
+	Paused      bool           `json:"paused"`
 	SiaPath     string         `json:"siapath"`
 	Destination DownloadWriter `json:"destination"`
 	Filesize    uint64         `json:"filesize"`
@@ -70,12 +267,44 @@ type DownloadWriter interface {
 	Close() error
 }
 
+// A Streamer reads the contents of a file from the Sia network. Chunks of the
+// file are downloaded as they are read, and the chunks following the current
+// position are prefetched. A Streamer is not safe for concurrent use.
+type Streamer interface {
+	io.ReadSeeker
+
+	// Close releases the chunks that were prefetched by the Streamer.
+	Close() error
+
+	// ETag returns an opaque identifier of the contents of the file, for use
+	// as an HTTP entity tag.
+	ETag() string
+}
+
 // FileUploadParams contains the information used by the Renter to upload a
 // file.
 type FileUploadParams struct {
 	Source      string
 	SiaPath     string
 	ErasureCode ErasureCoder
+
+	// Priority determines the order in which the chunks of files are
+	// uploaded and repaired. Chunks of files with a higher priority go first.
+	Priority int
+}
+
+// DirectoryInfo provides information about a directory of the renter. A
+// directory exists if it has been created explicitly, or if it contains any
+// files. Aggregate values include the contents of all subdirectories.
+type DirectoryInfo struct {
+	SiaPath    string `json:"siapath"`
+	NumFiles   uint64 `json:"numfiles"`
+	NumSubDirs uint64 `json:"numsubdirs"`
+
+	AggregateNumFiles uint64  `json:"aggregatenumfiles"`
+	AggregateSize     uint64  `json:"aggregatesize"`
+	Available         bool    `json:"available"`
+	MinRedundancy     float64 `json:"minredundancy"`
 }
 
 // FileInfo provides information about a file.
@@ -89,6 +318,40 @@ type FileInfo struct {
 	UploadedBytes  uint64            `json:"uploadedbytes"`
 	UploadProgress float64           `json:"uploadprogress"`
 	Expiration     types.BlockHeight `json:"expiration"`
+	Priority       int               `json:"priority"`
+	UploadPaused   bool              `json:"uploadpaused"`
+
+	// ChunksBelowThreshold is the number of chunks that are missing so much
+	// redundancy that they need to be repaired. The Redundancy of a file is
+	// the redundancy of its least redundant chunk.
+	ChunksBelowThreshold uint64 `json:"chunksbelowthreshold"`
+
+	// LastRepair is the time at which a piece of the file was last uploaded.
+	// A file is Stuck if its chunks below the repair threshold repeatedly
+	// fail to be repaired, for example because the file is not available
+	// locally and cannot be downloaded.
+	LastRepair time.Time `json:"lastrepair"`
+	Stuck      bool      `json:"stuck"`
+}
+
+// RenterHealth summarizes the health of the renter's files.
+type RenterHealth struct {
+	NumFiles uint64 `json:"numfiles"`
+
+	// MinRedundancy is the lowest redundancy of any file, or -1 if there are
+	// no files with data.
+	MinRedundancy float64 `json:"minredundancy"`
+
+	// ChunksBelowThreshold is the number of chunks that need to be repaired,
+	// and UnhealthyFiles is the number of files that contain them.
+	ChunksBelowThreshold uint64 `json:"chunksbelowthreshold"`
+	UnhealthyFiles       uint64 `json:"unhealthyfiles"`
+
+	// LastRepair is the time at which a piece of any file was last uploaded.
+	LastRepair time.Time `json:"lastrepair"`
+
+	// StuckFiles are the files whose repairs keep failing.
+	StuckFiles []FileInfo `json:"stuckfiles"`
 }
 
 // A HostDBEntry represents one host entry in the Renter's host DB. It
@@ -116,6 +379,36 @@ type HostDBEntry struct {
 	// The public key of the host, stored separately to minimize risk of certain
 	// MitM based vulnerabilities.
 	PublicKey types.SiaPublicKey `json:"publickey"`
+
+	// Rolling averages of the round trip time to the host, and of the
+	// throughput observed when uploading to and downloading from the host in
+	// bytes per second. A value of zero means that nothing was measured yet.
+	Latency            time.Duration `json:"latency"`
+	UploadThroughput   float64       `json:"uploadthroughput"`
+	DownloadThroughput float64       `json:"downloadthroughput"`
+
+	// IPNets are the subnets that the address of the host resolved to during
+	// the most recent scan, and LastIPNetChange is the time at which they
+	// last changed. Hosts that share a subnet are likely operated from the
+	// same machine or network.
+	IPNets          []string  `json:"ipnets"`
+	LastIPNetChange time.Time `json:"lastipnetchange"`
+
+	// IPHistory contains the sets of IP addresses that the address of the
+	// host resolved to, oldest first. An entry is added whenever the set of
+	// addresses changes, and only the most recent entries are kept.
+	IPHistory []HostDBResolvedIPs `json:"iphistory"`
+
+	// Filtered indicates that the host is excluded by the hostdb's filter
+	// mode. It is only set on the entries returned by the hostdb.
+	Filtered bool `json:"filtered"`
+}
+
+// HostDBResolvedIPs is a set of IP addresses that the address of a host
+// resolved to, and the time at which the host was first seen at them.
+type HostDBResolvedIPs struct {
+	IPs       []string  `json:"ips"`
+	Timestamp time.Time `json:"timestamp"`
 }
 
 // HostDBScan represents a single scan event.
@@ -124,6 +417,35 @@ type HostDBScan struct {
 	Success   bool      `json:"success"`
 }
 
+// A HostWeightProfile determines how much each adjustment of a host
+// contributes to the host's score. Every adjustment is raised to the power of
+// its exponent and multiplied by its multiplier, and the weighted adjustments
+// are multiplied together into the score. An exponent of 0 ignores an
+// adjustment and an exponent of 2 doubles its effect. A multiplier scales the
+// scores of all hosts equally, so it changes the scores that are reported, but
+// not the relative weights that hosts are selected by.
+type HostWeightProfile struct {
+	Name string `json:"name"`
+
+	AgeExponent              float64 `json:"ageexponent"`
+	CollateralExponent       float64 `json:"collateralexponent"`
+	InteractionExponent      float64 `json:"interactionexponent"`
+	PerformanceExponent      float64 `json:"performanceexponent"`
+	PriceExponent            float64 `json:"priceexponent"`
+	StorageRemainingExponent float64 `json:"storageremainingexponent"`
+	UptimeExponent           float64 `json:"uptimeexponent"`
+	VersionExponent          float64 `json:"versionexponent"`
+
+	AgeMultiplier              float64 `json:"agemultiplier"`
+	CollateralMultiplier       float64 `json:"collateralmultiplier"`
+	InteractionMultiplier      float64 `json:"interactionmultiplier"`
+	PerformanceMultiplier      float64 `json:"performancemultiplier"`
+	PriceMultiplier            float64 `json:"pricemultiplier"`
+	StorageRemainingMultiplier float64 `json:"storageremainingmultiplier"`
+	UptimeMultiplier           float64 `json:"uptimemultiplier"`
+	VersionMultiplier          float64 `json:"versionmultiplier"`
+}
+
 // HostScoreBreakdown provides a piece-by-piece explanation of why a host has
 // the score that they do.
 //
@@ -134,11 +456,13 @@ type HostDBScan struct {
 type HostScoreBreakdown struct {
 	Score          types.Currency `json:"score"`
 	ConversionRate float64        `json:"conversionrate"`
+	WeightProfile  string         `json:"weightprofile"`
 
 	AgeAdjustment              float64 `json:"ageadjustment"`
 	BurnAdjustment             float64 `json:"burnadjustment"`
 	CollateralAdjustment       float64 `json:"collateraladjustment"`
 	InteractionAdjustment      float64 `json:"interactionadjustment"`
+	PerformanceAdjustment      float64 `json:"performanceadjustment"`
 	PriceAdjustment            float64 `json:"pricesmultiplier"`
 	StorageRemainingAdjustment float64 `json:"storageremainingadjustment"`
 	UptimeAdjustment           float64 `json:"uptimeadjustment"`
@@ -165,6 +489,73 @@ type RenterPriceEstimation struct {
 // RenterSettings control the behavior of the Renter.
 type RenterSettings struct {
 	Allowance Allowance `json:"allowance"`
+
+	// ChunkCacheSize is the number of bytes of memory that are used to cache
+	// recently downloaded chunks. ChunkCacheDiskSize is the number of bytes
+	// of disk space that are used to keep chunks that no longer fit in
+	// memory. A size of zero disables the respective tier of the cache.
+	ChunkCacheSize     uint64 `json:"chunkcachesize"`
+	ChunkCacheDiskSize uint64 `json:"chunkcachedisksize"`
+
+	// MaxUploadSpeed and MaxDownloadSpeed limit the average number of bytes
+	// per second that the renter uploads to and downloads from all hosts
+	// combined. A limit of zero means that the speed is not limited.
+	MaxUploadSpeed   uint64 `json:"maxuploadspeed"`
+	MaxDownloadSpeed uint64 `json:"maxdownloadspeed"`
+
+	// TopUp lets the contractor refill contracts that run low on funds with
+	// money from the wallet once the allowance funds have been used up.
+	TopUp TopUpPolicy `json:"topup"`
+
+	// SpendingAlerts are percentages of the allowance funds. An alert is
+	// raised when the spending during the current billing period crosses one
+	// of them.
+	SpendingAlerts []uint64 `json:"spendingalerts"`
+}
+
+// A TopUpPolicy determines how much money the contractor may spend on top of
+// the allowance to refill contracts that run low on funds.
+type TopUpPolicy struct {
+	// MaxFunds is the most that may be spent on top of the allowance during a
+	// billing period. A MaxFunds of zero disables top-ups.
+	MaxFunds types.Currency `json:"maxfunds"`
+}
+
+// These are the types of alerts that the renter raises.
+const (
+	// AlertSpending is raised when the spending during the current billing
+	// period crosses one of the spending alert thresholds.
+	AlertSpending = "spending"
+
+	// AlertTopUp is raised when contracts were refilled with money from the
+	// wallet beyond the allowance during the current billing period.
+	AlertTopUp = "topup"
+
+	// AlertLowFunds is raised when a contract ran out of funds and could not
+	// be refilled, so that uploads to its host stall.
+	AlertLowFunds = "lowfunds"
+)
+
+// A RenterAlert warns the user about the renter's spending or funds.
+type RenterAlert struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+
+	// Threshold is the spending alert threshold that was crossed, as a
+	// percentage of the allowance funds. It is only set for spending alerts.
+	Threshold uint64 `json:"threshold,omitempty"`
+}
+
+// ChunkCacheMetrics contains metrics about the renter's cache of recently
+// downloaded chunks.
+type ChunkCacheMetrics struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+
+	// MemoryUsed and DiskUsed are the number of bytes of chunk data currently
+	// held in each tier of the cache.
+	MemoryUsed uint64 `json:"memoryused"`
+	DiskUsed   uint64 `json:"diskused"`
 }
 
 // HostDBScans represents a sortable slice of scans.
@@ -254,6 +645,36 @@ type RenterContract struct {
 	SiafundFee  types.Currency
 }
 
+// A ContractOutcome describes how the proof window of a contract ended, as
+// observed in the consensus set. Until the contract is resolved, the payouts
+// are zero.
+type ContractOutcome struct {
+	// StorageProofSubmitted is true if the host submitted a storage proof for
+	// the contract, and StorageProofHeight is the height of the block that
+	// contains the proof.
+	StorageProofSubmitted bool              `json:"storageproofsubmitted"`
+	StorageProofHeight    types.BlockHeight `json:"storageproofheight"`
+
+	// WindowClosed is true once the proof window of the contract has ended,
+	// or a storage proof has been submitted.
+	WindowClosed bool `json:"windowclosed"`
+
+	// Resolved is true once the consensus set has paid out the contract,
+	// either the valid proof outputs or the missed proof outputs. A contract
+	// whose window has closed without being resolved has an unknown outcome,
+	// for example because it was resolved before the renter tracked
+	// resolutions.
+	Resolved bool `json:"resolved"`
+
+	// RenterPayout and HostPayout are the amounts that the consensus set paid
+	// out to the renter and the host when the contract was resolved.
+	// CollateralLost is the amount that was burned because the storage proof
+	// was missed.
+	RenterPayout   types.Currency `json:"renterpayout"`
+	HostPayout     types.Currency `json:"hostpayout"`
+	CollateralLost types.Currency `json:"collaterallost"`
+}
+
 // ContractorSpending contains the metrics about how much the Contractor has
 // spent during the current billing period.
 type ContractorSpending struct {
@@ -262,6 +683,48 @@ type ContractorSpending struct {
 	StorageSpending  types.Currency `json:"storagespending"`
 	UploadSpending   types.Currency `json:"uploadspending"`
 	Unspent          types.Currency `json:"unspent"`
+
+	// ContractFees, TxnFees and SiafundFees are the parts of ContractSpending
+	// that were paid as fees when the contracts were formed.
+	ContractFees types.Currency `json:"contractfees"`
+	TxnFees      types.Currency `json:"txnfees"`
+	SiafundFees  types.Currency `json:"siafundfees"`
+}
+
+// SpendingBreakdown details the spending on one or more contracts.
+type SpendingBreakdown struct {
+	DownloadSpending types.Currency `json:"downloadspending"`
+	StorageSpending  types.Currency `json:"storagespending"`
+	UploadSpending   types.Currency `json:"uploadspending"`
+	ContractFees     types.Currency `json:"contractfees"`
+	TxnFees          types.Currency `json:"txnfees"`
+	SiafundFees      types.Currency `json:"siafundfees"`
+}
+
+// HostSpending is the spending on the contracts with a single host during a
+// billing period.
+type HostSpending struct {
+	HostPublicKey types.SiaPublicKey `json:"hostpublickey"`
+	SpendingBreakdown
+}
+
+// ContractSpending is the spending on a single contract.
+type ContractSpending struct {
+	ID            types.FileContractID `json:"id"`
+	HostPublicKey types.SiaPublicKey   `json:"hostpublickey"`
+	SpendingBreakdown
+}
+
+// A SpendingReport is a snapshot of the Contractor's spending during a
+// billing period, broken down by host and by contract. The contracts of a
+// period are the contracts that were formed or renewed during the period.
+type SpendingReport struct {
+	StartHeight types.BlockHeight `json:"startheight"`
+	EndHeight   types.BlockHeight `json:"endheight"`
+
+	Spending  ContractorSpending `json:"spending"`
+	Hosts     []HostSpending     `json:"hosts"`
+	Contracts []ContractSpending `json:"contracts"`
 }
 
 // A Renter uploads, tracks, repairs, and downloads a set of files for the
@@ -274,12 +737,52 @@ type Renter interface {
 	// AllHosts returns the full list of hosts known to the renter.
 	AllHosts() []HostDBEntry
 
+	// Alerts returns the renter's alerts about its spending and funds.
+	Alerts() []RenterAlert
+
+	// Health returns a summary of the health of the renter's files.
+	Health() RenterHealth
+
+	// ChunkCacheMetrics returns the hit and miss counts and the usage of the
+	// renter's chunk cache.
+	ChunkCacheMetrics() ChunkCacheMetrics
+
 	// Close closes the Renter.
 	Close() error
 
 	// Contracts returns the contracts formed by the renter.
 	Contracts() []RenterContract
 
+	// ContractOutcome returns the outcome of the proof window of a contract,
+	// along with a bool indicating if the contract exists.
+	ContractOutcome(types.FileContractID) (ContractOutcome, bool)
+
+	// ContractUtility returns the utility of a contract, along with a bool
+	// indicating if the contract exists.
+	ContractUtility(types.FileContractID) (ContractUtility, bool)
+
+	// FormContract forms a contract with the host that has the provided
+	// public key, funded with the provided amount.
+	FormContract(types.SiaPublicKey, types.Currency) (RenterContract, error)
+
+	// OldContracts returns the contracts that have expired or have been
+	// renewed.
+	OldContracts() []RenterContract
+
+	// RenewedIDs returns a map from the ID of every renewed contract to the
+	// ID of the contract that replaced it.
+	RenewedIDs() map[types.FileContractID]types.FileContractID
+
+	// RenewContract renews a contract with the provided funds. The renewed
+	// contract ends at the provided height, or with the current allowance
+	// period if the height is zero.
+	RenewContract(types.FileContractID, types.Currency, types.BlockHeight) (RenterContract, error)
+
+	// SetContractUtility restricts the utility of a contract, so that it is
+	// not used for uploads or not renewed. Setting both fields to true removes
+	// the restriction.
+	SetContractUtility(types.FileContractID, ContractUtility) error
+
 	// CurrentPeriod returns the height at which the current allowance period
 	// began.
 	CurrentPeriod() types.BlockHeight
@@ -288,9 +791,28 @@ type Renter interface {
 	// billing period.
 	PeriodSpending() ContractorSpending
 
+	// SpendingHistory returns a spending report for every past billing
+	// period, followed by a report for the current period.
+	SpendingHistory() []SpendingReport
+
+	// CreateDir creates an empty directory in the renter.
+	CreateDir(path string) error
+
+	// DeleteDir deletes a directory from the renter, including all of the
+	// files and directories that it contains.
+	DeleteDir(path string) error
+
 	// DeleteFile deletes a file entry from the renter.
 	DeleteFile(path string) error
 
+	// DirList returns information about the directory at the provided path,
+	// along with information about the subdirectories and files that it
+	// directly contains. The empty path refers to the root directory. The
+	// contents are sorted by path, subdirectories first, and only the limit
+	// entries starting at offset are returned. A limit of 0 returns all
+	// entries after offset.
+	DirList(path string, offset, limit int) (DirectoryInfo, []DirectoryInfo, []FileInfo, error)
+
 	// Download performs a download according to the parameters passed, including
 	// downloads of `offset` and `length` type.
 	Download(params RenterDownloadParameters) error
@@ -298,6 +820,17 @@ type Renter interface {
 	// DownloadQueue lists all the files that have been scheduled for download.
 	DownloadQueue() []DownloadInfo
 
+	// CancelDownload cancels a download in the download queue. Chunks that
+	// have already been written to the destination are left in place.
+	CancelDownload(id DownloadID) error
+
+	// PauseDownload stops the renter from downloading new chunks for a
+	// download until it is resumed.
+	PauseDownload(id DownloadID) error
+
+	// ResumeDownload resumes a paused download.
+	ResumeDownload(id DownloadID) error
+
 	// FileList returns information on all of the files stored by the renter.
 	FileList() []FileInfo
 
@@ -316,6 +849,10 @@ type Renter interface {
 	// storage and data operations.
 	PriceEstimation() RenterPriceEstimation
 
+	// RenameDir changes the path of a directory, including all of the files
+	// and directories that it contains.
+	RenameDir(path, newPath string) error
+
 	// RenameFile changes the path of a file.
 	RenameFile(path, newPath string) error
 
@@ -323,6 +860,20 @@ type Renter interface {
 	// settings, assuming perfect age and uptime adjustments
 	EstimateHostScore(entry HostDBEntry) HostScoreBreakdown
 
+	// Filter returns the hostdb's filter mode and the hosts on its filter
+	// list.
+	Filter() (FilterMode, []types.SiaPublicKey)
+
+	// SetFilterMode sets the hostdb's filter mode and replaces its filter
+	// list with the provided hosts.
+	SetFilterMode(fm FilterMode, hosts []types.SiaPublicKey) error
+
+	// SetWeightProfile sets the profile that the hostdb uses to weight hosts.
+	SetWeightProfile(HostWeightProfile) error
+
+	// WeightProfile returns the profile that the hostdb uses to weight hosts.
+	WeightProfile() HostWeightProfile
+
 	// ScoreBreakdown will return the score for a host db entry using the
 	// hostdb's weighting algorithm.
 	ScoreBreakdown(entry HostDBEntry) HostScoreBreakdown
@@ -330,6 +881,10 @@ type Renter interface {
 	// Settings returns the Renter's current settings.
 	Settings() RenterSettings
 
+	// SetFileErasureCode changes the erasure code of a file, re-encoding the
+	// file using the new erasure code.
+	SetFileErasureCode(path string, ec ErasureCoder) error
+
 	// SetSettings sets the Renter's settings.
 	SetSettings(RenterSettings) error
 
@@ -339,8 +894,29 @@ type Renter interface {
 	// ShareFilesAscii creates an ASCII-encoded '.sia' file.
 	ShareFilesAscii(paths []string) (asciiSia string, err error)
 
+	// Streamer creates a Streamer that reads the contents of a file from the
+	// Sia network.
+	Streamer(siaPath string) (Streamer, error)
+
 	// Upload uploads a file using the input parameters.
 	Upload(FileUploadParams) error
+
+	// SetFilePriority sets the upload priority of the file at siaPath, or of
+	// every file in the directory at siaPath.
+	SetFilePriority(siaPath string, priority int) error
+
+	// PauseUploads stops the renter from uploading and repairing the file at
+	// siaPath, or every file in the directory at siaPath.
+	PauseUploads(siaPath string) error
+
+	// ResumeUploads resumes uploading and repairing the file at siaPath, or
+	// every file in the directory at siaPath.
+	ResumeUploads(siaPath string) error
+
+	// UploadStreamFromReader reads from the provided reader until io.EOF is
+	// reached and uploads the data to the Sia network. The Source field of
+	// the upload parameters is ignored.
+	UploadStreamFromReader(up FileUploadParams, reader io.Reader) error
 }
 
 // RenterDownloadParameters defines the parameters passed to the Renter's
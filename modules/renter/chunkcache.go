@@ -0,0 +1,225 @@
+package renter
+
+// The chunk cache keeps recently recovered chunks, so that repeated downloads
+// of the same data do not need to fetch pieces from hosts and decode them
+// again. The cache has two tiers. Chunks are added to the memory tier, and
+// chunks that are evicted from memory move to the disk tier if it is enabled.
+// A chunk that is found on disk moves back to memory. Both tiers evict the
+// least recently used chunks first.
+//
+// Chunks are identified by the master key of their file and their index, so
+// the entries of a file are never used for another file, even if the file is
+// renamed or re-encoded. The disk tier is not persisted across restarts.
+
+import (
+	"container/list"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
+)
+
+const (
+	// chunkCacheDir is the name of the directory in the renter's persist
+	// directory that contains the disk tier of the chunk cache.
+	chunkCacheDir = "chunkcache"
+)
+
+type (
+	// chunkCache is a two tier LRU cache of recovered chunks. It is safe for
+	// concurrent use.
+	chunkCache struct {
+		entries map[crypto.Hash]*list.Element
+		memory  *list.List // most recently used chunks first
+		disk    *list.List // most recently used chunks first
+
+		maxMemory  uint64
+		maxDisk    uint64
+		memoryUsed uint64
+		diskUsed   uint64
+
+		hits   uint64
+		misses uint64
+
+		dir string
+		log *persist.Logger
+		mu  sync.Mutex
+	}
+
+	// chunkCacheEntry is a single chunk in the cache. data is nil if the
+	// chunk is stored on disk.
+	chunkCacheEntry struct {
+		id   crypto.Hash
+		data []byte
+		size uint64
+	}
+)
+
+// chunkCacheID returns the identifier of a chunk in the chunk cache.
+func chunkCacheID(masterKey crypto.TwofishKey, chunkIndex uint64) crypto.Hash {
+	return crypto.HashAll(masterKey, chunkIndex)
+}
+
+// newChunkCache creates an empty chunk cache that stores its disk tier in dir.
+// Any chunks that were left in dir are removed.
+func newChunkCache(dir string, maxMemory, maxDisk uint64, log *persist.Logger) *chunkCache {
+	if err := os.RemoveAll(dir); err != nil {
+		log.Println("WARN: could not clear chunk cache directory:", err)
+	}
+	return &chunkCache{
+		entries:   make(map[crypto.Hash]*list.Element),
+		memory:    list.New(),
+		disk:      list.New(),
+		maxMemory: maxMemory,
+		maxDisk:   maxDisk,
+		dir:       dir,
+		log:       log,
+	}
+}
+
+// path returns the location of a chunk in the disk tier.
+func (cc *chunkCache) path(id crypto.Hash) string {
+	return filepath.Join(cc.dir, id.String())
+}
+
+// removeFromDisk removes a chunk from the disk tier.
+func (cc *chunkCache) removeFromDisk(elem *list.Element) {
+	entry := elem.Value.(*chunkCacheEntry)
+	cc.disk.Remove(elem)
+	delete(cc.entries, entry.id)
+	cc.diskUsed -= entry.size
+	if err := os.Remove(cc.path(entry.id)); err != nil {
+		cc.log.Println("WARN: could not remove chunk from chunk cache:", err)
+	}
+}
+
+// addToDisk adds a chunk to the disk tier, evicting the least recently used
+// chunks to make room. The chunk is dropped if the disk tier is too small.
+func (cc *chunkCache) addToDisk(entry *chunkCacheEntry) {
+	if entry.size > cc.maxDisk {
+		return
+	}
+	for cc.diskUsed+entry.size > cc.maxDisk {
+		cc.removeFromDisk(cc.disk.Back())
+	}
+	if err := os.MkdirAll(cc.dir, 0700); err != nil {
+		cc.log.Println("WARN: could not create chunk cache directory:", err)
+		return
+	}
+	if err := ioutil.WriteFile(cc.path(entry.id), entry.data, 0600); err != nil {
+		cc.log.Println("WARN: could not write chunk to chunk cache:", err)
+		return
+	}
+	entry.data = nil
+	cc.entries[entry.id] = cc.disk.PushFront(entry)
+	cc.diskUsed += entry.size
+}
+
+// evictMemory moves the least recently used chunks from the memory tier to the
+// disk tier until the memory tier fits within its limit.
+func (cc *chunkCache) evictMemory() {
+	for cc.memoryUsed > cc.maxMemory {
+		elem := cc.memory.Back()
+		entry := elem.Value.(*chunkCacheEntry)
+		cc.memory.Remove(elem)
+		delete(cc.entries, entry.id)
+		cc.memoryUsed -= entry.size
+		cc.addToDisk(entry)
+	}
+}
+
+// addToMemory adds a chunk to the memory tier. Chunks that do not fit in
+// memory go straight to the disk tier.
+func (cc *chunkCache) addToMemory(entry *chunkCacheEntry) {
+	if entry.size > cc.maxMemory {
+		cc.addToDisk(entry)
+		return
+	}
+	cc.entries[entry.id] = cc.memory.PushFront(entry)
+	cc.memoryUsed += entry.size
+	cc.evictMemory()
+}
+
+// add adds a recovered chunk to the cache. The cache keeps its own copy of the
+// data.
+func (cc *chunkCache) add(id crypto.Hash, data []byte) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if _, exists := cc.entries[id]; exists {
+		return
+	}
+	cc.addToMemory(&chunkCacheEntry{
+		id:   id,
+		data: append([]byte(nil), data...),
+		size: uint64(len(data)),
+	})
+}
+
+// get returns the data of a chunk if it is in the cache. The returned slice
+// must not be modified.
+func (cc *chunkCache) get(id crypto.Hash) ([]byte, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	if cc.maxMemory == 0 && cc.maxDisk == 0 {
+		return nil, false
+	}
+	elem, exists := cc.entries[id]
+	if !exists {
+		cc.misses++
+		return nil, false
+	}
+	entry := elem.Value.(*chunkCacheEntry)
+	if entry.data != nil {
+		cc.memory.MoveToFront(elem)
+		cc.hits++
+		return entry.data, true
+	}
+
+	// Move the chunk from disk back to memory.
+	data, err := ioutil.ReadFile(cc.path(id))
+	cc.removeFromDisk(elem)
+	if err != nil || uint64(len(data)) != entry.size {
+		cc.log.Println("WARN: could not read chunk from chunk cache:", err)
+		cc.misses++
+		return nil, false
+	}
+	entry.data = data
+	cc.addToMemory(entry)
+	cc.hits++
+	return data, true
+}
+
+// setSize changes the size of both tiers of the cache, evicting chunks as
+// necessary.
+func (cc *chunkCache) setSize(maxMemory, maxDisk uint64) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	cc.maxMemory, cc.maxDisk = maxMemory, maxDisk
+	for cc.diskUsed > cc.maxDisk {
+		cc.removeFromDisk(cc.disk.Back())
+	}
+	cc.evictMemory()
+}
+
+// size returns the size of both tiers of the cache.
+func (cc *chunkCache) size() (maxMemory, maxDisk uint64) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return cc.maxMemory, cc.maxDisk
+}
+
+// metrics returns the hit and miss counts and the usage of the cache.
+func (cc *chunkCache) metrics() modules.ChunkCacheMetrics {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+	return modules.ChunkCacheMetrics{
+		Hits:       cc.hits,
+		Misses:     cc.misses,
+		MemoryUsed: cc.memoryUsed,
+		DiskUsed:   cc.diskUsed,
+	}
+}
@@ -0,0 +1,106 @@
+package renter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/persist"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// newTestingChunkCache creates a chunk cache for testing.
+func newTestingChunkCache(t *testing.T, maxMemory, maxDisk uint64) *chunkCache {
+	dir := build.TempDir("renter", t.Name())
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	log, err := persist.NewFileLogger(filepath.Join(dir, "chunkcache.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newChunkCache(filepath.Join(dir, chunkCacheDir), maxMemory, maxDisk, log)
+}
+
+// TestChunkCache probes the memory and disk tiers of the chunk cache.
+func TestChunkCache(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	cc := newTestingChunkCache(t, 200, 200)
+
+	key := crypto.GenerateTwofishKey()
+	ids := make([]crypto.Hash, 4)
+	data := make([][]byte, 4)
+	for i := range ids {
+		ids[i] = chunkCacheID(key, uint64(i))
+		data[i] = fastrand.Bytes(100)
+	}
+
+	// The first two chunks fit in memory.
+	cc.add(ids[0], data[0])
+	cc.add(ids[1], data[1])
+	if m := cc.metrics(); m.MemoryUsed != 200 || m.DiskUsed != 0 {
+		t.Fatal("unexpected cache usage:", m)
+	}
+	if d, ok := cc.get(ids[0]); !ok || !bytes.Equal(d, data[0]) {
+		t.Fatal("chunk was not served from memory")
+	}
+
+	// Adding two more chunks moves the least recently used chunks to disk.
+	cc.add(ids[2], data[2])
+	cc.add(ids[3], data[3])
+	if m := cc.metrics(); m.MemoryUsed != 200 || m.DiskUsed != 200 {
+		t.Fatal("unexpected cache usage:", m)
+	}
+	if _, err := os.Stat(cc.path(ids[1])); err != nil {
+		t.Fatal("evicted chunk was not written to disk:", err)
+	}
+	if d, ok := cc.get(ids[1]); !ok || !bytes.Equal(d, data[1]) {
+		t.Fatal("chunk was not served from disk")
+	}
+	if _, err := os.Stat(cc.path(ids[1])); !os.IsNotExist(err) {
+		t.Fatal("chunk was not moved back to memory:", err)
+	}
+
+	// Every chunk should still be available.
+	for i := range ids {
+		if d, ok := cc.get(ids[i]); !ok || !bytes.Equal(d, data[i]) {
+			t.Fatal("chunk", i, "is missing from the cache")
+		}
+	}
+	if d, ok := cc.get(chunkCacheID(key, 4)); ok || d != nil {
+		t.Fatal("unknown chunk was served")
+	}
+	if m := cc.metrics(); m.Hits != 6 || m.Misses != 1 {
+		t.Fatal("unexpected hit and miss counts:", m)
+	}
+
+	// Disabling the disk tier should remove all chunks from disk.
+	cc.setSize(200, 0)
+	if m := cc.metrics(); m.MemoryUsed != 200 || m.DiskUsed != 0 {
+		t.Fatal("unexpected cache usage:", m)
+	}
+	n := 0
+	for i := range ids {
+		if _, ok := cc.get(ids[i]); ok {
+			n++
+		}
+	}
+	if n != 2 {
+		t.Fatal("expected 2 chunks in the cache, got", n)
+	}
+
+	// Disabling the cache should drop every chunk.
+	cc.setSize(0, 0)
+	if m := cc.metrics(); m.MemoryUsed != 0 || m.DiskUsed != 0 {
+		t.Fatal("unexpected cache usage:", m)
+	}
+	cc.add(ids[0], data[0])
+	if _, ok := cc.get(ids[0]); ok {
+		t.Fatal("disabled cache served a chunk")
+	}
+}
@@ -16,6 +16,15 @@ var (
 		Testing:  1 * time.Minute,
 	}).(time.Duration)
 
+	// defaultChunkCacheSize is the default amount of memory that the renter
+	// uses to cache recently recovered chunks. The cache is disabled by
+	// default during testing, so that tests always fetch pieces from hosts.
+	defaultChunkCacheSize = build.Select(build.Var{
+		Dev:      uint64(1 << 26), // 64 MiB
+		Standard: uint64(1 << 28), // 256 MiB
+		Testing:  uint64(0),
+	}).(uint64)
+
 	// defaultMemory establishes the default amount of memory that the renter
 	// will use when performing uploads and downloads. Const should be a factor
 	// of 4 MiB, since most operations will be on data pieces that are 4 MiB
@@ -26,6 +35,25 @@ var (
 		Testing:  uint64(1 << 17),     // 128 KiB - 4 KiB sector size, need to test memory exhaustion
 	}).(uint64)
 
+	// downloadSaveInterval is the minimum amount of time between two saves
+	// of the download queue that are triggered by finished chunks. Saving
+	// syncs the destination of every download, so it is too expensive to do
+	// for every chunk.
+	downloadSaveInterval = build.Select(build.Var{
+		Dev:      10 * time.Second,
+		Standard: 30 * time.Second,
+		Testing:  time.Second,
+	}).(time.Duration)
+
+	// workerThroughputCacheTime is how long the download loop caches the
+	// download throughput of the hosts of its workers before fetching it from
+	// the hostdb again.
+	workerThroughputCacheTime = build.Select(build.Var{
+		Dev:      time.Minute,
+		Standard: 5 * time.Minute,
+		Testing:  5 * time.Second,
+	}).(time.Duration)
+
 	// Limit the number of doublings to prevent overflows.
 	maxConsecutivePenalty = build.Select(build.Var{
 		Dev:      4,
@@ -33,6 +61,14 @@ var (
 		Testing:  3,
 	}).(int)
 
+	// maxRepairFailures is the number of consecutive failed repairs of chunks
+	// below the repair threshold after which a file is marked stuck.
+	maxRepairFailures = build.Select(build.Var{
+		Dev:      5,
+		Standard: 10,
+		Testing:  3,
+	}).(int)
+
 	// maxScheduledDownloads specifies the number of chunks that can be downloaded
 	// for auto repair at once. If the limit is reached new ones will only be scheduled
 	// once old ones are scheduled for upload
@@ -50,6 +86,14 @@ var (
 		Testing:  3 * time.Second,
 	}).(time.Duration)
 
+	// streamPrefetchChunks is the number of chunks following the current
+	// position of a stream that are downloaded ahead of time.
+	streamPrefetchChunks = build.Select(build.Var{
+		Dev:      uint64(2),
+		Standard: uint64(2),
+		Testing:  uint64(1),
+	}).(uint64)
+
 	// Prime to avoid intersecting with regular events.
 	uploadFailureCooldown = build.Select(build.Var{
 		Dev:      time.Second * 7,
@@ -0,0 +1,134 @@
+package contractor
+
+// alerts.go implements the policies that protect the renter against running
+// out of funds. A top-up policy lets contract maintenance refill contracts
+// from the wallet once the allowance is used up, and spending alerts warn the
+// user as the spending during a billing period approaches the allowance.
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var errSpendingAlertZero = errors.New("spending alert thresholds must be non-zero")
+
+// periodSpent returns the amount spent during the current billing period,
+// including the fees paid to form contracts. The contractor's lock must be
+// held by the caller.
+func (c *Contractor) periodSpent() types.Currency {
+	end := c.currentPeriod + c.allowance.Period - c.allowance.RenewWindow
+	var spent types.Currency
+	for _, contract := range c.periodContracts(c.currentPeriod, end) {
+		if contract.TotalCost.Cmp(contract.RenterFunds) > 0 {
+			spent = spent.Add(contract.TotalCost.Sub(contract.RenterFunds))
+		}
+	}
+	return spent
+}
+
+// topUpAvailable returns the amount that may still be spent on top of the
+// allowance during the current billing period. The contractor's lock must be
+// held by the caller.
+func (c *Contractor) topUpAvailable() types.Currency {
+	if c.topUp.MaxFunds.Cmp(c.topUpSpent) <= 0 {
+		return types.ZeroCurrency
+	}
+	return c.topUp.MaxFunds.Sub(c.topUpSpent)
+}
+
+// spendingAlert returns the alert for the highest of the thresholds that the
+// spending has crossed, if any.
+func spendingAlert(spent, funds types.Currency, thresholds []uint64) (modules.RenterAlert, bool) {
+	if funds.IsZero() {
+		return modules.RenterAlert{}, false
+	}
+	var crossed uint64
+	for _, threshold := range thresholds {
+		if threshold > crossed && spent.Mul64(100).Cmp(funds.Mul64(threshold)) >= 0 {
+			crossed = threshold
+		}
+	}
+	if crossed == 0 {
+		return modules.RenterAlert{}, false
+	}
+	return modules.RenterAlert{
+		Type:      modules.AlertSpending,
+		Message:   fmt.Sprintf("spent %v of the %v allowance in the current period, crossing the %v%% alert", spent.HumanString(), funds.HumanString(), crossed),
+		Threshold: crossed,
+	}, true
+}
+
+// Alerts returns the alerts about the spending during the current billing
+// period and the contracts that ran out of funds.
+func (c *Contractor) Alerts() []modules.RenterAlert {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var alerts []modules.RenterAlert
+	if alert, ok := spendingAlert(c.periodSpent(), c.allowance.Funds, c.spendingAlerts); ok {
+		alerts = append(alerts, alert)
+	}
+	if !c.topUpSpent.IsZero() {
+		alerts = append(alerts, modules.RenterAlert{
+			Type:    modules.AlertTopUp,
+			Message: fmt.Sprintf("refilled contracts with %v beyond the allowance in the current period", c.topUpSpent.HumanString()),
+		})
+	}
+	for _, id := range c.lowFundsContracts {
+		alerts = append(alerts, modules.RenterAlert{
+			Type:    modules.AlertLowFunds,
+			Message: fmt.Sprintf("contract %v ran out of funds and could not be refilled because the allowance is used up", id),
+		})
+	}
+	return alerts
+}
+
+// SpendingAlerts returns the spending alert thresholds, as percentages of the
+// allowance funds.
+func (c *Contractor) SpendingAlerts() []uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append([]uint64(nil), c.spendingAlerts...)
+}
+
+// SetSpendingAlerts sets the spending alert thresholds, as percentages of the
+// allowance funds.
+func (c *Contractor) SetSpendingAlerts(thresholds []uint64) error {
+	for _, threshold := range thresholds {
+		if threshold == 0 {
+			return errSpendingAlertZero
+		}
+	}
+	thresholds = append([]uint64(nil), thresholds...)
+	sort.Slice(thresholds, func(i, j int) bool { return thresholds[i] < thresholds[j] })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spendingAlerts = thresholds
+	return c.saveSync()
+}
+
+// TopUpPolicy returns the policy for refilling contracts beyond the allowance.
+func (c *Contractor) TopUpPolicy() modules.TopUpPolicy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.topUp
+}
+
+// SetTopUpPolicy sets the policy for refilling contracts beyond the allowance
+// and starts a round of contract maintenance, so that contracts that ran out
+// of funds are refilled.
+func (c *Contractor) SetTopUpPolicy(p modules.TopUpPolicy) error {
+	c.mu.Lock()
+	c.topUp = p
+	err := c.saveSync()
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	c.RestartMaintenance()
+	return nil
+}
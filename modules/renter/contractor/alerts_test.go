@@ -0,0 +1,88 @@
+package contractor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/proto"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSpendingAlert checks that the alert for the highest crossed threshold
+// is raised.
+func TestSpendingAlert(t *testing.T) {
+	funds := types.NewCurrency64(1000)
+	thresholds := []uint64{50, 80, 100}
+	tests := []struct {
+		spent     uint64
+		threshold uint64
+	}{
+		{0, 0},
+		{499, 0},
+		{500, 50},
+		{850, 80},
+		{1000, 100},
+		{1500, 100},
+	}
+	for _, test := range tests {
+		alert, ok := spendingAlert(types.NewCurrency64(test.spent), funds, thresholds)
+		if ok != (test.threshold != 0) || alert.Threshold != test.threshold {
+			t.Errorf("spending %v: expected threshold %v, got %v", test.spent, test.threshold, alert.Threshold)
+		}
+		if ok && alert.Type != modules.AlertSpending {
+			t.Error("wrong alert type:", alert.Type)
+		}
+	}
+	if _, ok := spendingAlert(types.NewCurrency64(1500), types.ZeroCurrency, thresholds); ok {
+		t.Error("alert raised without an allowance")
+	}
+}
+
+// TestAlerts checks the alerts raised by the contractor, and that the top-up
+// policy limits the funds spent beyond the allowance.
+func TestAlerts(t *testing.T) {
+	cs, err := proto.NewContractSet(filepath.Join(build.TempDir("contractor", t.Name()), "contracts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Contractor{
+		persist:   new(memPersist),
+		contracts: cs,
+		allowance: modules.Allowance{Funds: types.NewCurrency64(1000), Period: 20, RenewWindow: 10},
+		oldContracts: map[types.FileContractID]modules.RenterContract{
+			{1}: {ID: types.FileContractID{1}, TotalCost: types.NewCurrency64(600), RenterFunds: types.NewCurrency64(100)},
+		},
+		lowFundsContracts: []types.FileContractID{{2}},
+	}
+	if err := c.SetSpendingAlerts([]uint64{0}); err != errSpendingAlertZero {
+		t.Fatal("expected errSpendingAlertZero, got", err)
+	}
+	if err := c.SetSpendingAlerts([]uint64{90, 50}); err != nil {
+		t.Fatal(err)
+	}
+	if alerts := c.SpendingAlerts(); len(alerts) != 2 || alerts[0] != 50 {
+		t.Fatal("spending alerts were not sorted:", alerts)
+	}
+
+	alerts := c.Alerts()
+	if len(alerts) != 2 || alerts[0].Threshold != 50 || alerts[1].Type != modules.AlertLowFunds {
+		t.Fatal("wrong alerts:", alerts)
+	}
+
+	// Spending beyond the allowance is limited by the top-up policy.
+	c.topUp = modules.TopUpPolicy{MaxFunds: types.NewCurrency64(300)}
+	c.topUpSpent = types.NewCurrency64(100)
+	if c.topUpAvailable().Cmp64(200) != 0 {
+		t.Fatal("wrong top-up available:", c.topUpAvailable())
+	}
+	alerts = c.Alerts()
+	if len(alerts) != 3 || alerts[1].Type != modules.AlertTopUp {
+		t.Fatal("top-up was not reported:", alerts)
+	}
+	c.topUpSpent = types.NewCurrency64(400)
+	if !c.topUpAvailable().IsZero() {
+		t.Fatal("top-up available beyond the policy:", c.topUpAvailable())
+	}
+}
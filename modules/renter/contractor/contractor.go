@@ -67,6 +67,28 @@ type Contractor struct {
 	contractUtilities map[types.FileContractID]modules.ContractUtility
 	oldContracts      map[types.FileContractID]modules.RenterContract
 	renewedIDs        map[types.FileContractID]types.FileContractID
+
+	// resolutions holds how the consensus set resolved the contractor's
+	// contracts. See history.go.
+	resolutions map[types.FileContractID]contractResolution
+
+	// manualUtilities holds the utility that was set manually on a contract.
+	// It restricts the utility that is determined from the hostdb, and is
+	// persisted.
+	manualUtilities map[types.FileContractID]modules.ContractUtility
+
+	// spendingHistory holds a spending report for every past billing period.
+	spendingHistory []modules.SpendingReport
+
+	// topUp is the policy for refilling contracts beyond the allowance, and
+	// topUpSpent is the amount spent on top of the allowance during the
+	// current period. spendingAlerts holds the spending alert thresholds.
+	// lowFundsContracts holds the contracts that ran out of funds and could
+	// not be refilled during the last round of contract maintenance.
+	topUp             modules.TopUpPolicy
+	topUpSpent        types.Currency
+	spendingAlerts    []uint64
+	lowFundsContracts []types.FileContractID
 }
 
 // resolveID returns the ID of the most recent renewal of id.
@@ -98,6 +120,9 @@ func (c *Contractor) PeriodSpending() modules.ContractorSpending {
 		spending.DownloadSpending = spending.DownloadSpending.Add(contract.DownloadSpending)
 		spending.UploadSpending = spending.UploadSpending.Add(contract.UploadSpending)
 		spending.StorageSpending = spending.StorageSpending.Add(contract.StorageSpending)
+		spending.ContractFees = spending.ContractFees.Add(contract.ContractFee)
+		spending.TxnFees = spending.TxnFees.Add(contract.TxnFee)
+		spending.SiafundFees = spending.SiafundFees.Add(contract.SiafundFee)
 		// TODO: fix PreviousContracts
 		// for _, pre := range contract.PreviousContracts {
 		// 	spending.ContractSpending = spending.ContractSpending.Add(pre.TotalCost)
@@ -217,10 +242,12 @@ func newContractor(cs consensusSet, w wallet, tp transactionPool, hdb hostDB, co
 		downloaders:       make(map[types.FileContractID]*hostDownloader),
 		editors:           make(map[types.FileContractID]*hostEditor),
 		contractUtilities: make(map[types.FileContractID]modules.ContractUtility),
+		manualUtilities:   make(map[types.FileContractID]modules.ContractUtility),
 		oldContracts:      make(map[types.FileContractID]modules.RenterContract),
 		renewedIDs:        make(map[types.FileContractID]types.FileContractID),
 		renewing:          make(map[types.FileContractID]bool),
 		revising:          make(map[types.FileContractID]bool),
+		resolutions:       make(map[types.FileContractID]contractResolution),
 	}
 	// Close the contract set and logger upon shutdown.
 	c.tg.AfterStop(func() {
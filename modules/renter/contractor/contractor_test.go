@@ -529,3 +529,32 @@ func TestWalletBridge(t *testing.T) {
 		t.Error("StartTransaction was not called on the shim")
 	}
 }
+
+// TestIPViolations checks that ipViolations flags the hosts that moved into a
+// subnet which is already used by another host.
+func TestIPViolations(t *testing.T) {
+	now := time.Now()
+	host := func(key string, changed time.Duration, ipNets ...string) modules.HostDBEntry {
+		var entry modules.HostDBEntry
+		entry.PublicKey.Key = []byte(key)
+		entry.IPNets = ipNets
+		entry.LastIPNetChange = now.Add(changed)
+		return entry
+	}
+	hosts := []modules.HostDBEntry{
+		host("late", -time.Hour, "1.2.3.0/24"),
+		host("early", -2*time.Hour, "1.2.3.0/24"),
+		host("other", -time.Minute, "4.5.6.0/24"),
+		host("unresolved", 0),
+		host("dualstack", 0, "4.5.6.0/24", "2001:db8::/54"),
+	}
+	violations := ipViolations(hosts)
+	if len(violations) != 2 {
+		t.Fatalf("expected 2 violations, got %v", len(violations))
+	}
+	for _, h := range []modules.HostDBEntry{hosts[0], hosts[4]} {
+		if _, exists := violations[h.PublicKey.String()]; !exists {
+			t.Errorf("host %s should be a violation", h.PublicKey.Key)
+		}
+	}
+}
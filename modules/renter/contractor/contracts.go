@@ -7,6 +7,7 @@ package contractor
 import (
 	"errors"
 	"math/big"
+	"sort"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
@@ -18,8 +19,10 @@ import (
 var (
 	// ErrInsufficientAllowance indicates that the renter's allowance is less
 	// than the amount necessary to store at least one sector
-	ErrInsufficientAllowance = errors.New("allowance is not large enough to cover fees of contract creation")
-	errTooExpensive          = errors.New("host price was too high")
+	ErrInsufficientAllowance   = errors.New("allowance is not large enough to cover fees of contract creation")
+	errContractNotFound        = errors.New("no contract with that id")
+	errContractNotGoodForRenew = errors.New("contract is marked as not good for renew")
+	errTooExpensive            = errors.New("host price was too high")
 )
 
 // contractEndHeight returns the height at which the Contractor's contracts
@@ -53,6 +56,44 @@ func (c *Contractor) managedInterruptContractMaintenance() {
 	}
 }
 
+// RestartMaintenance interrupts any running contract maintenance and starts a
+// new round, so that changes to the hostdb, such as a new filter mode, are
+// applied to the contracts without waiting for the next block.
+func (c *Contractor) RestartMaintenance() {
+	c.managedInterruptContractMaintenance()
+	go c.threadedContractMaintenance()
+}
+
+// ipViolations returns the public keys of the hosts that share a subnet with
+// another host. Of the hosts in a subnet, only the host whose subnets changed
+// the longest time ago is not a violation, so that a host cannot push another
+// host out of its contracts by moving into the same subnet.
+func ipViolations(hosts []modules.HostDBEntry) map[string]struct{} {
+	sorted := append([]modules.HostDBEntry(nil), hosts...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].LastIPNetChange.Before(sorted[j].LastIPNetChange)
+	})
+
+	violations := make(map[string]struct{})
+	usedIPNets := make(map[string]struct{})
+	for _, host := range sorted {
+		var violation bool
+		for _, ipNet := range host.IPNets {
+			if _, exists := usedIPNets[ipNet]; exists {
+				violation = true
+			}
+		}
+		if violation {
+			violations[host.PublicKey.String()] = struct{}{}
+			continue
+		}
+		for _, ipNet := range host.IPNets {
+			usedIPNets[ipNet] = struct{}{}
+		}
+	}
+	return violations
+}
+
 // managedMarkContractsUtility checks every active contract in the contractor and
 // figures out whether the contract is useful for uploading, and whehter the
 // contract should be renewed.
@@ -81,8 +122,18 @@ func (c *Contractor) managedMarkContractsUtility() {
 		minScore = lowestScore.Div(scoreLeeway)
 	}
 
+	// Find the hosts that share a subnet with the host of another contract.
+	contracts := c.contracts.ViewAll()
+	var contractHosts []modules.HostDBEntry
+	for _, contract := range contracts {
+		if host, exists := c.hdb.Host(contract.HostPublicKey); exists {
+			contractHosts = append(contractHosts, host)
+		}
+	}
+	badIPHosts := ipViolations(contractHosts)
+
 	// Update utility fields for each contract.
-	for _, contract := range c.contracts.ViewAll() {
+	for _, contract := range contracts {
 		utility := func() (u modules.ContractUtility) {
 			// Start the contract in good standing.
 			u.GoodForUpload = true
@@ -95,6 +146,20 @@ func (c *Contractor) managedMarkContractsUtility() {
 				u.GoodForRenew = false
 				return
 			}
+			// Contract has no utility if the host is excluded by the hostdb's
+			// filter mode.
+			if host.Filtered {
+				u.GoodForUpload = false
+				u.GoodForRenew = false
+				return
+			}
+			// Contract has no utility if the host moved into a subnet that is
+			// already used by the host of another contract.
+			if _, badIP := badIPHosts[contract.HostPublicKey.String()]; badIP {
+				u.GoodForUpload = false
+				u.GoodForRenew = false
+				return
+			}
 			// Contract has no utility if the score is poor.
 			if !minScore.IsZero() && c.hdb.ScoreBreakdown(host).Score.Cmp(minScore) < 0 {
 				u.GoodForUpload = false
@@ -129,8 +194,12 @@ func (c *Contractor) managedMarkContractsUtility() {
 			return
 		}()
 
-		// Apply changes.
+		// Apply changes, keeping the restrictions that were set manually on
+		// the contract.
 		c.mu.Lock()
+		if manual, exists := c.manualUtilities[contract.ID]; exists {
+			utility = restrictUtility(utility, manual)
+		}
 		c.contractUtilities[contract.ID] = utility
 		c.mu.Unlock()
 	}
@@ -233,6 +302,89 @@ func (c *Contractor) managedRenew(sc *proto.SafeContract, contractFunding types.
 	return newContract, nil
 }
 
+// managedRenewContract renews the contract with the provided id, replacing it
+// with the renewed contract. Active editors and downloaders of the contract are
+// invalidated before the renewal. Restrictions that were set manually on the
+// contract carry over to the renewed contract.
+func (c *Contractor) managedRenewContract(id types.FileContractID, amount types.Currency, endHeight types.BlockHeight) (modules.RenterContract, error) {
+	// Mark the contract as being renewed, and defer logic to unmark it once
+	// renewing is complete.
+	c.mu.Lock()
+	c.renewing[id] = true
+	c.mu.Unlock()
+	defer func() {
+		c.mu.Lock()
+		delete(c.renewing, id)
+		c.mu.Unlock()
+	}()
+
+	// Wait for any active editors and downloaders to finish for this
+	// contract, and then grab the latest revision.
+	c.mu.RLock()
+	e, eok := c.editors[id]
+	d, dok := c.downloaders[id]
+	c.mu.RUnlock()
+	if eok {
+		e.invalidate()
+	}
+	if dok {
+		d.invalidate()
+	}
+
+	// Fetch the contract that we are renewing.
+	oldContract, exists := c.contracts.Acquire(id)
+	if !exists {
+		return modules.RenterContract{}, errContractNotFound
+	}
+	// Return the contract if it's not useful for renewing.
+	c.mu.RLock()
+	oldUtility := c.contractUtilities[id]
+	c.mu.RUnlock()
+	if !oldUtility.GoodForRenew {
+		c.contracts.Return(oldContract)
+		return modules.RenterContract{}, errContractNotGoodForRenew
+	}
+	// Perform the actual renew. If the renew fails, return the contract.
+	newContract, err := c.managedRenew(oldContract, amount, endHeight)
+	if err != nil {
+		c.contracts.Return(oldContract)
+		return modules.RenterContract{}, err
+	}
+	c.log.Printf("Renewed contract %v\n", id)
+
+	// Lock the contractor as we update it to use the new contract instead of
+	// the old contract.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Update the utility values for the new contract, and for the old
+	// contract.
+	newUtility := modules.ContractUtility{
+		GoodForUpload: true,
+		GoodForRenew:  true,
+	}
+	if manual, exists := c.manualUtilities[id]; exists {
+		newUtility = restrictUtility(newUtility, manual)
+		c.manualUtilities[newContract.ID] = manual
+		delete(c.manualUtilities, id)
+	}
+	c.contractUtilities[newContract.ID] = newUtility
+	oldUtility.GoodForRenew = false
+	oldUtility.GoodForUpload = false
+	c.contractUtilities[id] = oldUtility
+	// Delete the old contract.
+	c.contracts.Delete(oldContract)
+	// Store the contract in the record of historic contracts.
+	c.oldContracts[id] = oldContract.Metadata()
+	// Add a mapping from the old contract to the new contract.
+	c.renewedIDs[id] = newContract.ID
+	// Save the contractor.
+	err = c.saveSync()
+	if err != nil {
+		c.log.Println("Failed to save the contractor after creating a new contract.")
+	}
+	return newContract, nil
+}
+
 // threadedContractMaintenance checks the set of contracts that the contractor
 // has against the allownace, renewing any contracts that need to be renewed,
 // dropping contracts which are no longer worthwhile, and adding contracts if
@@ -289,7 +441,9 @@ func (c *Contractor) threadedContractMaintenance() {
 	var endHeight types.BlockHeight
 	var fundsAvailable types.Currency
 	var renewSet []renewal
+	var lowFunds []types.FileContractID
 	refreshSet := make(map[types.FileContractID]struct{})
+	topUps := make(map[types.FileContractID]types.Currency)
 	func() {
 		c.mu.RLock()
 		defer c.mu.RUnlock()
@@ -342,7 +496,10 @@ func (c *Contractor) threadedContractMaintenance() {
 		}
 
 		// Iterate through the contracts again, figuring out which contracts to
-		// renew and how much extra funds to renew them with.
+		// renew and how much extra funds to renew them with. Contracts that
+		// run out of funds after the allowance is used up can be refilled
+		// with the funds allowed by the top-up policy.
+		topUpAvailable := c.topUpAvailable()
 		for _, contract := range c.contracts.ViewAll() {
 			if !c.contractUtilities[contract.ID].GoodForRenew {
 				continue
@@ -415,13 +572,27 @@ func (c *Contractor) threadedContractMaintenance() {
 							id:     contract.ID,
 							amount: refreshAmount,
 						})
+					} else if refreshAmount.Cmp(fundsAvailable.Add(topUpAvailable)) < 0 {
+						topUp := refreshAmount.Sub(fundsAvailable)
+						fundsAvailable = types.ZeroCurrency
+						topUpAvailable = topUpAvailable.Sub(topUp)
+						topUps[contract.ID] = topUp
+						refreshSet[contract.ID] = struct{}{}
+						renewSet = append(renewSet, renewal{
+							id:     contract.ID,
+							amount: refreshAmount,
+						})
 					} else {
 						c.log.Println("WARN: cannot refresh empty contract due to low allowance.")
+						lowFunds = append(lowFunds, contract.ID)
 					}
 				}
 			}
 		}
 	}()
+	c.mu.Lock()
+	c.lowFundsContracts = lowFunds
+	c.mu.Unlock()
 	if len(renewSet) != 0 {
 		c.log.Printf("renewing %v contracts", len(renewSet))
 	}
@@ -433,90 +604,27 @@ func (c *Contractor) threadedContractMaintenance() {
 		amount := renewal.amount
 
 		// Renew one contract.
-		func() {
-			// Mark the contract as being renewed, and defer logic to unmark it
-			// once renewing is complete.
-			c.mu.Lock()
-			c.renewing[id] = true
-			c.mu.Unlock()
-			defer func() {
-				c.mu.Lock()
-				delete(c.renewing, id)
-				c.mu.Unlock()
-			}()
-
-			// Wait for any active editors and downloaders to finish for this
-			// contract, and then grab the latest revision.
-			c.mu.RLock()
-			e, eok := c.editors[id]
-			d, dok := c.downloaders[id]
-			c.mu.RUnlock()
-			if eok {
-				e.invalidate()
-			}
-			if dok {
-				d.invalidate()
-			}
-
-			// Fetch the contract that we are renewing.
-			oldContract, exists := c.contracts.Acquire(id)
-			if !exists {
-				return
-			}
-			// Return the contract if it's not useful for renewing.
-			c.mu.RLock()
-			oldUtility := c.contractUtilities[id]
-			c.mu.RUnlock()
-			if !oldUtility.GoodForRenew {
-				c.log.Printf("Contract %v slated for renew is marked not good for renew", id)
-				c.contracts.Return(oldContract)
-				return
-			}
-			// Perform the actual renew. If the renew fails, return the
-			// contract.
-			newContract, err := c.managedRenew(oldContract, amount, endHeight)
-			if err != nil {
-				c.log.Printf("WARN: failed to renew contract %v: %v\n", id, err)
-				c.contracts.Return(oldContract)
-				return
-			}
-			c.log.Printf("Renewed contract %v\n", id)
-
-			// Update the utility values for the new contract, and for the old
-			// contract.
-			c.mu.Lock()
-			newUtility := modules.ContractUtility{
-				GoodForUpload: true,
-				GoodForRenew:  true,
-			}
-			c.contractUtilities[newContract.ID] = newUtility
-			oldUtility.GoodForRenew = false
-			oldUtility.GoodForUpload = false
-			c.contractUtilities[id] = oldUtility
-			c.mu.Unlock()
-			// If the contract is a mid-cycle renew, add the contract line to
-			// the new contract. The contract line is not included/extended if
-			// we are just renewing because the contract is expiring.
-			if _, exists := refreshSet[id]; exists {
-				// TODO: update PreviousContracts
-			}
-
-			// Lock the contractor as we update it to use the new contract
-			// instead of the old contract.
+		_, err := c.managedRenewContract(id, amount, endHeight)
+		if err != nil {
+			c.log.Printf("WARN: failed to renew contract %v: %v\n", id, err)
+		}
+		// If the contract is a mid-cycle renew, add the contract line to the
+		// new contract. The contract line is not included/extended if we are
+		// just renewing because the contract is expiring.
+		if _, exists := refreshSet[id]; exists && err == nil {
+			// TODO: update PreviousContracts
+		}
+		// Count the funds that were added on top of the allowance.
+		if topUp, exists := topUps[id]; exists && err == nil {
 			c.mu.Lock()
-			defer c.mu.Unlock()
-			// Delete the old contract.
-			c.contracts.Delete(oldContract)
-			// Store the contract in the record of historic contracts.
-			c.oldContracts[id] = oldContract.Metadata()
-			// Add a mapping from the old contract to the new contract.
-			c.renewedIDs[id] = newContract.ID
-			// Save the contractor.
+			c.topUpSpent = c.topUpSpent.Add(topUp)
 			err = c.saveSync()
+			c.mu.Unlock()
 			if err != nil {
-				c.log.Println("Failed to save the contractor after creating a new contract.")
+				c.log.Println("Unable to save the contractor:", err)
 			}
-		}()
+			c.log.Printf("INFO: refilled contract %v with %v beyond the allowance\n", id, topUp.HumanString())
+		}
 
 		// Soft sleep for a minute to allow all of the transactions to propagate
 		// the network.
@@ -21,6 +21,11 @@ type Downloader interface {
 	// retrieve.
 	Sector(root crypto.Hash) ([]byte, error)
 
+	// Range retrieves length bytes starting at offset from the sector with
+	// the specified Merkle root. Hosts that support range downloads send only
+	// the segments covering the range, with a Merkle proof for each segment.
+	Range(root crypto.Hash, offset, length uint64) ([]byte, error)
+
 	// Close terminates the connection to the host.
 	Close() error
 }
@@ -101,6 +106,24 @@ func (hd *hostDownloader) Sector(root crypto.Hash) ([]byte, error) {
 	return sector, nil
 }
 
+// Range retrieves length bytes starting at offset from the sector with the
+// specified Merkle root, and revises the underlying contract to pay the host
+// for the data retrieved.
+func (hd *hostDownloader) Range(root crypto.Hash, offset, length uint64) ([]byte, error) {
+	hd.mu.Lock()
+	defer hd.mu.Unlock()
+	if hd.invalid {
+		return nil, errInvalidDownloader
+	}
+
+	// Download the range.
+	_, data, err := hd.downloader.Range(root, offset, length)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
 // Downloader returns a Downloader object that can be used to download sectors
 // from a host.
 func (c *Contractor) Downloader(id types.FileContractID, cancel <-chan struct{}) (_ Downloader, err error) {
@@ -0,0 +1,172 @@
+package contractor
+
+// history.go keeps track of the contracts that have expired or have been
+// renewed. The contracts themselves are kept in oldContracts, and the renew
+// chain in renewedIDs. To tell how the proof window of a contract ended, the
+// contractor watches the consensus set for the storage proof outputs of its
+// contracts. Consensus creates the valid proof outputs when a storage proof
+// is accepted, and the missed proof outputs when the window closes without
+// one, so the outputs are the authoritative outcome of the contract.
+
+import (
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// maxProofOutputs is the number of storage proof outputs of a contract that
+// the contractor tracks. Renter contracts have two valid proof outputs and
+// three missed proof outputs.
+const maxProofOutputs = 3
+
+// contractResolution is how the consensus set resolved a contract. Height is
+// the height at which the contract was resolved, and Payouts holds the values
+// of the storage proof outputs that were created.
+type contractResolution struct {
+	Height  types.BlockHeight `json:"height"`
+	Proved  bool              `json:"proved"`
+	Payouts []types.Currency  `json:"payouts"`
+}
+
+// contractOutcome determines the outcome of a contract from its resolution, if
+// the consensus set resolved it, and the current block height.
+func contractOutcome(contract modules.RenterContract, res contractResolution, resolved bool, blockHeight types.BlockHeight) modules.ContractOutcome {
+	outcome := modules.ContractOutcome{
+		WindowClosed: resolved,
+		Resolved:     resolved,
+	}
+	if len(contract.Transaction.FileContractRevisions) != 0 {
+		rev := contract.Transaction.FileContractRevisions[0]
+		outcome.WindowClosed = resolved || blockHeight >= rev.NewWindowEnd
+	}
+	if !resolved {
+		return outcome
+	}
+
+	// The first output pays the renter and the second output pays the host.
+	// The third missed proof output burns the collateral that the host lost.
+	outcome.StorageProofSubmitted = res.Proved
+	if res.Proved {
+		outcome.StorageProofHeight = res.Height
+	}
+	if len(res.Payouts) > 0 {
+		outcome.RenterPayout = res.Payouts[0]
+	}
+	if len(res.Payouts) > 1 {
+		outcome.HostPayout = res.Payouts[1]
+	}
+	if !res.Proved && len(res.Payouts) > 2 {
+		outcome.CollateralLost = res.Payouts[2]
+	}
+	return outcome
+}
+
+// proofOutput identifies a storage proof output of one of the contractor's
+// contracts.
+type proofOutput struct {
+	id     types.FileContractID
+	proved bool
+	index  int
+}
+
+// proofOutputs returns the storage proof outputs of every contract that the
+// contractor has or had. The contractor's lock must be held by the caller.
+func (c *Contractor) proofOutputs() map[types.SiacoinOutputID]proofOutput {
+	outputs := make(map[types.SiacoinOutputID]proofOutput)
+	addContract := func(id types.FileContractID) {
+		for i := 0; i < maxProofOutputs; i++ {
+			outputs[id.StorageProofOutputID(types.ProofValid, uint64(i))] = proofOutput{id, true, i}
+			outputs[id.StorageProofOutputID(types.ProofMissed, uint64(i))] = proofOutput{id, false, i}
+		}
+	}
+	for id := range c.oldContracts {
+		addContract(id)
+	}
+	for _, id := range c.contracts.IDs() {
+		addContract(id)
+	}
+	return outputs
+}
+
+// updateResolutions records the resolutions of the contractor's contracts in a
+// consensus change, and removes them if the block that resolved the contract
+// is reverted. Consensus also removes delayed outputs when they mature, so
+// only the outputs that were created by a reverted block undo a resolution.
+// The contractor's lock must be held by the caller, and c.blockHeight must be
+// the height before the change is applied.
+func (c *Contractor) updateResolutions(cc modules.ConsensusChange) {
+	if len(cc.DelayedSiacoinOutputDiffs) == 0 {
+		return
+	}
+
+	// Determine the maturity heights of the outputs created by the reverted
+	// blocks.
+	reverted := make(map[types.BlockHeight]struct{})
+	height := c.blockHeight
+	for _, block := range cc.RevertedBlocks {
+		if block.ID() != types.GenesisID {
+			reverted[height+types.MaturityDelay] = struct{}{}
+			height--
+		}
+	}
+
+	outputs := c.proofOutputs()
+	for _, dscod := range cc.DelayedSiacoinOutputDiffs {
+		po, exists := outputs[dscod.ID]
+		if !exists {
+			continue
+		}
+		if dscod.Direction == modules.DiffRevert {
+			if _, ok := reverted[dscod.MaturityHeight]; ok {
+				delete(c.resolutions, po.id)
+			}
+			continue
+		}
+		res := c.resolutions[po.id]
+		res.Height = dscod.MaturityHeight - types.MaturityDelay
+		res.Proved = po.proved
+		for len(res.Payouts) <= po.index {
+			res.Payouts = append(res.Payouts, types.ZeroCurrency)
+		}
+		res.Payouts[po.index] = dscod.SiacoinOutput.Value
+		c.resolutions[po.id] = res
+	}
+}
+
+// OldContracts returns the contracts that have expired or have been renewed.
+func (c *Contractor) OldContracts() []modules.RenterContract {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	contracts := make([]modules.RenterContract, 0, len(c.oldContracts))
+	for _, contract := range c.oldContracts {
+		contracts = append(contracts, contract)
+	}
+	return contracts
+}
+
+// RenewedIDs returns a map from the id of every renewed contract to the id of
+// the contract that replaced it.
+func (c *Contractor) RenewedIDs() map[types.FileContractID]types.FileContractID {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	renewedIDs := make(map[types.FileContractID]types.FileContractID, len(c.renewedIDs))
+	for oldID, newID := range c.renewedIDs {
+		renewedIDs[oldID] = newID
+	}
+	return renewedIDs
+}
+
+// ContractOutcome returns the outcome of the proof window of a contract, as
+// resolved by the consensus set.
+func (c *Contractor) ContractOutcome(id types.FileContractID) (modules.ContractOutcome, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	contract, exists := c.oldContracts[id]
+	if !exists {
+		contract, exists = c.contracts.View(id)
+	}
+	if !exists {
+		return modules.ContractOutcome{}, false
+	}
+	res, resolved := c.resolutions[id]
+	return contractOutcome(contract, res, resolved, c.blockHeight), true
+}
@@ -0,0 +1,125 @@
+package contractor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/proto"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestContractOutcome checks the outcome of contracts whose host submitted or
+// missed a storage proof.
+func TestContractOutcome(t *testing.T) {
+	contract := modules.RenterContract{
+		Transaction: types.Transaction{
+			FileContractRevisions: []types.FileContractRevision{{
+				NewWindowEnd: 100,
+				NewValidProofOutputs: []types.SiacoinOutput{
+					{Value: types.NewCurrency64(10)},
+					{Value: types.NewCurrency64(50)},
+				},
+				NewMissedProofOutputs: []types.SiacoinOutput{
+					{Value: types.NewCurrency64(10)},
+					{Value: types.NewCurrency64(30)},
+					{Value: types.NewCurrency64(20)},
+				},
+			}},
+		},
+	}
+
+	valid := contractResolution{Height: 90, Proved: true, Payouts: []types.Currency{types.NewCurrency64(10), types.NewCurrency64(50)}}
+	missed := contractResolution{Height: 100, Payouts: []types.Currency{types.NewCurrency64(10), types.NewCurrency64(30), types.NewCurrency64(20)}}
+
+	// Before the window closes, the outcome is not known.
+	outcome := contractOutcome(contract, contractResolution{}, false, 99)
+	if outcome.WindowClosed || outcome.Resolved || !outcome.HostPayout.IsZero() {
+		t.Error("outcome of an open window should not be known:", outcome)
+	}
+
+	// A submitted proof closes the window and pays the valid proof outputs.
+	outcome = contractOutcome(contract, valid, true, 95)
+	if !outcome.WindowClosed || !outcome.Resolved || !outcome.StorageProofSubmitted || outcome.StorageProofHeight != 90 {
+		t.Error("submitted proof was not reported:", outcome)
+	}
+	if outcome.HostPayout.Cmp(types.NewCurrency64(50)) != 0 || !outcome.CollateralLost.IsZero() {
+		t.Error("wrong payouts for a submitted proof:", outcome)
+	}
+
+	// A missed proof pays the missed proof outputs and burns collateral.
+	outcome = contractOutcome(contract, missed, true, 100)
+	if !outcome.Resolved || outcome.StorageProofSubmitted {
+		t.Error("missed proof was not reported:", outcome)
+	}
+	if outcome.RenterPayout.Cmp(types.NewCurrency64(10)) != 0 || outcome.HostPayout.Cmp(types.NewCurrency64(30)) != 0 ||
+		outcome.CollateralLost.Cmp(types.NewCurrency64(20)) != 0 {
+		t.Error("wrong payouts for a missed proof:", outcome)
+	}
+
+	// A closed window without a resolution has an unknown outcome, and is not
+	// reported as missed.
+	outcome = contractOutcome(contract, contractResolution{}, false, 100)
+	if !outcome.WindowClosed || outcome.Resolved || !outcome.CollateralLost.IsZero() {
+		t.Error("unresolved contract should have an unknown outcome:", outcome)
+	}
+}
+
+// TestUpdateResolutions checks that only the resolutions of the contractor's
+// contracts are recorded, that they are kept when their outputs mature, and
+// that they are removed when their block is reverted.
+func TestUpdateResolutions(t *testing.T) {
+	cs, err := proto.NewContractSet(filepath.Join(build.TempDir("contractor", t.Name()), "contracts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Contractor{
+		blockHeight:  10,
+		contracts:    cs,
+		oldContracts: map[types.FileContractID]modules.RenterContract{{1}: {ID: types.FileContractID{1}}},
+		resolutions:  make(map[types.FileContractID]contractResolution),
+	}
+	id := types.FileContractID{1}
+	dscods := []modules.DelayedSiacoinOutputDiff{
+		{Direction: modules.DiffApply, ID: id.StorageProofOutputID(types.ProofValid, 1), SiacoinOutput: types.SiacoinOutput{Value: types.NewCurrency64(50)}, MaturityHeight: 10 + types.MaturityDelay},
+		{Direction: modules.DiffApply, ID: id.StorageProofOutputID(types.ProofValid, 0), SiacoinOutput: types.SiacoinOutput{Value: types.NewCurrency64(10)}, MaturityHeight: 10 + types.MaturityDelay},
+		{Direction: modules.DiffApply, ID: types.FileContractID{2}.StorageProofOutputID(types.ProofValid, 0), MaturityHeight: 10 + types.MaturityDelay},
+	}
+
+	c.updateResolutions(modules.ConsensusChange{DelayedSiacoinOutputDiffs: dscods})
+	if len(c.resolutions) != 1 {
+		t.Fatal("resolutions were not recorded correctly:", c.resolutions)
+	}
+	outcome, ok := c.ContractOutcome(id)
+	if !ok || !outcome.StorageProofSubmitted || outcome.StorageProofHeight != 10 ||
+		outcome.RenterPayout.Cmp(types.NewCurrency64(10)) != 0 || outcome.HostPayout.Cmp(types.NewCurrency64(50)) != 0 {
+		t.Error("outcome does not report the resolution:", outcome)
+	}
+
+	// Consensus removes the delayed outputs when they mature, which must not
+	// remove the resolution.
+	matured := make([]modules.DelayedSiacoinOutputDiff, len(dscods))
+	copy(matured, dscods)
+	for i := range matured {
+		matured[i].Direction = modules.DiffRevert
+	}
+	c.blockHeight = 10 + types.MaturityDelay - 1
+	c.updateResolutions(modules.ConsensusChange{
+		AppliedBlocks:             []types.Block{{Timestamp: 1}},
+		DelayedSiacoinOutputDiffs: matured,
+	})
+	if len(c.resolutions) != 1 {
+		t.Fatal("resolutions were removed when the outputs matured:", c.resolutions)
+	}
+
+	// Reverting the block that created the outputs removes the resolution.
+	c.blockHeight = 10
+	c.updateResolutions(modules.ConsensusChange{
+		RevertedBlocks:            []types.Block{{Timestamp: 1}},
+		DelayedSiacoinOutputDiffs: matured,
+	})
+	if len(c.resolutions) != 0 {
+		t.Fatal("resolutions were not removed:", c.resolutions)
+	}
+}
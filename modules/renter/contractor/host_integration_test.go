@@ -201,6 +201,83 @@ func TestIntegrationFormContract(t *testing.T) {
 	}
 }
 
+// TestIntegrationManualContracts tests forming, renewing and restricting
+// individual contracts.
+func TestIntegrationManualContracts(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	h, c, _, err := newTestingTrio(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	defer c.Close()
+
+	// Contracts cannot be formed without funds or without an allowance.
+	funds := types.SiacoinPrecision.Mul64(50)
+	if _, err := c.FormContract(h.PublicKey(), types.ZeroCurrency); err != errZeroContractFunds {
+		t.Fatal("expected errZeroContractFunds, got", err)
+	}
+	if _, err := c.FormContract(h.PublicKey(), funds); err != errNoAllowance {
+		t.Fatal("expected errNoAllowance, got", err)
+	}
+
+	// Set an allowance without hosts, so that maintenance does not form or
+	// renew any contracts by itself.
+	c.mu.Lock()
+	c.allowance = modules.Allowance{Period: 100, RenewWindow: 10}
+	c.currentPeriod = c.blockHeight
+	c.mu.Unlock()
+
+	contract, err := c.FormContract(h.PublicKey(), funds)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contract.EndHeight != c.contractEndHeight() {
+		t.Fatal("contract has the wrong end height:", contract.EndHeight)
+	}
+	if _, err := c.FormContract(h.PublicKey(), funds); err != errHostHasContract {
+		t.Fatal("expected errHostHasContract, got", err)
+	}
+
+	// Mark the contract as not good for upload. The restriction should carry
+	// over to the renewed contract.
+	err = c.SetContractUtility(contract.ID, modules.ContractUtility{GoodForRenew: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u, _ := c.ContractUtility(contract.ID); u.GoodForUpload || !u.GoodForRenew {
+		t.Fatal("contract utility was not restricted:", u)
+	}
+	renewed, err := c.RenewContract(contract.ID, funds, c.blockHeight+200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewed.EndHeight != c.blockHeight+200 {
+		t.Fatal("renewed contract has the wrong end height:", renewed.EndHeight)
+	}
+	if c.ResolveID(contract.ID) != renewed.ID {
+		t.Fatal("old contract does not resolve to the renewed contract")
+	}
+	if u, _ := c.ContractUtility(renewed.ID); u.GoodForUpload || !u.GoodForRenew {
+		t.Fatal("restriction did not carry over to the renewed contract:", u)
+	}
+
+	// A contract that is not good for renew cannot be renewed manually.
+	err = c.SetContractUtility(renewed.ID, modules.ContractUtility{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RenewContract(renewed.ID, funds, 0); err != errContractNotGoodForRenew {
+		t.Fatal("expected errContractNotGoodForRenew, got", err)
+	}
+	if err := c.SetContractUtility(types.FileContractID{1}, modules.ContractUtility{}); err != errContractNotFound {
+		t.Fatal("expected errContractNotFound, got", err)
+	}
+}
+
 // TestIntegrationReviseContract tests that the contractor can revise a
 // contract previously formed with a host.
 func TestIntegrationReviseContract(t *testing.T) {
@@ -304,6 +381,93 @@ func TestIntegrationUploadDownload(t *testing.T) {
 	}
 }
 
+// TestIntegrationRangeDownload tests that the contractor can download ranges
+// of a sector from a host that supports RPCDownloadRange, paying only for the
+// proven segments that cover each range.
+func TestIntegrationRangeDownload(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+	h, c, _, err := newTestingTrio(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	defer c.Close()
+
+	hostEntry, ok := c.hdb.Host(h.PublicKey())
+	if !ok {
+		t.Fatal("no entry for host in db")
+	}
+	if hostEntry.DownloadBandwidthPrice.IsZero() {
+		t.Fatal("host should charge for download bandwidth")
+	}
+	contract, err := c.managedNewContract(hostEntry, types.SiacoinPrecision.Mul64(50), c.blockHeight+100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	editor, err := c.Editor(contract.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := fastrand.Bytes(int(modules.SectorSize))
+	root, err := editor.Upload(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = editor.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	downloader, err := c.Downloader(contract.ID, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer downloader.Close()
+	spending := func() types.Currency {
+		for _, rc := range c.Contracts() {
+			if rc.ID == contract.ID {
+				return rc.DownloadSpending
+			}
+		}
+		t.Fatal("contract not found")
+		return types.Currency{}
+	}
+
+	// Download unaligned ranges, including ranges that span several proof
+	// blocks of the host and ranges at the ends of the sector.
+	ranges := []struct{ offset, length uint64 }{
+		{100, 300},
+		{0, 12},
+		{modules.SectorSize/2 - 1000, 2000},
+		{modules.SectorSize - 70, 70},
+	}
+	for _, r := range ranges {
+		before := spending()
+		retrieved, err := downloader.Range(root, r.offset, r.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data[r.offset:][:r.length], retrieved) {
+			t.Fatalf("range at offset %v with length %v does not match original", r.offset, r.length)
+		}
+
+		// The renter should have paid for the proven segments instead of the
+		// whole sector. Aligning the range adds at most two segments.
+		maxCost := hostEntry.DownloadBandwidthPrice.Mul64(modules.RangeDownloadBandwidth(r.length + 2*crypto.SegmentSize)).MulFloat(1.01)
+		if cost := spending().Sub(before); cost.Cmp(maxCost) > 0 {
+			t.Fatalf("range download cost %v, expected at most %v", cost, maxCost)
+		}
+	}
+
+	// Ranges outside of the sector are rejected.
+	if _, err := downloader.Range(root, modules.SectorSize-10, 20); err == nil {
+		t.Fatal("expected an error for a range outside of the sector")
+	}
+}
+
 // TestIntegrationRenew tests that the contractor can renew a previously-
 // formed file contract.
 func TestIntegrationRenew(t *testing.T) {
@@ -0,0 +1,148 @@
+package contractor
+
+// manual.go lets the user form, renew and restrict individual contracts,
+// alongside the contracts that are managed automatically from the allowance.
+// Manual operations pause contract maintenance while they run, so that a
+// contract is never renewed by maintenance and by the user at the same time.
+
+import (
+	"errors"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+var (
+	errContractEndHeight = errors.New("contract end height must be in the future")
+	errHostHasContract   = errors.New("a contract with that host already exists")
+	errNoAllowance       = errors.New("an allowance must be set to form or renew contracts")
+	errUnknownHost       = errors.New("no record of that host")
+	errZeroContractFunds = errors.New("contract funds must be non-zero")
+)
+
+// restrictUtility returns the utility u, restricted by the utility that was
+// set manually on the contract. A manual utility can only take away the
+// utility of a contract.
+func restrictUtility(u, manual modules.ContractUtility) modules.ContractUtility {
+	u.GoodForUpload = u.GoodForUpload && manual.GoodForUpload
+	u.GoodForRenew = u.GoodForRenew && manual.GoodForRenew
+	return u
+}
+
+// managedPauseMaintenance interrupts any running contract maintenance and
+// keeps new maintenance from running. The returned function resumes
+// maintenance and starts a new round.
+func (c *Contractor) managedPauseMaintenance() (resume func()) {
+	c.managedInterruptContractMaintenance()
+	c.maintenanceLock.Lock()
+	return func() {
+		c.maintenanceLock.Unlock()
+		go c.threadedContractMaintenance()
+	}
+}
+
+// FormContract forms a contract with the host that has the provided public
+// key, funded with the provided amount. The contract ends with the current
+// allowance period, and is renewed like the contracts formed from the
+// allowance.
+func (c *Contractor) FormContract(hostKey types.SiaPublicKey, funds types.Currency) (modules.RenterContract, error) {
+	if funds.IsZero() {
+		return modules.RenterContract{}, errZeroContractFunds
+	}
+	host, exists := c.hdb.Host(hostKey)
+	if !exists {
+		return modules.RenterContract{}, errUnknownHost
+	}
+
+	resume := c.managedPauseMaintenance()
+	defer resume()
+
+	c.mu.RLock()
+	noAllowance := c.allowance.Period == 0
+	endHeight := c.contractEndHeight()
+	c.mu.RUnlock()
+	if noAllowance {
+		return modules.RenterContract{}, errNoAllowance
+	}
+	for _, contract := range c.contracts.ViewAll() {
+		if contract.HostPublicKey.String() == hostKey.String() {
+			return modules.RenterContract{}, errHostHasContract
+		}
+	}
+
+	contract, err := c.managedNewContract(host, funds, endHeight)
+	if err != nil {
+		return modules.RenterContract{}, err
+	}
+
+	// Add this contract to the contractor and save.
+	c.mu.Lock()
+	c.contractUtilities[contract.ID] = modules.ContractUtility{
+		GoodForUpload: true,
+		GoodForRenew:  true,
+	}
+	err = c.saveSync()
+	c.mu.Unlock()
+	if err != nil {
+		c.log.Println("Unable to save the contractor:", err)
+	}
+	return contract, nil
+}
+
+// RenewContract renews the contract with the provided id, adding the provided
+// funds. The renewed contract ends at endHeight, which can be used to extend
+// the contract beyond the current allowance period. If endHeight is zero, the
+// renewed contract ends with the current allowance period.
+func (c *Contractor) RenewContract(id types.FileContractID, funds types.Currency, endHeight types.BlockHeight) (modules.RenterContract, error) {
+	if funds.IsZero() {
+		return modules.RenterContract{}, errZeroContractFunds
+	}
+
+	resume := c.managedPauseMaintenance()
+	defer resume()
+
+	c.mu.RLock()
+	id = c.resolveID(id)
+	noAllowance := c.allowance.Period == 0
+	if endHeight == 0 {
+		endHeight = c.contractEndHeight()
+	}
+	blockHeight := c.blockHeight
+	c.mu.RUnlock()
+	if noAllowance {
+		return modules.RenterContract{}, errNoAllowance
+	} else if endHeight <= blockHeight {
+		return modules.RenterContract{}, errContractEndHeight
+	}
+	return c.managedRenewContract(id, funds, endHeight)
+}
+
+// SetContractUtility sets the utility of the contract with the provided id.
+// The utility restricts the utility that the contractor determines from the
+// hostdb, so that a contract can be marked as not good for upload or not good
+// for renew regardless of its host. Marking a contract as good for both
+// removes the restriction. The utility carries over to renewed contracts.
+func (c *Contractor) SetContractUtility(id types.FileContractID, utility modules.ContractUtility) error {
+	c.mu.Lock()
+	id = c.resolveID(id)
+	if _, exists := c.contracts.View(id); !exists {
+		c.mu.Unlock()
+		return errContractNotFound
+	}
+	if utility.GoodForUpload && utility.GoodForRenew {
+		delete(c.manualUtilities, id)
+	} else {
+		c.manualUtilities[id] = utility
+	}
+	c.contractUtilities[id] = restrictUtility(c.contractUtilities[id], utility)
+	err := c.saveSync()
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// Restart maintenance, so that contracts that are no longer good for
+	// upload are replaced, and so that removed restrictions take effect.
+	c.RestartMaintenance()
+	return nil
+}
@@ -13,22 +13,40 @@ import (
 
 // contractorPersist defines what Contractor data persists across sessions.
 type contractorPersist struct {
-	Allowance     modules.Allowance         `json:"allowance"`
-	BlockHeight   types.BlockHeight         `json:"blockheight"`
-	CurrentPeriod types.BlockHeight         `json:"currentperiod"`
-	LastChange    modules.ConsensusChangeID `json:"lastchange"`
-	OldContracts  []modules.RenterContract  `json:"oldcontracts"`
-	RenewedIDs    map[string]string         `json:"renewedids"`
+	Allowance       modules.Allowance                  `json:"allowance"`
+	BlockHeight     types.BlockHeight                  `json:"blockheight"`
+	CurrentPeriod   types.BlockHeight                  `json:"currentperiod"`
+	LastChange      modules.ConsensusChangeID          `json:"lastchange"`
+	ManualUtilities map[string]modules.ContractUtility `json:"manualutilities"`
+	OldContracts    []modules.RenterContract           `json:"oldcontracts"`
+	RenewedIDs      map[string]string                  `json:"renewedids"`
+	Resolutions     map[string]contractResolution      `json:"resolutions"`
+	SpendingAlerts  []uint64                           `json:"spendingalerts"`
+	SpendingHistory []modules.SpendingReport           `json:"spendinghistory"`
+	TopUp           modules.TopUpPolicy                `json:"topup"`
+	TopUpSpent      types.Currency                     `json:"topupspent"`
 }
 
 // persistData returns the data in the Contractor that will be saved to disk.
 func (c *Contractor) persistData() contractorPersist {
 	data := contractorPersist{
-		Allowance:     c.allowance,
-		BlockHeight:   c.blockHeight,
-		CurrentPeriod: c.currentPeriod,
-		LastChange:    c.lastChange,
-		RenewedIDs:    make(map[string]string),
+		Allowance:       c.allowance,
+		BlockHeight:     c.blockHeight,
+		CurrentPeriod:   c.currentPeriod,
+		LastChange:      c.lastChange,
+		ManualUtilities: make(map[string]modules.ContractUtility),
+		RenewedIDs:      make(map[string]string),
+		Resolutions:     make(map[string]contractResolution),
+		SpendingAlerts:  c.spendingAlerts,
+		SpendingHistory: c.spendingHistory,
+		TopUp:           c.topUp,
+		TopUpSpent:      c.topUpSpent,
+	}
+	for id, utility := range c.manualUtilities {
+		data.ManualUtilities[id.String()] = utility
+	}
+	for id, resolution := range c.resolutions {
+		data.Resolutions[id.String()] = resolution
 	}
 	for _, contract := range c.oldContracts {
 		data.OldContracts = append(data.OldContracts, contract)
@@ -50,6 +68,10 @@ func (c *Contractor) load() error {
 	c.blockHeight = data.BlockHeight
 	c.currentPeriod = data.CurrentPeriod
 	c.lastChange = data.LastChange
+	c.spendingAlerts = data.SpendingAlerts
+	c.spendingHistory = data.SpendingHistory
+	c.topUp = data.TopUp
+	c.topUpSpent = data.TopUpSpent
 	for _, contract := range data.OldContracts {
 		c.oldContracts[contract.ID] = contract
 	}
@@ -59,6 +81,16 @@ func (c *Contractor) load() error {
 		newHash.LoadString(newString)
 		c.renewedIDs[types.FileContractID(oldHash)] = types.FileContractID(newHash)
 	}
+	for idString, utility := range data.ManualUtilities {
+		var id crypto.Hash
+		id.LoadString(idString)
+		c.manualUtilities[types.FileContractID(id)] = utility
+	}
+	for idString, resolution := range data.Resolutions {
+		var id crypto.Hash
+		id.LoadString(idString)
+		c.resolutions[types.FileContractID(id)] = resolution
+	}
 
 	return nil
 }
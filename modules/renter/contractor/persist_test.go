@@ -35,6 +35,9 @@ func TestSaveLoad(t *testing.T) {
 		{1}: {ID: types.FileContractID{1}, HostPublicKey: types.SiaPublicKey{Key: []byte("bar")}},
 		{2}: {ID: types.FileContractID{2}, HostPublicKey: types.SiaPublicKey{Key: []byte("baz")}},
 	}
+	c.manualUtilities = map[types.FileContractID]modules.ContractUtility{
+		{3}: {GoodForRenew: true},
+	}
 
 	// save, clear, and reload
 	err := c.save()
@@ -44,11 +47,15 @@ func TestSaveLoad(t *testing.T) {
 	c.hdb = stubHostDB{}
 	c.renewedIDs = make(map[types.FileContractID]types.FileContractID)
 	c.oldContracts = make(map[types.FileContractID]modules.RenterContract)
+	c.manualUtilities = make(map[types.FileContractID]modules.ContractUtility)
 	err = c.load()
 	if err != nil {
 		t.Fatal(err)
 	}
 	// check that all fields were restored
+	if u, ok := c.manualUtilities[types.FileContractID{3}]; !ok || u.GoodForUpload || !u.GoodForRenew {
+		t.Fatal("manual utilities were not restored properly:", c.manualUtilities)
+	}
 	_, ok0 := c.renewedIDs[types.FileContractID{0}]
 	_, ok1 := c.renewedIDs[types.FileContractID{1}]
 	_, ok2 := c.renewedIDs[types.FileContractID{2}]
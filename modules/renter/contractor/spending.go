@@ -0,0 +1,114 @@
+package contractor
+
+// spending.go keeps a spending report for every billing period. When a period
+// ends, a snapshot of the spending on the contracts of that period is added
+// to the spending history, so that it is not lost when the contracts are
+// renewed.
+
+import (
+	"sort"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// addSpending adds the spending on a contract to a breakdown.
+func addSpending(b modules.SpendingBreakdown, contract modules.RenterContract) modules.SpendingBreakdown {
+	b.DownloadSpending = b.DownloadSpending.Add(contract.DownloadSpending)
+	b.StorageSpending = b.StorageSpending.Add(contract.StorageSpending)
+	b.UploadSpending = b.UploadSpending.Add(contract.UploadSpending)
+	b.ContractFees = b.ContractFees.Add(contract.ContractFee)
+	b.TxnFees = b.TxnFees.Add(contract.TxnFee)
+	b.SiafundFees = b.SiafundFees.Add(contract.SiafundFee)
+	return b
+}
+
+// periodContracts returns the contracts that were formed or renewed between
+// the start and end heights, including those that have since expired or been
+// renewed. The contractor's lock must be held by the caller.
+func (c *Contractor) periodContracts(start, end types.BlockHeight) []modules.RenterContract {
+	var contracts []modules.RenterContract
+	inPeriod := func(contract modules.RenterContract) bool {
+		return contract.ID != metricsContractID && contract.StartHeight >= start && contract.StartHeight < end
+	}
+	for _, contract := range c.contracts.ViewAll() {
+		if inPeriod(contract) {
+			contracts = append(contracts, contract)
+		}
+	}
+	for _, contract := range c.oldContracts {
+		if inPeriod(contract) {
+			contracts = append(contracts, contract)
+		}
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		return contracts[i].StartHeight < contracts[j].StartHeight
+	})
+	return contracts
+}
+
+// spendingReport creates a report of the spending on the provided contracts
+// during the billing period between the start and end heights.
+func spendingReport(start, end types.BlockHeight, contracts []modules.RenterContract, funds types.Currency) modules.SpendingReport {
+	report := modules.SpendingReport{
+		StartHeight: start,
+		EndHeight:   end,
+	}
+	var total modules.SpendingBreakdown
+	hosts := make(map[string]modules.HostSpending)
+	for _, contract := range contracts {
+		report.Spending.ContractSpending = report.Spending.ContractSpending.Add(contract.TotalCost)
+		total = addSpending(total, contract)
+
+		hs, exists := hosts[contract.HostPublicKey.String()]
+		if !exists {
+			hs.HostPublicKey = contract.HostPublicKey
+		}
+		hs.SpendingBreakdown = addSpending(hs.SpendingBreakdown, contract)
+		hosts[contract.HostPublicKey.String()] = hs
+
+		report.Contracts = append(report.Contracts, modules.ContractSpending{
+			ID:                contract.ID,
+			HostPublicKey:     contract.HostPublicKey,
+			SpendingBreakdown: addSpending(modules.SpendingBreakdown{}, contract),
+		})
+	}
+	for _, hs := range hosts {
+		report.Hosts = append(report.Hosts, hs)
+	}
+	sort.Slice(report.Hosts, func(i, j int) bool {
+		return report.Hosts[i].HostPublicKey.String() < report.Hosts[j].HostPublicKey.String()
+	})
+
+	report.Spending.DownloadSpending = total.DownloadSpending
+	report.Spending.StorageSpending = total.StorageSpending
+	report.Spending.UploadSpending = total.UploadSpending
+	report.Spending.ContractFees = total.ContractFees
+	report.Spending.TxnFees = total.TxnFees
+	report.Spending.SiafundFees = total.SiafundFees
+	if funds.Cmp(report.Spending.ContractSpending) > 0 {
+		report.Spending.Unspent = funds.Sub(report.Spending.ContractSpending)
+	}
+	return report
+}
+
+// currentSpendingReport returns the spending report for the current billing
+// period. The contractor's lock must be held by the caller.
+func (c *Contractor) currentSpendingReport() modules.SpendingReport {
+	end := c.currentPeriod + c.allowance.Period - c.allowance.RenewWindow
+	return spendingReport(c.currentPeriod, end, c.periodContracts(c.currentPeriod, end), c.allowance.Funds)
+}
+
+// SpendingHistory returns a spending report for every past billing period,
+// followed by a report for the current period. The report for the current
+// period changes until the period ends.
+func (c *Contractor) SpendingHistory() []modules.SpendingReport {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	history := make([]modules.SpendingReport, 0, len(c.spendingHistory)+1)
+	history = append(history, c.spendingHistory...)
+	if c.allowance.Period != 0 {
+		history = append(history, c.currentSpendingReport())
+	}
+	return history
+}
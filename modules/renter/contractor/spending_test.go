@@ -0,0 +1,91 @@
+package contractor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/proto"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestSpendingReport checks that the spending on a set of contracts is broken
+// down correctly by host and by contract.
+func TestSpendingReport(t *testing.T) {
+	hostA := types.SiaPublicKey{Key: []byte("foo")}
+	hostB := types.SiaPublicKey{Key: []byte("bar")}
+	contracts := []modules.RenterContract{
+		{ID: types.FileContractID{1}, HostPublicKey: hostA, TotalCost: types.NewCurrency64(100), StorageSpending: types.NewCurrency64(10), TxnFee: types.NewCurrency64(1)},
+		{ID: types.FileContractID{2}, HostPublicKey: hostA, TotalCost: types.NewCurrency64(100), UploadSpending: types.NewCurrency64(20), ContractFee: types.NewCurrency64(2)},
+		{ID: types.FileContractID{3}, HostPublicKey: hostB, TotalCost: types.NewCurrency64(100), DownloadSpending: types.NewCurrency64(30), SiafundFee: types.NewCurrency64(3)},
+	}
+
+	report := spendingReport(10, 20, contracts, types.NewCurrency64(1000))
+	if report.StartHeight != 10 || report.EndHeight != 20 {
+		t.Fatal("wrong period:", report.StartHeight, report.EndHeight)
+	}
+	if report.Spending.ContractSpending.Cmp64(300) != 0 || report.Spending.Unspent.Cmp64(700) != 0 {
+		t.Error("wrong contract spending:", report.Spending)
+	}
+	if report.Spending.StorageSpending.Cmp64(10) != 0 || report.Spending.UploadSpending.Cmp64(20) != 0 ||
+		report.Spending.DownloadSpending.Cmp64(30) != 0 {
+		t.Error("wrong data spending:", report.Spending)
+	}
+	if report.Spending.TxnFees.Cmp64(1) != 0 || report.Spending.ContractFees.Cmp64(2) != 0 ||
+		report.Spending.SiafundFees.Cmp64(3) != 0 {
+		t.Error("wrong fees:", report.Spending)
+	}
+	if len(report.Contracts) != 3 || report.Contracts[2].DownloadSpending.Cmp64(30) != 0 {
+		t.Error("wrong contract breakdown:", report.Contracts)
+	}
+	if len(report.Hosts) != 2 {
+		t.Fatal("expected 2 hosts, got", len(report.Hosts))
+	}
+	for _, hs := range report.Hosts {
+		if hs.HostPublicKey.String() == hostA.String() && (hs.StorageSpending.Cmp64(10) != 0 || hs.UploadSpending.Cmp64(20) != 0) {
+			t.Error("wrong host breakdown:", hs)
+		}
+	}
+}
+
+// TestSpendingHistory checks that a spending report is kept when a billing
+// period ends, and that it survives a restart.
+func TestSpendingHistory(t *testing.T) {
+	cs, err := proto.NewContractSet(filepath.Join(build.TempDir("contractor", t.Name()), "contracts"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Contractor{
+		persist:      new(memPersist),
+		contracts:    cs,
+		allowance:    modules.Allowance{Funds: types.NewCurrency64(1000), Period: 20, RenewWindow: 10},
+		blockHeight:  9,
+		oldContracts: map[types.FileContractID]modules.RenterContract{{1}: {ID: types.FileContractID{1}, StartHeight: 5, TotalCost: types.NewCurrency64(100)}},
+		resolutions:  make(map[types.FileContractID]contractResolution),
+	}
+	if history := c.SpendingHistory(); len(history) != 1 || history[0].Spending.ContractSpending.Cmp64(100) != 0 {
+		t.Fatal("current period was not reported:", history)
+	}
+
+	// Enter the next period.
+	c.ProcessConsensusChange(modules.ConsensusChange{AppliedBlocks: []types.Block{{}}})
+	history := c.SpendingHistory()
+	if len(history) != 2 {
+		t.Fatal("expected a report for the past and the current period, got", len(history))
+	}
+	if history[0].StartHeight != 0 || history[0].EndHeight != 10 || len(history[0].Contracts) != 1 {
+		t.Error("wrong report for the past period:", history[0])
+	}
+	if history[1].StartHeight != 10 || len(history[1].Contracts) != 0 {
+		t.Error("wrong report for the current period:", history[1])
+	}
+
+	c.spendingHistory = nil
+	if err := c.load(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.spendingHistory) != 1 || c.spendingHistory[0].Spending.ContractSpending.Cmp64(100) != 0 {
+		t.Fatal("spending history was not restored:", c.spendingHistory)
+	}
+}
@@ -54,6 +54,7 @@ func (c *Contractor) managedArchiveContracts() {
 // is a change in the blockchain. Updates will always be called in order.
 func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 	c.mu.Lock()
+	c.updateResolutions(cc)
 	for _, block := range cc.RevertedBlocks {
 		if block.ID() != types.GenesisID {
 			c.blockHeight--
@@ -71,7 +72,12 @@ func (c *Contractor) ProcessConsensusChange(cc modules.ConsensusChange) {
 	// TODO: How to make this more explicit.
 	cycleLen := c.allowance.Period - c.allowance.RenewWindow
 	if c.blockHeight >= c.currentPeriod+cycleLen {
+		// Keep a snapshot of the spending during the period that ended.
+		if c.allowance.Period != 0 {
+			c.spendingHistory = append(c.spendingHistory, c.currentSpendingReport())
+		}
 		c.currentPeriod += cycleLen
+		c.topUpSpent = types.ZeroCurrency
 		// COMPATv1.0.4-lts
 		// if we were storing a special metrics contract, it will be invalid
 		// after we enter the next period.
@@ -0,0 +1,585 @@
+package renter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// Directories are not stored separately from files. A directory exists if any
+// file has a siapath that is prefixed by the directory's path, or if the
+// directory was created explicitly by the user. Explicitly created directories
+// are kept in the renter's persistence so that empty directories are not lost.
+// The renter keeps an index of the files and directories that each directory
+// contains, so that a directory can be found and listed without scanning all
+// of the files of the renter.
+
+// deleteExtension is the extension that the .sia files of a directory that is
+// being deleted are moved to, so that they can be restored if the deletion
+// fails.
+const deleteExtension = ".deleting"
+
+var (
+	ErrDirExists    = errors.New("a directory already exists at that location")
+	ErrParentIsFile = errors.New("a parent of the path is a file")
+	ErrUnknownDir   = errors.New("no directory known with that path")
+
+	errDirIntoItself = errors.New("cannot move a directory into itself")
+)
+
+// dirNode is a directory in the dirIndex. files and subDirs contain the full
+// siapaths of the files and directories that the directory directly contains.
+// explicit is set if the directory was created by the user.
+type dirNode struct {
+	files    map[string]struct{}
+	subDirs  map[string]struct{}
+	explicit bool
+}
+
+// dirIndex maps the path of every directory of the renter to its contents. A
+// directory is in the index while it contains a file or a directory, or if it
+// was created explicitly. The root directory is always in the index.
+type dirIndex map[string]*dirNode
+
+// newDirIndex returns an index that only contains the root directory.
+func newDirIndex() dirIndex {
+	di := make(dirIndex)
+	di.node("")
+	return di
+}
+
+// parentDir returns the path of the directory that contains siaPath.
+func parentDir(siaPath string) string {
+	if i := strings.LastIndex(siaPath, "/"); i >= 0 {
+		return siaPath[:i]
+	}
+	return ""
+}
+
+// node returns the directory at dir, adding it and its parents to the index if
+// they do not exist yet.
+func (di dirIndex) node(dir string) *dirNode {
+	n, exists := di[dir]
+	if !exists {
+		n = &dirNode{
+			files:   make(map[string]struct{}),
+			subDirs: make(map[string]struct{}),
+		}
+		di[dir] = n
+		if dir != "" {
+			di.node(parentDir(dir)).subDirs[dir] = struct{}{}
+		}
+	}
+	return n
+}
+
+// prune removes dir and its parents from the index for as long as they are
+// empty and were not created explicitly.
+func (di dirIndex) prune(dir string) {
+	for dir != "" {
+		n, exists := di[dir]
+		if !exists || n.explicit || len(n.files) > 0 || len(n.subDirs) > 0 {
+			return
+		}
+		delete(di, dir)
+		parent := parentDir(dir)
+		delete(di[parent].subDirs, dir)
+		dir = parent
+	}
+}
+
+// addFile adds the file at siaPath to the index.
+func (di dirIndex) addFile(siaPath string) {
+	di.node(parentDir(siaPath)).files[siaPath] = struct{}{}
+}
+
+// removeFile removes the file at siaPath from the index.
+func (di dirIndex) removeFile(siaPath string) {
+	dir := parentDir(siaPath)
+	if n, exists := di[dir]; exists {
+		delete(n.files, siaPath)
+		di.prune(dir)
+	}
+}
+
+// addDir adds the explicitly created directory at dir to the index.
+func (di dirIndex) addDir(dir string) {
+	di.node(dir).explicit = true
+}
+
+// removeDir removes the explicitly created directory at dir from the index.
+// The directory remains in the index while it is not empty.
+func (di dirIndex) removeDir(dir string) {
+	if n, exists := di[dir]; exists {
+		n.explicit = false
+		di.prune(dir)
+	}
+}
+
+// walk calls fn for the directory at dir and for every directory below it.
+func (di dirIndex) walk(dir string, fn func(string, *dirNode)) {
+	n, exists := di[dir]
+	if !exists {
+		return
+	}
+	fn(dir, n)
+	for subDir := range n.subDirs {
+		di.walk(subDir, fn)
+	}
+}
+
+// dirAggregate accumulates the information about the contents of a directory.
+type dirAggregate struct {
+	info modules.DirectoryInfo
+}
+
+// newDirAggregate returns a dirAggregate for the directory at siaPath, which
+// directly contains numSubDirs directories.
+func newDirAggregate(siaPath string, numSubDirs int) *dirAggregate {
+	return &dirAggregate{
+		info: modules.DirectoryInfo{
+			SiaPath:       siaPath,
+			NumSubDirs:    uint64(numSubDirs),
+			Available:     true,
+			MinRedundancy: -1,
+		},
+	}
+}
+
+// addFile adds a file to the aggregate. direct is set if the directory of the
+// aggregate directly contains the file.
+func (da *dirAggregate) addFile(fi modules.FileInfo, direct bool) {
+	if direct {
+		da.info.NumFiles++
+	}
+	da.info.AggregateNumFiles++
+	da.info.AggregateSize += fi.Filesize
+	da.info.Available = da.info.Available && fi.Available
+	// Empty files report a redundancy of -1, and should not affect the
+	// redundancy of the directory.
+	if fi.Redundancy >= 0 && (da.info.MinRedundancy < 0 || fi.Redundancy < da.info.MinRedundancy) {
+		da.info.MinRedundancy = fi.Redundancy
+	}
+}
+
+// dirPrefix returns the prefix shared by the siapaths of everything contained
+// in the directory at siaPath.
+func dirPrefix(siaPath string) string {
+	if siaPath == "" {
+		return ""
+	}
+	return siaPath + "/"
+}
+
+// movePath returns the path that path is moved to when the directory at oldDir
+// is moved to newDir.
+func movePath(path, oldDir, newDir string) string {
+	if path == oldDir {
+		return newDir
+	}
+	return dirPrefix(newDir) + strings.TrimPrefix(path, dirPrefix(oldDir))
+}
+
+// sortedPaths returns the paths of a set in sorted order.
+func sortedPaths(set map[string]struct{}) []string {
+	paths := make([]string, 0, len(set))
+	for path := range set {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// isDir returns whether siaPath refers to a directory. The root directory
+// always exists. The renter's lock must be held by the caller.
+func (r *Renter) isDir(siaPath string) bool {
+	_, exists := r.dirIndex[siaPath]
+	return exists
+}
+
+// checkPathAvailable returns an error if siaPath is already in use by a file or
+// directory, or if any of its parents is a file. The renter's lock must be
+// held by the caller.
+func (r *Renter) checkPathAvailable(siaPath string) error {
+	if _, exists := r.files[siaPath]; exists {
+		return ErrPathOverload
+	}
+	if r.isDir(siaPath) {
+		return ErrDirExists
+	}
+	for parent := siaPath; strings.Contains(parent, "/"); {
+		parent = parentDir(parent)
+		if _, exists := r.files[parent]; exists {
+			return ErrParentIsFile
+		}
+	}
+	return nil
+}
+
+// addFileEntry adds a file to the renter. The renter's lock must be held by
+// the caller.
+func (r *Renter) addFileEntry(f *file) {
+	r.files[f.name] = f
+	r.dirIndex.addFile(f.name)
+}
+
+// removeFileEntry removes the file at siaPath from the renter. The renter's
+// lock must be held by the caller.
+func (r *Renter) removeFileEntry(siaPath string) {
+	delete(r.files, siaPath)
+	r.dirIndex.removeFile(siaPath)
+}
+
+// filesInDir returns the siapaths of all files contained in the directory at
+// siaPath, including the files of its subdirectories. The renter's lock must
+// be held by the caller.
+func (r *Renter) filesInDir(siaPath string) []string {
+	var names []string
+	r.dirIndex.walk(siaPath, func(_ string, n *dirNode) {
+		for name := range n.files {
+			names = append(names, name)
+		}
+	})
+	return names
+}
+
+// explicitDirsInDir returns the explicitly created directories at or below
+// siaPath. The renter's lock must be held by the caller.
+func (r *Renter) explicitDirsInDir(siaPath string) []string {
+	var dirs []string
+	r.dirIndex.walk(siaPath, func(dir string, n *dirNode) {
+		if n.explicit {
+			dirs = append(dirs, dir)
+		}
+	})
+	return dirs
+}
+
+// CreateDir creates an empty directory at siaPath.
+func (r *Renter) CreateDir(siaPath string) error {
+	siaPath = strings.TrimSuffix(siaPath, "/")
+	if err := validateSiapath(siaPath); err != nil {
+		return err
+	}
+
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	if err := r.checkPathAvailable(siaPath); err != nil {
+		return err
+	}
+	r.directories[siaPath] = struct{}{}
+	r.dirIndex.addDir(siaPath)
+	err := r.saveSync()
+	if err != nil {
+		delete(r.directories, siaPath)
+		r.dirIndex.removeDir(siaPath)
+	}
+	return err
+}
+
+// DeleteDir removes a directory from the renter, deleting every file and
+// directory that it contains. Either the whole directory is deleted, or
+// nothing is.
+func (r *Renter) DeleteDir(siaPath string) error {
+	siaPath = strings.TrimSuffix(siaPath, "/")
+	if err := validateSiapath(siaPath); err != nil {
+		return err
+	}
+
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	if !r.isDir(siaPath) {
+		return ErrUnknownDir
+	}
+	names := r.filesInDir(siaPath)
+	dirs := r.explicitDirsInDir(siaPath)
+
+	// Move the .sia files of the directory aside, so that they can be
+	// restored if the directory cannot be deleted.
+	var moved []string
+	restoreFiles := func() {
+		for _, path := range moved {
+			if err := os.Rename(path+deleteExtension, path); err != nil {
+				r.log.Println("WARN: couldn't restore file of directory that was not deleted:", err)
+			}
+		}
+	}
+	for _, name := range names {
+		paths := []string{filepath.Join(r.persistDir, name+ShareExtension)}
+		if _, exists := r.reencoding[name]; exists {
+			paths = append(paths, r.reencodePath(name))
+		}
+		for _, path := range paths {
+			err := os.Rename(path, path+deleteExtension)
+			if os.IsNotExist(err) {
+				continue
+			} else if err != nil {
+				restoreFiles()
+				return err
+			}
+			moved = append(moved, path)
+		}
+	}
+
+	// Remove the contents of the directory from the renter, keeping them so
+	// that they can be restored if the renter cannot be saved.
+	files := make(map[string]*file, len(names))
+	tracking := make(map[string]trackedFile)
+	reencoding := make(map[string]*file)
+	for _, name := range names {
+		files[name] = r.files[name]
+		if t, exists := r.tracking[name]; exists {
+			tracking[name] = t
+		}
+		if old, exists := r.reencoding[name]; exists {
+			reencoding[name] = old
+		}
+		r.removeFileEntry(name)
+		delete(r.tracking, name)
+		delete(r.reencoding, name)
+	}
+	for _, dir := range dirs {
+		delete(r.directories, dir)
+		r.dirIndex.removeDir(dir)
+	}
+	err := r.saveSync()
+	if err != nil {
+		for _, f := range files {
+			r.addFileEntry(f)
+		}
+		for name, t := range tracking {
+			r.tracking[name] = t
+		}
+		for name, old := range reencoding {
+			r.reencoding[name] = old
+		}
+		for _, dir := range dirs {
+			r.directories[dir] = struct{}{}
+			r.dirIndex.addDir(dir)
+		}
+		restoreFiles()
+		return err
+	}
+
+	// The directory has been deleted, remove the .sia files for good.
+	for _, path := range moved {
+		if err := os.Remove(path + deleteExtension); err != nil {
+			r.log.Println("WARN: couldn't remove file :", err)
+		}
+	}
+	return nil
+}
+
+// DirList returns information about the directory at siaPath, as well as
+// information about the subdirectories and files that it directly contains.
+// The information about the subdirectories is aggregated over all of their
+// contents. The contents are sorted by siapath, subdirectories first, and
+// only the page of limit entries starting at offset is returned. A limit of 0
+// returns all entries after offset.
+func (r *Renter) DirList(siaPath string, offset, limit int) (modules.DirectoryInfo, []modules.DirectoryInfo, []modules.FileInfo, error) {
+	siaPath = strings.TrimSuffix(siaPath, "/")
+	lockID := r.mu.RLock()
+	defer r.mu.RUnlock(lockID)
+	n, exists := r.dirIndex[siaPath]
+	if !exists {
+		return modules.DirectoryInfo{}, nil, nil, ErrUnknownDir
+	}
+
+	// Select the page of subdirectories and files.
+	subDirPaths, filePaths := sortedPaths(n.subDirs), sortedPaths(n.files)
+	end := len(subDirPaths) + len(filePaths)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	start := offset
+	if start > end {
+		start = end
+	}
+	dirStart, dirEnd := start, end
+	if dirStart > len(subDirPaths) {
+		dirStart = len(subDirPaths)
+	}
+	if dirEnd > len(subDirPaths) {
+		dirEnd = len(subDirPaths)
+	}
+	subDirPaths = subDirPaths[dirStart:dirEnd]
+	filePaths = filePaths[start-dirStart : end-dirEnd]
+
+	// Aggregate the files below the directory. Only the subdirectories of the
+	// page are aggregated separately.
+	dir := newDirAggregate(siaPath, len(n.subDirs))
+	subDirs := make(map[string]*dirAggregate, len(subDirPaths))
+	for _, path := range subDirPaths {
+		subDirs[path] = newDirAggregate(path, len(r.dirIndex[path].subDirs))
+	}
+	for path, da := range subDirs {
+		r.dirIndex.walk(path, func(subDir string, sn *dirNode) {
+			for name := range sn.files {
+				fi := r.fileInfo(r.files[name])
+				da.addFile(fi, subDir == path)
+				dir.addFile(fi, false)
+			}
+		})
+	}
+	for subDir := range n.subDirs {
+		if _, exists := subDirs[subDir]; exists {
+			continue
+		}
+		r.dirIndex.walk(subDir, func(_ string, sn *dirNode) {
+			for name := range sn.files {
+				dir.addFile(r.fileInfo(r.files[name]), false)
+			}
+		})
+	}
+	inPage := make(map[string]struct{}, len(filePaths))
+	for _, name := range filePaths {
+		inPage[name] = struct{}{}
+	}
+	files := make([]modules.FileInfo, 0, len(filePaths))
+	for name := range n.files {
+		fi := r.fileInfo(r.files[name])
+		dir.addFile(fi, true)
+		if _, exists := inPage[name]; exists {
+			files = append(files, fi)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].SiaPath < files[j].SiaPath })
+
+	dirs := make([]modules.DirectoryInfo, 0, len(subDirPaths))
+	for _, path := range subDirPaths {
+		dirs = append(dirs, subDirs[path].info)
+	}
+	return dir.info, dirs, files, nil
+}
+
+// moveDirEntries moves the files and explicitly created directories with the
+// provided paths from the directory at oldDir to newDir. The renter's lock
+// must be held by the caller.
+func (r *Renter) moveDirEntries(names, dirs []string, oldDir, newDir string) {
+	move := func(path string) string {
+		return movePath(path, oldDir, newDir)
+	}
+	for _, name := range names {
+		f := r.files[name]
+		f.mu.Lock()
+		f.name = move(name)
+		f.mu.Unlock()
+		r.removeFileEntry(name)
+		r.addFileEntry(f)
+		if t, exists := r.tracking[name]; exists {
+			delete(r.tracking, name)
+			r.tracking[move(name)] = t
+		}
+		if old, exists := r.reencoding[name]; exists {
+			old.mu.Lock()
+			old.name = move(name)
+			old.mu.Unlock()
+			delete(r.reencoding, name)
+			r.reencoding[move(name)] = old
+		}
+	}
+	for _, dir := range dirs {
+		delete(r.directories, dir)
+		r.dirIndex.removeDir(dir)
+	}
+	for _, dir := range dirs {
+		r.directories[move(dir)] = struct{}{}
+		r.dirIndex.addDir(move(dir))
+	}
+}
+
+// RenameDir changes the path of a directory, moving every file and directory
+// that it contains. The new path must not be in use by a file or directory.
+// Either the whole directory is moved, or nothing is.
+func (r *Renter) RenameDir(currentPath, newPath string) error {
+	currentPath = strings.TrimSuffix(currentPath, "/")
+	newPath = strings.TrimSuffix(newPath, "/")
+	if err := validateSiapath(currentPath); err != nil {
+		return err
+	}
+	if err := validateSiapath(newPath); err != nil {
+		return err
+	}
+	if strings.HasPrefix(newPath, dirPrefix(currentPath)) {
+		return errDirIntoItself
+	}
+
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	if !r.isDir(currentPath) {
+		return ErrUnknownDir
+	}
+	if err := r.checkPathAvailable(newPath); err != nil {
+		return err
+	}
+	names := r.filesInDir(currentPath)
+	dirs := r.explicitDirsInDir(currentPath)
+	move := func(path string) string {
+		return movePath(path, currentPath, newPath)
+	}
+
+	// Write the files under their new paths. The renter is not changed until
+	// all of them have been written, so that a failure leaves the directory
+	// as it was.
+	var written []string
+	removeWritten := func() {
+		for _, path := range written {
+			if err := os.RemoveAll(path); err != nil {
+				r.log.Println("WARN: couldn't remove file of directory that was not renamed:", err)
+			}
+		}
+	}
+	saveAs := func(f *file, name, newName, path string) error {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.name = newName
+		err := saveFileAs(f, path)
+		f.name = name
+		written = append(written, path)
+		return err
+	}
+	for _, name := range names {
+		err := saveAs(r.files[name], name, move(name), filepath.Join(r.persistDir, move(name)+ShareExtension))
+		if old, exists := r.reencoding[name]; exists && err == nil {
+			err = saveAs(old, name, move(name), r.reencodePath(move(name)))
+		}
+		if err != nil {
+			removeWritten()
+			return err
+		}
+	}
+
+	// Move the entries in the renter.
+	r.moveDirEntries(names, dirs, currentPath, newPath)
+	err := r.saveSync()
+	if err != nil {
+		newNames := make([]string, len(names))
+		for i, name := range names {
+			newNames[i] = move(name)
+		}
+		newDirs := make([]string, len(dirs))
+		for i, dir := range dirs {
+			newDirs[i] = move(dir)
+		}
+		r.moveDirEntries(newNames, newDirs, newPath, currentPath)
+		removeWritten()
+		return err
+	}
+
+	// Delete the old .sia files.
+	for _, name := range names {
+		if err := os.RemoveAll(filepath.Join(r.persistDir, name+ShareExtension)); err != nil {
+			r.log.Println("WARN: couldn't remove old file :", err)
+		}
+		if _, exists := r.reencoding[move(name)]; exists {
+			if err := os.RemoveAll(r.reencodePath(name)); err != nil {
+				r.log.Println("WARN: couldn't remove old layout of file :", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package renter
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestRenterDirs probes the directory methods of the renter type.
+func TestRenterDirs(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	// Add some files to the renter.
+	for _, name := range []string{"a", "foo/b", "foo/bar/c", "foo/bar/d"} {
+		f := newTestingFile()
+		f.name = name
+		rt.renter.addFileEntry(f)
+		if err := rt.renter.saveFile(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rt.renter.CreateDir("foo/empty"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Paths that are in use should be rejected.
+	if err := rt.renter.CreateDir("foo"); err != ErrDirExists {
+		t.Error("expected ErrDirExists, got", err)
+	}
+	if err := rt.renter.CreateDir("a"); err != ErrPathOverload {
+		t.Error("expected ErrPathOverload, got", err)
+	}
+	if err := rt.renter.CreateDir("a/b"); err != ErrParentIsFile {
+		t.Error("expected ErrParentIsFile, got", err)
+	}
+	if err := rt.renter.RenameFile("a", "foo/bar"); err != ErrDirExists {
+		t.Error("expected ErrDirExists, got", err)
+	}
+
+	// List the contents of a directory.
+	dir, dirs, files, err := rt.renter.DirList("foo", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir.NumFiles != 1 || dir.NumSubDirs != 2 || dir.AggregateNumFiles != 3 {
+		t.Errorf("wrong directory info: %+v", dir)
+	}
+	if len(dirs) != 2 || len(files) != 1 || files[0].SiaPath != "foo/b" {
+		t.Fatal("wrong directory contents:", dirs, files)
+	}
+	if _, _, _, err := rt.renter.DirList("dne", 0, 0); err != ErrUnknownDir {
+		t.Error("expected ErrUnknownDir, got", err)
+	}
+
+	// List a page of the contents, which spans the subdirectories and the
+	// files.
+	dir, dirs, files, err = rt.renter.DirList("foo", 1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dir.AggregateNumFiles != 3 || len(dirs) != 1 || dirs[0].SiaPath != "foo/empty" || len(files) != 1 {
+		t.Fatal("wrong page of directory contents:", dir, dirs, files)
+	}
+	if _, dirs, files, _ = rt.renter.DirList("foo", 5, 0); len(dirs) != 0 || len(files) != 0 {
+		t.Error("page after the end of the directory is not empty:", dirs, files)
+	}
+
+	// A rename that fails halfway leaves the directory as it was.
+	blocker := filepath.Join(rt.renter.persistDir, "qux")
+	if err := ioutil.WriteFile(blocker, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.renter.RenameDir("foo", "qux"); err == nil {
+		t.Fatal("expected rename to fail")
+	}
+	if len(rt.renter.filesInDir("foo")) != 3 || rt.renter.isDir("qux") {
+		t.Fatal("failed rename changed the directory")
+	}
+	if err := os.Remove(blocker); err != nil {
+		t.Fatal(err)
+	}
+
+	// Rename a directory.
+	if err := rt.renter.RenameDir("foo", "foo/baz"); err != errDirIntoItself {
+		t.Error("expected errDirIntoItself, got", err)
+	}
+	if err := rt.renter.RenameDir("foo", "qux"); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"qux/b", "qux/bar/c", "qux/bar/d"} {
+		if _, exists := rt.renter.files[name]; !exists {
+			t.Error("file was not renamed:", name)
+		}
+	}
+	if _, exists := rt.renter.directories["qux/empty"]; !exists {
+		t.Error("empty directory was not renamed")
+	}
+	if rt.renter.isDir("foo") {
+		t.Error("old directory still exists after rename")
+	}
+
+	// A delete that fails halfway leaves the directory as it was.
+	blocker = filepath.Join(rt.renter.persistDir, "qux", "bar", "d"+ShareExtension+deleteExtension, "x")
+	if err := os.MkdirAll(blocker, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.renter.DeleteDir("qux"); err == nil {
+		t.Fatal("expected delete to fail")
+	}
+	if len(rt.renter.filesInDir("qux")) != 3 || !rt.renter.isDir("qux/empty") {
+		t.Fatal("failed delete changed the directory")
+	}
+	for _, name := range []string{"qux/b", "qux/bar/c", "qux/bar/d"} {
+		if _, err := os.Stat(filepath.Join(rt.renter.persistDir, name+ShareExtension)); err != nil {
+			t.Error("file of directory was not restored:", err)
+		}
+	}
+	if err := os.RemoveAll(filepath.Dir(blocker)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Delete a directory.
+	if err := rt.renter.DeleteDir("qux"); err != nil {
+		t.Fatal(err)
+	}
+	if len(rt.renter.files) != 1 || len(rt.renter.directories) != 0 || len(rt.renter.dirIndex) != 1 {
+		t.Error("directory contents were not deleted:", rt.renter.files, rt.renter.directories, rt.renter.dirIndex)
+	}
+	if err := rt.renter.DeleteDir("qux"); err != ErrUnknownDir {
+		t.Error("expected ErrUnknownDir, got", err)
+	}
+}
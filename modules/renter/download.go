@@ -6,9 +6,11 @@ package renter
 
 import (
 	"bytes"
+	"encoding/hex"
 	"errors"
 	"io"
 	"os"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,6 +19,7 @@ import (
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
+	"github.com/NebulousLabs/fastrand"
 )
 
 const (
@@ -56,6 +59,17 @@ type (
 		// have tried to fetch a piece of the chunk.
 		completedPieces map[uint64][]byte
 		workerAttempts  map[types.FileContractID]bool
+
+		// If only part of the chunk is needed, and that part lies within a
+		// single data piece of a systematic erasure code, only that part of
+		// the piece is downloaded. partialPiece is the index of the piece,
+		// and partialOffset and partialLength are the range of the piece
+		// that is needed. partial is cleared if no worker can provide the
+		// range, and the chunk is then recovered from whole pieces.
+		partial       bool
+		partialPiece  uint64
+		partialOffset uint64
+		partialLength uint64
 	}
 
 	// A download is a file download that has been queued by the renter.
@@ -68,15 +82,24 @@ type (
 		offset             uint64
 		length             uint64
 
+		// paused indicates that no new chunks of the download should be
+		// scheduled. queuedChunks contains the chunks that have been added to
+		// the renter's chunk queue, so that resuming a download does not queue
+		// a chunk twice.
+		paused       bool
+		queuedChunks map[uint64]bool
+
 		// Timestamp information.
 		completeTime time.Time
 		startTime    time.Time
 
 		// Static information about the file - can be read without a lock.
+		chunkCache  *chunkCache
 		chunkSize   uint64
 		destination modules.DownloadWriter
 		erasureCode modules.ErasureCoder
 		fileSize    uint64
+		id          modules.DownloadID
 		masterKey   crypto.TwofishKey
 		numChunks   uint64
 
@@ -114,17 +137,24 @@ type (
 		//
 		// resultChan is the channel that is used to receive completed worker
 		// downloads.
-		activePieces     int
-		activeWorkers    map[types.FileContractID]struct{}
-		availableWorkers []*worker
-		incompleteChunks []*chunkDownload
-		resultChan       chan finishedDownload
+		//
+		// workerThroughput caches the download throughput of the hosts of the
+		// workers, which is used to order the available workers.
+		// throughputUpdated is the time at which the cache was last cleared.
+		activePieces      int
+		activeWorkers     map[types.FileContractID]struct{}
+		availableWorkers  []*worker
+		incompleteChunks  []*chunkDownload
+		resultChan        chan finishedDownload
+		workerThroughput  map[types.FileContractID]float64
+		throughputUpdated time.Time
 	}
 )
 
 // newSectionDownload initializes and returns a download object for the specified chunk.
 func (r *Renter) newSectionDownload(f *file, destination modules.DownloadWriter, offset, length uint64) *download {
 	d := newDownload(f, destination)
+	d.chunkCache = r.chunkCache
 
 	if length == 0 {
 		build.Critical("download length should not be zero")
@@ -157,11 +187,13 @@ func newDownload(f *file, destination modules.DownloadWriter) *download {
 		destination:      destination,
 		erasureCode:      f.erasureCode,
 		fileSize:         f.size,
+		id:               modules.DownloadID(hex.EncodeToString(fastrand.Bytes(8))),
 		masterKey:        f.masterKey,
 		numChunks:        f.numChunks(),
 		siapath:          f.name,
 		downloadFinished: make(chan struct{}),
 		finishedChunks:   make(map[uint64]bool),
+		queuedChunks:     make(map[uint64]bool),
 	}
 }
 
@@ -199,6 +231,33 @@ func (d *download) initPieceSet(f *file, r *Renter) {
 	f.mu.RUnlock()
 }
 
+// partialRange returns the range of a data piece that contains the part of the
+// chunk at chunkIndex that is needed by the download. False is returned if the
+// whole chunk is needed, if the needed part spans several pieces, or if the
+// erasure code is not systematic.
+func (d *download) partialRange(chunkIndex uint64) (piece, offset, length uint64, ok bool) {
+	if _, systematic := d.erasureCode.(modules.SystematicErasureCoder); !systematic {
+		return 0, 0, 0, false
+	}
+	chunkBase := chunkIndex * d.chunkSize
+	start, end := chunkBase, chunkBase+d.chunkSize
+	if d.offset > start {
+		start = d.offset
+	}
+	if d.offset+d.length < end {
+		end = d.offset + d.length
+	}
+	if start == chunkBase && end == chunkBase+d.chunkSize {
+		return 0, 0, 0, false
+	}
+	pieceSize := d.chunkSize / uint64(d.erasureCode.MinPieces())
+	piece = (start - chunkBase) / pieceSize
+	if (end-1-chunkBase)/pieceSize != piece {
+		return 0, 0, 0, false
+	}
+	return piece, start - chunkBase - piece*pieceSize, end - start, true
+}
+
 // Err returns the error encountered by a download, if it exists.
 func (d *download) Err() error {
 	d.mu.Lock()
@@ -267,7 +326,38 @@ func (cd *chunkDownload) recoverChunk() error {
 	}
 
 	result := recoverWriter.Bytes()
+	cd.download.chunkCache.add(chunkCacheID(cd.download.masterKey, cd.index), result)
+	return cd.writeChunk(result)
+}
 
+// recoverPartialChunk decrypts the range of a piece that was downloaded for a
+// partial chunk and writes it to the download's destination. data contains the
+// nonce of the piece, followed by the encrypted range. The data has already
+// been verified against the Merkle root of the piece.
+func (cd *chunkDownload) recoverPartialChunk(data []byte) error {
+	cd.download.mu.Lock()
+	complete := cd.download.downloadComplete
+	prevErr := cd.download.downloadErr
+	cd.download.mu.Unlock()
+	if complete {
+		return build.ComposeErrors(errPrevErr, prevErr)
+	}
+
+	if uint64(len(data)) != crypto.TwofishNonceSize+cd.partialLength {
+		return errors.New("downloaded range of the piece has the wrong size")
+	}
+	key := deriveKey(cd.download.masterKey, cd.index, cd.partialPiece)
+	plaintext, err := key.DecryptRange(data[:crypto.TwofishNonceSize], data[crypto.TwofishNonceSize:], cd.partialOffset)
+	if err != nil {
+		return build.ExtendErr("unable to decrypt piece", err)
+	}
+	pieceSize := cd.download.chunkSize / uint64(cd.download.erasureCode.MinPieces())
+	return cd.writeData(plaintext, cd.index*cd.download.chunkSize+cd.partialPiece*pieceSize+cd.partialOffset)
+}
+
+// writeChunk writes the part of a recovered chunk that was requested by the
+// download to the download's destination, and marks the chunk as finished.
+func (cd *chunkDownload) writeChunk(result []byte) error {
 	// Calculate the offset. If the offset is within the chunk, the
 	// requested offset is passed, otherwise the offset of the chunk
 	// within the overall file is passed.
@@ -292,10 +382,14 @@ func (cd *chunkDownload) recoverChunk() error {
 		upperBound = uint64(len(result))
 	}
 
-	result = result[lowerBound:upperBound]
+	return cd.writeData(result[lowerBound:upperBound], off)
+}
 
+// writeData writes data to the download's destination at offset off, and marks
+// the chunk as finished.
+func (cd *chunkDownload) writeData(data []byte, off uint64) error {
 	// Write the bytes to the requested output.
-	_, err = cd.download.destination.WriteAt(result, int64(off))
+	_, err := cd.download.destination.WriteAt(data, int64(off))
 	if err != nil {
 		return build.ExtendErr("unable to write to download destination", err)
 	}
@@ -336,18 +430,21 @@ func (r *Renter) addDownloadToChunkQueue(d *download) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
 
-	// Skip this file if it has already errored out or has already finished
-	// downloading.
-	if d.downloadComplete {
+	// Skip this file if it has already errored out, has already finished
+	// downloading or is paused. Paused downloads are added again when they
+	// are resumed.
+	if d.downloadComplete || d.paused {
 		return
 	}
 
 	// Add the unfinished chunks one at a time.
 	for i, isChunkFinished := range d.finishedChunks {
-		// Skip chunks that have already finished downloading.
-		if isChunkFinished {
+		// Skip chunks that have already finished downloading, and chunks
+		// that are still in the chunk queue.
+		if isChunkFinished || d.queuedChunks[i] {
 			continue
 		}
+		d.queuedChunks[i] = true
 
 		// Add this chunk to the chunk queue.
 		cd := &chunkDownload{
@@ -360,6 +457,7 @@ func (r *Renter) addDownloadToChunkQueue(d *download) {
 		for fcid := range d.pieceSet[i] {
 			cd.workerAttempts[fcid] = false
 		}
+		cd.partialPiece, cd.partialOffset, cd.partialLength, cd.partial = d.partialRange(i)
 		r.chunkQueue = append(r.chunkQueue, cd)
 	}
 }
@@ -409,6 +507,25 @@ func (r *Renter) managedDownloadIteration(ds *downloadState) {
 	}
 	r.mu.Unlock(id)
 
+	// Order the available workers by the download throughput of their hosts,
+	// so that pieces are fetched from the fastest hosts first. Hosts that have
+	// not been measured yet are tried after the measured hosts. The
+	// throughput is cached, and only fetched from the hostdb for new workers
+	// and once the cache has expired.
+	if time.Since(ds.throughputUpdated) > workerThroughputCacheTime {
+		ds.workerThroughput = make(map[types.FileContractID]float64)
+		ds.throughputUpdated = time.Now()
+	}
+	for _, worker := range ds.availableWorkers {
+		if _, cached := ds.workerThroughput[worker.contract.ID]; !cached {
+			host, _ := r.hostDB.Host(worker.hostPubKey)
+			ds.workerThroughput[worker.contract.ID] = host.DownloadThroughput
+		}
+	}
+	sort.SliceStable(ds.availableWorkers, func(i, j int) bool {
+		return ds.workerThroughput[ds.availableWorkers[i].contract.ID] > ds.workerThroughput[ds.availableWorkers[j].contract.ID]
+	})
+
 	// Add new chunks to the extent that resources allow.
 	r.managedScheduleNewChunks(ds)
 
@@ -444,7 +561,13 @@ loop:
 		}
 
 		// Try to find a worker that is able to pick up the slack on the
-		// incomplete download from the set of available workers.
+		// incomplete download from the set of available workers. If the
+		// erasure code is systematic, workers that hold data pieces are
+		// preferred, so that the chunk can be read without decoding.
+		_, systematic := incompleteChunk.download.erasureCode.(modules.SystematicErasureCoder)
+		dataPieces := uint64(incompleteChunk.download.erasureCode.MinPieces())
+		best := -1
+		var bestPiece pieceData
 		for i, worker := range ds.availableWorkers {
 			scheduled, exists := incompleteChunk.workerAttempts[worker.contract.ID]
 			if scheduled || !exists {
@@ -455,18 +578,29 @@ loop:
 			}
 
 			piece, exists := incompleteChunk.download.pieceSet[incompleteChunk.index][worker.contract.ID]
-			if !exists {
+			if !exists || (incompleteChunk.partial && piece.Piece != incompleteChunk.partialPiece) {
 				continue
 			}
-
+			if best == -1 || (bestPiece.Piece >= dataPieces && piece.Piece < dataPieces) {
+				best, bestPiece = i, piece
+			}
+			if !systematic || bestPiece.Piece < dataPieces {
+				break
+			}
+		}
+		if best != -1 {
+			worker, piece := ds.availableWorkers[best], bestPiece
 			dw := downloadWork{
 				dataRoot:      piece.MerkleRoot,
 				pieceIndex:    piece.Piece,
+				partial:       incompleteChunk.partial,
+				offset:        incompleteChunk.partialOffset,
+				length:        incompleteChunk.partialLength,
 				chunkDownload: incompleteChunk,
 				resultChan:    ds.resultChan,
 			}
 			incompleteChunk.workerAttempts[worker.contract.ID] = true
-			ds.availableWorkers = append(ds.availableWorkers[:i], ds.availableWorkers[i+1:]...)
+			ds.availableWorkers = append(ds.availableWorkers[:best], ds.availableWorkers[best+1:]...)
 			ds.activeWorkers[worker.contract.ID] = struct{}{}
 			select {
 			case worker.priorityDownloadChan <- dw:
@@ -481,8 +615,11 @@ loop:
 		// completed just not at this time.
 		for fcid := range ds.activeWorkers {
 			// Check whether a piece exists for this worker.
-			_, exists1 := incompleteChunk.download.pieceSet[incompleteChunk.index][fcid]
+			piece, exists1 := incompleteChunk.download.pieceSet[incompleteChunk.index][fcid]
 			scheduled, exists2 := incompleteChunk.workerAttempts[fcid]
+			if incompleteChunk.partial && piece.Piece != incompleteChunk.partialPiece {
+				continue
+			}
 			if !scheduled && exists1 && exists2 {
 				// This worker is able to complete the download for this chunk,
 				// but is busy. Keep this chunk until the next iteration of the
@@ -496,6 +633,17 @@ loop:
 		// or the active set is able to pick up the slack. Verify that they are
 		// safe to be scheduled, and then schedule them if so.
 
+		// If no worker can provide the range of a partial chunk, recover the
+		// chunk from whole pieces instead.
+		if incompleteChunk.partial {
+			incompleteChunk.partial = false
+			for i := 0; i < incompleteChunk.download.erasureCode.MinPieces(); i++ {
+				newIncompleteChunks = append(newIncompleteChunks, incompleteChunk)
+			}
+			ds.activePieces += incompleteChunk.download.erasureCode.MinPieces() - 1
+			continue
+		}
+
 		// Cannot find workers to complete this download, fail the download
 		// connected to this chunk.
 		r.log.Println("Not enough workers to finish download:", errInsufficientHosts)
@@ -525,8 +673,50 @@ func (r *Renter) managedScheduleNewChunks(ds *downloadState) {
 		// View the next chunk.
 		nextChunk := r.chunkQueue[0]
 
+		// Drop the chunks of paused downloads from the queue. They are added
+		// again when the download is resumed.
+		nextChunk.download.mu.Lock()
+		paused := nextChunk.download.paused
+		if paused {
+			nextChunk.download.queuedChunks[nextChunk.index] = false
+		}
+		nextChunk.download.mu.Unlock()
+		if paused {
+			r.chunkQueue = r.chunkQueue[1:]
+			continue
+		}
+
+		// Serve the chunk from the chunk cache if it was recovered recently.
+		// No pieces need to be downloaded for the chunk in that case.
+		data, cached := nextChunk.download.chunkCache.get(chunkCacheID(nextChunk.download.masterKey, nextChunk.index))
+		if cached {
+			r.chunkQueue = r.chunkQueue[1:]
+			nextChunk.download.mu.Lock()
+			downloadComplete := nextChunk.download.downloadComplete
+			nextChunk.download.mu.Unlock()
+			if downloadComplete {
+				continue
+			}
+			atomic.AddUint64(&nextChunk.download.atomicDataReceived, nextChunk.download.reportedPieceSize*uint64(nextChunk.download.erasureCode.MinPieces()))
+			if err := nextChunk.writeChunk(data); err != nil {
+				r.log.Println("Download failed - could not write a cached chunk:", err)
+				nextChunk.download.mu.Lock()
+				nextChunk.download.fail(err)
+				nextChunk.download.mu.Unlock()
+			}
+			if nextChunk.download.persisted() {
+				r.managedSaveDownloadProgress(nextChunk.download)
+			}
+			continue
+		}
+
 		// Check whether there are enough resources to perform the download.
-		if ds.activePieces+nextChunk.download.erasureCode.MinPieces() > maxActiveDownloadPieces {
+		// Partial chunks only need a single piece.
+		pieces := nextChunk.download.erasureCode.MinPieces()
+		if nextChunk.partial {
+			pieces = 1
+		}
+		if ds.activePieces+pieces > maxActiveDownloadPieces {
 			// There is a limited amount of RAM available, and scheduling the
 			// next piece would consume too much RAM.
 			return
@@ -546,10 +736,10 @@ func (r *Renter) managedScheduleNewChunks(ds *downloadState) {
 		}
 
 		// Add an incomplete chunk entry for every piece of the download.
-		for i := 0; i < nextChunk.download.erasureCode.MinPieces(); i++ {
+		for i := 0; i < pieces; i++ {
 			ds.incompleteChunks = append(ds.incompleteChunks, nextChunk)
 		}
-		ds.activePieces += nextChunk.download.erasureCode.MinPieces()
+		ds.activePieces += pieces
 	}
 }
 
@@ -595,6 +785,23 @@ func (r *Renter) managedWaitOnDownloadWork(ds *downloadState) {
 		return
 	}
 
+	// A partial chunk is complete once its range has been downloaded.
+	if finishedDownload.partial {
+		atomic.AddUint64(&cd.download.atomicDataReceived, cd.download.reportedPieceSize*uint64(cd.download.erasureCode.MinPieces()))
+		err := cd.recoverPartialChunk(finishedDownload.data)
+		ds.activePieces--
+		if err != nil {
+			r.log.Println("Download failed - could not recover a partial chunk:", err)
+			cd.download.mu.Lock()
+			cd.download.fail(err)
+			cd.download.mu.Unlock()
+		}
+		if cd.download.persisted() {
+			r.managedSaveDownloadProgress(cd.download)
+		}
+		return
+	}
+
 	// Add this returned piece to the appropriate chunk.
 	if _, ok := cd.completedPieces[finishedDownload.pieceIndex]; ok {
 		r.log.Debugln("Piece", finishedDownload.pieceIndex, "already added")
@@ -615,6 +822,12 @@ func (r *Renter) managedWaitOnDownloadWork(ds *downloadState) {
 			cd.download.fail(err)
 			cd.download.mu.Unlock()
 		}
+
+		// Record the progress of downloads that can be resumed after a
+		// restart.
+		if cd.download.persisted() {
+			r.managedSaveDownloadProgress(cd.download)
+		}
 	}
 }
 
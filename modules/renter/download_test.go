@@ -1,11 +1,16 @@
 package renter
 
 import (
+	"bytes"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/Sia/modules"
 )
 
 // TestRenterDownloadFileWriter verifies that the renter's DownloadFileWriter
@@ -50,3 +55,176 @@ func TestRenterDownloadFileWriter(t *testing.T) {
 		t.Fatal("expected read to return file already closed, got", err, "instead.")
 	}
 }
+
+// TestRenterDownloadQueuePersist probes pausing, persisting, resuming and
+// cancelling downloads in the download queue.
+func TestRenterDownloadQueuePersist(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	// Add a file with two chunks to the renter, and a download of the whole
+	// file to the download queue. The file has no hosts, so the download is
+	// paused before it is handed to the download loop.
+	rsc, _ := NewRSCode(1, 1)
+	f := newFile("foo", rsc, pieceSize, 2*pieceSize)
+	destination := filepath.Join(build.SiaTestingDir, "renter", t.Name(), "foo.dat")
+	dfw, err := NewDownloadFileWriter(destination, 0, f.size)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := rt.renter.mu.Lock()
+	rt.renter.files["foo"] = f
+	d := rt.renter.newSectionDownload(f, dfw, 0, f.size)
+	d.paused = true
+	rt.renter.downloadQueue = append(rt.renter.downloadQueue, d)
+	rt.renter.mu.Unlock(id)
+
+	// Pretend that the first chunk has been written.
+	d.mu.Lock()
+	d.finishedChunks[0] = true
+	d.mu.Unlock()
+	dfw.written += d.chunkOverlap(0)
+	if err := rt.renter.managedSaveDownloads(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Progress that is reported right after a save should not be saved
+	// again until downloadSaveInterval has passed.
+	downloadsPath := filepath.Join(rt.renter.persistDir, downloadsFilename)
+	saved, err := ioutil.ReadFile(downloadsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.mu.Lock()
+	d.paused = false
+	d.mu.Unlock()
+	rt.renter.managedSaveDownloadProgress(d)
+	if contents, err := ioutil.ReadFile(downloadsPath); err != nil {
+		t.Fatal(err)
+	} else if !bytes.Equal(contents, saved) {
+		t.Fatal("download queue was saved before downloadSaveInterval passed")
+	}
+	d.mu.Lock()
+	d.paused = true
+	d.mu.Unlock()
+	rt.renter.downloadSaveMu.Lock()
+	rt.renter.lastDownloadSave = time.Time{}
+	rt.renter.downloadSaveMu.Unlock()
+	rt.renter.managedSaveDownloadProgress(d)
+	rt.renter.downloadSaveMu.Lock()
+	lastSave := rt.renter.lastDownloadSave
+	rt.renter.downloadSaveMu.Unlock()
+	if lastSave.IsZero() {
+		t.Fatal("download queue was not saved after downloadSaveInterval passed")
+	}
+
+	// Pausing a paused download and resuming an unknown download should fail.
+	if err := rt.renter.PauseDownload(d.id); err != errDownloadPaused {
+		t.Fatal("expected errDownloadPaused, got", err)
+	}
+	if err := rt.renter.ResumeDownload("foo"); err != errUnknownDownload {
+		t.Fatal("expected errUnknownDownload, got", err)
+	}
+
+	// Reload the download queue, as if the renter had restarted.
+	id = rt.renter.mu.Lock()
+	rt.renter.downloadQueue = nil
+	err = rt.renter.loadDownloads()
+	rt.renter.mu.Unlock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue := rt.renter.DownloadQueue()
+	if len(queue) != 1 || queue[0].ID != d.id || !queue[0].Paused {
+		t.Fatal("download was not restored:", queue)
+	}
+	id = rt.renter.mu.RLock()
+	restored, _ := rt.renter.downloadByID(d.id)
+	rt.renter.mu.RUnlock(id)
+	if !restored.finishedChunks[0] || restored.finishedChunks[1] {
+		t.Fatal("progress of the download was not restored:", restored.finishedChunks)
+	}
+
+	// Cancel the download. It should no longer be persisted.
+	if err := rt.renter.CancelDownload(d.id); err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.Err(); err != errDownloadCancelled {
+		t.Fatal("expected errDownloadCancelled, got", err)
+	}
+	if err := rt.renter.CancelDownload(d.id); err != errDownloadComplete {
+		t.Fatal("expected errDownloadComplete, got", err)
+	}
+	id = rt.renter.mu.Lock()
+	rt.renter.downloadQueue = nil
+	err = rt.renter.loadDownloads()
+	rt.renter.mu.Unlock(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queue := rt.renter.DownloadQueue(); len(queue) != 0 {
+		t.Fatal("cancelled download was restored:", queue)
+	}
+}
+
+// TestDownloadPartialRange checks which parts of a chunk are downloaded as a
+// range of a single piece.
+func TestDownloadPartialRange(t *testing.T) {
+	systematic, err := NewSystematicRSCode(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rs, err := NewRSCode(2, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const pieceSize = 1000
+	tests := []struct {
+		name           string
+		ec             modules.ErasureCoder
+		offset, length uint64
+		piece, off     uint64
+		partial        bool
+	}{
+		{"whole chunk", systematic, 0, 2 * pieceSize, 0, 0, false},
+		{"start of first piece", systematic, 0, 10, 0, 0, true},
+		{"within second piece", systematic, pieceSize + 100, 200, 1, 100, true},
+		{"end of second piece", systematic, 2*pieceSize - 10, 10, 1, pieceSize - 10, true},
+		{"across pieces", systematic, pieceSize - 10, 20, 0, 0, false},
+		{"not systematic", rs, 0, 10, 0, 0, false},
+	}
+	for _, test := range tests {
+		d := &download{
+			chunkSize:   2 * pieceSize,
+			erasureCode: test.ec,
+			offset:      test.offset,
+			length:      test.length,
+		}
+		piece, off, length, partial := d.partialRange(0)
+		if partial != test.partial {
+			t.Errorf("%v: expected partial %v, got %v", test.name, test.partial, partial)
+		} else if partial && (piece != test.piece || off != test.off || length != test.length) {
+			t.Errorf("%v: got piece %v, offset %v, length %v", test.name, piece, off, length)
+		}
+	}
+
+	// Only the part of the chunk that belongs to the download is needed.
+	d := &download{
+		chunkSize:   2 * pieceSize,
+		erasureCode: systematic,
+		offset:      pieceSize + 100,
+		length:      10 * pieceSize,
+	}
+	if piece, off, length, partial := d.partialRange(0); !partial || piece != 1 || off != 100 || length != pieceSize-100 {
+		t.Errorf("got piece %v, offset %v, length %v, partial %v", piece, off, length, partial)
+	}
+	if _, _, _, partial := d.partialRange(1); partial {
+		t.Error("whole chunk should not be partial")
+	}
+}
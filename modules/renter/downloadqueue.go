@@ -3,17 +3,246 @@ package renter
 import (
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"sync/atomic"
+	"time"
 
 	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/persist"
 )
 
+const (
+	// downloadsFilename is the name of the file in the renter's persist
+	// directory that contains the downloads that can be resumed after a
+	// restart.
+	downloadsFilename = "downloads.json"
+)
+
+var (
+	errDownloadCancelled = errors.New("download was cancelled")
+	errDownloadComplete  = errors.New("download has already completed")
+	errDownloadNotPaused = errors.New("download is not paused")
+	errDownloadPaused    = errors.New("download is already paused")
+	errUnknownDownload   = errors.New("no download with that id")
+
+	downloadsMetadata = persist.Metadata{
+		Header:  "Renter Downloads",
+		Version: "1.3.1",
+	}
+)
+
+// persistedDownload is the persisted form of an in-progress download. Only
+// downloads to a file on disk are persisted, as the chunks that have already
+// been written to the file do not need to be downloaded again.
+type persistedDownload struct {
+	ID             modules.DownloadID `json:"id"`
+	SiaPath        string             `json:"siapath"`
+	Destination    string             `json:"destination"`
+	Offset         uint64             `json:"offset"`
+	Length         uint64             `json:"length"`
+	ChunkSize      uint64             `json:"chunksize"`
+	FinishedChunks []uint64           `json:"finishedchunks"`
+	Paused         bool               `json:"paused"`
+	StartTime      time.Time          `json:"starttime"`
+}
+
+// persisted returns whether the progress of the download is persisted, so
+// that the download can be resumed after a restart.
+func (d *download) persisted() bool {
+	_, ok := d.destination.(*DownloadFileWriter)
+	return ok
+}
+
+// chunkOverlap returns the number of bytes of the chunk at index that fall
+// within the download.
+func (d *download) chunkOverlap(index uint64) uint64 {
+	start, end := index*d.chunkSize, (index+1)*d.chunkSize
+	if start < d.offset {
+		start = d.offset
+	}
+	if end > d.offset+d.length {
+		end = d.offset + d.length
+	}
+	return end - start
+}
+
+// queueDownload sends a download to the download loop without blocking the
+// caller.
+func (r *Renter) queueDownload(d *download) {
+	go func() {
+		select {
+		case r.newDownloads <- d:
+		case <-r.tg.StopChan():
+		}
+	}()
+}
+
+// downloadByID returns the download in the download queue with the provided
+// id. The renter's lock must be held by the caller.
+func (r *Renter) downloadByID(id modules.DownloadID) (*download, bool) {
+	for _, d := range r.downloadQueue {
+		if d.id == id {
+			return d, true
+		}
+	}
+	return nil, false
+}
+
+// downloadsSnapshot returns the persisted form of every incomplete download
+// to a file, along with the destination of each download. The renter's lock
+// must be held by the caller.
+func (r *Renter) downloadsSnapshot() ([]persistedDownload, []*DownloadFileWriter) {
+	var persisted []persistedDownload
+	var destinations []*DownloadFileWriter
+	for _, d := range r.downloadQueue {
+		dfw, ok := d.destination.(*DownloadFileWriter)
+		if !ok {
+			continue
+		}
+		d.mu.Lock()
+		if d.downloadComplete {
+			d.mu.Unlock()
+			continue
+		}
+		pd := persistedDownload{
+			ID:          d.id,
+			SiaPath:     d.siapath,
+			Destination: dfw.location,
+			Offset:      d.offset,
+			Length:      d.length,
+			ChunkSize:   d.chunkSize,
+			Paused:      d.paused,
+			StartTime:   d.startTime,
+		}
+		for i, finished := range d.finishedChunks {
+			if finished {
+				pd.FinishedChunks = append(pd.FinishedChunks, i)
+			}
+		}
+		d.mu.Unlock()
+		persisted = append(persisted, pd)
+		destinations = append(destinations, dfw)
+	}
+	return persisted, destinations
+}
+
+// managedSaveDownloads persists the progress of every incomplete download to
+// a file. The destinations of the downloads are synced first, so that the
+// chunks that are recorded as finished are durable. The file IO is done
+// without holding the renter's lock.
+func (r *Renter) managedSaveDownloads() error {
+	// Saves are serialized, so that a snapshot of the queue is never
+	// overwritten by an older one.
+	r.downloadSaveMu.Lock()
+	defer r.downloadSaveMu.Unlock()
+
+	lockID := r.mu.RLock()
+	snapshot, destinations := r.downloadsSnapshot()
+	r.mu.RUnlock(lockID)
+
+	persisted := make([]persistedDownload, 0, len(snapshot))
+	for i, pd := range snapshot {
+		if err := destinations[i].f.Sync(); err != nil {
+			r.log.Println("WARN: could not sync download destination:", err)
+			continue
+		}
+		persisted = append(persisted, pd)
+	}
+	r.lastDownloadSave = time.Now()
+	return persist.SaveJSON(downloadsMetadata, persisted, filepath.Join(r.persistDir, downloadsFilename))
+}
+
+// managedSaveDownloadProgress persists the progress of the downloads after a
+// chunk of d has been written. To avoid syncing every destination for every
+// chunk, progress is saved at most once per downloadSaveInterval, unless d has
+// completed. Chunks that finish in between are downloaded again if the renter
+// does not shut down cleanly. Errors are logged rather than returned, as they
+// do not affect the downloads themselves.
+func (r *Renter) managedSaveDownloadProgress(d *download) {
+	d.mu.Lock()
+	complete := d.downloadComplete
+	d.mu.Unlock()
+	r.downloadSaveMu.Lock()
+	recent := time.Since(r.lastDownloadSave) < downloadSaveInterval
+	r.downloadSaveMu.Unlock()
+	if recent && !complete {
+		return
+	}
+	if err := r.managedSaveDownloads(); err != nil {
+		r.log.Println("WARN: could not save download queue:", err)
+	}
+}
+
+// loadDownloads restores the downloads that were in progress when the renter
+// was last shut down. The chunks that had already been written to the
+// destination of a download are not downloaded again. Downloads that can no
+// longer be resumed are logged and dropped.
+func (r *Renter) loadDownloads() error {
+	var persisted []persistedDownload
+	err := persist.LoadJSON(downloadsMetadata, &persisted, filepath.Join(r.persistDir, downloadsFilename))
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for _, pd := range persisted {
+		f, exists := r.files[pd.SiaPath]
+		if !exists {
+			r.log.Println("WARN: dropping download of missing file", pd.SiaPath)
+			continue
+		}
+		f = r.downloadLayout(f)
+		if pd.Length == 0 || pd.Offset+pd.Length > f.size {
+			r.log.Println("WARN: dropping download of", pd.SiaPath, "as the file has changed")
+			continue
+		}
+		dfw, err := NewDownloadFileWriter(pd.Destination, pd.Offset, pd.Length)
+		if err != nil {
+			r.log.Println("WARN: could not reopen download destination:", err)
+			continue
+		}
+		d := r.newSectionDownload(f, dfw, pd.Offset, pd.Length)
+		d.id = pd.ID
+		d.paused = pd.Paused
+		d.startTime = pd.StartTime
+
+		// If the file has been re-encoded since the download was started,
+		// its chunks are different and the download has to start over.
+		if pd.ChunkSize == d.chunkSize {
+			for _, i := range pd.FinishedChunks {
+				if finished, exists := d.finishedChunks[i]; !exists || finished {
+					continue
+				}
+				d.finishedChunks[i] = true
+				dfw.written += d.chunkOverlap(i)
+				atomic.AddUint64(&d.atomicDataReceived, d.reportedPieceSize*uint64(d.erasureCode.MinPieces()))
+			}
+		}
+		if dfw.written == d.length {
+			// The download finished before it could be removed from the
+			// persisted downloads.
+			dfw.Close()
+			continue
+		}
+
+		r.downloadQueue = append(r.downloadQueue, d)
+		if !d.paused {
+			r.queueDownload(d)
+		}
+	}
+	return nil
+}
+
 // Download performs a file download using the passed parameters.
 func (r *Renter) Download(p modules.RenterDownloadParameters) error {
 	// lookup the file associated with the nickname.
 	lockID := r.mu.RLock()
 	file, exists := r.files[p.Siapath]
+	if exists {
+		file = r.downloadLayout(file)
+	}
 	r.mu.RUnlock(lockID)
 	if !exists {
 		return errors.New(fmt.Sprintf("no file with that path: %s", p.Siapath))
@@ -65,6 +294,11 @@ func (r *Renter) Download(p modules.RenterDownloadParameters) error {
 	lockID = r.mu.Lock()
 	r.downloadQueue = append(r.downloadQueue, d)
 	r.mu.Unlock(lockID)
+	if d.persisted() {
+		if err := r.managedSaveDownloads(); err != nil {
+			r.log.Println("WARN: could not save download queue:", err)
+		}
+	}
 	r.newDownloads <- d
 
 	// Block until the download has completed.
@@ -89,12 +323,16 @@ func (r *Renter) DownloadQueue() []modules.DownloadInfo {
 	for i := range r.downloadQueue {
 		d := r.downloadQueue[len(r.downloadQueue)-i-1]
 
+		d.mu.Lock()
 		downloads[i] = modules.DownloadInfo{
+			ID:          d.id,
+			Paused:      d.paused,
 			SiaPath:     d.siapath,
 			Destination: d.destination,
 			Filesize:    d.length,
 			StartTime:   d.startTime,
 		}
+		d.mu.Unlock()
 		downloads[i].Received = atomic.LoadUint64(&d.atomicDataReceived)
 
 		if err := d.Err(); err != nil {
@@ -103,3 +341,69 @@ func (r *Renter) DownloadQueue() []modules.DownloadInfo {
 	}
 	return downloads
 }
+
+// CancelDownload cancels a download in the download queue. Chunks that have
+// already been written to the destination are left in place.
+func (r *Renter) CancelDownload(id modules.DownloadID) error {
+	lockID := r.mu.RLock()
+	d, exists := r.downloadByID(id)
+	r.mu.RUnlock(lockID)
+	if !exists {
+		return errUnknownDownload
+	}
+
+	d.mu.Lock()
+	complete := d.downloadComplete
+	d.fail(errDownloadCancelled)
+	d.mu.Unlock()
+	if complete {
+		return errDownloadComplete
+	}
+	return r.managedSaveDownloads()
+}
+
+// PauseDownload stops the renter from scheduling new chunks of a download.
+// Chunks that are already being downloaded are finished.
+func (r *Renter) PauseDownload(id modules.DownloadID) error {
+	lockID := r.mu.RLock()
+	d, exists := r.downloadByID(id)
+	r.mu.RUnlock(lockID)
+	if !exists {
+		return errUnknownDownload
+	}
+
+	d.mu.Lock()
+	complete, paused := d.downloadComplete, d.paused
+	if !complete {
+		d.paused = true
+	}
+	d.mu.Unlock()
+	if complete {
+		return errDownloadComplete
+	} else if paused {
+		return errDownloadPaused
+	}
+	return r.managedSaveDownloads()
+}
+
+// ResumeDownload resumes a paused download.
+func (r *Renter) ResumeDownload(id modules.DownloadID) error {
+	lockID := r.mu.RLock()
+	d, exists := r.downloadByID(id)
+	r.mu.RUnlock(lockID)
+	if !exists {
+		return errUnknownDownload
+	}
+
+	d.mu.Lock()
+	complete, paused := d.downloadComplete, d.paused
+	d.paused = false
+	d.mu.Unlock()
+	if complete {
+		return errDownloadComplete
+	} else if !paused {
+		return errDownloadNotPaused
+	}
+	r.queueDownload(d)
+	return r.managedSaveDownloads()
+}
@@ -1,6 +1,8 @@
 package renter
 
 import (
+	"errors"
+	"fmt"
 	"io"
 
 	"github.com/klauspost/reedsolomon"
@@ -8,6 +10,56 @@ import (
 	"github.com/NebulousLabs/Sia/modules"
 )
 
+// maxReplicationCopies is the largest number of copies a replication code can
+// store. Every copy is a full copy of the data, so larger values multiply the
+// cost of the file without a meaningful gain in reliability, and a corrupt
+// file header could otherwise make the renter allocate an unbounded number of
+// pieces.
+const maxReplicationCopies = 32
+
+var (
+	errNoDataPieces      = errors.New("not all data pieces are present")
+	errUnknownECType     = errors.New("unknown erasure code type")
+	errNotEnoughPieces   = errors.New("not enough pieces to recover data")
+	errTooFewCopies      = errors.New("replication code needs at least one copy")
+	errTooManyCopies     = fmt.Errorf("replication code can store at most %v copies", maxReplicationCopies)
+	errPieceSizeTooSmall = errors.New("piece is smaller than the requested data")
+)
+
+// erasureCoders contains a constructor for every known type of erasure coder.
+// The constructor creates an erasure coder from the parameters that are
+// returned by its Params method.
+var erasureCoders = map[modules.ErasureCoderType]func(params []uint64) (modules.ErasureCoder, error){
+	modules.ECReedSolomon: func(params []uint64) (modules.ErasureCoder, error) {
+		if len(params) != 2 {
+			return nil, fmt.Errorf("Reed-Solomon code expects 2 parameters, got %v", len(params))
+		}
+		return NewRSCode(int(params[0]), int(params[1]))
+	},
+	modules.ECSystematicRS: func(params []uint64) (modules.ErasureCoder, error) {
+		if len(params) != 2 {
+			return nil, fmt.Errorf("systematic Reed-Solomon code expects 2 parameters, got %v", len(params))
+		}
+		return NewSystematicRSCode(int(params[0]), int(params[1]))
+	},
+	modules.ECReplication: func(params []uint64) (modules.ErasureCoder, error) {
+		if len(params) != 1 {
+			return nil, fmt.Errorf("replication code expects 1 parameter, got %v", len(params))
+		}
+		return NewReplicationCode(int(params[0]))
+	},
+}
+
+// NewErasureCoder creates an erasure coder of the provided type using the
+// provided parameters.
+func NewErasureCoder(ecType modules.ErasureCoderType, params []uint64) (modules.ErasureCoder, error) {
+	newCoder, exists := erasureCoders[ecType]
+	if !exists {
+		return nil, errUnknownECType
+	}
+	return newCoder(params)
+}
+
 // rsCode is a Reed-Solomon encoder/decoder. It implements the
 // modules.ErasureCoder interface.
 type rsCode struct {
@@ -17,6 +69,14 @@ type rsCode struct {
 	dataPieces int
 }
 
+// Type returns the type of the erasure coder.
+func (rs *rsCode) Type() modules.ErasureCoderType { return modules.ECReedSolomon }
+
+// Params returns the number of data pieces and the number of parity pieces.
+func (rs *rsCode) Params() []uint64 {
+	return []uint64{uint64(rs.dataPieces), uint64(rs.numPieces - rs.dataPieces)}
+}
+
 // NumPieces returns the number of pieces returned by Encode.
 func (rs *rsCode) NumPieces() int { return rs.numPieces }
 
@@ -64,3 +124,112 @@ func NewRSCode(nData, nParity int) (modules.ErasureCoder, error) {
 		dataPieces: nData,
 	}, nil
 }
+
+// systematicRSCode is a Reed-Solomon encoder/decoder that keeps the original
+// data in its first MinPieces pieces. It implements the
+// modules.SystematicErasureCoder interface.
+type systematicRSCode struct {
+	rsCode
+}
+
+// Type returns the type of the erasure coder.
+func (rs *systematicRSCode) Type() modules.ErasureCoderType { return modules.ECSystematicRS }
+
+// ReadData writes the first n bytes of the original data to w by
+// concatenating the data pieces.
+func (rs *systematicRSCode) ReadData(pieces [][]byte, n uint64, w io.Writer) error {
+	if len(pieces) < rs.dataPieces {
+		return errNoDataPieces
+	}
+	for _, piece := range pieces[:rs.dataPieces] {
+		if piece == nil {
+			return errNoDataPieces
+		}
+	}
+	return rs.enc.Join(w, pieces, int(n))
+}
+
+// Recover recovers the original data from pieces and writes it to w. If all
+// of the data pieces are present, the data is read from them directly.
+func (rs *systematicRSCode) Recover(pieces [][]byte, n uint64, w io.Writer) error {
+	if err := rs.ReadData(pieces, n, w); err != errNoDataPieces {
+		return err
+	}
+	return rs.rsCode.Recover(pieces, n, w)
+}
+
+// NewSystematicRSCode creates a new systematic Reed-Solomon encoder/decoder
+// using the supplied parameters.
+func NewSystematicRSCode(nData, nParity int) (modules.ErasureCoder, error) {
+	enc, err := reedsolomon.New(nData, nParity)
+	if err != nil {
+		return nil, err
+	}
+	return &systematicRSCode{
+		rsCode: rsCode{
+			enc:        enc,
+			numPieces:  nData + nParity,
+			dataPieces: nData,
+		},
+	}, nil
+}
+
+// replicationCode is an encoder/decoder that stores a full copy of the data in
+// every piece. It is much cheaper than a Reed-Solomon code, which makes it a
+// better fit for small files. It implements the modules.ErasureCoder
+// interface.
+type replicationCode struct {
+	copies int
+}
+
+// Type returns the type of the erasure coder.
+func (rc *replicationCode) Type() modules.ErasureCoderType { return modules.ECReplication }
+
+// Params returns the number of copies.
+func (rc *replicationCode) Params() []uint64 { return []uint64{uint64(rc.copies)} }
+
+// NumPieces returns the number of pieces returned by Encode.
+func (rc *replicationCode) NumPieces() int { return rc.copies }
+
+// MinPieces return the minimum number of pieces that must be present to
+// recover the original data.
+func (rc *replicationCode) MinPieces() int { return 1 }
+
+// Encode returns a copy of data for every piece.
+func (rc *replicationCode) Encode(data []byte) ([][]byte, error) {
+	if len(data) == 0 {
+		return nil, errors.New("cannot encode empty data")
+	}
+	pieces := make([][]byte, rc.copies)
+	for i := range pieces {
+		pieces[i] = append([]byte(nil), data...)
+	}
+	return pieces, nil
+}
+
+// Recover writes the first n bytes of any of the pieces to w.
+func (rc *replicationCode) Recover(pieces [][]byte, n uint64, w io.Writer) error {
+	for _, piece := range pieces {
+		if piece == nil {
+			continue
+		}
+		if uint64(len(piece)) < n {
+			return errPieceSizeTooSmall
+		}
+		_, err := w.Write(piece[:n])
+		return err
+	}
+	return errNotEnoughPieces
+}
+
+// NewReplicationCode creates a new replication encoder/decoder that stores the
+// supplied number of copies, which must be between 1 and maxReplicationCopies.
+func NewReplicationCode(copies int) (modules.ErasureCoder, error) {
+	if copies < 1 {
+		return nil, errTooFewCopies
+	}
+	if copies > maxReplicationCopies {
+		return nil, errTooManyCopies
+	}
+	return &replicationCode{copies: copies}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"io/ioutil"
 	"testing"
 
+	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/fastrand"
 )
 
@@ -57,6 +58,116 @@ func TestRSEncode(t *testing.T) {
 	}
 }
 
+// TestSystematicRSCode tests the systematicRSCode type.
+func TestSystematicRSCode(t *testing.T) {
+	ec, err := NewSystematicRSCode(10, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, ok := ec.(modules.SystematicErasureCoder)
+	if !ok {
+		t.Fatal("systematic code does not implement modules.SystematicErasureCoder")
+	}
+
+	data := fastrand.Bytes(777)
+	pieces, err := src.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The data pieces should contain the original data.
+	buf := new(bytes.Buffer)
+	if err := src.ReadData(pieces, 777, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("data read from data pieces does not match original")
+	}
+
+	// Reading the data directly should fail if a data piece is missing, but
+	// it should still be recoverable.
+	pieces[0], pieces[12] = nil, nil
+	if err := src.ReadData(pieces, 777, new(bytes.Buffer)); err != errNoDataPieces {
+		t.Fatal("expected errNoDataPieces, got", err)
+	}
+	buf.Reset()
+	if err := src.Recover(pieces, 777, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("recovered data does not match original")
+	}
+}
+
+// TestReplicationCode tests the replicationCode type.
+func TestReplicationCode(t *testing.T) {
+	if _, err := NewReplicationCode(0); err != errTooFewCopies {
+		t.Fatal("expected errTooFewCopies, got", err)
+	}
+	if _, err := NewReplicationCode(maxReplicationCopies + 1); err != errTooManyCopies {
+		t.Fatal("expected errTooManyCopies, got", err)
+	}
+	rc, err := NewReplicationCode(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rc.NumPieces() != 3 || rc.MinPieces() != 1 {
+		t.Fatal("replication code has wrong number of pieces")
+	}
+
+	data := fastrand.Bytes(777)
+	pieces, err := rc.Encode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pieces) != 3 {
+		t.Fatal("expected 3 pieces, got", len(pieces))
+	}
+	// Modifying one copy should not affect the others.
+	pieces[0][0]++
+
+	// The data should be recoverable from any single piece.
+	pieces[0], pieces[1] = nil, nil
+	buf := new(bytes.Buffer)
+	if err := rc.Recover(pieces, 777, buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, buf.Bytes()) {
+		t.Fatal("recovered data does not match original")
+	}
+	if err := rc.Recover(make([][]byte, 3), 777, buf); err != errNotEnoughPieces {
+		t.Fatal("expected errNotEnoughPieces, got", err)
+	}
+}
+
+// TestNewErasureCoder checks that every erasure coder can be recreated from
+// its type and parameters.
+func TestNewErasureCoder(t *testing.T) {
+	rsc, _ := NewRSCode(10, 3)
+	src, _ := NewSystematicRSCode(4, 2)
+	rc, _ := NewReplicationCode(5)
+	for _, ec := range []modules.ErasureCoder{rsc, src, rc} {
+		ec2, err := NewErasureCoder(ec.Type(), ec.Params())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameErasureCode(ec, ec2) || ec.NumPieces() != ec2.NumPieces() || ec.MinPieces() != ec2.MinPieces() {
+			t.Fatal("recreated erasure coder does not match original:", ec.Type())
+		}
+	}
+
+	if _, err := NewErasureCoder(modules.ErasureCoderType{'f', 'o', 'o'}, nil); err != errUnknownECType {
+		t.Fatal("expected errUnknownECType, got", err)
+	}
+	if _, err := NewErasureCoder(modules.ECReplication, []uint64{1, 2}); err == nil {
+		t.Fatal("expected error for wrong number of parameters")
+	}
+	// A file header with too many copies is rejected.
+	if _, err := NewErasureCoder(modules.ECReplication, []uint64{1 << 40}); err != errTooManyCopies {
+		t.Fatal("expected errTooManyCopies, got", err)
+	}
+}
+
 func BenchmarkRSEncode(b *testing.B) {
 	rsc, err := NewRSCode(80, 20)
 	if err != nil {
@@ -40,9 +40,9 @@ type file struct {
 // A fileContract is a contract covering an arbitrary number of file pieces.
 // Chunk/Piece metadata is used to split the raw contract data appropriately.
 type fileContract struct {
-	ID     types.FileContractID
-	IP     modules.NetAddress
-	Pieces []pieceData
+	ID            types.FileContractID
+	HostPublicKey types.SiaPublicKey
+	Pieces        []pieceData
 
 	WindowStart types.BlockHeight
 }
@@ -133,7 +133,7 @@ func (f *file) redundancy(isOffline func(types.FileContractID) bool) float64 {
 	if f.size == 0 {
 		return -1
 	}
-	piecesPerChunk := make([]int, f.numChunks())
+	piecesPerChunk := f.chunkPieces(isOffline)
 	// If the file has non-0 size then the number of chunks should also be
 	// non-0. Therefore the f.size == 0 conditional block above must appear
 	// before this check.
@@ -141,6 +141,19 @@ func (f *file) redundancy(isOffline func(types.FileContractID) bool) float64 {
 		build.Critical("cannot get redundancy of a file with 0 chunks")
 		return -1
 	}
+	minPieces := piecesPerChunk[0]
+	for _, numPieces := range piecesPerChunk {
+		if numPieces < minPieces {
+			minPieces = numPieces
+		}
+	}
+	return float64(minPieces) / float64(f.erasureCode.MinPieces())
+}
+
+// chunkPieces returns the number of pieces of every chunk of the file that
+// are stored on hosts that are online.
+func (f *file) chunkPieces(isOffline func(types.FileContractID) bool) []int {
+	piecesPerChunk := make([]int, f.numChunks())
 	for _, fc := range f.contracts {
 		// do not count pieces from the contract if the contract is offline
 		if isOffline(fc.ID) {
@@ -150,13 +163,23 @@ func (f *file) redundancy(isOffline func(types.FileContractID) bool) float64 {
 			piecesPerChunk[p.Chunk]++
 		}
 	}
-	minPieces := piecesPerChunk[0]
-	for _, numPieces := range piecesPerChunk {
-		if numPieces < minPieces {
-			minPieces = numPieces
+	return piecesPerChunk
+}
+
+// chunksBelowThreshold returns the number of chunks of the file that have
+// fewer pieces than the repair threshold.
+func (f *file) chunksBelowThreshold(isOffline func(types.FileContractID) bool) uint64 {
+	if f.size == 0 {
+		return 0
+	}
+	threshold := repairThreshold(f.erasureCode.MinPieces(), f.erasureCode.NumPieces())
+	var below uint64
+	for _, pieces := range f.chunkPieces(isOffline) {
+		if pieces < threshold {
+			below++
 		}
 	}
-	return float64(minPieces) / float64(f.erasureCode.MinPieces())
+	return below
 }
 
 // expiration returns the lowest height at which any of the file's contracts
@@ -198,13 +221,20 @@ func (r *Renter) DeleteFile(nickname string) error {
 		r.mu.Unlock(lockID)
 		return ErrUnknownPath
 	}
-	delete(r.files, nickname)
+	r.removeFileEntry(nickname)
 	delete(r.tracking, nickname)
 
 	err := persist.RemoveFile(filepath.Join(r.persistDir, f.name+ShareExtension))
 	if err != nil {
 		r.log.Println("WARN: couldn't remove file :", err)
 	}
+	if _, exists := r.reencoding[nickname]; exists {
+		delete(r.reencoding, nickname)
+		err = persist.RemoveFile(r.reencodePath(nickname))
+		if err != nil {
+			r.log.Println("WARN: couldn't remove old layout of file :", err)
+		}
+	}
 
 	r.saveSync()
 	r.mu.Unlock(lockID)
@@ -218,6 +248,55 @@ func (r *Renter) DeleteFile(nickname string) error {
 	return nil
 }
 
+// isOffline reports whether the host of the provided contract is considered
+// offline. The most recent renewal of the contract is used.
+func (r *Renter) isOffline(id types.FileContractID) bool {
+	id = r.hostContractor.ResolveID(id)
+	return r.hostContractor.IsOffline(id)
+}
+
+// fileInfo returns the information about a file that is presented to the
+// user. The renter's lock must be held by the caller.
+func (r *Renter) fileInfo(f *file) modules.FileInfo {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	renewing := true
+	var localPath string
+	tf, exists := r.tracking[f.name]
+	if exists {
+		localPath = tf.RepairPath
+	}
+	fi := modules.FileInfo{
+		SiaPath:        f.name,
+		LocalPath:      localPath,
+		Filesize:       f.size,
+		Renewing:       renewing,
+		Available:      f.available(r.isOffline),
+		Redundancy:     f.redundancy(r.isOffline),
+		UploadedBytes:  f.uploadedBytes(),
+		UploadProgress: f.uploadProgress(),
+		Expiration:     f.expiration(),
+		Priority:       tf.Priority,
+		UploadPaused:   tf.Paused,
+
+		ChunksBelowThreshold: f.chunksBelowThreshold(r.isOffline),
+		LastRepair:           tf.LastRepair,
+		Stuck:                tf.Stuck,
+	}
+
+	// While a file is being re-encoded, its data can still be retrieved using
+	// the old layout.
+	if old, exists := r.reencoding[f.name]; exists {
+		old.mu.RLock()
+		fi.Available = fi.Available || old.available(r.isOffline)
+		if redundancy := old.redundancy(r.isOffline); redundancy > fi.Redundancy {
+			fi.Redundancy = redundancy
+		}
+		old.mu.RUnlock()
+	}
+	return fi
+}
+
 // FileList returns all of the files that the renter has.
 func (r *Renter) FileList() []modules.FileInfo {
 	var files []*file
@@ -227,35 +306,11 @@ func (r *Renter) FileList() []modules.FileInfo {
 	}
 	r.mu.RUnlock(lockID)
 
-	isOffline := func(id types.FileContractID) bool {
-		id = r.hostContractor.ResolveID(id)
-		offline := r.hostContractor.IsOffline(id)
-		return offline
-	}
-
 	var fileList []modules.FileInfo
 	for _, f := range files {
-		lockId := r.mu.RLock()
-		f.mu.RLock()
-		renewing := true
-		var localPath string
-		tf, exists := r.tracking[f.name]
-		if exists {
-			localPath = tf.RepairPath
-		}
-		fileList = append(fileList, modules.FileInfo{
-			SiaPath:        f.name,
-			LocalPath:      localPath,
-			Filesize:       f.size,
-			Renewing:       renewing,
-			Available:      f.available(isOffline),
-			Redundancy:     f.redundancy(isOffline),
-			UploadedBytes:  f.uploadedBytes(),
-			UploadProgress: f.uploadProgress(),
-			Expiration:     f.expiration(),
-		})
-		f.mu.RUnlock()
-		r.mu.RUnlock(lockId)
+		lockID := r.mu.RLock()
+		fileList = append(fileList, r.fileInfo(f))
+		r.mu.RUnlock(lockID)
 	}
 	return fileList
 }
@@ -272,14 +327,14 @@ func (r *Renter) RenameFile(currentName, newName string) error {
 		return ErrEmptyFilename
 	}
 
-	// Check that currentName exists and newName doesn't.
+	// Check that currentName exists and newName is not in use by another
+	// file or directory.
 	file, exists := r.files[currentName]
 	if !exists {
 		return ErrUnknownPath
 	}
-	_, exists = r.files[newName]
-	if exists {
-		return ErrPathOverload
+	if err := r.checkPathAvailable(newName); err != nil {
+		return err
 	}
 
 	// Modify the file and save it to disk.
@@ -291,9 +346,25 @@ func (r *Renter) RenameFile(currentName, newName string) error {
 		return err
 	}
 
+	// Move the old layout of the file if it is being re-encoded.
+	if old, ok := r.reencoding[currentName]; ok {
+		old.mu.Lock()
+		old.name = newName
+		err = saveFileAs(old, r.reencodePath(newName))
+		old.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		delete(r.reencoding, currentName)
+		r.reencoding[newName] = old
+		if err := os.RemoveAll(r.reencodePath(currentName)); err != nil {
+			r.log.Println("WARN: couldn't remove old layout of file :", err)
+		}
+	}
+
 	// Update the entries in the renter.
-	delete(r.files, currentName)
-	r.files[newName] = file
+	r.removeFileEntry(currentName)
+	r.addFileEntry(file)
 	if t, ok := r.tracking[currentName]; ok {
 		delete(r.tracking, currentName)
 		r.tracking[newName] = t
@@ -79,7 +79,6 @@ func TestFileUploadedBytes(t *testing.T) {
 	f.contracts = make(map[types.FileContractID]fileContract)
 	f.contracts[types.FileContractID{}] = fileContract{
 		ID:     types.FileContractID{},
-		IP:     modules.NetAddress(""),
 		Pieces: make([]pieceData, 4),
 	}
 	if f.uploadedBytes() != 4*modules.SectorSize {
@@ -95,7 +94,6 @@ func TestFileUploadProgressPinning(t *testing.T) {
 	f.contracts = make(map[types.FileContractID]fileContract)
 	f.contracts[types.FileContractID{}] = fileContract{
 		ID:     types.FileContractID{},
-		IP:     modules.NetAddress(""),
 		Pieces: make([]pieceData, 4),
 	}
 	rsc, _ := NewRSCode(1, 1)
@@ -439,7 +437,7 @@ func TestRenterRenameFile(t *testing.T) {
 	}
 
 	// Renaming should also update the tracking set
-	rt.renter.tracking["1"] = trackedFile{"foo"}
+	rt.renter.tracking["1"] = trackedFile{RepairPath: "foo"}
 	err = rt.renter.RenameFile("1", "1b")
 	if err != nil {
 		t.Fatal(err)
@@ -0,0 +1,96 @@
+package renter
+
+// health.go reports the health of the renter's files. A chunk is below the
+// repair threshold once more than a quarter of its redundancy beyond the
+// minimum is missing; such chunks are repaired even if their file is not
+// available locally. Files whose chunks below the threshold repeatedly fail to
+// be repaired are marked stuck. The outcome of a repair is recorded once the
+// workers are done with the chunk, and the stuck mark is cleared when a health
+// scan finds that none of the file's chunks are below the threshold.
+
+import (
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// repairThreshold returns the number of pieces below which a chunk with the
+// provided erasure coding parameters needs to be repaired.
+func repairThreshold(minPieces, numPieces int) int {
+	return numPieces - (numPieces-minPieces)/4
+}
+
+// clearStuck clears the stuck mark of f once none of its chunks are below the
+// repair threshold, for example because the hosts storing its pieces came back
+// online. It returns true if the mark was cleared. The renter's lock must be
+// held by the caller.
+func (r *Renter) clearStuck(f *file) bool {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	tf, exists := r.tracking[f.name]
+	if !exists || !tf.Stuck || f.chunksBelowThreshold(r.isOffline) > 0 {
+		return false
+	}
+	tf.Stuck = false
+	tf.RepairFailures = 0
+	r.tracking[f.name] = tf
+	r.log.Println("INFO: stuck file has become healthy", f.name)
+	return true
+}
+
+// managedRecordRepair records the outcome of an attempt to repair a chunk of
+// f that was below the repair threshold. The file is marked stuck after
+// maxRepairFailures consecutive failed repairs, and is no longer stuck once a
+// repair succeeds.
+func (r *Renter) managedRecordRepair(f *file, success bool) {
+	id := r.mu.Lock()
+	defer r.mu.Unlock(id)
+	f.mu.RLock()
+	name := f.name
+	f.mu.RUnlock()
+
+	tf, exists := r.tracking[name]
+	if !exists {
+		return
+	}
+	wasStuck := tf.Stuck
+	if success {
+		tf.RepairFailures = 0
+	} else {
+		tf.RepairFailures++
+	}
+	tf.Stuck = tf.RepairFailures >= maxRepairFailures
+	r.tracking[name] = tf
+	if tf.Stuck == wasStuck {
+		return
+	}
+	if tf.Stuck {
+		r.log.Printf("WARN: marked %v as stuck after %v failed repairs\n", name, tf.RepairFailures)
+	} else {
+		r.log.Println("INFO: repaired stuck file", name)
+	}
+	if err := r.saveSync(); err != nil {
+		r.log.Println("ERROR: unable to save the renter after a file became stuck:", err)
+	}
+}
+
+// Health returns a summary of the health of the renter's files.
+func (r *Renter) Health() modules.RenterHealth {
+	var health modules.RenterHealth
+	health.MinRedundancy = -1
+	for _, fi := range r.FileList() {
+		health.NumFiles++
+		if fi.Redundancy >= 0 && (health.MinRedundancy < 0 || fi.Redundancy < health.MinRedundancy) {
+			health.MinRedundancy = fi.Redundancy
+		}
+		if fi.ChunksBelowThreshold > 0 {
+			health.UnhealthyFiles++
+			health.ChunksBelowThreshold += fi.ChunksBelowThreshold
+		}
+		if fi.LastRepair.After(health.LastRepair) {
+			health.LastRepair = fi.LastRepair
+		}
+		if fi.Stuck {
+			health.StuckFiles = append(health.StuckFiles, fi)
+		}
+	}
+	return health
+}
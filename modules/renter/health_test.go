@@ -0,0 +1,176 @@
+package renter
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// TestChunksBelowThreshold checks that chunks are counted as below the repair
+// threshold once more than a quarter of their redundancy is missing.
+func TestChunksBelowThreshold(t *testing.T) {
+	if threshold := repairThreshold(10, 30); threshold != 25 {
+		t.Fatal("expected a threshold of 25 pieces, got", threshold)
+	}
+
+	neverOffline := func(types.FileContractID) bool { return false }
+	rsc, _ := NewRSCode(2, 6)
+	f := &file{
+		size:        200,
+		pieceSize:   50,
+		contracts:   make(map[types.FileContractID]fileContract),
+		erasureCode: rsc,
+	}
+	if below := f.chunksBelowThreshold(neverOffline); below != f.numChunks() {
+		t.Fatal("expected all chunks to be below the threshold, got", below)
+	}
+
+	// Upload 7 of the 8 pieces of the first chunk and 6 pieces of the second.
+	// The threshold is 7 pieces.
+	for i := 0; i < 7; i++ {
+		fc := fileContract{ID: types.FileContractID{byte(i)}}
+		fc.Pieces = append(fc.Pieces, pieceData{Chunk: 0, Piece: uint64(i)})
+		if i < 6 {
+			fc.Pieces = append(fc.Pieces, pieceData{Chunk: 1, Piece: uint64(i)})
+		}
+		f.contracts[fc.ID] = fc
+	}
+	if below := f.chunksBelowThreshold(neverOffline); below != 1 {
+		t.Fatal("expected 1 chunk below the threshold, got", below)
+	}
+	// Pieces on offline hosts do not count.
+	offline := func(id types.FileContractID) bool { return id == types.FileContractID{0} }
+	if below := f.chunksBelowThreshold(offline); below != 2 {
+		t.Fatal("expected 2 chunks below the threshold, got", below)
+	}
+}
+
+// TestRecordRepair checks that a file is marked stuck after repeated failed
+// repairs, and that a successful repair clears the mark.
+func TestRecordRepair(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	rsc, _ := NewRSCode(1, 1)
+	f := newFile("foo", rsc, pieceSize, 1)
+	id := rt.renter.mu.Lock()
+	rt.renter.files["foo"] = f
+	rt.renter.tracking["foo"] = trackedFile{}
+	rt.renter.mu.Unlock(id)
+
+	for i := 0; i < maxRepairFailures-1; i++ {
+		rt.renter.managedRecordRepair(f, false)
+	}
+	if len(rt.renter.Health().StuckFiles) != 0 {
+		t.Fatal("file was marked stuck too early")
+	}
+	rt.renter.managedRecordRepair(f, false)
+	health := rt.renter.Health()
+	if len(health.StuckFiles) != 1 || health.StuckFiles[0].SiaPath != "foo" || health.UnhealthyFiles != 1 {
+		t.Fatal("file was not marked stuck:", health)
+	}
+
+	rt.renter.managedRecordRepair(f, true)
+	if len(rt.renter.Health().StuckFiles) != 0 {
+		t.Fatal("stuck mark was not cleared after a successful repair")
+	}
+}
+
+// TestRepairOutcome checks that the outcome of a repair is recorded once the
+// last worker drops the chunk, based on the pieces that were uploaded.
+func TestRepairOutcome(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	rsc, _ := NewRSCode(1, 1)
+	f := newFile("foo", rsc, pieceSize, 1)
+	id := rt.renter.mu.Lock()
+	rt.renter.files["foo"] = f
+	rt.renter.tracking["foo"] = trackedFile{}
+	rt.renter.mu.Unlock(id)
+	w := &worker{renter: rt.renter}
+
+	// A chunk whose workers all failed to upload a piece is a failed repair,
+	// even though it was distributed to the workers.
+	newChunk := func() *unfinishedChunk {
+		return &unfinishedChunk{
+			renterFile:        f,
+			minimumPieces:     1,
+			piecesNeeded:      1,
+			pieceUsage:        []bool{false},
+			physicalChunkData: [][]byte{nil},
+			workersRemaining:  2,
+			repairing:         true,
+		}
+	}
+	uc := newChunk()
+	rt.renter.heapWG.Add(2)
+	w.dropChunk(uc)
+	if failures := rt.renter.tracking["foo"].RepairFailures; failures != 0 {
+		t.Fatal("repair was recorded before the last worker dropped the chunk:", failures)
+	}
+	w.dropChunk(uc)
+	if failures := rt.renter.tracking["foo"].RepairFailures; failures != 1 {
+		t.Fatal("failed repair was not recorded:", failures)
+	}
+
+	// A chunk that got its pieces uploaded is a successful repair.
+	uc = newChunk()
+	uc.workersRemaining = 1
+	uc.piecesCompleted = 1
+	rt.renter.heapWG.Add(1)
+	w.dropChunk(uc)
+	if failures := rt.renter.tracking["foo"].RepairFailures; failures != 0 {
+		t.Fatal("successful repair was not recorded:", failures)
+	}
+}
+
+// TestClearStuck checks that a health scan clears the stuck mark of a file
+// once none of its chunks are below the repair threshold.
+func TestClearStuck(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	// The file has no pieces on any host, so its chunk stays below the
+	// threshold and the file stays stuck.
+	rsc, _ := NewRSCode(1, 1)
+	f := newFile("foo", rsc, pieceSize, 1)
+	id := rt.renter.mu.Lock()
+	rt.renter.files["foo"] = f
+	rt.renter.tracking["foo"] = trackedFile{RepairFailures: maxRepairFailures, Stuck: true}
+	rt.renter.mu.Unlock(id)
+	rt.renter.managedBuildChunkHeap(make(map[string]struct{}))
+	if len(rt.renter.Health().StuckFiles) != 1 {
+		t.Fatal("unhealthy file is no longer stuck")
+	}
+
+	// An empty file has no chunks below the threshold.
+	f.mu.Lock()
+	f.size = 0
+	f.mu.Unlock()
+	rt.renter.managedBuildChunkHeap(make(map[string]struct{}))
+	if len(rt.renter.Health().StuckFiles) != 0 {
+		t.Fatal("healthy file is still stuck")
+	}
+	if failures := rt.renter.tracking["foo"].RepairFailures; failures != 0 {
+		t.Fatal("repair failures were not reset:", failures)
+	}
+}
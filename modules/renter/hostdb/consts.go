@@ -15,6 +15,10 @@ const (
 	// interactions required before decay is applied.
 	historicInteractionDecayLimit = 500
 
+	// hostPerformanceDecay is the weight of the previous value of a rolling
+	// performance statistic when a new measurement is added to it.
+	hostPerformanceDecay = 0.8
+
 	// hostRequestTimeout indicates how long a host has to respond to a dial.
 	hostRequestTimeout = 2 * time.Minute
 
@@ -22,6 +26,16 @@ const (
 	// scan.
 	hostScanDeadline = 4 * time.Minute
 
+	// ipv4SubnetBits and ipv6SubnetBits are the prefix lengths of the subnets
+	// that hosts are grouped by. Hosts in the same subnet are likely operated
+	// from the same machine or network.
+	ipv4SubnetBits = 24
+	ipv6SubnetBits = 54
+
+	// maxIPHistoryLen is the number of sets of resolved IP addresses that are
+	// kept in the IP history of a host.
+	maxIPHistoryLen = 10
+
 	// maxHostDowntime specifies the maximum amount of time that a host is
 	// allowed to be offline while still being in the hostdb.
 	maxHostDowntime = 10 * 24 * time.Hour
@@ -15,6 +15,7 @@ type (
 		dialTimeout(modules.NetAddress, time.Duration) (net.Conn, error)
 		disrupt(string) bool
 		loadFile(persist.Metadata, interface{}, string) error
+		lookupIP(string) ([]net.IP, error)
 		saveFileSync(persist.Metadata, interface{}, string) error
 		sleep(time.Duration)
 	}
@@ -32,6 +33,8 @@ func (prodDependencies) loadFile(meta persist.Metadata, data interface{}, filena
 	return persist.LoadJSON(meta, data, filename)
 }
 
+func (prodDependencies) lookupIP(host string) ([]net.IP, error) { return net.LookupIP(host) }
+
 func (prodDependencies) saveFileSync(meta persist.Metadata, data interface{}, filename string) error {
 	return persist.SaveJSON(meta, data, filename)
 }
@@ -19,8 +19,9 @@ import (
 )
 
 var (
-	errNilCS      = errors.New("cannot create hostdb with nil consensus set")
-	errNilGateway = errors.New("cannot create hostdb with nil gateway")
+	errEmptyWhitelist = errors.New("cannot enable whitelist without any hosts")
+	errNilCS          = errors.New("cannot create hostdb with nil consensus set")
+	errNilGateway     = errors.New("cannot create hostdb with nil gateway")
 )
 
 // The HostDB is a database of potential hosts. It assigns a weight to each
@@ -50,6 +51,16 @@ type HostDB struct {
 	scanWait        bool
 	scanningThreads int
 
+	// filterMode determines whether the hosts in filteredHosts are the only
+	// hosts that may be selected, or the hosts that may never be selected.
+	// filteredHosts is keyed by the string representation of the public key.
+	filterMode    modules.FilterMode
+	filteredHosts map[string]types.SiaPublicKey
+
+	// weightProfile determines how the adjustments of a host are combined
+	// into its weight in the host tree.
+	weightProfile modules.HostWeightProfile
+
 	blockHeight types.BlockHeight
 	lastChange  modules.ConsensusChangeID
 }
@@ -78,7 +89,9 @@ func newHostDB(g modules.Gateway, cs modules.ConsensusSet, persistDir string, de
 		gateway:    g,
 		persistDir: persistDir,
 
-		scanMap: make(map[string]struct{}),
+		filteredHosts: make(map[string]types.SiaPublicKey),
+		scanMap:       make(map[string]struct{}),
+		weightProfile: modules.DefaultHostWeightProfile,
 	}
 
 	// Create the persist directory if it does not yet exist.
@@ -169,6 +182,18 @@ func newHostDB(g modules.Gateway, cs modules.ConsensusSet, persistDir string, de
 // ActiveHosts returns a list of hosts that are currently online, sorted by
 // weight.
 func (hdb *HostDB) ActiveHosts() (activeHosts []modules.HostDBEntry) {
+	activeHosts = hdb.activeHosts()
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	for i := range activeHosts {
+		activeHosts[i].Filtered = hdb.isFiltered(activeHosts[i].PublicKey)
+	}
+	return activeHosts
+}
+
+// activeHosts returns the hosts that are currently online and accepting
+// contracts, without marking the filtered hosts.
+func (hdb *HostDB) activeHosts() (activeHosts []modules.HostDBEntry) {
 	allHosts := hdb.hostTree.All()
 	for _, entry := range allHosts {
 		if len(entry.ScanHistory) == 0 {
@@ -188,13 +213,19 @@ func (hdb *HostDB) ActiveHosts() (activeHosts []modules.HostDBEntry) {
 // AllHosts returns all of the hosts known to the hostdb, including the
 // inactive ones.
 func (hdb *HostDB) AllHosts() (allHosts []modules.HostDBEntry) {
-	return hdb.hostTree.All()
+	allHosts = hdb.hostTree.All()
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	for i := range allHosts {
+		allHosts[i].Filtered = hdb.isFiltered(allHosts[i].PublicKey)
+	}
+	return allHosts
 }
 
 // AverageContractPrice returns the average price of a host.
 func (hdb *HostDB) AverageContractPrice() (totalPrice types.Currency) {
 	sampleSize := 32
-	hosts := hdb.hostTree.SelectRandom(sampleSize, nil)
+	hosts := hdb.hostTree.SelectRandom(sampleSize, nil, nil)
 	if len(hosts) == 0 {
 		return totalPrice
 	}
@@ -218,13 +249,82 @@ func (hdb *HostDB) Host(spk types.SiaPublicKey) (modules.HostDBEntry, bool) {
 	}
 	hdb.mu.RLock()
 	updateHostHistoricInteractions(&host, hdb.blockHeight)
+	host.Filtered = hdb.isFiltered(spk)
 	hdb.mu.RUnlock()
 	return host, exists
 }
 
 // RandomHosts implements the HostDB interface's RandomHosts() method. It takes
 // a number of hosts to return, and a slice of netaddresses to ignore, and
-// returns a slice of entries.
+// returns a slice of entries. Hosts that are excluded by the filter mode are
+// never returned, and neither are hosts that share a subnet with an ignored
+// host or with another returned host.
 func (hdb *HostDB) RandomHosts(n int, excludeKeys []types.SiaPublicKey) []modules.HostDBEntry {
-	return hdb.hostTree.SelectRandom(n, excludeKeys)
+	blacklist := append([]types.SiaPublicKey(nil), excludeKeys...)
+	hdb.mu.RLock()
+	switch hdb.filterMode {
+	case modules.HostDBFilterBlacklist:
+		for _, spk := range hdb.filteredHosts {
+			blacklist = append(blacklist, spk)
+		}
+	case modules.HostDBFilterWhitelist:
+		for _, host := range hdb.hostTree.All() {
+			if _, exists := hdb.filteredHosts[host.PublicKey.String()]; !exists {
+				blacklist = append(blacklist, host.PublicKey)
+			}
+		}
+	}
+	hdb.mu.RUnlock()
+	return hdb.hostTree.SelectRandom(n, blacklist, excludeKeys)
+}
+
+// isFiltered returns true if the host is excluded by the filter mode. The
+// hostdb's lock must be held by the caller.
+func (hdb *HostDB) isFiltered(spk types.SiaPublicKey) bool {
+	_, listed := hdb.filteredHosts[spk.String()]
+	switch hdb.filterMode {
+	case modules.HostDBFilterBlacklist:
+		return listed
+	case modules.HostDBFilterWhitelist:
+		return !listed
+	default:
+		return false
+	}
+}
+
+// Filter returns the filter mode of the hostdb and the hosts on its filter
+// list.
+func (hdb *HostDB) Filter() (modules.FilterMode, []types.SiaPublicKey) {
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	hosts := make([]types.SiaPublicKey, 0, len(hdb.filteredHosts))
+	for _, spk := range hdb.filteredHosts {
+		hosts = append(hosts, spk)
+	}
+	return hdb.filterMode, hosts
+}
+
+// SetFilterMode sets the filter mode of the hostdb and replaces its filter
+// list with the provided hosts. Disabling the filter clears the list.
+func (hdb *HostDB) SetFilterMode(fm modules.FilterMode, hosts []types.SiaPublicKey) error {
+	switch fm {
+	case modules.HostDBFilterDisabled:
+		hosts = nil
+	case modules.HostDBFilterBlacklist:
+	case modules.HostDBFilterWhitelist:
+		if len(hosts) == 0 {
+			return errEmptyWhitelist
+		}
+	default:
+		return modules.ErrUnknownFilterMode
+	}
+
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	hdb.filterMode = fm
+	hdb.filteredHosts = make(map[string]types.SiaPublicKey)
+	for _, spk := range hosts {
+		hdb.filteredHosts[spk.String()] = spk
+	}
+	return hdb.saveSync()
 }
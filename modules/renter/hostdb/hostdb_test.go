@@ -39,7 +39,8 @@ type hdbTester struct {
 // dependencies or scanning threads. It is only intended for use in unit tests.
 func bareHostDB() *HostDB {
 	hdb := &HostDB{
-		log: persist.NewLogger(ioutil.Discard),
+		log:           persist.NewLogger(ioutil.Discard),
+		weightProfile: modules.DefaultHostWeightProfile,
 	}
 	hdb.hostTree = hosttree.New(hdb.calculateHostWeight)
 	return hdb
@@ -393,6 +394,84 @@ func TestRandomHosts(t *testing.T) {
 	}
 }
 
+// TestRandomHostsFilterMode checks that RandomHosts respects the filter mode of
+// the hostdb, and that the filter mode persists.
+func TestRandomHostsFilterMode(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	hdbt, err := newHDBTesterDeps(t.Name(), disableScanLoopDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []types.SiaPublicKey
+	for i := 0; i < 10; i++ {
+		entry := makeHostDBEntry()
+		keys = append(keys, entry.PublicKey)
+		if err := hdbt.hdb.hostTree.Insert(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A whitelist requires at least one host.
+	if err := hdbt.hdb.SetFilterMode(modules.HostDBFilterWhitelist, nil); err != errEmptyWhitelist {
+		t.Fatal("expected errEmptyWhitelist, got", err)
+	}
+
+	// Blacklisted hosts should never be selected.
+	if err := hdbt.hdb.SetFilterMode(modules.HostDBFilterBlacklist, keys[:3]); err != nil {
+		t.Fatal(err)
+	}
+	hosts := hdbt.hdb.RandomHosts(len(keys), nil)
+	if len(hosts) != len(keys)-3 {
+		t.Fatalf("RandomHosts returned %v hosts, expected %v", len(hosts), len(keys)-3)
+	}
+	for _, host := range hosts {
+		for _, key := range keys[:3] {
+			if host.PublicKey.String() == key.String() {
+				t.Fatal("RandomHosts returned a blacklisted host")
+			}
+		}
+	}
+	if host, _ := hdbt.hdb.Host(keys[0]); !host.Filtered {
+		t.Fatal("blacklisted host is not marked as filtered")
+	}
+
+	// Only whitelisted hosts should be selected.
+	if err := hdbt.hdb.SetFilterMode(modules.HostDBFilterWhitelist, keys[:3]); err != nil {
+		t.Fatal(err)
+	}
+	hosts = hdbt.hdb.RandomHosts(len(keys), nil)
+	if len(hosts) != 3 {
+		t.Fatalf("RandomHosts returned %v hosts, expected 3", len(hosts))
+	}
+	if host, _ := hdbt.hdb.Host(keys[5]); !host.Filtered {
+		t.Fatal("host missing from the whitelist is not marked as filtered")
+	}
+
+	// The filter should persist.
+	if err := hdbt.hdb.Close(); err != nil {
+		t.Fatal(err)
+	}
+	hdbt.hdb, err = newHostDB(hdbt.gateway, hdbt.cs, filepath.Join(hdbt.persistDir, modules.RenterDir), quitAfterLoadDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm, filtered := hdbt.hdb.Filter()
+	if fm != modules.HostDBFilterWhitelist || len(filtered) != 3 {
+		t.Fatalf("filter was not persisted: mode %v with %v hosts", fm, len(filtered))
+	}
+
+	// Disabling the filter clears the filter list.
+	if err := hdbt.hdb.SetFilterMode(modules.HostDBFilterDisabled, keys); err != nil {
+		t.Fatal(err)
+	}
+	if fm, filtered = hdbt.hdb.Filter(); fm != modules.HostDBFilterDisabled || len(filtered) != 0 {
+		t.Fatal("disabling the filter did not clear the filter list")
+	}
+}
+
 // TestRemoveNonexistingHostFromHostTree checks that the host tree interface
 // correctly responds to having a nonexisting host removed from the host tree.
 func TestRemoveNonexistingHostFromHostTree(t *testing.T) {
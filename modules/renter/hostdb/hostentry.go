@@ -2,6 +2,7 @@ package hostdb
 
 import (
 	"math"
+	"time"
 
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
@@ -112,3 +113,54 @@ func (hdb *HostDB) IncrementFailedInteractions(key types.SiaPublicKey) {
 	host.RecentFailedInteractions++
 	hdb.hostTree.Modify(host)
 }
+
+// rollingAverage adds a measurement to a rolling average. The first
+// measurement becomes the average.
+func rollingAverage(average, measurement float64) float64 {
+	if average == 0 {
+		return measurement
+	}
+	return average*hostPerformanceDecay + measurement*(1-hostPerformanceDecay)
+}
+
+// updateHostLatency adds a round trip time measurement to the rolling latency
+// of a host.
+func updateHostLatency(host *modules.HostDBEntry, rtt time.Duration) {
+	host.Latency = time.Duration(rollingAverage(float64(host.Latency), float64(rtt)))
+}
+
+// throughput returns the throughput of a transfer in bytes per second.
+func throughput(bytes uint64, d time.Duration) float64 {
+	if d <= 0 {
+		d = time.Nanosecond
+	}
+	return float64(bytes) / d.Seconds()
+}
+
+// RecordUpload adds the throughput of an upload to a host to the host's
+// rolling upload throughput.
+func (hdb *HostDB) RecordUpload(key types.SiaPublicKey, bytes uint64, d time.Duration) {
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+
+	host, haveHost := hdb.hostTree.Select(key)
+	if !haveHost {
+		return
+	}
+	host.UploadThroughput = rollingAverage(host.UploadThroughput, throughput(bytes, d))
+	hdb.hostTree.Modify(host)
+}
+
+// RecordDownload adds the throughput of a download from a host to the host's
+// rolling download throughput.
+func (hdb *HostDB) RecordDownload(key types.SiaPublicKey, bytes uint64, d time.Duration) {
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+
+	host, haveHost := hdb.hostTree.Select(key)
+	if !haveHost {
+		return
+	}
+	host.DownloadThroughput = rollingAverage(host.DownloadThroughput, throughput(bytes, d))
+	hdb.hostTree.Modify(host)
+}
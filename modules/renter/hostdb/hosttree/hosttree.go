@@ -263,6 +263,32 @@ func (ht *HostTree) Modify(hdbe modules.HostDBEntry) error {
 	return nil
 }
 
+// SetWeightFunction replaces the weight function of the host tree and rebuilds
+// the tree, recalculating the weight of every host.
+func (ht *HostTree) SetWeightFunction(wf WeightFunc) {
+	ht.mu.Lock()
+	defer ht.mu.Unlock()
+
+	var entries []modules.HostDBEntry
+	for _, node := range ht.hosts {
+		entries = append(entries, node.entry.HostDBEntry)
+	}
+
+	ht.root = &node{
+		count: 1,
+	}
+	ht.hosts = make(map[string]*node)
+	ht.weightFn = wf
+	for _, hdbe := range entries {
+		entry := &hostEntry{
+			HostDBEntry: hdbe,
+			weight:      wf(hdbe),
+		}
+		_, node := ht.root.recursiveInsert(entry)
+		ht.hosts[string(entry.PublicKey.Key)] = node
+	}
+}
+
 // Select returns the host with the provided public key, should the host exist.
 func (ht *HostTree) Select(spk types.SiaPublicKey) (modules.HostDBEntry, bool) {
 	ht.mu.Lock()
@@ -278,15 +304,29 @@ func (ht *HostTree) Select(spk types.SiaPublicKey) (modules.HostDBEntry, bool) {
 // SelectRandom grabs a random n hosts from the tree. There will be no repeats, but
 // the length of the slice returned may be less than n, and may even be zero.
 // The hosts that are returned first have the higher priority. Hosts passed to
-// 'ignore' will not be considered; pass `nil` if no blacklist is desired.
-func (ht *HostTree) SelectRandom(n int, ignore []types.SiaPublicKey) []modules.HostDBEntry {
+// 'blacklist' will not be considered; pass `nil` if no blacklist is desired.
+// Hosts that share a subnet with a host passed to 'addressBlacklist', or with
+// a host that was already selected, will not be returned either, so that the
+// returned hosts are not operated from the same machine or network.
+func (ht *HostTree) SelectRandom(n int, blacklist, addressBlacklist []types.SiaPublicKey) []modules.HostDBEntry {
 	ht.mu.Lock()
 	defer ht.mu.Unlock()
 
 	var hosts []modules.HostDBEntry
 	var removedEntries []*hostEntry
 
-	for _, pubkey := range ignore {
+	usedIPNets := make(map[string]struct{})
+	for _, pubkey := range addressBlacklist {
+		node, exists := ht.hosts[string(pubkey.Key)]
+		if !exists {
+			continue
+		}
+		for _, ipNet := range node.entry.IPNets {
+			usedIPNets[ipNet] = struct{}{}
+		}
+	}
+
+	for _, pubkey := range blacklist {
 		node, exists := ht.hosts[string(pubkey.Key)]
 		if !exists {
 			continue
@@ -302,10 +342,14 @@ func (ht *HostTree) SelectRandom(n int, ignore []types.SiaPublicKey) []modules.H
 
 		if node.entry.AcceptingContracts &&
 			len(node.entry.ScanHistory) > 0 &&
-			node.entry.ScanHistory[len(node.entry.ScanHistory)-1].Success {
-			// The host must be online and accepting contracts to be returned
-			// by the random function.
+			node.entry.ScanHistory[len(node.entry.ScanHistory)-1].Success &&
+			!sharesIPNet(node.entry.IPNets, usedIPNets) {
+			// The host must be online, accepting contracts and in an unused
+			// subnet to be returned by the random function.
 			hosts = append(hosts, node.entry.HostDBEntry)
+			for _, ipNet := range node.entry.IPNets {
+				usedIPNets[ipNet] = struct{}{}
+			}
 		}
 
 		removedEntries = append(removedEntries, node.entry)
@@ -320,3 +364,13 @@ func (ht *HostTree) SelectRandom(n int, ignore []types.SiaPublicKey) []modules.H
 
 	return hosts
 }
+
+// sharesIPNet returns true if any of the subnets in ipNets is in usedIPNets.
+func sharesIPNet(ipNets []string, usedIPNets map[string]struct{}) bool {
+	for _, ipNet := range ipNets {
+		if _, exists := usedIPNets[ipNet]; exists {
+			return true
+		}
+	}
+	return false
+}
@@ -35,7 +35,7 @@ func verifyTree(tree *HostTree, nentries int) error {
 		selectionMap := make(map[string]int)
 		expected := 100
 		for i := 0; i < expected*nentries; i++ {
-			entries := tree.SelectRandom(1, nil)
+			entries := tree.SelectRandom(1, nil, nil)
 			if len(entries) == 0 {
 				return errors.New("no hosts")
 			}
@@ -222,7 +222,7 @@ func TestHostTreeParallel(t *testing.T) {
 
 					// FETCH
 					case 3:
-						tree.SelectRandom(3, nil)
+						tree.SelectRandom(3, nil, nil)
 					}
 				}
 			}
@@ -281,6 +281,41 @@ func TestHostTreeModify(t *testing.T) {
 	}
 }
 
+// TestHostTreeSetWeightFunction checks that replacing the weight function of
+// the tree recalculates the weight of every host.
+func TestHostTreeSetWeightFunction(t *testing.T) {
+	tree := New(func(dbe modules.HostDBEntry) types.Currency {
+		return types.NewCurrency64(10)
+	})
+
+	treeSize := 50
+	for i := 0; i < treeSize; i++ {
+		err := tree.Insert(makeHostDBEntry())
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tree.SetWeightFunction(func(dbe modules.HostDBEntry) types.Currency {
+		return types.NewCurrency64(30)
+	})
+	if tree.root.weight.Cmp(types.NewCurrency64(30*uint64(treeSize))) != 0 {
+		t.Fatal("tree was not reweighted, total weight is", tree.root.weight)
+	}
+	if err := verifyTree(tree, treeSize); err != nil {
+		t.Fatal(err)
+	}
+
+	// New entries should use the new weight function.
+	entry := makeHostDBEntry()
+	if err := tree.Insert(entry); err != nil {
+		t.Fatal(err)
+	}
+	if tree.hosts[string(entry.PublicKey.Key)].entry.weight.Cmp(types.NewCurrency64(30)) != 0 {
+		t.Fatal("inserted entry does not use the new weight function")
+	}
+}
+
 // TestVariedWeights runs broad statistical tests on selecting hosts with
 // multiple different weights.
 func TestVariedWeights(t *testing.T) {
@@ -310,7 +345,7 @@ func TestVariedWeights(t *testing.T) {
 	// time.
 	selectionMap := make(map[string]int)
 	for i := 0; i < selections; i++ {
-		randEntry := tree.SelectRandom(1, nil)
+		randEntry := tree.SelectRandom(1, nil, nil)
 		if len(randEntry) == 0 {
 			t.Fatal("no hosts!")
 		}
@@ -389,7 +424,7 @@ func TestRandomHosts(t *testing.T) {
 	})
 
 	// Empty.
-	hosts := tree.SelectRandom(1, nil)
+	hosts := tree.SelectRandom(1, nil, nil)
 	if len(hosts) != 0 {
 		t.Errorf("empty hostdb returns %v hosts: %v", len(hosts), hosts)
 	}
@@ -418,13 +453,13 @@ func TestRandomHosts(t *testing.T) {
 	}
 
 	// Grab 1 random host.
-	randHosts := tree.SelectRandom(1, nil)
+	randHosts := tree.SelectRandom(1, nil, nil)
 	if len(randHosts) != 1 {
 		t.Error("didn't get 1 hosts")
 	}
 
 	// Grab 2 random hosts.
-	randHosts = tree.SelectRandom(2, nil)
+	randHosts = tree.SelectRandom(2, nil, nil)
 	if len(randHosts) != 2 {
 		t.Error("didn't get 2 hosts")
 	}
@@ -433,7 +468,7 @@ func TestRandomHosts(t *testing.T) {
 	}
 
 	// Grab 3 random hosts.
-	randHosts = tree.SelectRandom(3, nil)
+	randHosts = tree.SelectRandom(3, nil, nil)
 	if len(randHosts) != 3 {
 		t.Error("didn't get 3 hosts")
 	}
@@ -443,7 +478,7 @@ func TestRandomHosts(t *testing.T) {
 	}
 
 	// Grab 4 random hosts. 3 should be returned.
-	randHosts = tree.SelectRandom(4, nil)
+	randHosts = tree.SelectRandom(4, nil, nil)
 	if len(randHosts) != 3 {
 		t.Error("didn't get 3 hosts")
 	}
@@ -458,13 +493,13 @@ func TestRandomHosts(t *testing.T) {
 		randHosts[0].PublicKey,
 		randHosts[1].PublicKey,
 		randHosts[2].PublicKey,
-	})
+	}, nil)
 	if len(uniqueHosts) != 0 {
 		t.Error("didn't get 0 hosts")
 	}
 
 	// Ask for 3 hosts, blacklisting non-existent hosts. 3 should be returned.
-	randHosts = tree.SelectRandom(3, []types.SiaPublicKey{{}, {}, {}})
+	randHosts = tree.SelectRandom(3, []types.SiaPublicKey{{}, {}, {}}, nil)
 	if len(randHosts) != 3 {
 		t.Error("didn't get 3 hosts")
 	}
@@ -473,3 +508,53 @@ func TestRandomHosts(t *testing.T) {
 		t.Error("doubled up")
 	}
 }
+
+// TestSelectRandomIPNets checks that SelectRandom does not return multiple
+// hosts from the same subnet, or hosts that share a subnet with a host on the
+// address blacklist.
+func TestSelectRandomIPNets(t *testing.T) {
+	tree := New(func(dbe modules.HostDBEntry) types.Currency {
+		return types.NewCurrency64(20)
+	})
+
+	// Create two hosts in one subnet, two hosts in another subnet and a host
+	// with an unresolved address.
+	var entries []modules.HostDBEntry
+	for _, ipNets := range [][]string{{"1.2.3.0/24"}, {"1.2.3.0/24"}, {"4.5.6.0/24"}, {"4.5.6.0/24", "7.8.9.0/24"}, nil} {
+		entry := makeHostDBEntry()
+		entry.IPNets = ipNets
+		entries = append(entries, entry)
+		if err := tree.Insert(entry); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// At most one host of each subnet should be returned.
+	for i := 0; i < 10; i++ {
+		hosts := tree.SelectRandom(len(entries), nil, nil)
+		if len(hosts) != 3 {
+			t.Fatalf("expected 3 hosts, got %v", len(hosts))
+		}
+		used := make(map[string]struct{})
+		for _, host := range hosts {
+			if sharesIPNet(host.IPNets, used) {
+				t.Fatal("SelectRandom returned two hosts in the same subnet")
+			}
+			for _, ipNet := range host.IPNets {
+				used[ipNet] = struct{}{}
+			}
+		}
+	}
+
+	// Hosts that share a subnet with a host on the address blacklist should
+	// not be returned.
+	hosts := tree.SelectRandom(len(entries), nil, []types.SiaPublicKey{entries[3].PublicKey})
+	if len(hosts) != 2 {
+		t.Fatalf("expected 2 hosts, got %v", len(hosts))
+	}
+	for _, host := range hosts {
+		if sharesIPNet(host.IPNets, map[string]struct{}{"4.5.6.0/24": {}, "7.8.9.0/24": {}}) {
+			t.Fatal("SelectRandom returned a host that shares a subnet with the address blacklist")
+		}
+	}
+}
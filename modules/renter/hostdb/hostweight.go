@@ -1,14 +1,22 @@
 package hostdb
 
 import (
+	"errors"
 	"math"
 	"math/big"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/types"
 )
 
+var (
+	errInvalidWeightExponent   = errors.New("weight exponents must be finite and non-negative")
+	errInvalidWeightMultiplier = errors.New("weight multipliers must be finite and positive")
+	errUnnamedWeightProfile    = errors.New("weight profile must have a name")
+)
+
 var (
 	// Because most weights would otherwise be fractional, we set the base
 	// weight to be very large.
@@ -32,6 +40,16 @@ var (
 	// of storage changes, and as the price of the siacoin changes.
 	minTotalPrice = types.SiacoinPrecision.Mul64(25).Div64(tbMonth)
 
+	// performanceLatencyTarget and performanceThroughputTarget are the
+	// latency and the upload and download throughput that a host needs to
+	// avoid a performance penalty.
+	performanceLatencyTarget    = 200 * time.Millisecond
+	performanceThroughputTarget = float64(1 << 20) // 1 MiB/s
+
+	// minPerformanceAdjustment is the largest penalty that each of the
+	// performance measurements can apply to a host.
+	minPerformanceAdjustment = 0.05
+
 	// priceDiveNormalization reduces the raw value of the price so that not so
 	// many digits are needed when operating on the weight. This also allows the
 	// base weight to be a lot lower.
@@ -130,6 +148,22 @@ func (hdb *HostDB) interactionAdjustments(entry modules.HostDBEntry) float64 {
 	return math.Pow(ratio, 15)
 }
 
+// performanceAdjustments penalizes the host for a high latency and a low
+// upload or download throughput. Measurements that have not been taken yet do
+// not penalize the host.
+func performanceAdjustments(entry modules.HostDBEntry) float64 {
+	base := float64(1)
+	if entry.Latency > performanceLatencyTarget {
+		base *= math.Max(float64(performanceLatencyTarget)/float64(entry.Latency), minPerformanceAdjustment)
+	}
+	for _, measured := range []float64{entry.UploadThroughput, entry.DownloadThroughput} {
+		if measured > 0 && measured < performanceThroughputTarget {
+			base *= math.Max(measured/performanceThroughputTarget, minPerformanceAdjustment)
+		}
+	}
+	return base
+}
+
 // priceAdjustments will adjust the weight of the entry according to the prices
 // that it has set.
 func (hdb *HostDB) priceAdjustments(entry modules.HostDBEntry) float64 {
@@ -382,20 +416,37 @@ func (hdb *HostDB) uptimeAdjustments(entry modules.HostDBEntry) float64 {
 	return math.Pow(uptimeRatio, exp)
 }
 
-// calculateHostWeight returns the weight of a host according to the settings of
-// the host database entry.
-func (hdb *HostDB) calculateHostWeight(entry modules.HostDBEntry) types.Currency {
-	collateralReward := hdb.collateralAdjustments(entry)
-	interactionPenalty := hdb.interactionAdjustments(entry)
-	lifetimePenalty := hdb.lifetimeAdjustments(entry)
-	pricePenalty := hdb.priceAdjustments(entry)
-	storageRemainingPenalty := storageRemainingAdjustments(entry)
-	uptimePenalty := hdb.uptimeAdjustments(entry)
-	versionPenalty := versionAdjustments(entry)
-
-	// Combine the adjustments.
-	fullPenalty := collateralReward * interactionPenalty * lifetimePenalty *
-		pricePenalty * storageRemainingPenalty * uptimePenalty * versionPenalty
+// weightAdjustment raises an adjustment to the power of its exponent, and
+// multiplies the result by its multiplier.
+func weightAdjustment(adjustment, exponent, multiplier float64) float64 {
+	return multiplier * math.Pow(adjustment, exponent)
+}
+
+// weightedAdjustments returns the adjustments of a host, weighted by the
+// exponents and multipliers of the hostdb's weight profile.
+func (hdb *HostDB) weightedAdjustments(entry modules.HostDBEntry) modules.HostScoreBreakdown {
+	wp := hdb.weightProfile
+	return modules.HostScoreBreakdown{
+		WeightProfile: wp.Name,
+
+		AgeAdjustment:              weightAdjustment(hdb.lifetimeAdjustments(entry), wp.AgeExponent, wp.AgeMultiplier),
+		BurnAdjustment:             1,
+		CollateralAdjustment:       weightAdjustment(hdb.collateralAdjustments(entry), wp.CollateralExponent, wp.CollateralMultiplier),
+		InteractionAdjustment:      weightAdjustment(hdb.interactionAdjustments(entry), wp.InteractionExponent, wp.InteractionMultiplier),
+		PerformanceAdjustment:      weightAdjustment(performanceAdjustments(entry), wp.PerformanceExponent, wp.PerformanceMultiplier),
+		PriceAdjustment:            weightAdjustment(hdb.priceAdjustments(entry), wp.PriceExponent, wp.PriceMultiplier),
+		StorageRemainingAdjustment: weightAdjustment(storageRemainingAdjustments(entry), wp.StorageRemainingExponent, wp.StorageRemainingMultiplier),
+		UptimeAdjustment:           weightAdjustment(hdb.uptimeAdjustments(entry), wp.UptimeExponent, wp.UptimeMultiplier),
+		VersionAdjustment:          weightAdjustment(versionAdjustments(entry), wp.VersionExponent, wp.VersionMultiplier),
+	}
+}
+
+// combineAdjustments multiplies the adjustments of a score breakdown into a
+// weight.
+func combineAdjustments(sb modules.HostScoreBreakdown) types.Currency {
+	fullPenalty := sb.AgeAdjustment * sb.BurnAdjustment * sb.CollateralAdjustment *
+		sb.InteractionAdjustment * sb.PerformanceAdjustment * sb.PriceAdjustment *
+		sb.StorageRemainingAdjustment * sb.UptimeAdjustment * sb.VersionAdjustment
 
 	// Return a types.Currency.
 	weight := baseWeight.MulFloat(fullPenalty)
@@ -406,12 +457,18 @@ func (hdb *HostDB) calculateHostWeight(entry modules.HostDBEntry) types.Currency
 	return weight
 }
 
+// calculateHostWeight returns the weight of a host according to the settings of
+// the host database entry and the weight profile of the hostdb.
+func (hdb *HostDB) calculateHostWeight(entry modules.HostDBEntry) types.Currency {
+	return combineAdjustments(hdb.weightedAdjustments(entry))
+}
+
 // calculateConversionRate calculates the conversion rate of the provided
 // host score, comparing it to the hosts in the database and returning what
 // percentage of contracts it is likely to participate in.
 func (hdb *HostDB) calculateConversionRate(score types.Currency) float64 {
 	var totalScore types.Currency
-	for _, h := range hdb.ActiveHosts() {
+	for _, h := range hdb.activeHosts() {
 		totalScore = totalScore.Add(hdb.calculateHostWeight(h))
 	}
 	if totalScore.IsZero() {
@@ -427,54 +484,78 @@ func (hdb *HostDB) calculateConversionRate(score types.Currency) float64 {
 // EstimateHostScore takes a HostExternalSettings and returns the estimated
 // score of that host in the hostdb, assuming no penalties for age or uptime.
 func (hdb *HostDB) EstimateHostScore(entry modules.HostDBEntry) modules.HostScoreBreakdown {
-	// Grab the adjustments. Age, and uptime penalties are set to '1', to
-	// assume best behavior from the host.
-	collateralReward := hdb.collateralAdjustments(entry)
-	pricePenalty := hdb.priceAdjustments(entry)
-	storageRemainingPenalty := storageRemainingAdjustments(entry)
-	versionPenalty := versionAdjustments(entry)
+	hdb.mu.RLock()
+	wp := hdb.weightProfile
+	hdb.mu.RUnlock()
 
-	// Combine into a full penalty, then determine the resulting estimated
-	// score.
-	fullPenalty := collateralReward * pricePenalty * storageRemainingPenalty * versionPenalty
-	estimatedScore := baseWeight.MulFloat(fullPenalty)
-	if estimatedScore.IsZero() {
-		estimatedScore = types.NewCurrency64(1)
-	}
+	// Grab the adjustments. Age, interaction, performance and uptime penalties
+	// are set to '1' before they are weighted, to assume best behavior from
+	// the host.
+	sb := modules.HostScoreBreakdown{
+		WeightProfile: wp.Name,
 
-	// Compile the estimates into a host score breakdown.
-	return modules.HostScoreBreakdown{
-		Score:          estimatedScore,
-		ConversionRate: hdb.calculateConversionRate(estimatedScore),
-
-		AgeAdjustment:              1,
+		AgeAdjustment:              weightAdjustment(1, wp.AgeExponent, wp.AgeMultiplier),
 		BurnAdjustment:             1,
-		CollateralAdjustment:       collateralReward,
-		PriceAdjustment:            pricePenalty,
-		StorageRemainingAdjustment: storageRemainingPenalty,
-		UptimeAdjustment:           1,
-		VersionAdjustment:          versionPenalty,
+		CollateralAdjustment:       weightAdjustment(hdb.collateralAdjustments(entry), wp.CollateralExponent, wp.CollateralMultiplier),
+		InteractionAdjustment:      weightAdjustment(1, wp.InteractionExponent, wp.InteractionMultiplier),
+		PerformanceAdjustment:      weightAdjustment(1, wp.PerformanceExponent, wp.PerformanceMultiplier),
+		PriceAdjustment:            weightAdjustment(hdb.priceAdjustments(entry), wp.PriceExponent, wp.PriceMultiplier),
+		StorageRemainingAdjustment: weightAdjustment(storageRemainingAdjustments(entry), wp.StorageRemainingExponent, wp.StorageRemainingMultiplier),
+		UptimeAdjustment:           weightAdjustment(1, wp.UptimeExponent, wp.UptimeMultiplier),
+		VersionAdjustment:          weightAdjustment(versionAdjustments(entry), wp.VersionExponent, wp.VersionMultiplier),
 	}
+
+	// Combine into a full penalty, then determine the resulting estimated
+	// score.
+	sb.Score = combineAdjustments(sb)
+	sb.ConversionRate = hdb.calculateConversionRate(sb.Score)
+	return sb
 }
 
 // ScoreBreakdown provdes a detailed set of scalars and bools indicating
-// elements of the host's overall score.
+// elements of the host's overall score. The adjustments are weighted by the
+// hostdb's weight profile, so that the score is their product.
 func (hdb *HostDB) ScoreBreakdown(entry modules.HostDBEntry) modules.HostScoreBreakdown {
 	hdb.mu.Lock()
 	defer hdb.mu.Unlock()
 
-	score := hdb.calculateHostWeight(entry)
-	return modules.HostScoreBreakdown{
-		Score:          score,
-		ConversionRate: hdb.calculateConversionRate(score),
+	sb := hdb.weightedAdjustments(entry)
+	sb.Score = combineAdjustments(sb)
+	sb.ConversionRate = hdb.calculateConversionRate(sb.Score)
+	return sb
+}
 
-		AgeAdjustment:              hdb.lifetimeAdjustments(entry),
-		BurnAdjustment:             1,
-		CollateralAdjustment:       hdb.collateralAdjustments(entry),
-		InteractionAdjustment:      hdb.interactionAdjustments(entry),
-		PriceAdjustment:            hdb.priceAdjustments(entry),
-		StorageRemainingAdjustment: storageRemainingAdjustments(entry),
-		UptimeAdjustment:           hdb.uptimeAdjustments(entry),
-		VersionAdjustment:          versionAdjustments(entry),
+// SetWeightProfile sets the weight profile of the hostdb and rebuilds the host
+// tree using the new weights.
+func (hdb *HostDB) SetWeightProfile(wp modules.HostWeightProfile) error {
+	if wp.Name == "" {
+		return errUnnamedWeightProfile
+	}
+	for _, exp := range []float64{wp.AgeExponent, wp.CollateralExponent, wp.InteractionExponent,
+		wp.PerformanceExponent, wp.PriceExponent, wp.StorageRemainingExponent, wp.UptimeExponent,
+		wp.VersionExponent} {
+		if exp < 0 || math.IsNaN(exp) || math.IsInf(exp, 0) {
+			return errInvalidWeightExponent
+		}
+	}
+	for _, mult := range []float64{wp.AgeMultiplier, wp.CollateralMultiplier, wp.InteractionMultiplier,
+		wp.PerformanceMultiplier, wp.PriceMultiplier, wp.StorageRemainingMultiplier, wp.UptimeMultiplier,
+		wp.VersionMultiplier} {
+		if mult <= 0 || math.IsNaN(mult) || math.IsInf(mult, 0) {
+			return errInvalidWeightMultiplier
+		}
 	}
+
+	hdb.mu.Lock()
+	defer hdb.mu.Unlock()
+	hdb.weightProfile = wp
+	hdb.hostTree.SetWeightFunction(hdb.calculateHostWeight)
+	return hdb.saveSync()
+}
+
+// WeightProfile returns the weight profile of the hostdb.
+func (hdb *HostDB) WeightProfile() modules.HostWeightProfile {
+	hdb.mu.RLock()
+	defer hdb.mu.RUnlock()
+	return hdb.weightProfile
 }
@@ -1,6 +1,8 @@
 package hostdb
 
 import (
+	"math"
+	"math/big"
 	"testing"
 	"time"
 
@@ -284,3 +286,130 @@ func TestHostWeightUptimeDifferences4(t *testing.T) {
 		t.Error("Been around longer should have more weight")
 	}
 }
+
+// TestHostWeightProfiles checks that the weight profile of the hostdb changes
+// how much each adjustment influences the weight of a host.
+func TestHostWeightProfiles(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	hdb := bareHostDB()
+	var entry modules.HostDBEntry
+	entry.RemainingStorage = 250e3
+	entry.StoragePrice = types.NewCurrency64(300).Mul(types.SiacoinPrecision).Div64(4032).Div64(1e9)
+	entry2 := entry
+	entry2.StoragePrice = types.NewCurrency64(600).Mul(types.SiacoinPrecision).Div64(4032).Div64(1e9)
+
+	// priceRatio returns how much more weight the cheap host has than the
+	// expensive host.
+	priceRatio := func() float64 {
+		w1, w2 := hdb.calculateHostWeight(entry), hdb.calculateHostWeight(entry2)
+		ratio, _ := new(big.Rat).SetFrac(w1.Big(), w2.Big()).Float64()
+		return ratio
+	}
+	defaultRatio := priceRatio()
+	if defaultRatio <= 1 {
+		t.Fatal("cheap host should have more weight")
+	}
+	hdb.weightProfile = modules.PriceHostWeightProfile
+	if ratio := priceRatio(); ratio <= defaultRatio {
+		t.Error("price profile should favor cheap hosts more than the default profile", ratio, defaultRatio)
+	}
+	hdb.weightProfile = modules.ReliabilityHostWeightProfile
+	if ratio := priceRatio(); ratio >= defaultRatio {
+		t.Error("reliability profile should favor cheap hosts less than the default profile", ratio, defaultRatio)
+	}
+
+	// Multipliers scale the weights of all hosts equally.
+	hdb.weightProfile = modules.DefaultHostWeightProfile
+	w := hdb.calculateHostWeight(entry)
+	hdb.weightProfile.PriceMultiplier = 4
+	if ratio := priceRatio(); math.Abs(ratio-defaultRatio) > 1e-9*defaultRatio {
+		t.Error("price multiplier should not change the relative weights of hosts", ratio, defaultRatio)
+	}
+	scaled, _ := new(big.Rat).SetFrac(hdb.calculateHostWeight(entry).Big(), w.Big()).Float64()
+	if math.Abs(scaled-4) > 1e-9 {
+		t.Error("price multiplier should scale the weight of a host", scaled)
+	}
+	hdb.weightProfile = modules.ReliabilityHostWeightProfile
+
+	// The score breakdown should report the profile and weighted adjustments.
+	sb := hdb.ScoreBreakdown(entry)
+	if sb.WeightProfile != modules.ReliabilityHostWeightProfile.Name {
+		t.Error("score breakdown reports the wrong weight profile:", sb.WeightProfile)
+	}
+	if sb.Score.Cmp(hdb.calculateHostWeight(entry)) != 0 {
+		t.Error("score breakdown does not match the weight of the host")
+	}
+
+	// Invalid profiles should be rejected.
+	wp := modules.DefaultHostWeightProfile
+	wp.PriceExponent = -1
+	if err := hdb.SetWeightProfile(wp); err != errInvalidWeightExponent {
+		t.Error("expected errInvalidWeightExponent, got", err)
+	}
+	wp = modules.DefaultHostWeightProfile
+	wp.UptimeMultiplier = 0
+	if err := hdb.SetWeightProfile(wp); err != errInvalidWeightMultiplier {
+		t.Error("expected errInvalidWeightMultiplier, got", err)
+	}
+	wp = modules.DefaultHostWeightProfile
+	wp.Name = ""
+	if err := hdb.SetWeightProfile(wp); err != errUnnamedWeightProfile {
+		t.Error("expected errUnnamedWeightProfile, got", err)
+	}
+}
+
+// TestHostWeightPerformance checks that the recorded performance of a host
+// only influences its weight if the weight profile uses it.
+func TestHostWeightPerformance(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	hdb := bareHostDB()
+	fast := makeHostDBEntry()
+	fast.RemainingStorage = 250e3
+	slow := makeHostDBEntry()
+	slow.RemainingStorage = 250e3
+	if err := hdb.hostTree.Insert(fast); err != nil {
+		t.Fatal(err)
+	}
+	if err := hdb.hostTree.Insert(slow); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first measurement becomes the throughput of the host, later
+	// measurements are averaged in.
+	hdb.RecordDownload(fast.PublicKey, 4<<20, time.Second)
+	hdb.RecordDownload(slow.PublicKey, 64<<10, time.Second)
+	hdb.RecordDownload(slow.PublicKey, 128<<10, time.Second)
+	fast, _ = hdb.hostTree.Select(fast.PublicKey)
+	slow, _ = hdb.hostTree.Select(slow.PublicKey)
+	if fast.DownloadThroughput != 4<<20 {
+		t.Fatal("first measurement was not used as the throughput:", fast.DownloadThroughput)
+	}
+	if slow.DownloadThroughput <= 64<<10 || slow.DownloadThroughput >= 128<<10 {
+		t.Fatal("measurements were not averaged:", slow.DownloadThroughput)
+	}
+	updateHostLatency(&slow, 2*time.Second)
+
+	// The default profile ignores performance.
+	if hdb.calculateHostWeight(fast).Cmp(hdb.calculateHostWeight(slow)) != 0 {
+		t.Error("default profile should ignore the performance of hosts")
+	}
+	hdb.weightProfile = modules.PerformanceHostWeightProfile
+	if hdb.calculateHostWeight(fast).Cmp(hdb.calculateHostWeight(slow)) <= 0 {
+		t.Error("performance profile should favor fast hosts")
+	}
+	if sb := hdb.ScoreBreakdown(fast); sb.PerformanceAdjustment != 1 {
+		t.Error("fast host should not be penalized:", sb.PerformanceAdjustment)
+	}
+
+	// A slow upload throughput is penalized as well.
+	before := hdb.ScoreBreakdown(fast).PerformanceAdjustment
+	hdb.RecordUpload(fast.PublicKey, 256<<10, time.Second)
+	fast, _ = hdb.hostTree.Select(fast.PublicKey)
+	if after := hdb.ScoreBreakdown(fast).PerformanceAdjustment; after >= before {
+		t.Error("slow uploads should penalize the host:", after, before)
+	}
+}
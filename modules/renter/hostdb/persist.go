@@ -24,16 +24,24 @@ var (
 
 // hdbPersist defines what HostDB data persists across sessions.
 type hdbPersist struct {
-	AllHosts    []modules.HostDBEntry
-	BlockHeight types.BlockHeight
-	LastChange  modules.ConsensusChangeID
+	AllHosts      []modules.HostDBEntry
+	BlockHeight   types.BlockHeight
+	FilterMode    modules.FilterMode
+	FilteredHosts []types.SiaPublicKey
+	LastChange    modules.ConsensusChangeID
+	WeightProfile modules.HostWeightProfile
 }
 
 // persistData returns the data in the hostdb that will be saved to disk.
 func (hdb *HostDB) persistData() (data hdbPersist) {
 	data.AllHosts = hdb.hostTree.All()
 	data.BlockHeight = hdb.blockHeight
+	data.FilterMode = hdb.filterMode
+	for _, spk := range hdb.filteredHosts {
+		data.FilteredHosts = append(data.FilteredHosts, spk)
+	}
 	data.LastChange = hdb.lastChange
+	data.WeightProfile = hdb.weightProfile
 	return data
 }
 
@@ -53,7 +61,27 @@ func (hdb *HostDB) load() error {
 
 	// Set the hostdb internal values.
 	hdb.blockHeight = data.BlockHeight
+	hdb.filterMode = data.FilterMode
+	for _, spk := range data.FilteredHosts {
+		hdb.filteredHosts[spk.String()] = spk
+	}
 	hdb.lastChange = data.LastChange
+	if data.WeightProfile.Name != "" {
+		hdb.weightProfile = data.WeightProfile
+	}
+	// COMPATv1.3.1
+	//
+	// Profiles that were saved before multipliers were added have no
+	// multipliers. Multipliers must be positive, so missing multipliers are
+	// set to 1.
+	wp := &hdb.weightProfile
+	for _, mult := range []*float64{&wp.AgeMultiplier, &wp.CollateralMultiplier, &wp.InteractionMultiplier,
+		&wp.PerformanceMultiplier, &wp.PriceMultiplier, &wp.StorageRemainingMultiplier, &wp.UptimeMultiplier,
+		&wp.VersionMultiplier} {
+		if *mult == 0 {
+			*mult = 1
+		}
+	}
 
 	// Load each of the hosts into the host tree.
 	for _, host := range data.AllHosts {
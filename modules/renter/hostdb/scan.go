@@ -6,6 +6,7 @@ package hostdb
 
 import (
 	"net"
+	"sort"
 	"time"
 
 	"github.com/NebulousLabs/Sia/build"
@@ -111,12 +112,44 @@ func (hdb *HostDB) updateEntry(entry modules.HostDBEntry, netErr error) {
 		return
 	}
 
-	// Grab the host from the host tree, and update it with the neew settings.
+	// Grab the host from the host tree, and merge the results of the scan into
+	// it. The entry was copied when the scan was queued, so only the fields
+	// that were set by the scan are taken from it. The latency of the entry is
+	// the round trip time that was measured by the scan, or 0 if none was
+	// measured, and is added to the rolling latency of the host.
 	newEntry, exists := hdb.hostTree.Select(entry.PublicKey)
 	if exists {
-		newEntry.HostExternalSettings = entry.HostExternalSettings
+		if netErr == nil {
+			newEntry.HostExternalSettings = entry.HostExternalSettings
+		}
 	} else {
 		newEntry = entry
+		newEntry.Latency = 0
+	}
+	if entry.Latency > 0 {
+		updateHostLatency(&newEntry, entry.Latency)
+	}
+
+	// Update the subnets of the host if its address was resolved. The time of
+	// the change is kept so that a host that moves into a subnet which is
+	// already in use can be told apart from the host that was there first.
+	if len(entry.IPNets) > 0 && (!exists || !equalIPNets(newEntry.IPNets, entry.IPNets)) {
+		newEntry.IPNets = entry.IPNets
+		newEntry.LastIPNetChange = time.Now()
+	}
+
+	// Add the resolved addresses of the host to its IP history if they
+	// changed, dropping the oldest sets of addresses once the history is
+	// full.
+	if len(entry.IPHistory) > 0 {
+		resolved := entry.IPHistory[len(entry.IPHistory)-1]
+		n := len(newEntry.IPHistory)
+		if n == 0 || !equalIPNets(newEntry.IPHistory[n-1].IPs, resolved.IPs) {
+			newEntry.IPHistory = append(newEntry.IPHistory, resolved)
+		}
+		if len(newEntry.IPHistory) > maxIPHistoryLen {
+			newEntry.IPHistory = newEntry.IPHistory[len(newEntry.IPHistory)-maxIPHistoryLen:]
+		}
 	}
 
 	// Update the recent interactions with this host.
@@ -227,15 +260,19 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 	hdb.mu.RUnlock()
 
 	var settings modules.HostExternalSettings
+	var rtt time.Duration
 	err := func() error {
 		dialer := &net.Dialer{
 			Cancel:  hdb.tg.StopChan(),
 			Timeout: hostRequestTimeout,
 		}
+		dialStart := time.Now()
 		conn, err := dialer.Dial("tcp", string(netAddr))
 		if err != nil {
 			return err
 		}
+		// Establishing the TCP connection takes one round trip.
+		rtt = time.Since(dialStart)
 		connCloseChan := make(chan struct{})
 		go func() {
 			select {
@@ -263,13 +300,78 @@ func (hdb *HostDB) managedScanHost(entry modules.HostDBEntry) {
 		entry.HostExternalSettings = settings
 	}
 
+	// Resolve the addresses and subnets of the host. If the address cannot be
+	// resolved, the subnets and addresses from the previous scan are kept.
+	// Otherwise the resolved addresses are passed to updateEntry as the only
+	// element of the IP history of the entry.
+	entry.IPNets = nil
+	entry.IPHistory = nil
+	ips, ipNets, lookupErr := hdb.lookupIPNets(netAddr)
+	if lookupErr != nil {
+		hdb.log.Debugf("Unable to resolve the address of host %v: %v", netAddr, lookupErr)
+	} else {
+		entry.IPNets = ipNets
+		entry.IPHistory = []modules.HostDBResolvedIPs{{IPs: ips, Timestamp: time.Now()}}
+	}
+
 	// Update the host tree to have a new entry, including the new error. Then
 	// delete the entry from the scan map as the scan has been successful.
+	// The round trip time is only recorded for successful scans.
+	entry.Latency = 0
+	if err == nil {
+		entry.Latency = rtt
+	}
 	hdb.mu.Lock()
 	hdb.updateEntry(entry, err)
 	hdb.mu.Unlock()
 }
 
+// lookupIPNets resolves the address of a host and returns its sorted IP
+// addresses and the sorted subnets of those addresses. Loopback addresses are
+// not grouped into subnets, as they are only used by hosts that run on the
+// same machine during testing.
+func (hdb *HostDB) lookupIPNets(address modules.NetAddress) (ips, ipNets []string, err error) {
+	resolved, err := hdb.deps.lookupIP(address.Host())
+	if err != nil {
+		return nil, nil, err
+	}
+	seen := make(map[string]struct{})
+	for _, ip := range resolved {
+		ips = append(ips, ip.String())
+		if ip.IsLoopback() {
+			continue
+		}
+		mask := net.CIDRMask(ipv6SubnetBits, 8*net.IPv6len)
+		if ip.To4() != nil {
+			ip = ip.To4()
+			mask = net.CIDRMask(ipv4SubnetBits, 8*net.IPv4len)
+		}
+		ipNet := (&net.IPNet{IP: ip.Mask(mask), Mask: mask}).String()
+		if _, exists := seen[ipNet]; exists {
+			continue
+		}
+		seen[ipNet] = struct{}{}
+		ipNets = append(ipNets, ipNet)
+	}
+	sort.Strings(ips)
+	sort.Strings(ipNets)
+	return ips, ipNets, nil
+}
+
+// equalIPNets returns true if a and b contain the same subnets, or the same IP
+// addresses, in the same order.
+func equalIPNets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // threadedProbeHosts pulls hosts from the thread pool and runs a scan on them.
 func (hdb *HostDB) threadedProbeHosts(scanPool <-chan modules.HostDBEntry) {
 	err := hdb.tg.Add()
@@ -2,6 +2,8 @@ package hostdb
 
 import (
 	"errors"
+	"fmt"
+	"net"
 	"testing"
 	"time"
 
@@ -182,3 +184,157 @@ func TestUpdateEntry(t *testing.T) {
 		t.Error("host not reporting historic uptime?")
 	}
 }
+
+// lookupIPDeps resolves every address to a fixed set of IP addresses.
+type lookupIPDeps struct {
+	disableScanLoopDeps
+	ips []net.IP
+}
+
+func (d lookupIPDeps) lookupIP(string) ([]net.IP, error) { return d.ips, nil }
+
+// TestUpdateEntryMerge checks that updateEntry merges the results of a scan
+// into the current entry of a host, rather than replacing the entry with the
+// copy that was made when the scan was queued.
+func TestUpdateEntryMerge(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	hdbt, err := newHDBTesterDeps(t.Name(), disableScanLoopDeps{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := modules.HostDBEntry{PublicKey: types.SiaPublicKey{Key: []byte{1}}}
+	entry.Latency = 100 * time.Millisecond
+	entry.StoragePrice = types.NewCurrency64(5)
+	hdbt.hdb.updateEntry(entry, nil)
+
+	// Measurements that are taken while a scan is in progress should survive
+	// the scan.
+	stale := entry
+	hdbt.hdb.RecordDownload(entry.PublicKey, 1e6, time.Second)
+	stale.Latency = 300 * time.Millisecond
+	hdbt.hdb.updateEntry(stale, nil)
+	updated, _ := hdbt.hdb.hostTree.Select(entry.PublicKey)
+	if updated.DownloadThroughput != 1e6 {
+		t.Error("download throughput was overwritten by the scan:", updated.DownloadThroughput)
+	}
+	expected := time.Duration(rollingAverage(float64(100*time.Millisecond), float64(300*time.Millisecond)))
+	if updated.Latency != expected {
+		t.Error("round trip time was not added to the rolling latency:", updated.Latency, expected)
+	}
+
+	// A failed scan has no latency and no settings.
+	stale.Latency = 0
+	stale.StoragePrice = types.NewCurrency64(10)
+	hdbt.hdb.updateEntry(stale, errors.New("testing err"))
+	updated, _ = hdbt.hdb.hostTree.Select(entry.PublicKey)
+	if updated.Latency != expected {
+		t.Error("failed scan changed the latency:", updated.Latency)
+	}
+	if !updated.StoragePrice.Equals(entry.StoragePrice) {
+		t.Error("failed scan changed the settings of the host:", updated.StoragePrice)
+	}
+}
+
+// TestLookupIPNets checks that the addresses of hosts are grouped into subnets,
+// and that updateEntry tracks when the subnets of a host change.
+func TestLookupIPNets(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	deps := lookupIPDeps{
+		ips: []net.IP{
+			net.ParseIP("1.2.3.4"),
+			net.ParseIP("1.2.3.5"),
+			net.ParseIP("127.0.0.1"),
+			net.ParseIP("2001:db8:abcd:1234::1"),
+		},
+	}
+	hdbt, err := newHDBTesterDeps(t.Name(), deps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ips, ipNets, err := hdbt.hdb.lookupIPNets("host.example.com:9982")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !equalIPNets(ipNets, []string{"1.2.3.0/24", "2001:db8:abcd:1000::/54"}) {
+		t.Fatal("wrong subnets:", ipNets)
+	}
+	if !equalIPNets(ips, []string{"1.2.3.4", "1.2.3.5", "127.0.0.1", "2001:db8:abcd:1234::1"}) {
+		t.Fatal("wrong addresses:", ips)
+	}
+
+	// A new host should get its subnets and a change time.
+	entry := modules.HostDBEntry{
+		PublicKey: types.SiaPublicKey{Key: []byte{1}},
+		IPNets:    ipNets,
+	}
+	hdbt.hdb.updateEntry(entry, nil)
+	updated, _ := hdbt.hdb.hostTree.Select(entry.PublicKey)
+	if !equalIPNets(updated.IPNets, ipNets) || updated.LastIPNetChange.IsZero() {
+		t.Fatal("subnets of a new host were not set")
+	}
+
+	// The change time should only move when the subnets change.
+	changed := updated.LastIPNetChange
+	hdbt.hdb.updateEntry(entry, nil)
+	updated, _ = hdbt.hdb.hostTree.Select(entry.PublicKey)
+	if !updated.LastIPNetChange.Equal(changed) {
+		t.Fatal("change time moved without a change of subnets")
+	}
+	entry.IPNets = []string{"4.5.6.0/24"}
+	hdbt.hdb.updateEntry(entry, nil)
+	updated, _ = hdbt.hdb.hostTree.Select(entry.PublicKey)
+	if !equalIPNets(updated.IPNets, entry.IPNets) || !updated.LastIPNetChange.After(changed) {
+		t.Fatal("change of subnets was not recorded")
+	}
+}
+
+// TestIPHistory checks that updateEntry records the addresses that a host
+// resolved to whenever they change, and bounds the length of the history.
+func TestIPHistory(t *testing.T) {
+	hdb := bareHostDB()
+	entry := modules.HostDBEntry{PublicKey: types.SiaPublicKey{Key: []byte{1}}}
+	resolve := func(ips ...string) {
+		entry.IPHistory = []modules.HostDBResolvedIPs{{IPs: ips, Timestamp: time.Now()}}
+		hdb.updateEntry(entry, nil)
+	}
+	history := func() []modules.HostDBResolvedIPs {
+		updated, _ := hdb.hostTree.Select(entry.PublicKey)
+		return updated.IPHistory
+	}
+
+	resolve("1.2.3.4")
+	resolve("1.2.3.4")
+	if h := history(); len(h) != 1 || !equalIPNets(h[0].IPs, []string{"1.2.3.4"}) {
+		t.Fatal("unchanged addresses should be recorded once:", h)
+	}
+
+	// A scan that could not resolve the address should not change the history.
+	entry.IPHistory = nil
+	hdb.updateEntry(entry, nil)
+	if h := history(); len(h) != 1 {
+		t.Fatal("unresolved scan changed the history:", h)
+	}
+
+	resolve("4.5.6.7", "4.5.6.8")
+	if h := history(); len(h) != 2 || !equalIPNets(h[1].IPs, []string{"4.5.6.7", "4.5.6.8"}) {
+		t.Fatal("changed addresses were not recorded:", h)
+	}
+
+	// Only the most recent maxIPHistoryLen sets of addresses are kept.
+	for i := 0; i < maxIPHistoryLen; i++ {
+		resolve(fmt.Sprintf("10.0.0.%v", i))
+	}
+	h := history()
+	if len(h) != maxIPHistoryLen {
+		t.Fatal("history was not bounded:", len(h))
+	}
+	if !equalIPNets(h[0].IPs, []string{"10.0.0.0"}) || !equalIPNets(h[len(h)-1].IPs, []string{fmt.Sprintf("10.0.0.%v", maxIPHistoryLen-1)}) {
+		t.Fatal("wrong addresses kept in the history:", h)
+	}
+}
@@ -10,7 +10,6 @@ import (
 	"path/filepath"
 	"strconv"
 
-	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/encoding"
 	"github.com/NebulousLabs/Sia/modules"
 	"github.com/NebulousLabs/Sia/persist"
@@ -35,64 +34,32 @@ var (
 	}
 
 	shareHeader  = [15]byte{'S', 'i', 'a', ' ', 'S', 'h', 'a', 'r', 'e', 'd', ' ', 'F', 'i', 'l', 'e'}
-	shareVersion = "0.4"
-)
+	shareVersion = "1.0"
 
-// MarshalSia implements the encoding.SiaMarshaller interface, writing the
-// file data to w.
-func (f *file) MarshalSia(w io.Writer) error {
-	enc := encoding.NewEncoder(w)
-
-	// encode easy fields
-	err := enc.EncodeAll(
-		f.name,
-		f.size,
-		f.masterKey,
-		f.pieceSize,
-		f.mode,
-	)
-	if err != nil {
-		return err
-	}
-	// COMPATv0.4.3 - encode the bytesUploaded and chunksUploaded fields
-	// TODO: the resulting .sia file may confuse old clients.
-	err = enc.EncodeAll(f.pieceSize*f.numChunks()*uint64(f.erasureCode.NumPieces()), f.numChunks())
-	if err != nil {
-		return err
-	}
+	// COMPATv1.3.1 - version 0.4 .sia files are converted when they are
+	// loaded.
+	compatShareVersionV04 = "0.4"
+)
 
-	// encode erasureCode
-	switch code := f.erasureCode.(type) {
-	case *rsCode:
-		err = enc.EncodeAll(
-			"Reed-Solomon",
-			uint64(code.dataPieces),
-			uint64(code.numPieces-code.dataPieces),
-		)
-		if err != nil {
-			return err
-		}
-	default:
-		if build.DEBUG {
-			panic("unknown erasure code")
-		}
-		return errors.New("unknown erasure code")
-	}
-	// encode contracts
-	if err := enc.Encode(uint64(len(f.contracts))); err != nil {
-		return err
-	}
-	for _, c := range f.contracts {
-		if err := enc.Encode(c); err != nil {
-			return err
-		}
-	}
-	return nil
+// compatFileContractV04 is the encoding of a fileContract in version 0.4 of
+// the .sia format. Hosts were identified by their address instead of their
+// public key.
+//
+// COMPATv1.3.1
+type compatFileContractV04 struct {
+	ID     types.FileContractID
+	IP     modules.NetAddress
+	Pieces []pieceData
+
+	WindowStart types.BlockHeight
 }
 
-// UnmarshalSia implements the encoding.SiaUnmarshaller interface,
-// reconstructing a file from the encoded bytes read from r.
-func (f *file) UnmarshalSia(r io.Reader) error {
+// unmarshalSiaCompatV04 reconstructs a file from data encoded in version 0.4
+// of the .sia format. The returned file does not know the public keys of its
+// hosts; these are filled in by the renter when the file is converted.
+//
+// COMPATv1.3.1
+func (f *file) unmarshalSiaCompatV04(r io.Reader) error {
 	dec := encoding.NewDecoder(r)
 
 	// COMPATv0.4.3 - decode bytesUploaded and chunksUploaded into dummy vars.
@@ -142,27 +109,52 @@ func (f *file) UnmarshalSia(r io.Reader) error {
 		return err
 	}
 	f.contracts = make(map[types.FileContractID]fileContract)
-	var contract fileContract
+	var contract compatFileContractV04
 	for i := uint64(0); i < nContracts; i++ {
 		if err := dec.Decode(&contract); err != nil {
 			return err
 		}
-		f.contracts[contract.ID] = contract
+		f.contracts[contract.ID] = fileContract{
+			ID:          contract.ID,
+			Pieces:      contract.Pieces,
+			WindowStart: contract.WindowStart,
+		}
 	}
 	return nil
 }
 
+// convertCompatV04File fills in the host public keys of a file that was loaded
+// from version 0.4 of the .sia format, using the renter's contracts. Contracts
+// that the renter does not know about are left without a public key. The
+// renter's lock must be held by the caller.
+//
+// COMPATv1.3.1
+func (r *Renter) convertCompatV04File(f *file) {
+	for id, fc := range f.contracts {
+		c, exists := r.hostContractor.ContractByID(r.hostContractor.ResolveID(id))
+		if !exists {
+			continue
+		}
+		fc.HostPublicKey = c.HostPublicKey
+		f.contracts[id] = fc
+	}
+}
+
 // saveFile saves a file to the renter directory.
 func (r *Renter) saveFile(f *file) error {
+	return saveFileAs(f, filepath.Join(r.persistDir, f.name+ShareExtension))
+}
+
+// saveFileAs saves a file to the provided path.
+func saveFileAs(f *file, fullPath string) error {
 	// Create directory structure specified in nickname.
-	fullPath := filepath.Join(r.persistDir, f.name+ShareExtension)
 	err := os.MkdirAll(filepath.Dir(fullPath), 0700)
 	if err != nil {
 		return err
 	}
 
 	// Open SafeFile handle.
-	handle, err := persist.NewSafeFile(filepath.Join(r.persistDir, f.name+ShareExtension))
+	handle, err := persist.NewSafeFile(fullPath)
 	if err != nil {
 		return err
 	}
@@ -180,9 +172,15 @@ func (r *Renter) saveFile(f *file) error {
 
 // saveSync stores the current renter data to disk and then syncs to disk.
 func (r *Renter) saveSync() error {
+	maxMemory, maxDisk := r.chunkCache.size()
 	data := struct {
-		Tracking map[string]trackedFile
-	}{r.tracking}
+		Tracking           map[string]trackedFile
+		Directories        map[string]struct{}
+		ChunkCacheSize     uint64
+		ChunkCacheDiskSize uint64
+		MaxUploadSpeed     uint64
+		MaxDownloadSpeed   uint64
+	}{r.tracking, r.directories, maxMemory, maxDisk, r.uploadLimit.Limit(), r.downloadLimit.Limit()}
 
 	return persist.SaveJSON(saveMetadata, data, filepath.Join(r.persistDir, PersistFilename))
 }
@@ -200,7 +198,7 @@ func (r *Renter) load() error {
 		}
 
 		// Skip folders and non-sia files.
-		if info.IsDir() || filepath.Ext(path) != ShareExtension {
+		if info.IsDir() || (filepath.Ext(path) != ShareExtension && filepath.Ext(path) != reencodeExtension) {
 			return nil
 		}
 
@@ -212,6 +210,20 @@ func (r *Renter) load() error {
 		}
 		defer file.Close()
 
+		// The old layouts of files that are being re-encoded are kept
+		// separately from the files themselves.
+		if filepath.Ext(path) == reencodeExtension {
+			files, err := r.readSharedFiles(file)
+			if err != nil {
+				r.log.Println("ERROR: could not load old layout of re-encoded file:", err)
+				return nil
+			}
+			for _, f := range files {
+				r.reencoding[f.name] = f
+			}
+			return nil
+		}
+
 		// Load the file contents into the renter.
 		_, err = r.loadSharedFiles(file)
 		if err != nil {
@@ -226,9 +238,16 @@ func (r *Renter) load() error {
 
 	// Load contracts, repair set, and entropy.
 	data := struct {
-		Tracking  map[string]trackedFile
-		Repairing map[string]string // COMPATv0.4.8
-	}{}
+		Tracking           map[string]trackedFile
+		Directories        map[string]struct{}
+		ChunkCacheSize     uint64
+		ChunkCacheDiskSize uint64
+		MaxUploadSpeed     uint64
+		MaxDownloadSpeed   uint64
+		Repairing          map[string]string // COMPATv0.4.8
+	}{
+		ChunkCacheSize: defaultChunkCacheSize,
+	}
 	err = persist.LoadJSON(saveMetadata, &data, filepath.Join(r.persistDir, PersistFilename))
 	if err != nil {
 		return err
@@ -236,6 +255,15 @@ func (r *Renter) load() error {
 	if data.Tracking != nil {
 		r.tracking = data.Tracking
 	}
+	if data.Directories != nil {
+		r.directories = data.Directories
+	}
+	for dir := range r.directories {
+		r.dirIndex.addDir(dir)
+	}
+	r.chunkCache.setSize(data.ChunkCacheSize, data.ChunkCacheDiskSize)
+	r.uploadLimit.SetLimit(data.MaxUploadSpeed)
+	r.downloadLimit.SetLimit(data.MaxDownloadSpeed)
 
 	return nil
 }
@@ -255,11 +283,12 @@ func shareFiles(files []*file, w io.Writer) error {
 
 	// Create compressor.
 	zip, _ := gzip.NewWriterLevel(w, gzip.BestSpeed)
-	enc := encoding.NewEncoder(zip)
 
-	// Encode each file.
+	// Encode each file. The files are not encoded through an encoding.Encoder
+	// so that each part of a file is subject to its own size limit when
+	// decoding.
 	for _, f := range files {
-		err = enc.Encode(f)
+		err = f.MarshalSia(zip)
 		if err != nil {
 			return err
 		}
@@ -327,9 +356,10 @@ func (r *Renter) ShareFilesAscii(nicknames []string) (string, error) {
 	return buf.String(), nil
 }
 
-// loadSharedFiles reads .sia data from reader and registers the contained
-// files in the renter. It returns the nicknames of the loaded files.
-func (r *Renter) loadSharedFiles(reader io.Reader) ([]string, error) {
+// readSharedFiles reads the files contained in the .sia data from reader.
+// Files in an older version of the .sia format are converted to the current
+// version.
+func (r *Renter) readSharedFiles(reader io.Reader) ([]*file, error) {
 	// read header
 	var header [15]byte
 	var version string
@@ -343,7 +373,7 @@ func (r *Renter) loadSharedFiles(reader io.Reader) ([]string, error) {
 		return nil, err
 	} else if header != shareHeader {
 		return nil, ErrBadFile
-	} else if version != shareVersion {
+	} else if version != shareVersion && version != compatShareVersionV04 {
 		return nil, ErrIncompatible
 	}
 
@@ -352,37 +382,63 @@ func (r *Renter) loadSharedFiles(reader io.Reader) ([]string, error) {
 	if err != nil {
 		return nil, err
 	}
-	dec := encoding.NewDecoder(unzip)
 
 	// Read each file.
-	files := make([]*file, numFiles)
-	for i := range files {
-		files[i] = new(file)
-		err := dec.Decode(files[i])
+	var files []*file
+	for i := uint64(0); i < numFiles; i++ {
+		f := new(file)
+		if version == compatShareVersionV04 {
+			err = f.unmarshalSiaCompatV04(unzip)
+			if err == nil {
+				r.convertCompatV04File(f)
+			}
+		} else {
+			err = f.UnmarshalSia(unzip)
+		}
 		if err != nil {
 			return nil, err
 		}
+		if err := validateSiapath(f.name); err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// loadSharedFiles reads .sia data from reader and registers the contained
+// files in the renter. It returns the nicknames of the loaded files.
+func (r *Renter) loadSharedFiles(reader io.Reader) ([]string, error) {
+	files, err := r.readSharedFiles(reader)
+	if err != nil {
+		return nil, err
+	}
 
-		// Make sure the file's name does not conflict with existing files.
+	// Make sure the names of the files do not conflict with existing files,
+	// including the files that were loaded before them. Loaded files may share
+	// their path with a directory, since older versions of the renter allowed
+	// files to be nested below other files.
+	for _, f := range files {
 		dupCount := 0
-		origName := files[i].name
+		origName := f.name
 		for {
-			_, exists := r.files[files[i].name]
+			_, exists := r.files[f.name]
 			if !exists {
 				break
 			}
 			dupCount++
-			files[i].name = origName + "_" + strconv.Itoa(dupCount)
+			f.name = origName + "_" + strconv.Itoa(dupCount)
 		}
+		r.addFileEntry(f)
 	}
 
 	// Add files to renter.
-	names := make([]string, numFiles)
+	names := make([]string, len(files))
 	for i, f := range files {
-		r.files[f.name] = f
 		names[i] = f.name
 	}
-	// Save the files.
+	// Save the files. Files that were loaded from an older version of the
+	// .sia format are converted to the current version in the process.
 	for _, f := range files {
 		r.saveFile(f)
 	}
@@ -405,12 +461,19 @@ func (r *Renter) initPersist() error {
 		return err
 	}
 
+	// Initialize the chunk cache. Its size is loaded along with the rest of
+	// the renter's settings.
+	r.chunkCache = newChunkCache(filepath.Join(r.persistDir, chunkCacheDir), defaultChunkCacheSize, 0, r.log)
+
 	// Load the prior persistence structures.
 	err = r.load()
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
-	return nil
+
+	// Resume the downloads that were in progress. This requires the files to
+	// be loaded.
+	return r.loadDownloads()
 }
 
 // LoadSharedFiles loads a .sia file into the renter. It returns the nicknames
@@ -11,6 +11,7 @@ import (
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
 	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/types"
 	"github.com/NebulousLabs/fastrand"
 )
 
@@ -69,6 +70,65 @@ func TestFileMarshalling(t *testing.T) {
 	}
 }
 
+// TestFileMarshallingPieces checks that the piece tables and hosts of a file
+// survive a round trip through the siafile format.
+func TestFileMarshallingPieces(t *testing.T) {
+	savedFile := newTestingFile()
+	savedFile.pieceSize = 64
+	savedFile.size = savedFile.chunkSize() * 3
+	savedFile.contracts = make(map[types.FileContractID]fileContract)
+	for i := 0; i < 3; i++ {
+		var fc fileContract
+		fastrand.Read(fc.ID[:])
+		fc.HostPublicKey = types.SiaPublicKey{
+			Algorithm: types.SignatureEd25519,
+			Key:       fastrand.Bytes(32),
+		}
+		fc.WindowStart = types.BlockHeight(i)
+		for chunk := uint64(0); chunk < 3; chunk++ {
+			var p pieceData
+			p.Chunk = chunk
+			p.Piece = uint64(fastrand.Intn(savedFile.erasureCode.NumPieces()))
+			fastrand.Read(p.MerkleRoot[:])
+			fc.Pieces = append(fc.Pieces, p)
+		}
+		savedFile.contracts[fc.ID] = fc
+	}
+
+	buf := new(bytes.Buffer)
+	if err := savedFile.MarshalSia(buf); err != nil {
+		t.Fatal(err)
+	}
+	loadedFile := new(file)
+	if err := loadedFile.UnmarshalSia(buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := equalFiles(savedFile, loadedFile); err != nil {
+		t.Fatal(err)
+	}
+	if loadedFile.erasureCode.MinPieces() != savedFile.erasureCode.MinPieces() || loadedFile.erasureCode.NumPieces() != savedFile.erasureCode.NumPieces() {
+		t.Fatal("erasure code parameters do not match")
+	}
+	if len(loadedFile.contracts) != len(savedFile.contracts) {
+		t.Fatal("wrong number of contracts:", len(loadedFile.contracts))
+	}
+	for id, fc := range savedFile.contracts {
+		lfc := loadedFile.contracts[id]
+		if lfc.HostPublicKey.String() != fc.HostPublicKey.String() || lfc.WindowStart != fc.WindowStart {
+			t.Fatal("contract metadata does not match")
+		}
+		if len(lfc.Pieces) != len(fc.Pieces) {
+			t.Fatal("wrong number of pieces:", len(lfc.Pieces), len(fc.Pieces))
+		}
+		// The pieces of a contract are stored in the order of their chunks.
+		for i := range fc.Pieces {
+			if lfc.Pieces[i] != fc.Pieces[i] {
+				t.Fatal("piece does not match:", lfc.Pieces[i], fc.Pieces[i])
+			}
+		}
+	}
+}
+
 // TestFileShareLoad tests the sharing/loading functions of the renter.
 func TestFileShareLoad(t *testing.T) {
 	if testing.Short() {
@@ -314,4 +374,21 @@ func TestSiafileCompatibility(t *testing.T) {
 	if len(names) != 1 || names[0] != "testfile-183" {
 		t.Fatal("nickname not loaded properly:", names)
 	}
+
+	// The file should have been converted to the current format when it was
+	// saved.
+	f, err := os.Open(filepath.Join(rt.renter.persistDir, names[0]+ShareExtension))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var header [15]byte
+	var version string
+	err = encoding.NewDecoder(f).DecodeAll(&header, &version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != shareVersion {
+		t.Fatal("file was not converted to the current format:", version)
+	}
 }
@@ -2,6 +2,7 @@ package proto
 
 import (
 	"errors"
+	"fmt"
 	"net"
 	"sync"
 	"time"
@@ -12,8 +13,47 @@ import (
 	"github.com/NebulousLabs/Sia/types"
 )
 
+var (
+	// errBadRangeProof is returned if a segment of a range download does not
+	// match its Merkle proof.
+	errBadRangeProof = errors.New("host sent segment with an invalid Merkle proof")
+
+	// errRangeOutOfBounds is returned if a range download asks for data
+	// outside of the sector.
+	errRangeOutOfBounds = errors.New("range is outside of the sector")
+)
+
+// alignRange extends the range [offset, offset+length) of a sector to segment
+// boundaries, returning the offset and length of the aligned range.
+func alignRange(offset, length uint64) (alignedOffset, alignedLength uint64) {
+	alignedOffset = offset - offset%crypto.SegmentSize
+	end := offset + length
+	if end%crypto.SegmentSize != 0 {
+		end += crypto.SegmentSize - end%crypto.SegmentSize
+	}
+	return alignedOffset, end - alignedOffset
+}
+
+// verifyRange checks that every segment of data, which starts at the segment
+// with index start of the sector with the provided Merkle root, matches its
+// Merkle proof.
+func verifyRange(data []byte, proofs [][]crypto.Hash, start uint64, root crypto.Hash) error {
+	numSegments := uint64(len(data)) / crypto.SegmentSize
+	if uint64(len(proofs)) != numSegments {
+		return fmt.Errorf("host sent %v proofs for %v segments", len(proofs), numSegments)
+	}
+	for i := uint64(0); i < numSegments; i++ {
+		segment := data[i*crypto.SegmentSize:][:crypto.SegmentSize]
+		if !crypto.VerifySegment(segment, proofs[i], modules.SectorSize/crypto.SegmentSize, start+i, root) {
+			return errBadRangeProof
+		}
+	}
+	return nil
+}
+
 // A Downloader retrieves sectors by calling the download RPC on a host.
-// Downloaders are NOT thread- safe; calls to Sector must be serialized.
+// Downloaders are NOT thread- safe; calls to Sector and Range must be
+// serialized.
 type Downloader struct {
 	contractID  types.FileContractID
 	contractSet *ContractSet
@@ -22,12 +62,43 @@ type Downloader struct {
 	closeChan   chan struct{}
 	once        sync.Once
 	hdb         hostDB
+
+	// withProofs is set if the host supports RPCDownloadRange, which is then
+	// used instead of RPCDownload.
+	withProofs bool
 }
 
 // Sector retrieves the sector with the specified Merkle root, and revises
 // the underlying contract to pay the host proportionally to the data
 // retrieve.
-func (hd *Downloader) Sector(root crypto.Hash) (_ modules.RenterContract, _ []byte, err error) {
+func (hd *Downloader) Sector(root crypto.Hash) (modules.RenterContract, []byte, error) {
+	return hd.download(root, 0, modules.SectorSize)
+}
+
+// Range retrieves length bytes starting at offset from the sector with the
+// specified Merkle root, and revises the underlying contract to pay the host
+// for the data retrieved. If the host supports it, only the segments that
+// cover the range are downloaded, each with a Merkle proof. Otherwise, or if
+// the proofs would cost more than the sector, the whole sector is downloaded.
+func (hd *Downloader) Range(root crypto.Hash, offset, length uint64) (modules.RenterContract, []byte, error) {
+	if length == 0 || offset+length > modules.SectorSize || offset+length < offset {
+		return modules.RenterContract{}, nil, errRangeOutOfBounds
+	}
+	alignedOffset, alignedLength := alignRange(offset, length)
+	if !hd.withProofs || modules.RangeDownloadBandwidth(alignedLength) >= modules.SectorSize {
+		alignedOffset, alignedLength = 0, modules.SectorSize
+	}
+	contract, data, err := hd.download(root, alignedOffset, alignedLength)
+	if err != nil {
+		return modules.RenterContract{}, nil, err
+	}
+	return contract, data[offset-alignedOffset:][:length], nil
+}
+
+// download retrieves the segment-aligned range [offset, offset+length) of the
+// sector with the specified Merkle root, and verifies it either against the
+// Merkle root or, for partial sectors, against the proofs sent by the host.
+func (hd *Downloader) download(root crypto.Hash, offset, length uint64) (_ modules.RenterContract, _ []byte, err error) {
 	// Reset deadline when finished.
 	defer extendDeadline(hd.conn, time.Hour) // TODO: Constant.
 
@@ -41,16 +112,20 @@ func (hd *Downloader) Sector(root crypto.Hash) (_ modules.RenterContract, _ []by
 	contract := sc.header // for convenience
 
 	// calculate price
-	sectorPrice := hd.host.DownloadBandwidthPrice.Mul64(modules.SectorSize)
-	if contract.RenterFunds().Cmp(sectorPrice) < 0 {
+	bandwidth := length
+	if hd.withProofs {
+		bandwidth = modules.RangeDownloadBandwidth(length)
+	}
+	price := hd.host.DownloadBandwidthPrice.Mul64(bandwidth)
+	if contract.RenterFunds().Cmp(price) < 0 {
 		return modules.RenterContract{}, nil, errors.New("contract has insufficient funds to support download")
 	}
 	// To mitigate small errors (e.g. differing block heights), fudge the
 	// price and collateral by 0.2%.
-	sectorPrice = sectorPrice.MulFloat(1 + hostPriceLeeway)
+	price = price.MulFloat(1 + hostPriceLeeway)
 
 	// create the download revision
-	rev := newDownloadRevision(contract.LastRevision(), sectorPrice)
+	rev := newDownloadRevision(contract.LastRevision(), price)
 
 	// initiate download by confirming host settings
 	extendDeadline(hd.conn, modules.NegotiateSettingsTime)
@@ -61,7 +136,7 @@ func (hd *Downloader) Sector(root crypto.Hash) (_ modules.RenterContract, _ []by
 	// record the change we are about to make to the contract. If we lose power
 	// mid-revision, this allows us to restore either the pre-revision or
 	// post-revision contract.
-	walTxn, err := sc.recordDownloadIntent(rev, sectorPrice)
+	walTxn, err := sc.recordDownloadIntent(rev, price)
 	if err != nil {
 		return modules.RenterContract{}, nil, err
 	}
@@ -70,8 +145,8 @@ func (hd *Downloader) Sector(root crypto.Hash) (_ modules.RenterContract, _ []by
 	extendDeadline(hd.conn, 2*time.Minute) // TODO: Constant.
 	err = encoding.WriteObject(hd.conn, []modules.DownloadAction{{
 		MerkleRoot: root,
-		Offset:     0,
-		Length:     modules.SectorSize,
+		Offset:     offset,
+		Length:     length,
 	}})
 	if err != nil {
 		return modules.RenterContract{}, nil, err
@@ -101,20 +176,33 @@ func (hd *Downloader) Sector(root crypto.Hash) (_ modules.RenterContract, _ []by
 	// read sector data, completing one iteration of the download loop
 	extendDeadline(hd.conn, modules.NegotiateDownloadTime)
 	var sectors [][]byte
-	if err := encoding.ReadObject(hd.conn, &sectors, modules.SectorSize+16); err != nil {
+	if err := encoding.ReadObject(hd.conn, &sectors, length+16); err != nil {
 		return modules.RenterContract{}, nil, err
 	} else if len(sectors) != 1 {
 		return modules.RenterContract{}, nil, errors.New("host did not send enough sectors")
 	}
 	sector := sectors[0]
-	if uint64(len(sector)) != modules.SectorSize {
+	if uint64(len(sector)) != length {
 		return modules.RenterContract{}, nil, errors.New("host did not send enough sector data")
-	} else if crypto.MerkleRoot(sector) != root {
+	}
+	var proofs [][][]crypto.Hash
+	if hd.withProofs {
+		if err := encoding.ReadObject(hd.conn, &proofs, bandwidth+16); err != nil {
+			return modules.RenterContract{}, nil, err
+		} else if len(proofs) != 1 {
+			return modules.RenterContract{}, nil, errors.New("host did not send enough proofs")
+		}
+	}
+	if length == modules.SectorSize && crypto.MerkleRoot(sector) != root {
 		return modules.RenterContract{}, nil, errors.New("host sent bad sector data")
+	} else if length != modules.SectorSize {
+		if err := verifyRange(sector, proofs[0], offset/crypto.SegmentSize, root); err != nil {
+			return modules.RenterContract{}, nil, err
+		}
 	}
 
 	// update contract and metrics
-	if err := sc.commitDownload(walTxn, signedTxn, sectorPrice); err != nil {
+	if err := sc.commitDownload(walTxn, signedTxn, price); err != nil {
 		return modules.RenterContract{}, nil, err
 	}
 
@@ -165,11 +253,19 @@ func (cs *ContractSet) NewDownloader(host modules.HostDBEntry, id types.FileCont
 		}
 	}()
 
-	conn, closeChan, err := initiateRevisionLoop(host, contract, modules.RPCDownload, cancel)
+	// Hosts that advertise range downloads in their settings prove partial
+	// sector downloads with Merkle proofs.
+	rpc := modules.RPCDownload
+	withProofs := host.RangeDownloads
+	if withProofs {
+		rpc = modules.RPCDownloadRange
+	}
+
+	conn, closeChan, err := initiateRevisionLoop(host, contract, rpc, cancel)
 	if IsRevisionMismatch(err) && len(sc.unappliedTxns) > 0 {
 		// we have desynced from the host. If we have unapplied updates from the
 		// WAL, try applying them.
-		conn, closeChan, err = initiateRevisionLoop(host, sc.unappliedHeader(), modules.RPCDownload, cancel)
+		conn, closeChan, err = initiateRevisionLoop(host, sc.unappliedHeader(), rpc, cancel)
 		if err != nil {
 			return nil, err
 		}
@@ -189,5 +285,6 @@ func (cs *ContractSet) NewDownloader(host modules.HostDBEntry, id types.FileCont
 		conn:        conn,
 		closeChan:   closeChan,
 		hdb:         hdb,
+		withProofs:  withProofs,
 	}, nil
 }
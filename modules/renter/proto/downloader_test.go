@@ -0,0 +1,62 @@
+package proto
+
+import (
+	"testing"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestAlignRange checks that ranges are extended to segment boundaries.
+func TestAlignRange(t *testing.T) {
+	tests := []struct {
+		offset, length               uint64
+		alignedOffset, alignedLength uint64
+	}{
+		{0, 64, 0, 64},
+		{0, 1, 0, 64},
+		{63, 2, 0, 128},
+		{100, 300, 64, 384},
+		{128, 128, 128, 128},
+	}
+	for _, test := range tests {
+		offset, length := alignRange(test.offset, test.length)
+		if offset != test.alignedOffset || length != test.alignedLength {
+			t.Errorf("alignRange(%v, %v): expected (%v, %v), got (%v, %v)", test.offset, test.length,
+				test.alignedOffset, test.alignedLength, offset, length)
+		}
+	}
+}
+
+// TestVerifyRange checks that verifyRange accepts segments with valid Merkle
+// proofs and rejects modified segments.
+func TestVerifyRange(t *testing.T) {
+	sector := fastrand.Bytes(int(modules.SectorSize))
+	root := crypto.MerkleRoot(sector)
+
+	start, end := uint64(2), uint64(6)
+	data := append([]byte(nil), sector[start*crypto.SegmentSize:end*crypto.SegmentSize]...)
+	var proofs [][]crypto.Hash
+	for i := start; i < end; i++ {
+		_, proof := crypto.MerkleProof(sector, i)
+		proofs = append(proofs, proof)
+	}
+	if err := verifyRange(data, proofs, start, root); err != nil {
+		t.Fatal(err)
+	}
+
+	// A missing proof should be rejected.
+	if err := verifyRange(data, proofs[1:], start, root); err == nil {
+		t.Fatal("expected missing proof to be rejected")
+	}
+	// Data from the wrong offset should be rejected.
+	if err := verifyRange(data, proofs, start+1, root); err != errBadRangeProof {
+		t.Fatal("expected errBadRangeProof, got", err)
+	}
+	// Modified data should be rejected.
+	data[0]++
+	if err := verifyRange(data, proofs, start, root); err != errBadRangeProof {
+		t.Fatal("expected errBadRangeProof, got", err)
+	}
+}
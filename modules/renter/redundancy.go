@@ -0,0 +1,140 @@
+package renter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+// Changing the erasure code of a file invalidates all of the pieces that have
+// been uploaded for it. Instead of modifying the file in place, the renter
+// replaces the file with a new, empty layout that uses the new erasure code,
+// which the repair loop then uploads like any other incomplete file. The old
+// layout is kept until the new layout has been fully uploaded, and serves as
+// the source of the data for the repair loop and for downloads in the
+// meantime.
+
+// reencodeExtension is the extension of the files that contain the old
+// layout of a file that is being re-encoded.
+const reencodeExtension = ".reencode"
+
+var errFileNotTracked = errors.New("only files that are maintained by the renter can be re-encoded")
+
+// reencodePath returns the path of the file that contains the old layout of
+// the file at siaPath.
+func (r *Renter) reencodePath(siaPath string) string {
+	return filepath.Join(r.persistDir, siaPath+ShareExtension+reencodeExtension)
+}
+
+// sameErasureCode returns whether two erasure coders produce the same
+// pieces.
+func sameErasureCode(ec1, ec2 modules.ErasureCoder) bool {
+	params1, params2 := ec1.Params(), ec2.Params()
+	if ec1.Type() != ec2.Type() || len(params1) != len(params2) {
+		return false
+	}
+	for i := range params1 {
+		if params1[i] != params2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// downloadLayout returns the layout of a file that should be used to
+// download its data. While a file is being re-encoded, the old layout is used
+// until the new layout is available. The renter's lock must be held by the
+// caller.
+func (r *Renter) downloadLayout(f *file) *file {
+	old, exists := r.reencoding[f.name]
+	if !exists {
+		return f
+	}
+	f.mu.RLock()
+	available := f.available(r.isOffline)
+	f.mu.RUnlock()
+	if available {
+		return f
+	}
+	return old
+}
+
+// finishReencode removes the old layout of a file once its new layout has
+// been fully uploaded. The renter's lock must be held by the caller.
+func (r *Renter) finishReencode(siaPath string) {
+	if _, exists := r.reencoding[siaPath]; !exists {
+		return
+	}
+	delete(r.reencoding, siaPath)
+	err := os.RemoveAll(r.reencodePath(siaPath))
+	if err != nil {
+		r.log.Println("WARN: couldn't remove old layout of re-encoded file:", err)
+	}
+	r.log.Println("Finished re-encoding", siaPath)
+}
+
+// SetFileErasureCode changes the erasure code of a file, which changes the
+// redundancy of the file. The file is re-encoded by the repair loop, which
+// fetches the data of the file from disk or from the network and uploads it
+// again using the new erasure code.
+func (r *Renter) SetFileErasureCode(siaPath string, ec modules.ErasureCoder) error {
+	if ec == nil {
+		return errors.New("no erasure code provided")
+	}
+	if err := r.checkContractsForErasureCode(ec); err != nil {
+		return err
+	}
+
+	lockID := r.mu.Lock()
+	f, exists := r.files[siaPath]
+	if !exists {
+		r.mu.Unlock(lockID)
+		return ErrUnknownPath
+	}
+	if _, tracked := r.tracking[siaPath]; !tracked {
+		r.mu.Unlock(lockID)
+		return errFileNotTracked
+	}
+	if sameErasureCode(f.erasureCode, ec) {
+		r.mu.Unlock(lockID)
+		return nil
+	}
+
+	// Keep the old layout of the file as the source of its data. If the file
+	// is already being re-encoded, the layout that is being replaced has not
+	// been fully uploaded, so the original layout is kept instead.
+	if _, reencoding := r.reencoding[siaPath]; !reencoding {
+		f.mu.RLock()
+		err := saveFileAs(f, r.reencodePath(siaPath))
+		f.mu.RUnlock()
+		if err != nil {
+			r.mu.Unlock(lockID)
+			return err
+		}
+		r.reencoding[siaPath] = f
+	}
+
+	// Replace the file with an empty layout that uses the new erasure code.
+	// The new layout uses a new master key, so that no piece is encrypted
+	// with the same key as a piece of the old layout.
+	f.mu.RLock()
+	nf := newFile(siaPath, ec, f.pieceSize, f.size)
+	nf.mode = f.mode
+	f.mu.RUnlock()
+	r.files[siaPath] = nf
+	err := r.saveFile(nf)
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return err
+	}
+
+	// Send the new layout to the repair loop.
+	select {
+	case r.newUploads <- nf:
+	case <-r.tg.StopChan():
+		return errors.New("re-encode interrupted by stop call")
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package renter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/build"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestRenterSetFileErasureCode probes the SetFileErasureCode method of the
+// renter type.
+func TestRenterSetFileErasureCode(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	// Create a local file so that the repair loop can read the data of the
+	// file from disk.
+	localPath := filepath.Join(build.SiaTestingDir, "renter", t.Name(), "local.dat")
+	data := fastrand.Bytes(1024)
+	f, err := os.Create(localPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write(data)
+	f.Close()
+
+	rsc, _ := NewRSCode(1, 1)
+	oldFile := newFile("foo", rsc, pieceSize, uint64(len(data)))
+	newCode, _ := NewRSCode(2, 4)
+
+	// Re-encoding a file that doesn't exist or that isn't tracked should fail.
+	if err := rt.renter.SetFileErasureCode("foo", newCode); err != ErrUnknownPath {
+		t.Fatal("expected ErrUnknownPath, got", err)
+	}
+	id := rt.renter.mu.Lock()
+	rt.renter.files["foo"] = oldFile
+	rt.renter.mu.Unlock(id)
+	if err := rt.renter.SetFileErasureCode("foo", newCode); err != errFileNotTracked {
+		t.Fatal("expected errFileNotTracked, got", err)
+	}
+
+	// Re-encode the file.
+	id = rt.renter.mu.Lock()
+	rt.renter.tracking["foo"] = trackedFile{RepairPath: localPath}
+	rt.renter.mu.Unlock(id)
+	if err := rt.renter.SetFileErasureCode("foo", newCode); err != nil {
+		t.Fatal(err)
+	}
+	id = rt.renter.mu.RLock()
+	nf := rt.renter.files["foo"]
+	old := rt.renter.reencoding["foo"]
+	rt.renter.mu.RUnlock(id)
+	if old != oldFile {
+		t.Fatal("old layout of the file was not kept")
+	}
+	if nf == oldFile || !sameErasureCode(nf.erasureCode, newCode) || nf.size != oldFile.size {
+		t.Fatal("file was not replaced with a new layout")
+	}
+	if nf.masterKey == oldFile.masterKey {
+		t.Fatal("new layout reuses the master key of the old layout")
+	}
+	if _, err := os.Stat(rt.renter.reencodePath("foo")); err != nil {
+		t.Fatal("old layout was not saved:", err)
+	}
+
+	// Renaming the file should move the old layout too.
+	if err := rt.renter.RenameFile("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+	id = rt.renter.mu.RLock()
+	_, oldExists := rt.renter.reencoding["foo"]
+	_, newExists := rt.renter.reencoding["bar"]
+	rt.renter.mu.RUnlock(id)
+	if oldExists || !newExists {
+		t.Fatal("old layout was not renamed")
+	}
+	if _, err := os.Stat(rt.renter.reencodePath("bar")); err != nil {
+		t.Fatal("old layout was not saved after rename:", err)
+	}
+
+	// The old layout should be readable from disk.
+	reencodeFile, err := os.Open(rt.renter.reencodePath("bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id = rt.renter.mu.RLock()
+	loaded, err := rt.renter.readSharedFiles(reencodeFile)
+	rt.renter.mu.RUnlock(id)
+	reencodeFile.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || loaded[0].name != "bar" || !sameErasureCode(loaded[0].erasureCode, rsc) {
+		t.Fatal("old layout was not saved correctly")
+	}
+
+	// Finishing the re-encode should remove the old layout.
+	id = rt.renter.mu.Lock()
+	rt.renter.finishReencode("bar")
+	_, exists := rt.renter.reencoding["bar"]
+	rt.renter.mu.Unlock(id)
+	if exists {
+		t.Fatal("old layout was not removed")
+	}
+	if _, err := os.Stat(rt.renter.reencodePath("bar")); !os.IsNotExist(err) {
+		t.Fatal("old layout was not removed from disk:", err)
+	}
+}
@@ -30,6 +30,7 @@ import (
 	"errors"
 	"reflect"
 	"sync"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/modules"
@@ -82,6 +83,14 @@ type hostDB interface {
 	// Host returns the HostDBEntry for a given host.
 	Host(types.SiaPublicKey) (modules.HostDBEntry, bool)
 
+	// RecordDownload updates the download throughput of a host after a
+	// download of the provided size and duration.
+	RecordDownload(types.SiaPublicKey, uint64, time.Duration)
+
+	// RecordUpload updates the upload throughput of a host after an upload of
+	// the provided size and duration.
+	RecordUpload(types.SiaPublicKey, uint64, time.Duration)
+
 	// RandomHosts returns a set of random hosts, weighted by their estimated
 	// usefulness / attractiveness to the renter. RandomHosts will not return
 	// any offline or inactive hosts.
@@ -94,6 +103,21 @@ type hostDB interface {
 	// EstimateHostScore returns the estimated score breakdown of a host with the
 	// provided settings.
 	EstimateHostScore(modules.HostDBEntry) modules.HostScoreBreakdown
+
+	// Filter returns the filter mode of the hostdb and the hosts on its
+	// filter list.
+	Filter() (modules.FilterMode, []types.SiaPublicKey)
+
+	// SetFilterMode sets the filter mode of the hostdb and replaces its
+	// filter list.
+	SetFilterMode(modules.FilterMode, []types.SiaPublicKey) error
+
+	// SetWeightProfile sets the profile that the hostdb uses to weight
+	// hosts, rebuilding the host tree.
+	SetWeightProfile(modules.HostWeightProfile) error
+
+	// WeightProfile returns the profile that the hostdb uses to weight hosts.
+	WeightProfile() modules.HostWeightProfile
 }
 
 // A hostContractor negotiates, revises, renews, and provides access to file
@@ -108,6 +132,10 @@ type hostContractor interface {
 	// Allowance returns the current allowance
 	Allowance() modules.Allowance
 
+	// Alerts returns the alerts about the spending during the current
+	// billing period and the contracts that ran out of funds.
+	Alerts() []modules.RenterAlert
+
 	// Close closes the hostContractor.
 	Close() error
 
@@ -117,6 +145,10 @@ type hostContractor interface {
 	// ContractByID returns the contract associated with the file contract id.
 	ContractByID(types.FileContractID) (modules.RenterContract, bool)
 
+	// ContractOutcome returns the outcome of the proof window of a given
+	// contract, along with a bool indicating if the contract exists.
+	ContractOutcome(types.FileContractID) (modules.ContractOutcome, bool)
+
 	// ContractUtility returns the utility field for a given contract, along
 	// with a bool indicating if it exists.
 	ContractUtility(types.FileContractID) (modules.ContractUtility, bool)
@@ -125,10 +157,42 @@ type hostContractor interface {
 	// began.
 	CurrentPeriod() types.BlockHeight
 
+	// FormContract forms a contract with a specific host.
+	FormContract(types.SiaPublicKey, types.Currency) (modules.RenterContract, error)
+
+	// OldContracts returns the contracts that have expired or have been
+	// renewed.
+	OldContracts() []modules.RenterContract
+
 	// PeriodSpending returns the amount spent on contracts during the current
 	// billing period.
 	PeriodSpending() modules.ContractorSpending
 
+	// SpendingHistory returns a spending report for every past billing
+	// period, followed by a report for the current period.
+	SpendingHistory() []modules.SpendingReport
+
+	// SpendingAlerts returns the spending alert thresholds, and
+	// SetSpendingAlerts sets them.
+	SpendingAlerts() []uint64
+	SetSpendingAlerts([]uint64) error
+
+	// TopUpPolicy returns the policy for refilling contracts beyond the
+	// allowance, and SetTopUpPolicy sets it.
+	TopUpPolicy() modules.TopUpPolicy
+	SetTopUpPolicy(modules.TopUpPolicy) error
+
+	// RenewedIDs returns a map from every renewed contract to the contract
+	// that replaced it.
+	RenewedIDs() map[types.FileContractID]types.FileContractID
+
+	// RenewContract renews a specific contract, ending the renewed contract
+	// at the provided height.
+	RenewContract(types.FileContractID, types.Currency, types.BlockHeight) (modules.RenterContract, error)
+
+	// SetContractUtility restricts the utility of a specific contract.
+	SetContractUtility(types.FileContractID, modules.ContractUtility) error
+
 	// Editor creates an Editor from the specified contract ID, allowing the
 	// insertion, deletion, and modification of sectors.
 	Editor(types.FileContractID, <-chan struct{}) (contractor.Editor, error)
@@ -136,6 +200,10 @@ type hostContractor interface {
 	// IsOffline reports whether the specified host is considered offline.
 	IsOffline(types.FileContractID) bool
 
+	// RestartMaintenance interrupts any running contract maintenance and
+	// starts a new round.
+	RestartMaintenance()
+
 	// Downloader creates a Downloader from the specified contract ID,
 	// allowing the retrieval of sectors.
 	Downloader(types.FileContractID, <-chan struct{}) (contractor.Downloader, error)
@@ -151,6 +219,20 @@ type hostContractor interface {
 type trackedFile struct {
 	// location of original file on disk
 	RepairPath string
+
+	// Priority orders the chunks of the file in the repair heap. Paused files
+	// are not uploaded or repaired.
+	Priority int
+	Paused   bool
+
+	// LastRepair is the time at which a piece of the file was last uploaded.
+	// RepairFailures is the number of consecutive failed repairs of chunks
+	// below the repair threshold, and Stuck is set once it reaches
+	// maxRepairFailures. Stuck is cleared once none of the file's chunks are
+	// below the threshold.
+	LastRepair     time.Time
+	RepairFailures int
+	Stuck          bool
 }
 
 // A Renter is responsible for tracking all of the files that a user has
@@ -160,8 +242,18 @@ type Renter struct {
 	//
 	// tracking contains a list of files that the user intends to maintain. By
 	// default, files loaded through sharing are not maintained by the user.
-	files    map[string]*file
-	tracking map[string]trackedFile // map from nickname to metadata
+	//
+	// directories contains the directories that were created explicitly by
+	// the user. Directories that contain files exist implicitly. dirIndex
+	// indexes the contents of every directory.
+	//
+	// reencoding contains the old layout of files whose erasure code has been
+	// changed, until the new layout has been fully uploaded.
+	files       map[string]*file
+	tracking    map[string]trackedFile // map from nickname to metadata
+	directories map[string]struct{}
+	dirIndex    dirIndex
+	reencoding  map[string]*file
 
 	// Work management.
 	//
@@ -175,8 +267,15 @@ type Renter struct {
 	downloadQueue []*download
 	newDownloads  chan *download
 	newUploads    chan *file
+	rebuildHeap   chan struct{}
 	workerPool    map[types.FileContractID]*worker
 
+	// downloadSaveMu serializes saves of the download queue, which are done
+	// without holding the renter's lock. lastDownloadSave is the time of the
+	// last save, and is protected by downloadSaveMu.
+	downloadSaveMu   sync.Mutex
+	lastDownloadSave time.Time
+
 	// Memory management - baseMemory tracks how much memory the renter is
 	// allowed to consume, memoryAvailable tracks how much more memory the
 	// renter can allocate before hitting the cap, and newMemory is a channel
@@ -186,6 +285,18 @@ type Renter struct {
 	memoryAvailable uint64
 	newMemory       chan struct{}
 
+	// chunkCache contains recently recovered chunks, which are served to
+	// downloads without fetching pieces from hosts.
+	chunkCache *chunkCache
+
+	// uploadLimit and downloadLimit restrict the bandwidth used by all of the
+	// workers combined. Before a worker transfers a sector, it waits until the
+	// bytes of the sector fit within the corresponding limit. Transfers are
+	// not throttled while they are in progress, so the limits are only
+	// accurate over periods that are long compared to a single transfer.
+	uploadLimit   *siasync.RateLimit
+	downloadLimit *siasync.RateLimit
+
 	// Utilities.
 	cs             modules.ConsensusSet
 	g              modules.Gateway
@@ -234,17 +345,24 @@ func newRenter(g modules.Gateway, cs modules.ConsensusSet, tpool modules.Transac
 	}
 
 	r := &Renter{
-		files:    make(map[string]*file),
-		tracking: make(map[string]trackedFile),
+		files:       make(map[string]*file),
+		tracking:    make(map[string]trackedFile),
+		directories: make(map[string]struct{}),
+		dirIndex:    newDirIndex(),
+		reencoding:  make(map[string]*file),
 
 		newDownloads: make(chan *download),
 		newUploads:   make(chan *file),
+		rebuildHeap:  make(chan struct{}, 1),
 		workerPool:   make(map[types.FileContractID]*worker),
 
 		baseMemory:      defaultMemory,
 		memoryAvailable: defaultMemory,
 		newMemory:       make(chan struct{}, 1),
 
+		uploadLimit:   siasync.NewRateLimit(0),
+		downloadLimit: siasync.NewRateLimit(0),
+
 		cs:             cs,
 		g:              g,
 		hostDB:         hdb,
@@ -268,6 +386,15 @@ func newRenter(g modules.Gateway, cs modules.ConsensusSet, tpool modules.Transac
 	go r.threadedRepairScan()
 	go r.threadedDownloadLoop()
 
+	// Save the progress of the downloads once the download loop has stopped.
+	r.tg.AfterStop(func() error {
+		err := r.managedSaveDownloads()
+		if err != nil {
+			r.log.Println("WARN: could not save download queue:", err)
+		}
+		return err
+	})
+
 	// Kill workers on shutdown.
 	r.tg.OnStop(func() error {
 		id := r.mu.RLock()
@@ -309,17 +436,17 @@ func (r *Renter) managedMemoryAvailableGet() uint64 {
 	return memAvail
 }
 
-// managedMemoryAvailableSub subtracts the amount provided from the renter's
-// total memory available.
-func (r *Renter) managedMemoryAvailableSub(amt uint64) {
+// managedTryMemoryAvailableSub subtracts the amount provided from the renter's
+// total memory available if enough memory is available, returning whether the
+// memory was acquired.
+func (r *Renter) managedTryMemoryAvailableSub(amt uint64) bool {
 	id := r.mu.Lock()
+	defer r.mu.Unlock(id)
 	if r.memoryAvailable < amt {
-		r.mu.Unlock(id)
-		r.log.Critical("Memory available is underflowing", r.memoryAvailable, amt)
-		return
+		return false
 	}
 	r.memoryAvailable -= amt
-	r.mu.Unlock(id)
+	return true
 }
 
 // Close closes the Renter and its dependencies
@@ -401,7 +528,31 @@ func (r *Renter) PriceEstimation() modules.RenterPriceEstimation {
 
 // SetSettings will update the settings for the renter.
 func (r *Renter) SetSettings(s modules.RenterSettings) error {
-	err := r.hostContractor.SetAllowance(s.Allowance)
+	// Only set the allowance if it changed, since setting the allowance
+	// interrupts contract maintenance.
+	if !reflect.DeepEqual(s.Allowance, r.hostContractor.Allowance()) {
+		err := r.hostContractor.SetAllowance(s.Allowance)
+		if err != nil {
+			return err
+		}
+	}
+	if !reflect.DeepEqual(s.TopUp, r.hostContractor.TopUpPolicy()) {
+		err := r.hostContractor.SetTopUpPolicy(s.TopUp)
+		if err != nil {
+			return err
+		}
+	}
+	err := r.hostContractor.SetSpendingAlerts(s.SpendingAlerts)
+	if err != nil {
+		return err
+	}
+
+	id := r.mu.Lock()
+	r.chunkCache.setSize(s.ChunkCacheSize, s.ChunkCacheDiskSize)
+	r.uploadLimit.SetLimit(s.MaxUploadSpeed)
+	r.downloadLimit.SetLimit(s.MaxDownloadSpeed)
+	err = r.saveSync()
+	r.mu.Unlock(id)
 	if err != nil {
 		return err
 	}
@@ -410,6 +561,12 @@ func (r *Renter) SetSettings(s modules.RenterSettings) error {
 	return nil
 }
 
+// ChunkCacheMetrics returns the hit and miss counts and the usage of the
+// renter's chunk cache.
+func (r *Renter) ChunkCacheMetrics() modules.ChunkCacheMetrics {
+	return r.chunkCache.metrics()
+}
+
 // hostdb passthroughs
 func (r *Renter) ActiveHosts() []modules.HostDBEntry                      { return r.hostDB.ActiveHosts() }
 func (r *Renter) AllHosts() []modules.HostDBEntry                         { return r.hostDB.AllHosts() }
@@ -420,14 +577,66 @@ func (r *Renter) ScoreBreakdown(e modules.HostDBEntry) modules.HostScoreBreakdow
 func (r *Renter) EstimateHostScore(e modules.HostDBEntry) modules.HostScoreBreakdown {
 	return r.hostDB.EstimateHostScore(e)
 }
+func (r *Renter) Filter() (modules.FilterMode, []types.SiaPublicKey) { return r.hostDB.Filter() }
+
+// SetFilterMode sets the filter mode of the hostdb and restarts contract
+// maintenance, so that contracts with hosts that are filtered out are replaced.
+func (r *Renter) SetFilterMode(fm modules.FilterMode, hosts []types.SiaPublicKey) error {
+	if err := r.hostDB.SetFilterMode(fm, hosts); err != nil {
+		return err
+	}
+	r.hostContractor.RestartMaintenance()
+	return nil
+}
+
+// SetWeightProfile sets the weight profile of the hostdb and restarts contract
+// maintenance, so that the contracts are judged using the new scores.
+func (r *Renter) SetWeightProfile(wp modules.HostWeightProfile) error {
+	if err := r.hostDB.SetWeightProfile(wp); err != nil {
+		return err
+	}
+	r.hostContractor.RestartMaintenance()
+	return nil
+}
+func (r *Renter) WeightProfile() modules.HostWeightProfile { return r.hostDB.WeightProfile() }
 
 // contractor passthroughs
 func (r *Renter) Contracts() []modules.RenterContract        { return r.hostContractor.Contracts() }
 func (r *Renter) CurrentPeriod() types.BlockHeight           { return r.hostContractor.CurrentPeriod() }
 func (r *Renter) PeriodSpending() modules.ContractorSpending { return r.hostContractor.PeriodSpending() }
+func (r *Renter) OldContracts() []modules.RenterContract     { return r.hostContractor.OldContracts() }
+func (r *Renter) RenewedIDs() map[types.FileContractID]types.FileContractID {
+	return r.hostContractor.RenewedIDs()
+}
+func (r *Renter) Alerts() []modules.RenterAlert { return r.hostContractor.Alerts() }
+func (r *Renter) SpendingHistory() []modules.SpendingReport {
+	return r.hostContractor.SpendingHistory()
+}
+func (r *Renter) ContractOutcome(id types.FileContractID) (modules.ContractOutcome, bool) {
+	return r.hostContractor.ContractOutcome(id)
+}
+func (r *Renter) ContractUtility(id types.FileContractID) (modules.ContractUtility, bool) {
+	return r.hostContractor.ContractUtility(id)
+}
+func (r *Renter) FormContract(hostKey types.SiaPublicKey, funds types.Currency) (modules.RenterContract, error) {
+	return r.hostContractor.FormContract(hostKey, funds)
+}
+func (r *Renter) RenewContract(id types.FileContractID, funds types.Currency, endHeight types.BlockHeight) (modules.RenterContract, error) {
+	return r.hostContractor.RenewContract(id, funds, endHeight)
+}
+func (r *Renter) SetContractUtility(id types.FileContractID, utility modules.ContractUtility) error {
+	return r.hostContractor.SetContractUtility(id, utility)
+}
 func (r *Renter) Settings() modules.RenterSettings {
+	maxMemory, maxDisk := r.chunkCache.size()
 	return modules.RenterSettings{
-		Allowance: r.hostContractor.Allowance(),
+		Allowance:          r.hostContractor.Allowance(),
+		ChunkCacheSize:     maxMemory,
+		ChunkCacheDiskSize: maxDisk,
+		MaxUploadSpeed:     r.uploadLimit.Limit(),
+		MaxDownloadSpeed:   r.downloadLimit.Limit(),
+		TopUp:              r.hostContractor.TopUpPolicy(),
+		SpendingAlerts:     r.hostContractor.SpendingAlerts(),
 	}
 }
 func (r *Renter) ProcessConsensusChange(cc modules.ConsensusChange) {
@@ -4,6 +4,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/NebulousLabs/Sia/build"
 	"github.com/NebulousLabs/Sia/crypto"
@@ -162,11 +163,13 @@ func newContractorTester(name string, hdb hostDB, hc hostContractor) (*renterTes
 // of the hostDB's methods on every mock.
 type stubHostDB struct{}
 
-func (stubHostDB) ActiveHosts() []modules.HostDBEntry   { return nil }
-func (stubHostDB) AllHosts() []modules.HostDBEntry      { return nil }
-func (stubHostDB) AverageContractPrice() types.Currency { return types.Currency{} }
-func (stubHostDB) Close() error                         { return nil }
-func (stubHostDB) IsOffline(modules.NetAddress) bool    { return true }
+func (stubHostDB) ActiveHosts() []modules.HostDBEntry                       { return nil }
+func (stubHostDB) AllHosts() []modules.HostDBEntry                          { return nil }
+func (stubHostDB) AverageContractPrice() types.Currency                     { return types.Currency{} }
+func (stubHostDB) Close() error                                             { return nil }
+func (stubHostDB) IsOffline(modules.NetAddress) bool                        { return true }
+func (stubHostDB) RecordDownload(types.SiaPublicKey, uint64, time.Duration) {}
+func (stubHostDB) RecordUpload(types.SiaPublicKey, uint64, time.Duration)   {}
 func (stubHostDB) RandomHosts(int, []types.SiaPublicKey) []modules.HostDBEntry {
 	return []modules.HostDBEntry{}
 }
@@ -179,6 +182,14 @@ func (stubHostDB) Host(types.SiaPublicKey) (modules.HostDBEntry, bool) {
 func (stubHostDB) ScoreBreakdown(modules.HostDBEntry) modules.HostScoreBreakdown {
 	return modules.HostScoreBreakdown{}
 }
+func (stubHostDB) Filter() (modules.FilterMode, []types.SiaPublicKey) {
+	return modules.HostDBFilterDisabled, nil
+}
+func (stubHostDB) SetFilterMode(modules.FilterMode, []types.SiaPublicKey) error { return nil }
+func (stubHostDB) SetWeightProfile(modules.HostWeightProfile) error             { return nil }
+func (stubHostDB) WeightProfile() modules.HostWeightProfile {
+	return modules.DefaultHostWeightProfile
+}
 
 // stubContractor is the minimal implementation of the hostContractor
 // interface.
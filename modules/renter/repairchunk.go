@@ -27,6 +27,10 @@ func (r *Renter) managedDistributeChunkToWorkers(uc *unfinishedChunk) {
 	for _, worker := range workers {
 		worker.managedQueueChunkRepair(uc)
 	}
+	// Without workers, no pieces of the chunk can be uploaded.
+	if len(workers) == 0 && uc.repairing {
+		r.managedRecordRepair(uc.renterFile, false)
+	}
 
 	// Perform cleanup for any pieces that will never be used by a worker.
 	r.managedReleaseIdleChunkPieces(uc)
@@ -46,9 +50,24 @@ func (r *Renter) managedDownloadLogicalChunkData(chunk *unfinishedChunk) error {
 	// from the same memory pool while other processes are asynchronously doing
 	// the same, we risk deadlock.
 	buf := NewDownloadBufferWriter(chunk.length, chunk.offset)
+	source := chunk.sourceFile
+	if source == nil {
+		source = chunk.renterFile
+	}
+	// The chunks of the source may have a different size than the chunks of
+	// the file, in which case the download must not extend past the end of
+	// the source. The rest of the buffer is padding.
+	length := chunk.length
+	if source != chunk.renterFile && uint64(chunk.offset)+length > source.size {
+		length = source.size - uint64(chunk.offset)
+	}
+	if length == 0 {
+		chunk.logicalChunkData = buf.Bytes()
+		return nil
+	}
 	// TODO: Should convert the inputs of newSectionDownload to use an int64 for
 	// the offset.
-	d := r.newSectionDownload(chunk.renterFile, buf, uint64(chunk.offset), chunk.length)
+	d := r.newSectionDownload(source, buf, uint64(chunk.offset), length)
 	select {
 	case r.newDownloads <- d:
 	case <-r.tg.StopChan():
@@ -72,9 +91,8 @@ func (r *Renter) managedDownloadLogicalChunkData(chunk *unfinishedChunk) error {
 // the physical pieces for the chunk, and then distribute them. The returned
 // bool indicates whether the chunk was successfully distributed to workers.
 func (r *Renter) managedFetchAndRepairChunk(chunk *unfinishedChunk) bool {
-	// Only download this file if more than 25% of the redundancy is missing.
-	minMissingPiecesToDownload := (chunk.piecesNeeded - chunk.minimumPieces) / 4
-	download := chunk.piecesCompleted+minMissingPiecesToDownload < chunk.piecesNeeded
+	// Only download this file if the chunk is below the repair threshold.
+	download := chunk.piecesCompleted < repairThreshold(chunk.minimumPieces, chunk.piecesNeeded)
 
 	// Fetch the logical data for the chunk.
 	err := r.managedFetchLogicalChunkData(chunk, download)
@@ -131,6 +149,11 @@ func (r *Renter) managedFetchAndRepairChunk(chunk *unfinishedChunk) bool {
 // chunk.data should be passed as 'nil' to the download, to keep memory usage as
 // light as possible.
 func (r *Renter) managedFetchLogicalChunkData(chunk *unfinishedChunk, download bool) error {
+	// Chunks of streamed uploads already have their logical data in memory.
+	if chunk.logicalChunkData != nil {
+		return nil
+	}
+
 	// Download the chunk if it's not on disk.
 	if chunk.localPath == "" && download {
 		return r.managedDownloadLogicalChunkData(chunk)
@@ -26,18 +26,23 @@ import (
 	"github.com/NebulousLabs/Sia/crypto"
 )
 
-// ChunkHeap is a bunch of chunks sorted by percentage-completion for uploading.
-// This is a temporary situation, once we have a filesystem we can do
-// tree-diving instead to build out our chunk profile. This just simulates that.
+// ChunkHeap is a bunch of chunks sorted by the priority of their file and then
+// by percentage-completion for uploading. This is a temporary situation, once
+// we have a filesystem we can do tree-diving instead to build out our chunk
+// profile. This just simulates that.
 type chunkHeap []*unfinishedChunk
 
 // unfinishedChunk contains a chunk from the filesystem that has not finished
 // uploading, including knowledge of the progress.
 type unfinishedChunk struct {
 	// Information about the file. localPath may be the empty string if the file
-	// is known not to exist locally.
+	// is known not to exist locally. sourceFile is the layout that the logical
+	// data is downloaded from if it is not available locally; it differs from
+	// renterFile while the file is being re-encoded.
 	renterFile *file
+	sourceFile *file
 	localPath  string
+	priority   int
 
 	// Information about the chunk, namely where it exists within the file.
 	//
@@ -66,11 +71,46 @@ type unfinishedChunk struct {
 	piecesRegistered int                 // number of pieces that are being uploaded, but aren't finished yet.
 	unusedHosts      map[string]struct{} // hosts that aren't yet storing any pieces
 	workersRemaining int                 // number of workers who have received the chunk, but haven't finished processing it.
+
+	// availableChan is closed once enough pieces have been uploaded for the
+	// chunk to be recoverable, or once no workers remain that could upload
+	// more pieces. It is only set for chunks of streamed uploads, which need
+	// to wait on a chunk before reading the next one from the stream.
+	availableChan   chan struct{}
+	availableClosed bool
+
+	// repairing is set if the chunk was below the repair threshold when it was
+	// prepared. The outcome of the repair is recorded once the last worker has
+	// dropped the chunk, based on the pieces that were actually uploaded.
+	repairing bool
+}
+
+// repaired returns true if enough pieces of the chunk have been uploaded for it
+// to no longer be below the repair threshold. The chunk's mutex must be held by
+// the caller.
+func (uc *unfinishedChunk) repaired() bool {
+	return uc.piecesCompleted >= repairThreshold(uc.minimumPieces, uc.piecesNeeded)
+}
+
+// notifyAvailable closes the availableChan of the chunk if the chunk has
+// become available or if no more progress can be made on it. The chunk's mutex
+// must be held by the caller.
+func (uc *unfinishedChunk) notifyAvailable() {
+	if uc.availableChan == nil || uc.availableClosed {
+		return
+	}
+	if uc.piecesCompleted >= uc.minimumPieces || uc.workersRemaining == 0 {
+		uc.availableClosed = true
+		close(uc.availableChan)
+	}
 }
 
 // Implementation of heap.Interface for chunkHeap.
 func (ch chunkHeap) Len() int { return len(ch) }
 func (ch chunkHeap) Less(i, j int) bool {
+	if ch[i].priority != ch[j].priority {
+		return ch[i].priority > ch[j].priority
+	}
 	return float64(ch[i].piecesCompleted)/float64(ch[i].piecesNeeded) < float64(ch[j].piecesCompleted)/float64(ch[j].piecesNeeded)
 }
 func (ch chunkHeap) Swap(i, j int)       { ch[i], ch[j] = ch[j], ch[i] }
@@ -93,12 +133,20 @@ func (r *Renter) buildUnfinishedChunks(f *file, hosts map[string]struct{}) []*un
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
-	// If the file is not being tracked, don't repair it.
+	// If the file is not being tracked, or if uploading the file has been
+	// paused, don't repair it.
 	trackedFile, exists := r.tracking[f.name]
-	if !exists {
+	if !exists || trackedFile.Paused {
 		return nil
 	}
 
+	// If the file is being re-encoded, its data has to be downloaded using the
+	// old layout.
+	sourceFile := f
+	if old, exists := r.reencoding[f.name]; exists {
+		sourceFile = old
+	}
+
 	// Assemble the set of chunks.
 	//
 	// TODO / NOTE: Future files may have a different method for determining the
@@ -109,7 +157,9 @@ func (r *Renter) buildUnfinishedChunks(f *file, hosts map[string]struct{}) []*un
 	for i := uint64(0); i < chunkCount; i++ {
 		newUnfinishedChunks[i] = &unfinishedChunk{
 			renterFile: f,
+			sourceFile: sourceFile,
 			localPath:  trackedFile.RepairPath,
+			priority:   trackedFile.Priority,
 
 			index:  i,
 			length: f.chunkSize(),
@@ -190,6 +240,12 @@ func (r *Renter) buildUnfinishedChunks(f *file, hosts map[string]struct{}) []*un
 			incompleteChunks = append(incompleteChunks, newUnfinishedChunks[i])
 		}
 	}
+
+	// Once the new layout of a re-encoded file has been fully uploaded, the
+	// old layout is no longer needed.
+	if len(incompleteChunks) == 0 && sourceFile != f {
+		r.finishReencode(f.name)
+	}
 	return incompleteChunks
 }
 
@@ -200,12 +256,21 @@ func (r *Renter) managedBuildChunkHeap(hosts map[string]struct{}) *chunkHeap {
 	ch := new(chunkHeap)
 	heap.Init(ch)
 	id := r.mu.Lock()
+	unstuck := false
 	for _, file := range r.files {
+		if r.clearStuck(file) {
+			unstuck = true
+		}
 		unfinishedChunks := r.buildUnfinishedChunks(file, hosts)
 		for i := 0; i < len(unfinishedChunks); i++ {
 			heap.Push(ch, unfinishedChunks[i])
 		}
 	}
+	if unstuck {
+		if err := r.saveSync(); err != nil {
+			r.log.Println("ERROR: unable to save the renter after files became healthy:", err)
+		}
+	}
 	r.mu.Unlock(id)
 
 	// Init the heap.
@@ -235,29 +300,43 @@ func (r *Renter) managedInsertFileIntoChunkHeap(f *file, ch *chunkHeap, hosts ma
 // pool object or something, and then that object can worry about breaking and
 // stuff, and can also make sure that the memory goes to only one place.
 func (r *Renter) managedPrepareNextChunk(ch *chunkHeap, hosts map[string]struct{}) {
-	// Grab the next chunk, loop until we have enough memory, update the amount
-	// of memory available, and then spin up a thread to asynchronously handle
-	// the rest of the chunk tasks.
-	memoryAvailable := r.managedMemoryAvailableGet()
+	// Grab the next chunk, loop until we have acquired enough memory, and then
+	// spin up a thread to asynchronously handle the rest of the chunk tasks.
+	// The memory is acquired atomically because streamed uploads draw from
+	// the same pool.
 	nextChunk := heap.Pop(ch).(*unfinishedChunk)
-	for nextChunk.memoryNeeded > memoryAvailable {
+
+	// Skip the chunk if uploading its file was paused after the heap was
+	// built.
+	id := r.mu.RLock()
+	nextChunk.renterFile.mu.RLock()
+	tf := r.tracking[nextChunk.renterFile.name]
+	nextChunk.renterFile.mu.RUnlock()
+	r.mu.RUnlock(id)
+	if tf.Paused {
+		return
+	}
+
+	for !r.managedTryMemoryAvailableSub(nextChunk.memoryNeeded) {
 		select {
 		case newFile := <-r.newUploads:
 			r.managedInsertFileIntoChunkHeap(newFile, ch, hosts)
 		case <-r.newMemory:
-			memoryAvailable = r.managedMemoryAvailableGet()
 		case <-r.tg.StopChan():
 			return
 		}
 	}
-	r.managedMemoryAvailableSub(nextChunk.memoryNeeded)
 	// Add this thread to the waitgroup. This Add will be released once the
 	// worker threads have been added to the wg.
 	r.heapWG.Add(1)
+	nextChunk.repairing = !nextChunk.repaired()
 	go func() {
 		workDistributed := r.managedFetchAndRepairChunk(nextChunk)
 		r.heapWG.Done()
 		if !workDistributed {
+			if nextChunk.repairing {
+				r.managedRecordRepair(nextChunk.renterFile, false)
+			}
 			// Release any data that did not get distributed to workers.
 			r.managedMemoryAvailableAdd(nextChunk.memoryNeeded - nextChunk.memoryReleased)
 		}
@@ -337,10 +416,14 @@ func (r *Renter) threadedRepairScan() {
 		rebuildHeapSignal := time.After(rebuildChunkHeapInterval)
 	LOOP:
 		for {
-			// Return if the renter has shut down.
+			// Return if the renter has shut down, and rebuild the heap if
+			// the priority of a file has changed or a file has been resumed.
 			select {
 			case <-r.tg.StopChan():
 				return
+			case <-r.rebuildHeap:
+				r.heapWG.Wait()
+				break LOOP
 			default:
 			}
 
@@ -359,6 +442,9 @@ func (r *Renter) threadedRepairScan() {
 					hosts = r.managedRefreshHostsAndWorkers()
 					r.managedInsertFileIntoChunkHeap(newFile, chunkHeap, hosts)
 					continue
+				case <-r.rebuildHeap:
+					r.heapWG.Wait()
+					break LOOP
 				case <-rebuildHeapSignal:
 					// If the rebuild heap signal is received, break out to the
 					// outer loop which will check the health of all filess
@@ -0,0 +1,204 @@
+package renter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sort"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/encoding"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/types"
+)
+
+// The siafile format describes a single file in a .sia file. Every file starts
+// with a header that contains everything needed to interpret the rest of the
+// file: the erasure coding parameters and the cipher that was used to encrypt
+// the pieces. The header is followed by the list of hosts that store pieces of
+// the file, identified by their public key, and finally the piece table of
+// each chunk.
+//
+// The hosts and each of the chunks are encoded as separate objects, so that
+// the size of a file is not limited by the maximum size of a single encoded
+// object.
+
+var (
+	// cipherTypeTwofish identifies the Twofish-GCM cipher that is used to
+	// encrypt pieces with a key derived from the master key of the file.
+	cipherTypeTwofish = types.Specifier{'T', 'w', 'o', 'f', 'i', 's', 'h', '-', 'G', 'C', 'M'}
+
+	errBadPieceTable     = errors.New("piece table of siafile is inconsistent with its header")
+	errUnknownCipherType = errors.New("unknown cipher type")
+)
+
+type (
+	// siaFileHeader contains the metadata of a file that is needed to
+	// interpret its piece tables.
+	siaFileHeader struct {
+		Name      string
+		Size      uint64
+		Mode      uint32
+		PieceSize uint64
+
+		CipherType types.Specifier
+		MasterKey  []byte
+
+		ErasureCodeType   modules.ErasureCoderType
+		ErasureCodeParams []uint64
+	}
+
+	// siaFileHost is a host that stores pieces of a file, along with the
+	// contract that the pieces were uploaded under.
+	siaFileHost struct {
+		PublicKey   types.SiaPublicKey
+		ContractID  types.FileContractID
+		WindowStart types.BlockHeight
+	}
+
+	// siaFilePiece is a single copy of a piece, stored on the host with the
+	// index HostIndex in the list of hosts of the file.
+	siaFilePiece struct {
+		HostIndex  uint64
+		MerkleRoot crypto.Hash
+	}
+
+	// siaFileChunk is the piece table of a single chunk. Pieces[i] contains
+	// every known copy of the i'th piece of the chunk.
+	siaFileChunk struct {
+		Pieces [][]siaFilePiece
+	}
+)
+
+// MarshalSia implements the encoding.SiaMarshaler interface, writing the file
+// data to w in the siafile format. The file's lock must be held by the caller.
+func (f *file) MarshalSia(w io.Writer) error {
+	header := siaFileHeader{
+		Name:      f.name,
+		Size:      f.size,
+		Mode:      f.mode,
+		PieceSize: f.pieceSize,
+
+		CipherType: cipherTypeTwofish,
+		MasterKey:  f.masterKey[:],
+
+		ErasureCodeType:   f.erasureCode.Type(),
+		ErasureCodeParams: f.erasureCode.Params(),
+	}
+
+	// Sort the contracts so that the same file is always encoded the same
+	// way.
+	contracts := make([]fileContract, 0, len(f.contracts))
+	for _, fc := range f.contracts {
+		contracts = append(contracts, fc)
+	}
+	sort.Slice(contracts, func(i, j int) bool {
+		return bytes.Compare(contracts[i].ID[:], contracts[j].ID[:]) < 0
+	})
+
+	// Build the host list and the piece tables. The piece table of a chunk
+	// is only allocated once a piece of the chunk is found, so that chunks
+	// that have not been uploaded yet take up little space.
+	hosts := make([]siaFileHost, len(contracts))
+	chunks := make([]siaFileChunk, f.numChunks())
+	for i, fc := range contracts {
+		hosts[i] = siaFileHost{
+			PublicKey:   fc.HostPublicKey,
+			ContractID:  fc.ID,
+			WindowStart: fc.WindowStart,
+		}
+		for _, p := range fc.Pieces {
+			if p.Chunk >= uint64(len(chunks)) || p.Piece >= uint64(f.erasureCode.NumPieces()) {
+				return errBadPieceTable
+			}
+			if chunks[p.Chunk].Pieces == nil {
+				chunks[p.Chunk].Pieces = make([][]siaFilePiece, f.erasureCode.NumPieces())
+			}
+			chunks[p.Chunk].Pieces[p.Piece] = append(chunks[p.Chunk].Pieces[p.Piece], siaFilePiece{
+				HostIndex:  uint64(i),
+				MerkleRoot: p.MerkleRoot,
+			})
+		}
+	}
+
+	enc := encoding.NewEncoder(w)
+	if err := enc.EncodeAll(header, hosts, uint64(len(chunks))); err != nil {
+		return err
+	}
+	for _, chunk := range chunks {
+		if err := enc.Encode(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UnmarshalSia implements the encoding.SiaUnmarshaler interface, reconstructing
+// a file from the siafile data read from r.
+func (f *file) UnmarshalSia(r io.Reader) error {
+	dec := encoding.NewDecoder(r)
+
+	// Decode and validate the header.
+	var header siaFileHeader
+	var hosts []siaFileHost
+	var numChunks uint64
+	if err := dec.DecodeAll(&header, &hosts, &numChunks); err != nil {
+		return err
+	}
+	if header.CipherType != cipherTypeTwofish {
+		return errUnknownCipherType
+	} else if len(header.MasterKey) != len(f.masterKey) {
+		return errors.New("master key has wrong length for cipher")
+	}
+	ec, err := NewErasureCoder(header.ErasureCodeType, header.ErasureCodeParams)
+	if err != nil {
+		return err
+	}
+	f.name = header.Name
+	f.size = header.Size
+	f.mode = header.Mode
+	f.pieceSize = header.PieceSize
+	copy(f.masterKey[:], header.MasterKey)
+	f.erasureCode = ec
+	if f.pieceSize == 0 && f.size != 0 {
+		return errBadPieceTable
+	}
+	if numChunks != f.numChunks() {
+		return errBadPieceTable
+	}
+
+	// Decode the piece tables, rebuilding the contracts of the file.
+	f.contracts = make(map[types.FileContractID]fileContract)
+	for _, h := range hosts {
+		f.contracts[h.ContractID] = fileContract{
+			ID:            h.ContractID,
+			HostPublicKey: h.PublicKey,
+			WindowStart:   h.WindowStart,
+		}
+	}
+	for chunkIndex := uint64(0); chunkIndex < numChunks; chunkIndex++ {
+		var chunk siaFileChunk
+		if err := dec.Decode(&chunk); err != nil {
+			return err
+		}
+		if len(chunk.Pieces) > ec.NumPieces() {
+			return errBadPieceTable
+		}
+		for pieceIndex, pieces := range chunk.Pieces {
+			for _, p := range pieces {
+				if p.HostIndex >= uint64(len(hosts)) {
+					return errBadPieceTable
+				}
+				id := hosts[p.HostIndex].ContractID
+				fc := f.contracts[id]
+				fc.Pieces = append(fc.Pieces, pieceData{
+					Chunk:      chunkIndex,
+					Piece:      uint64(pieceIndex),
+					MerkleRoot: p.MerkleRoot,
+				})
+				f.contracts[id] = fc
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,175 @@
+package renter
+
+import (
+	"errors"
+	"io"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	errNegativeOffset = errors.New("cannot seek to a negative offset")
+	errStreamClosed   = errors.New("stream has been closed")
+)
+
+type (
+	// streamChunk is a chunk of a file that is being downloaded for a stream.
+	streamChunk struct {
+		d   *download
+		buf *DownloadBufferWriter
+	}
+
+	// streamer implements the modules.Streamer interface. It downloads the
+	// chunk at its current position on demand, and prefetches the chunks
+	// that follow it.
+	streamer struct {
+		chunks map[uint64]*streamChunk
+		etag   string
+		file   *file
+		offset int64
+		r      *Renter
+	}
+)
+
+// startChunk queues the download of a chunk of the streamed file. Stream
+// downloads are not added to the download history.
+func (s *streamer) startChunk(index uint64) *streamChunk {
+	offset := index * s.file.chunkSize()
+	length := s.file.chunkSize()
+	if offset+length > s.file.size {
+		length = s.file.size - offset
+	}
+	buf := NewDownloadBufferWriter(length, int64(offset))
+	d := s.r.newSectionDownload(s.file, buf, offset, length)
+	go func() {
+		select {
+		case s.r.newDownloads <- d:
+		case <-s.r.tg.StopChan():
+		}
+	}()
+	return &streamChunk{d: d, buf: buf}
+}
+
+// cancel stops the download of a chunk that is no longer needed. No new
+// pieces of the chunk are scheduled, but pieces that are already being
+// downloaded are finished by their workers.
+func (sc *streamChunk) cancel() {
+	sc.d.mu.Lock()
+	sc.d.fail(errDownloadCancelled)
+	sc.d.mu.Unlock()
+}
+
+// managedChunk returns the data of the chunk at index, waiting for its
+// download to finish if necessary. The chunks that follow it are prefetched,
+// and the downloads of any other chunks are cancelled.
+func (s *streamer) managedChunk(index uint64) ([]byte, error) {
+	if s.chunks == nil {
+		return nil, errStreamClosed
+	}
+	last := index + streamPrefetchChunks
+	if last >= s.file.numChunks() {
+		last = s.file.numChunks() - 1
+	}
+	for i := range s.chunks {
+		if i < index || i > last {
+			s.chunks[i].cancel()
+			delete(s.chunks, i)
+		}
+	}
+	for i := index; i <= last; i++ {
+		if _, exists := s.chunks[i]; !exists {
+			s.chunks[i] = s.startChunk(i)
+		}
+	}
+
+	sc := s.chunks[index]
+	select {
+	case <-sc.d.downloadFinished:
+	case <-s.r.tg.StopChan():
+		return nil, errors.New("stream interrupted by shutdown")
+	}
+	if err := sc.d.Err(); err != nil {
+		// Drop the chunk so that the download is retried by the next read.
+		delete(s.chunks, index)
+		return nil, err
+	}
+	return sc.buf.Bytes(), nil
+}
+
+// Read implements the io.Reader interface.
+func (s *streamer) Read(p []byte) (int, error) {
+	if s.offset >= int64(s.file.size) {
+		return 0, io.EOF
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	index := uint64(s.offset) / s.file.chunkSize()
+	data, err := s.managedChunk(index)
+	if err != nil {
+		return 0, err
+	}
+	n := copy(p, data[uint64(s.offset)-index*s.file.chunkSize():])
+	s.offset += int64(n)
+	return n, nil
+}
+
+// Seek implements the io.Seeker interface. Seeking past the end of the file
+// is allowed; subsequent reads return io.EOF.
+func (s *streamer) Seek(offset int64, whence int) (int64, error) {
+	var newOffset int64
+	switch whence {
+	case io.SeekStart:
+		newOffset = offset
+	case io.SeekCurrent:
+		newOffset = s.offset + offset
+	case io.SeekEnd:
+		newOffset = int64(s.file.size) + offset
+	default:
+		return s.offset, errors.New("invalid whence")
+	}
+	if newOffset < 0 {
+		return s.offset, errNegativeOffset
+	}
+	s.offset = newOffset
+	return s.offset, nil
+}
+
+// Close cancels the downloads of the chunks of the stream.
+func (s *streamer) Close() error {
+	for _, sc := range s.chunks {
+		sc.cancel()
+	}
+	s.chunks = nil
+	return nil
+}
+
+// ETag returns an identifier of the contents of the streamed file.
+func (s *streamer) ETag() string {
+	return s.etag
+}
+
+// Streamer creates a modules.Streamer that reads the contents of the file at
+// siaPath.
+func (r *Renter) Streamer(siaPath string) (modules.Streamer, error) {
+	lockID := r.mu.RLock()
+	f, exists := r.files[siaPath]
+	var layout *file
+	if exists {
+		layout = r.downloadLayout(f)
+	}
+	r.mu.RUnlock(lockID)
+	if !exists {
+		return nil, ErrUnknownPath
+	}
+
+	// The entity tag is derived from the master key of the file, which is
+	// different for every upload.
+	return &streamer{
+		chunks: make(map[uint64]*streamChunk),
+		etag:   `"` + crypto.HashAll("etag", f.masterKey).String() + `"`,
+		file:   layout,
+		r:      r,
+	}, nil
+}
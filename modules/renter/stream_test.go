@@ -0,0 +1,41 @@
+package renter
+
+import (
+	"testing"
+)
+
+// TestStreamerClose checks that closing a streamer cancels the downloads of
+// its chunks.
+func TestStreamerClose(t *testing.T) {
+	rc, err := NewReplicationCode(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newFile("foo", rc, 100, 1000)
+	s := &streamer{
+		chunks: make(map[uint64]*streamChunk),
+		file:   f,
+	}
+	for i := uint64(0); i < 3; i++ {
+		buf := NewDownloadBufferWriter(f.chunkSize(), int64(i*f.chunkSize()))
+		s.chunks[i] = &streamChunk{d: newDownload(f, buf), buf: buf}
+	}
+	chunks := s.chunks
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for i, sc := range chunks {
+		select {
+		case <-sc.d.downloadFinished:
+		default:
+			t.Fatal("download of chunk", i, "was not stopped")
+		}
+		if err := sc.d.Err(); err != errDownloadCancelled {
+			t.Fatal("expected errDownloadCancelled, got", err)
+		}
+	}
+	if _, err := s.managedChunk(0); err != errStreamClosed {
+		t.Fatal("expected errStreamClosed, got", err)
+	}
+}
@@ -83,40 +83,56 @@ func validateSource(sourcePath string) error {
 	return nil
 }
 
-// Upload instructs the renter to start tracking a file. The renter will
-// automatically upload and repair tracked files using a background loop.
-func (r *Renter) Upload(up modules.FileUploadParams) error {
+// managedValidateUploadParams enforces the rules that apply to every upload,
+// regardless of where the data comes from. Missing upload params are filled
+// in with sensible defaults.
+func (r *Renter) managedValidateUploadParams(up *modules.FileUploadParams) error {
 	// Enforce nickname rules.
 	if err := validateSiapath(up.SiaPath); err != nil {
 		return err
 	}
-	// Enforce source rules.
-	if err := validateSource(up.Source); err != nil {
-		return err
-	}
 
-	// Check for a nickname conflict.
+	// Check for a nickname conflict with an existing file or directory.
 	lockID := r.mu.RLock()
-	_, exists := r.files[up.SiaPath]
+	err := r.checkPathAvailable(up.SiaPath)
 	r.mu.RUnlock(lockID)
-	if exists {
-		return ErrPathOverload
-	}
-
-	// Fill in any missing upload params with sensible defaults.
-	fileInfo, err := os.Stat(up.Source)
 	if err != nil {
 		return err
 	}
+
+	// Fill in any missing upload params with sensible defaults.
 	if up.ErasureCode == nil {
 		up.ErasureCode, _ = NewRSCode(defaultDataPieces, defaultParityPieces)
 	}
 
+	return r.checkContractsForErasureCode(up.ErasureCode)
+}
+
+// checkContractsForErasureCode returns an error if the renter does not have
+// enough contracts to upload a file using the provided erasure code.
+func (r *Renter) checkContractsForErasureCode(ec modules.ErasureCoder) error {
 	// Check that we have contracts to upload to. We need at least (data +
 	// parity/2) contracts; since NumPieces = data + parity, we arrive at the
 	// expression below.
-	if nContracts := len(r.hostContractor.Contracts()); nContracts < (up.ErasureCode.NumPieces()+up.ErasureCode.MinPieces())/2 && build.Release != "testing" {
-		return fmt.Errorf("not enough contracts to upload file: got %v, needed %v", nContracts, (up.ErasureCode.NumPieces()+up.ErasureCode.MinPieces())/2)
+	if nContracts := len(r.hostContractor.Contracts()); nContracts < (ec.NumPieces()+ec.MinPieces())/2 && build.Release != "testing" {
+		return fmt.Errorf("not enough contracts to upload file: got %v, needed %v", nContracts, (ec.NumPieces()+ec.MinPieces())/2)
+	}
+	return nil
+}
+
+// Upload instructs the renter to start tracking a file. The renter will
+// automatically upload and repair tracked files using a background loop.
+func (r *Renter) Upload(up modules.FileUploadParams) error {
+	// Enforce source rules.
+	if err := validateSource(up.Source); err != nil {
+		return err
+	}
+	if err := r.managedValidateUploadParams(&up); err != nil {
+		return err
+	}
+	fileInfo, err := os.Stat(up.Source)
+	if err != nil {
+		return err
 	}
 
 	// Create file object.
@@ -124,10 +140,11 @@ func (r *Renter) Upload(up modules.FileUploadParams) error {
 	f.mode = uint32(fileInfo.Mode())
 
 	// Add file to renter.
-	lockID = r.mu.Lock()
-	r.files[up.SiaPath] = f
+	lockID := r.mu.Lock()
+	r.addFileEntry(f)
 	r.tracking[up.SiaPath] = trackedFile{
 		RepairPath: up.Source,
+		Priority:   up.Priority,
 	}
 	r.saveSync()
 	err = r.saveFile(f)
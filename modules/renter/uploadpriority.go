@@ -0,0 +1,97 @@
+package renter
+
+import (
+	"errors"
+)
+
+var (
+	errNoTrackedFiles = errors.New("no files at that path are maintained by the renter")
+)
+
+// trackedFilesAt returns the siapaths of the tracked files at siaPath. If
+// siaPath refers to a directory, the tracked files that it contains are
+// returned. The renter's lock must be held by the caller.
+func (r *Renter) trackedFilesAt(siaPath string) ([]string, error) {
+	var names []string
+	if _, exists := r.files[siaPath]; exists {
+		names = []string{siaPath}
+	} else if r.isDir(siaPath) {
+		names = r.filesInDir(siaPath)
+	} else {
+		return nil, ErrUnknownPath
+	}
+
+	var tracked []string
+	for _, name := range names {
+		if _, exists := r.tracking[name]; exists {
+			tracked = append(tracked, name)
+		}
+	}
+	if len(tracked) == 0 {
+		return nil, errNoTrackedFiles
+	}
+	return tracked, nil
+}
+
+// signalRebuildHeap tells the repair loop to rebuild its chunk heap, so that
+// changes to the priority or paused state of files take effect before the
+// next scheduled rebuild.
+func (r *Renter) signalRebuildHeap() {
+	select {
+	case r.rebuildHeap <- struct{}{}:
+	default:
+	}
+}
+
+// managedUpdateTracking applies fn to the metadata of every tracked file at
+// siaPath and saves the renter.
+func (r *Renter) managedUpdateTracking(siaPath string, fn func(*trackedFile)) error {
+	lockID := r.mu.Lock()
+	defer r.mu.Unlock(lockID)
+	names, err := r.trackedFilesAt(siaPath)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		tf := r.tracking[name]
+		fn(&tf)
+		r.tracking[name] = tf
+	}
+	return r.saveSync()
+}
+
+// SetFilePriority sets the upload priority of the file at siaPath, or of every
+// file in the directory at siaPath. Chunks of files with a higher priority are
+// uploaded and repaired first.
+func (r *Renter) SetFilePriority(siaPath string, priority int) error {
+	err := r.managedUpdateTracking(siaPath, func(tf *trackedFile) {
+		tf.Priority = priority
+	})
+	if err != nil {
+		return err
+	}
+	r.signalRebuildHeap()
+	return nil
+}
+
+// PauseUploads stops the renter from uploading and repairing the file at
+// siaPath, or every file in the directory at siaPath. Chunks that are already
+// being uploaded are finished.
+func (r *Renter) PauseUploads(siaPath string) error {
+	return r.managedUpdateTracking(siaPath, func(tf *trackedFile) {
+		tf.Paused = true
+	})
+}
+
+// ResumeUploads resumes uploading and repairing the file at siaPath, or every
+// file in the directory at siaPath.
+func (r *Renter) ResumeUploads(siaPath string) error {
+	err := r.managedUpdateTracking(siaPath, func(tf *trackedFile) {
+		tf.Paused = false
+	})
+	if err != nil {
+		return err
+	}
+	r.signalRebuildHeap()
+	return nil
+}
@@ -0,0 +1,103 @@
+package renter
+
+import (
+	"container/heap"
+	"testing"
+)
+
+// TestChunkHeapPriority checks that the chunk heap orders chunks by the
+// priority of their file first, and by their upload progress second.
+func TestChunkHeapPriority(t *testing.T) {
+	ch := new(chunkHeap)
+	heap.Init(ch)
+	heap.Push(ch, &unfinishedChunk{priority: 0, piecesCompleted: 0, piecesNeeded: 10})
+	heap.Push(ch, &unfinishedChunk{priority: 5, piecesCompleted: 8, piecesNeeded: 10})
+	heap.Push(ch, &unfinishedChunk{priority: 5, piecesCompleted: 2, piecesNeeded: 10})
+	heap.Push(ch, &unfinishedChunk{priority: -1, piecesCompleted: 0, piecesNeeded: 10})
+
+	expected := []struct {
+		priority        int
+		piecesCompleted int
+	}{{5, 2}, {5, 8}, {0, 0}, {-1, 0}}
+	for i, e := range expected {
+		uc := heap.Pop(ch).(*unfinishedChunk)
+		if uc.priority != e.priority || uc.piecesCompleted != e.piecesCompleted {
+			t.Fatalf("chunk %v has priority %v and %v completed pieces, expected %v and %v", i, uc.priority, uc.piecesCompleted, e.priority, e.piecesCompleted)
+		}
+	}
+}
+
+// TestRenterUploadPriority probes the SetFilePriority, PauseUploads and
+// ResumeUploads methods of the renter.
+func TestRenterUploadPriority(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	// Add two tracked files in a directory and an untracked file.
+	rsc, _ := NewRSCode(1, 1)
+	id := rt.renter.mu.Lock()
+	for _, name := range []string{"dir/foo", "dir/bar", "baz"} {
+		rt.renter.addFileEntry(newFile(name, rsc, pieceSize, 1))
+	}
+	rt.renter.tracking["dir/foo"] = trackedFile{}
+	rt.renter.tracking["dir/bar"] = trackedFile{}
+	rt.renter.mu.Unlock(id)
+
+	// Untracked and unknown files cannot be changed.
+	if err := rt.renter.SetFilePriority("baz", 1); err != errNoTrackedFiles {
+		t.Fatal("expected errNoTrackedFiles, got", err)
+	}
+	if err := rt.renter.PauseUploads("qux"); err != ErrUnknownPath {
+		t.Fatal("expected ErrUnknownPath, got", err)
+	}
+
+	// Change the priority of a single file, then of the directory.
+	if err := rt.renter.SetFilePriority("dir/foo", 3); err != nil {
+		t.Fatal(err)
+	}
+	id = rt.renter.mu.RLock()
+	foo, bar := rt.renter.tracking["dir/foo"], rt.renter.tracking["dir/bar"]
+	rt.renter.mu.RUnlock(id)
+	if foo.Priority != 3 || bar.Priority != 0 {
+		t.Fatal("priority was not set on the file:", foo, bar)
+	}
+	if err := rt.renter.SetFilePriority("dir", 7); err != nil {
+		t.Fatal(err)
+	}
+	id = rt.renter.mu.RLock()
+	foo, bar = rt.renter.tracking["dir/foo"], rt.renter.tracking["dir/bar"]
+	rt.renter.mu.RUnlock(id)
+	if foo.Priority != 7 || bar.Priority != 7 {
+		t.Fatal("priority was not set on the directory:", foo, bar)
+	}
+
+	// Paused files should not be added to the chunk heap.
+	if err := rt.renter.PauseUploads("dir"); err != nil {
+		t.Fatal(err)
+	}
+	id = rt.renter.mu.Lock()
+	chunks := rt.renter.buildUnfinishedChunks(rt.renter.files["dir/foo"], nil)
+	rt.renter.mu.Unlock(id)
+	if len(chunks) != 0 {
+		t.Fatal("chunks of a paused file were added to the heap")
+	}
+	if err := rt.renter.ResumeUploads("dir/foo"); err != nil {
+		t.Fatal(err)
+	}
+	id = rt.renter.mu.Lock()
+	chunks = rt.renter.buildUnfinishedChunks(rt.renter.files["dir/foo"], nil)
+	bar = rt.renter.tracking["dir/bar"]
+	rt.renter.mu.Unlock(id)
+	if len(chunks) != 1 || chunks[0].priority != 7 {
+		t.Fatal("chunks of a resumed file were not added to the heap with its priority")
+	}
+	if !bar.Paused {
+		t.Fatal("resuming a file resumed another file")
+	}
+}
@@ -0,0 +1,199 @@
+package renter
+
+import (
+	"errors"
+	"io"
+
+	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+)
+
+var (
+	errStreamChunkUnavailable = errors.New("unable to upload enough pieces of a streamed chunk to make it available")
+	errStreamInterrupted      = errors.New("stream upload interrupted by stop call")
+)
+
+// managedAcquireStreamMemory blocks until the requested amount of memory has
+// been acquired from the renter's memory pool. The repair loop draws from the
+// same pool, so the memory notification is passed along after the memory has
+// been acquired in case the repair loop is also waiting on it.
+func (r *Renter) managedAcquireStreamMemory(amt uint64) error {
+	for !r.managedTryMemoryAvailableSub(amt) {
+		select {
+		case <-r.newMemory:
+		case <-r.tg.StopChan():
+			return errStreamInterrupted
+		}
+	}
+	select {
+	case r.newMemory <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// managedUploadStreamChunk erasure codes and distributes a single chunk of a
+// streamed upload, blocking until the chunk is available on the network.
+// chunk.logicalChunkData must already contain the data that was read from the
+// stream, and the memory for the chunk must already have been acquired.
+func (r *Renter) managedUploadStreamChunk(chunk *unfinishedChunk) error {
+	// The chunk is new, so there is nothing to download; the logical data is
+	// already in memory.
+	if !r.managedFetchAndRepairChunk(chunk) {
+		// Release any data that did not get distributed to workers.
+		r.managedMemoryAvailableAdd(chunk.memoryNeeded - chunk.memoryReleased)
+		return errStreamChunkUnavailable
+	}
+
+	// It is possible that there were no workers to distribute the chunk to, in
+	// which case nobody else will close the availableChan.
+	chunk.mu.Lock()
+	chunk.notifyAvailable()
+	chunk.mu.Unlock()
+
+	select {
+	case <-chunk.availableChan:
+	case <-r.tg.StopChan():
+		return errStreamInterrupted
+	}
+	chunk.mu.Lock()
+	available := chunk.piecesCompleted >= chunk.minimumPieces
+	chunk.mu.Unlock()
+	if !available {
+		return errStreamChunkUnavailable
+	}
+	return nil
+}
+
+// managedUploadStreamChunks reads the stream chunk by chunk, growing the file
+// and uploading each chunk as it arrives.
+func (r *Renter) managedUploadStreamChunks(f *file, reader io.Reader) error {
+	// Every chunk can be uploaded to any of the hosts that we have contracts
+	// with.
+	hosts := r.managedRefreshHostsAndWorkers()
+	chunkSize := f.chunkSize()
+	for index := uint64(0); ; index++ {
+		chunk := &unfinishedChunk{
+			renterFile: f,
+
+			index:  index,
+			length: chunkSize,
+			offset: int64(index * chunkSize),
+
+			memoryNeeded:  f.pieceSize*uint64(f.erasureCode.NumPieces()+f.erasureCode.MinPieces()) + uint64(f.erasureCode.NumPieces()*crypto.TwofishOverhead),
+			minimumPieces: f.erasureCode.MinPieces(),
+			piecesNeeded:  f.erasureCode.NumPieces(),
+			pieceUsage:    make([]bool, f.erasureCode.NumPieces()),
+			unusedHosts:   make(map[string]struct{}),
+			availableChan: make(chan struct{}),
+		}
+		for host := range hosts {
+			chunk.unusedHosts[host] = struct{}{}
+		}
+
+		// Read the next chunk from the stream. The final chunk is padded with
+		// zeroes, the same way that the final chunk of a file on disk is.
+		err := r.managedAcquireStreamMemory(chunk.memoryNeeded)
+		if err != nil {
+			return err
+		}
+		chunk.logicalChunkData = make([]byte, chunkSize)
+		n, err := io.ReadFull(reader, chunk.logicalChunkData)
+		if err == io.EOF && index > 0 {
+			// The stream ended exactly at the end of the previous chunk.
+			// Empty streams still get a single chunk, the same as empty
+			// files.
+			chunk.logicalChunkData = nil
+			r.managedMemoryAvailableAdd(chunk.memoryNeeded)
+			break
+		} else if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			chunk.logicalChunkData = nil
+			r.managedMemoryAvailableAdd(chunk.memoryNeeded)
+			return err
+		}
+
+		// Grow the file to include the new data.
+		lockID := r.mu.Lock()
+		f.mu.Lock()
+		f.size += uint64(n)
+		err = r.saveFile(f)
+		f.mu.Unlock()
+		r.mu.Unlock(lockID)
+		if err != nil {
+			chunk.logicalChunkData = nil
+			r.managedMemoryAvailableAdd(chunk.memoryNeeded)
+			return err
+		}
+
+		err = r.managedUploadStreamChunk(chunk)
+		if err != nil {
+			return err
+		}
+		if uint64(n) < chunkSize {
+			// The stream has been read to completion.
+			break
+		}
+	}
+	return nil
+}
+
+// UploadStreamFromReader reads from the provided reader until io.EOF is
+// reached and uploads the data to the Sia network. Chunks are erasure coded as
+// soon as they have been read, and the next chunk is only read once the
+// previous chunk is available on the network.
+//
+// Streamed files have no local copy, so they are tracked without a repair
+// path. Future repairs fetch the logical data from the hosts instead.
+func (r *Renter) UploadStreamFromReader(up modules.FileUploadParams, reader io.Reader) error {
+	if err := r.managedValidateUploadParams(&up); err != nil {
+		return err
+	}
+
+	// Create the file object. The size of the file is not known in advance,
+	// it grows as chunks are read from the stream.
+	f := newFile(up.SiaPath, up.ErasureCode, pieceSize, 0)
+	f.mode = defaultFilePerm
+
+	// Add the file to the renter. The file is only tracked once the stream has
+	// been fully uploaded, otherwise the repair loop would try to repair the
+	// chunks that are still being uploaded.
+	lockID := r.mu.Lock()
+	if err := r.checkPathAvailable(up.SiaPath); err != nil {
+		r.mu.Unlock(lockID)
+		return err
+	}
+	r.addFileEntry(f)
+	err := r.saveFile(f)
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return err
+	}
+
+	// Upload the stream. If the stream could not be uploaded, the partial
+	// file is removed from the renter again.
+	err = r.managedUploadStreamChunks(f, reader)
+	if err != nil {
+		if delErr := r.DeleteFile(up.SiaPath); delErr != nil {
+			r.log.Println("WARN: unable to remove partially streamed file:", delErr)
+		}
+		return err
+	}
+
+	// Start tracking the file so that the repair loop will bring the file to
+	// full redundancy.
+	lockID = r.mu.Lock()
+	r.tracking[up.SiaPath] = trackedFile{Priority: up.Priority}
+	err = r.saveSync()
+	r.mu.Unlock(lockID)
+	if err != nil {
+		return err
+	}
+
+	// Send the upload to the repair loop.
+	select {
+	case r.newUploads <- f:
+	case <-r.tg.StopChan():
+		return errStreamInterrupted
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package renter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/fastrand"
+)
+
+// TestUnfinishedChunkNotifyAvailable checks that the availableChan of a chunk
+// is closed exactly when the chunk becomes available or runs out of workers.
+func TestUnfinishedChunkNotifyAvailable(t *testing.T) {
+	isClosed := func(c chan struct{}) bool {
+		select {
+		case <-c:
+			return true
+		default:
+			return false
+		}
+	}
+
+	// Chunks without an availableChan should be ignored.
+	uc := &unfinishedChunk{minimumPieces: 2}
+	uc.notifyAvailable()
+
+	// The chunk should not be available while workers are still processing
+	// it.
+	uc = &unfinishedChunk{
+		minimumPieces:    2,
+		workersRemaining: 3,
+		availableChan:    make(chan struct{}),
+	}
+	uc.piecesCompleted++
+	uc.notifyAvailable()
+	if isClosed(uc.availableChan) {
+		t.Fatal("chunk was signaled as available with too few pieces")
+	}
+	uc.piecesCompleted++
+	uc.notifyAvailable()
+	if !isClosed(uc.availableChan) {
+		t.Fatal("chunk was not signaled as available")
+	}
+	// Calling notifyAvailable again should not close the channel twice.
+	uc.workersRemaining = 0
+	uc.notifyAvailable()
+
+	// A chunk without remaining workers should also be signaled.
+	uc = &unfinishedChunk{
+		minimumPieces:    2,
+		workersRemaining: 1,
+		availableChan:    make(chan struct{}),
+	}
+	uc.workersRemaining--
+	uc.notifyAvailable()
+	if !isClosed(uc.availableChan) {
+		t.Fatal("chunk without workers was not signaled")
+	}
+}
+
+// TestRenterUploadStreamNoWorkers checks that a streamed upload fails cleanly
+// if there are no workers to upload the chunks to.
+func TestRenterUploadStreamNoWorkers(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	rt, err := newRenterTester(t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rt.Close()
+
+	up := modules.FileUploadParams{
+		SiaPath: "stream",
+	}
+	data := fastrand.Bytes(int(pieceSize) * 3)
+	err = rt.renter.UploadStreamFromReader(up, bytes.NewReader(data))
+	if err != errStreamChunkUnavailable {
+		t.Fatal("expected errStreamChunkUnavailable, got", err)
+	}
+
+	// The partial file should have been removed.
+	if len(rt.renter.FileList()) != 0 {
+		t.Fatal("partially streamed file was not removed from the renter")
+	}
+
+	// All of the memory should have been returned.
+	if rt.renter.managedMemoryAvailableGet() != defaultMemory {
+		t.Fatal("memory was not returned after failed stream:", rt.renter.managedMemoryAvailableGet(), defaultMemory)
+	}
+}
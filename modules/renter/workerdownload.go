@@ -1,7 +1,11 @@
 package renter
 
 import (
+	"time"
+
 	"github.com/NebulousLabs/Sia/crypto"
+	"github.com/NebulousLabs/Sia/modules"
+	"github.com/NebulousLabs/Sia/modules/renter/contractor"
 	"github.com/NebulousLabs/Sia/types"
 )
 
@@ -14,6 +18,12 @@ type (
 		dataRoot   crypto.Hash
 		pieceIndex uint64
 
+		// If partial is set, only length bytes of the piece starting at
+		// offset are downloaded, instead of the whole sector.
+		partial bool
+		offset  uint64
+		length  uint64
+
 		chunkDownload *chunkDownload
 
 		// resultChan is a channel that the worker will use to return the
@@ -28,16 +38,57 @@ type (
 		err           error
 		pieceIndex    uint64
 		workerID      types.FileContractID
+
+		// partial is set if data contains the nonce of the piece followed
+		// by part of the encrypted piece, instead of the whole sector.
+		partial bool
 	}
 )
 
+// downloadPieceRange downloads length bytes of the plaintext of a piece
+// starting at offset. The nonce of the piece is returned, followed by the
+// encrypted range.
+func downloadPieceRange(d contractor.Downloader, root crypto.Hash, offset, length uint64) ([]byte, error) {
+	// The nonce is at the start of the sector. If the range is close to the
+	// start, both are downloaded at once.
+	rangeOffset := crypto.TwofishNonceSize + offset
+	if rangeOffset <= length {
+		data, err := d.Range(root, 0, rangeOffset+length)
+		if err != nil {
+			return nil, err
+		}
+		return append(data[:crypto.TwofishNonceSize:crypto.TwofishNonceSize], data[rangeOffset:]...), nil
+	}
+	nonce, err := d.Range(root, 0, crypto.TwofishNonceSize)
+	if err != nil {
+		return nil, err
+	}
+	data, err := d.Range(root, rangeOffset, length)
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, data...), nil
+}
+
 // download will perform some download work.
 func (w *worker) download(dw downloadWork) {
+	// Wait until the sector can be downloaded without exceeding the download
+	// rate limit. The connection is opened afterwards, so that it does not sit
+	// idle.
+	size := modules.SectorSize
+	if dw.partial {
+		size = crypto.TwofishNonceSize + dw.length
+	}
+	err := w.renter.downloadLimit.Wait(size, w.renter.tg.StopChan())
+	if err != nil {
+		return
+	}
+
 	d, err := w.renter.hostContractor.Downloader(w.contract.ID, w.renter.tg.StopChan())
 	if err != nil {
 		go func() {
 			select {
-			case dw.resultChan <- finishedDownload{dw.chunkDownload, nil, err, dw.pieceIndex, w.contract.ID}:
+			case dw.resultChan <- finishedDownload{dw.chunkDownload, nil, err, dw.pieceIndex, w.contract.ID, dw.partial}:
 			case <-w.renter.tg.StopChan():
 			}
 		}()
@@ -45,10 +96,21 @@ func (w *worker) download(dw downloadWork) {
 	}
 	defer d.Close()
 
-	data, err := d.Sector(dw.dataRoot)
+	// Only downloads of whole sectors are recorded, so that the throughput of
+	// hosts is measured on transfers of the same size.
+	var data []byte
+	if dw.partial {
+		data, err = downloadPieceRange(d, dw.dataRoot, dw.offset, dw.length)
+	} else {
+		start := time.Now()
+		data, err = d.Sector(dw.dataRoot)
+		if err == nil {
+			w.renter.hostDB.RecordDownload(w.hostPubKey, modules.SectorSize, time.Since(start))
+		}
+	}
 	go func() {
 		select {
-		case dw.resultChan <- finishedDownload{dw.chunkDownload, data, err, dw.pieceIndex, w.contract.ID}:
+		case dw.resultChan <- finishedDownload{dw.chunkDownload, data, err, dw.pieceIndex, w.contract.ID, dw.partial}:
 		case <-w.renter.tg.StopChan():
 		}
 	}()
@@ -5,11 +5,19 @@ import (
 )
 
 // dropChunk will remove a worker from the responsibility of tracking a chunk.
+// Once the last worker has dropped a chunk that was being repaired, the outcome
+// of the repair is recorded.
 func (w *worker) dropChunk(uc *unfinishedChunk) {
 	uc.mu.Lock()
 	uc.workersRemaining--
+	uc.notifyAvailable()
+	repairDone := uc.repairing && uc.workersRemaining == 0
+	repaired := uc.repaired()
 	uc.mu.Unlock()
 	w.renter.managedReleaseIdleChunkPieces(uc)
+	if repairDone {
+		w.renter.managedRecordRepair(uc.renterFile, repaired)
+	}
 	w.renter.heapWG.Done()
 }
 
@@ -160,6 +168,16 @@ func (w *worker) uploadFailed(uc *unfinishedChunk, pieceIndex uint64) {
 
 // managedUpload will perform some upload work.
 func (w *worker) managedUpload(uc *unfinishedChunk, pieceIndex uint64) {
+	// Wait until the piece can be uploaded without exceeding the upload rate
+	// limit. The connection is opened afterwards, so that it does not sit idle.
+	err := w.renter.uploadLimit.Wait(uint64(len(uc.physicalChunkData[pieceIndex])), w.renter.tg.StopChan())
+	if err != nil {
+		w.mu.Lock()
+		w.uploadFailed(uc, pieceIndex)
+		w.mu.Unlock()
+		return
+	}
+
 	// Open an editing connection to the host.
 	e, err := w.renter.hostContractor.Editor(w.contract.ID, w.renter.tg.StopChan())
 	if err != nil {
@@ -172,7 +190,9 @@ func (w *worker) managedUpload(uc *unfinishedChunk, pieceIndex uint64) {
 	defer e.Close()
 
 	// Perform the upload, and update the failure stats based on the success of
-	// the upload attempt.
+	// the upload attempt. Successful uploads update the throughput of the
+	// host in the hostdb.
+	start := time.Now()
 	root, err := e.Upload(uc.physicalChunkData[pieceIndex])
 	if err != nil {
 		w.renter.log.Debugln("Worker failed to upload via the editor:", err)
@@ -181,21 +201,21 @@ func (w *worker) managedUpload(uc *unfinishedChunk, pieceIndex uint64) {
 		w.mu.Unlock()
 		return
 	}
+	w.renter.hostDB.RecordUpload(w.hostPubKey, uint64(len(uc.physicalChunkData[pieceIndex])), time.Since(start))
 	w.mu.Lock()
 	w.uploadConsecutiveFailures = 0
 	w.mu.Unlock()
 
 	// Update the renter metadata.
-	addr := e.Address()
 	endHeight := e.EndHeight()
 	id := w.renter.mu.Lock()
 	uc.renterFile.mu.Lock()
 	contract, exists := uc.renterFile.contracts[w.contract.ID]
 	if !exists {
 		contract = fileContract{
-			ID:          w.contract.ID,
-			IP:          addr,
-			WindowStart: endHeight,
+			ID:            w.contract.ID,
+			HostPublicKey: w.contract.HostPublicKey,
+			WindowStart:   endHeight,
 		}
 	}
 	contract.Pieces = append(contract.Pieces, pieceData{
@@ -204,7 +224,15 @@ func (w *worker) managedUpload(uc *unfinishedChunk, pieceIndex uint64) {
 		MerkleRoot: root,
 	})
 	uc.renterFile.contracts[w.contract.ID] = contract
-	w.renter.saveFile(uc.renterFile)
+	if tf, exists := w.renter.tracking[uc.renterFile.name]; exists {
+		tf.LastRepair = time.Now()
+		w.renter.tracking[uc.renterFile.name] = tf
+	}
+	// The file may have been replaced while the piece was uploading, in which
+	// case it must not overwrite the file on disk.
+	if w.renter.files[uc.renterFile.name] == uc.renterFile {
+		w.renter.saveFile(uc.renterFile)
+	}
 	uc.renterFile.mu.Unlock()
 	w.renter.mu.Unlock(id)
 
@@ -216,6 +244,7 @@ func (w *worker) managedUpload(uc *unfinishedChunk, pieceIndex uint64) {
 	uc.piecesCompleted++
 	uc.physicalChunkData[pieceIndex] = nil
 	uc.memoryReleased += uint64(releaseSize)
+	uc.notifyAvailable()
 	uc.mu.Unlock()
 	w.renter.managedMemoryAvailableAdd(uint64(releaseSize))
 	w.dropChunk(uc)
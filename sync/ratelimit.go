@@ -0,0 +1,86 @@
+package sync
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimitInterrupted is returned by RateLimit.Wait if the wait is
+// cancelled.
+var ErrRateLimitInterrupted = errors.New("rate limited transfer was interrupted")
+
+// A RateLimit limits the average rate at which bytes are transferred. Before a
+// transfer, the caller reserves its bytes with Wait, which blocks until the
+// transfer fits within the limit. Every reservation pushes back the time at
+// which the next transfer may start by the time that its bytes take at the
+// maximum rate. A RateLimit is safe for concurrent use.
+type RateLimit struct {
+	// bytesPerSecond is the maximum average transfer rate. A limit of zero
+	// disables rate limiting.
+	bytesPerSecond uint64
+
+	// next is the time at which the next transfer may start.
+	next time.Time
+	mu   sync.Mutex
+}
+
+// NewRateLimit returns a RateLimit with the supplied maximum rate.
+func NewRateLimit(bytesPerSecond uint64) *RateLimit {
+	return &RateLimit{
+		bytesPerSecond: bytesPerSecond,
+	}
+}
+
+// Limit returns the maximum rate of rl.
+func (rl *RateLimit) Limit() uint64 {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.bytesPerSecond
+}
+
+// SetLimit changes the maximum rate of rl. When the limit is raised, the
+// backlog of reserved bytes is rescaled to the new rate, so that future
+// transfers do not wait out reservations made at the old rate. Bytes reserved
+// before the limit is lowered keep their reservations, as do transfers that
+// are already waiting. Removing the limit drops all reservations.
+func (rl *RateLimit) SetLimit(bytesPerSecond uint64) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	now := time.Now()
+	switch {
+	case bytesPerSecond == 0 || rl.bytesPerSecond == 0:
+		rl.next = time.Time{}
+	case bytesPerSecond > rl.bytesPerSecond && rl.next.After(now):
+		backlog := float64(rl.next.Sub(now)) * float64(rl.bytesPerSecond) / float64(bytesPerSecond)
+		rl.next = now.Add(time.Duration(backlog))
+	}
+	rl.bytesPerSecond = bytesPerSecond
+}
+
+// Wait blocks until n bytes can be transferred without exceeding the rate
+// limit, or until cancel is closed.
+func (rl *RateLimit) Wait(n uint64, cancel <-chan struct{}) error {
+	rl.mu.Lock()
+	if rl.bytesPerSecond == 0 {
+		rl.mu.Unlock()
+		return nil
+	}
+	now := time.Now()
+	start := rl.next
+	if start.Before(now) {
+		start = now
+	}
+	rl.next = start.Add(time.Duration(n * uint64(time.Second) / rl.bytesPerSecond))
+	rl.mu.Unlock()
+
+	if !start.After(now) {
+		return nil
+	}
+	select {
+	case <-time.After(start.Sub(now)):
+		return nil
+	case <-cancel:
+		return ErrRateLimitInterrupted
+	}
+}
@@ -0,0 +1,67 @@
+package sync
+
+import (
+	"testing"
+	"time"
+)
+
+// TestRateLimit probes the RateLimit type.
+func TestRateLimit(t *testing.T) {
+	if testing.Short() {
+		t.SkipNow()
+	}
+	t.Parallel()
+
+	// Without a limit, transfers should not wait.
+	rl := NewRateLimit(0)
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if err := rl.Wait(1e9, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if time.Since(start) > 100*time.Millisecond {
+		t.Fatal("unlimited transfers were delayed")
+	}
+
+	// With a limit of 1000 bytes per second, the first transfer starts
+	// immediately and the following transfers wait for the previous ones.
+	rl.SetLimit(1000)
+	if rl.Limit() != 1000 {
+		t.Fatal("limit was not set")
+	}
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		if err := rl.Wait(250, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond || elapsed > time.Second {
+		t.Fatal("transfers were not limited correctly, took", elapsed)
+	}
+
+	// A waiting transfer should be interrupted by closing the cancel channel.
+	rl.SetLimit(1)
+	rl.Wait(10, nil)
+	cancel := make(chan struct{})
+	close(cancel)
+	if err := rl.Wait(10, cancel); err != ErrRateLimitInterrupted {
+		t.Fatal("expected ErrRateLimitInterrupted, got", err)
+	}
+
+	// Removing the limit should drop any pending reservations.
+	rl.SetLimit(0)
+	start = time.Now()
+	if err := rl.Wait(10, nil); err != nil || time.Since(start) > 100*time.Millisecond {
+		t.Fatal("transfer was delayed after the limit was removed:", err)
+	}
+
+	// Raising the limit should shorten the pending reservations.
+	rl.SetLimit(1)
+	rl.Wait(1000, nil)
+	rl.SetLimit(10e6)
+	start = time.Now()
+	if err := rl.Wait(10, nil); err != nil || time.Since(start) > 100*time.Millisecond {
+		t.Fatal("transfer was delayed after the limit was raised:", err)
+	}
+}